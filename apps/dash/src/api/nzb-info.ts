@@ -4,26 +4,55 @@ import { api } from "@/lib/api";
 
 export type NZBContentFile = {
   alias?: string;
+  crc?: "mismatch" | "ok" | "unverified";
+  disc?: boolean;
   errors?: string[];
   files?: NZBContentFile[];
   name: string;
   parts?: NZBContentFile[];
+  reasons?: NZBContentFileReason[];
   size: number;
   streamable: boolean;
   type: string;
   volume?: number;
 };
 
+export type NZBContentFileReason = {
+  code: string;
+  message: string;
+};
+
+export type NZBEpisode = {
+  episodes?: number[];
+  name: string;
+  path: string;
+};
+
+export type NZBSeasons = {
+  items: { episodes: NZBEpisode[]; season: number }[];
+  other: NZBEpisode[];
+};
+
+export type NZBHealth = {
+  failed: number;
+  sampled: number;
+  score: number;
+};
+
 export type NZBInfoItem = {
   cached: boolean;
   created_at: string;
   date: string;
+  downloaded_bytes: number;
   file_count: number;
   files: null | NZBContentFile[];
   hash: string;
+  health?: NZBHealth;
   id: string;
   name: string;
   password: string;
+  providers: string[];
+  seasons?: NZBSeasons;
   size: number;
   status: string;
   streamable: boolean;
@@ -40,6 +69,14 @@ export function useNzbInfo() {
   });
 }
 
+export function useNzbInfoItem(id?: string) {
+  return useQuery({
+    enabled: Boolean(id),
+    queryFn: () => getNzbInfoItem(id!),
+    queryKey: ["/usenet/nzb", id],
+  });
+}
+
 export function useNzbInfoMutation() {
   const remove = useMutation({
     mutationFn: deleteNzbInfoItem,
@@ -58,13 +95,28 @@ export function useNzbInfoMutation() {
     },
   });
 
-  return { remove, requeue };
+  const update = useMutation({
+    mutationFn: updateNzbInfoItem,
+    onSuccess: async (data, _, __, ctx) => {
+      ctx.client.setQueryData<NZBInfoItem[]>(["/usenet/nzb"], (list) =>
+        list?.map((item) => (item.id === data.id ? data : item)),
+      );
+      await ctx.client.invalidateQueries({ queryKey: ["/usenet/queue"] });
+    },
+  });
+
+  return { remove, requeue, update };
 }
 
 async function deleteNzbInfoItem(id: string) {
   await api(`DELETE /usenet/nzb/${id}`);
 }
 
+async function getNzbInfoItem(id: string) {
+  const { data } = await api<NZBInfoItem>(`/usenet/nzb/${id}`);
+  return data;
+}
+
 async function getNzbInfoItems() {
   const { data } = await api<NZBInfoItem[]>("/usenet/nzb");
   return data;
@@ -73,3 +125,18 @@ async function getNzbInfoItems() {
 async function requeueNzbInfoItem(id: string) {
   await api(`POST /usenet/nzb/${id}/requeue`);
 }
+
+async function updateNzbInfoItem({
+  id,
+  ...payload
+}: {
+  id: string;
+  password?: string;
+  providers?: string[];
+  requeue?: boolean;
+}) {
+  const { data } = await api<NZBInfoItem>(`PATCH /usenet/nzb/${id}`, {
+    body: payload,
+  });
+  return data;
+}
@@ -11,6 +11,7 @@ export type ParsedNZB = {
 export type UsenetConfig = {
   indexer_request_header: {
     grab: Record<string, string>;
+    grab_by_host: Record<string, Record<string, string>>;
     query: Record<string, Record<string, string>>;
   };
   max_connection_per_stream: number;
@@ -25,6 +26,7 @@ export type UsenetPoolInfo = {
   active_connections: number;
   idle_connections: number;
   max_connections: number;
+  online_providers: number;
   providers: UsenetPoolProviderInfo[];
   total_providers: number;
 };
@@ -36,8 +38,10 @@ export type UsenetPoolProviderInfo = {
   is_backup: boolean;
   max_connections: number;
   priority: number;
+  selections: number;
   state: "auth_failed" | "connecting" | "disabled" | "offline" | "online";
   total_connections: number;
+  weight: number;
 };
 
 type ParsedNZBFile = {
@@ -113,12 +117,31 @@ async function parseNzbFile(file: File) {
   return data;
 }
 
+export type NZBUploadDuplicate = {
+  action: "linked" | "merged";
+  added_segments?: number;
+  id: string;
+  name: string;
+};
+
+export type NZBUploadEncryption = {
+  header_encrypted: boolean;
+  name: string;
+  password_required: boolean;
+  warning?: string;
+};
+
 async function uploadNzbFile({ file, name }: { file: File; name: string }) {
   const formData = new FormData();
   formData.append("file", file);
   formData.append("name", name);
+  formData.append("probe_encryption", "true");
 
-  const { data } = await api<{ id: string }>("POST /usenet/nzb/upload", {
+  const { data } = await api<{
+    duplicate?: NZBUploadDuplicate;
+    encryption?: NZBUploadEncryption;
+    id?: string;
+  }>("POST /usenet/nzb/upload", {
     body: formData,
   });
   return data;
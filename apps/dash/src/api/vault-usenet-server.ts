@@ -16,6 +16,7 @@ export type UsenetServer = {
   tls_skip_verify: boolean;
   updated_at: string;
   username: string;
+  weight: number;
 };
 
 type CreateUsenetServerParams = {
@@ -29,6 +30,7 @@ type CreateUsenetServerParams = {
   tls: boolean;
   tls_skip_verify: boolean;
   username: string;
+  weight: number;
 };
 
 type PingUsenetServerParams = {
@@ -57,6 +59,7 @@ type UpdateUsenetServerParams = Partial<{
   tls: boolean;
   tls_skip_verify: boolean;
   username: string;
+  weight: number;
 }>;
 
 export function useUsenetServerMutation() {
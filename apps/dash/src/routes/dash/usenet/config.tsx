@@ -263,6 +263,9 @@ function PoolInfoCard() {
                       <div>
                         <span>Priority: {provider.priority}</span>
                       </div>
+                      <div>
+                        <span>Weight: {provider.weight}</span>
+                      </div>
                       {provider.is_backup && (
                         <div>
                           <span>Backup</span>
@@ -277,6 +280,9 @@ function PoolInfoCard() {
                       <div>
                         <span>{provider.idle_connections} idle</span>
                       </div>
+                      <div>
+                        <span>{provider.selections} selected</span>
+                      </div>
                     </div>
                   </ItemDescription>
                 </ItemContent>
@@ -345,6 +351,26 @@ function RouteComponent() {
             <h3 className="mb-3 text-sm font-semibold">Grab Headers</h3>
             <HeaderTable headers={config.indexer_request_header.grab} />
           </div>
+          {Object.keys(config.indexer_request_header.grab_by_host).length >
+            0 && (
+            <div>
+              <h3 className="mb-3 text-sm font-semibold">
+                Grab Headers by Host
+              </h3>
+              <div className="flex flex-col gap-4">
+                {Object.entries(
+                  config.indexer_request_header.grab_by_host,
+                ).map(([hostname, headers]) => (
+                  <div key={hostname}>
+                    <div className="text-foreground mb-1 text-sm font-medium">
+                      {hostname}
+                    </div>
+                    <HeaderTable headers={headers} />
+                  </div>
+                ))}
+              </div>
+            </div>
+          )}
         </CardContent>
       </Card>
       <PoolInfoCard />
@@ -188,10 +188,19 @@ function RouteComponent() {
                               };
                             },
                             loading: "Uploading NZB file...",
-                            success() {
+                            success(data) {
+                              if (data.duplicate?.action === "linked") {
+                                return {
+                                  closeButton: true,
+                                  message: `NZB already exists as ${data.duplicate.name}!`,
+                                };
+                              }
+                              const warning = data.encryption?.warning;
                               return {
                                 closeButton: true,
-                                message: "NZB queued for processing!",
+                                message: warning
+                                  ? `NZB queued for processing, but ${warning}!`
+                                  : "NZB queued for processing!",
                               };
                             },
                           });
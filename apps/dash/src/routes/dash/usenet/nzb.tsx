@@ -3,6 +3,7 @@ import { ColumnDef, createColumnHelper } from "@tanstack/react-table";
 import {
   ChevronDown,
   ChevronRight,
+  Disc3,
   Download,
   ExternalLink,
   Eye,
@@ -22,6 +23,7 @@ import {
   NZBContentFile,
   NZBInfoItem,
   useNzbInfo,
+  useNzbInfoItem,
   useNzbInfoMutation,
 } from "@/api/nzb-info";
 import { DataTable } from "@/components/data-table";
@@ -100,10 +102,18 @@ function StatusBadge({ status }: { status: string }) {
       text = "Downloading";
       variant = "default";
       break;
+    case "cancelled":
+      text = "Cancelled";
+      variant = "secondary";
+      break;
     case "failed":
       text = "Failed";
       variant = "destructive";
       break;
+    case "invalid":
+      text = "Invalid";
+      variant = "destructive";
+      break;
     case "queued":
       text = "Queued";
       variant = "default";
@@ -309,9 +319,20 @@ const columns: ColumnDef<NZBInfoItem>[] = [
   }),
 ];
 
-function ContentFileIcon({ isPack, type }: { isPack: boolean; type: string }) {
+function ContentFileIcon({
+  isDisc,
+  isPack,
+  type,
+}: {
+  isDisc: boolean;
+  isPack: boolean;
+  type: string;
+}) {
   switch (type) {
     case "archive":
+      if (isDisc) {
+        return <Disc3 className="size-4 text-purple-500" />;
+      }
       return isPack ? (
         <PackageOpen className="size-4 text-amber-700" />
       ) : (
@@ -352,7 +373,11 @@ function ContentFileNode({
       >
         <ItemMedia>
           <div className="flex h-10 flex-col justify-between">
-            <ContentFileIcon isPack={isPack} type={file.type} />
+            <ContentFileIcon
+              isDisc={Boolean(file.disc)}
+              isPack={isPack}
+              type={file.type}
+            />
             {hasChildren ? (
               <button
                 className="flex size-4 shrink-0 items-center justify-center"
@@ -407,15 +432,36 @@ function ContentFileNode({
                 >
                   Streamable
                 </Badge>
-                {file.errors?.map((error) => (
-                  <Badge className="py-0" key={error} variant="destructive">
-                    {error === "article_not_found"
-                      ? "Article Not Found"
-                      : error === "open_failed"
-                        ? "Open Failed"
-                        : error}
+                {file.crc === "ok" && (
+                  <Badge className="bg-green-600 py-0">CRC OK</Badge>
+                )}
+                {file.crc === "unverified" && (
+                  <Badge className="py-0" variant="outline">
+                    CRC Unverified
+                  </Badge>
+                )}
+                {file.reasons?.map((reason) => (
+                  <Badge
+                    className="py-0"
+                    key={reason.code}
+                    title={reason.message}
+                    variant="destructive"
+                  >
+                    {reason.code.replaceAll("_", " ")}
                   </Badge>
                 ))}
+                {!file.reasons?.length &&
+                  file.errors?.map((error) => (
+                    <Badge className="py-0" key={error} variant="destructive">
+                      {error === "article_not_found"
+                        ? "Article Not Found"
+                        : error === "open_failed"
+                          ? "Open Failed"
+                          : error === "empty_file"
+                            ? "Empty File"
+                            : error}
+                    </Badge>
+                  ))}
               </div>
               <div className="ml-auto">
                 {!isPack && file.streamable && (
@@ -481,12 +527,15 @@ function ContentFileTree({
 }
 
 function NzbInfoDetailDialog({
-  item,
+  item: listItem,
   onClose,
 }: {
   item: null | NZBInfoItem;
   onClose: () => void;
 }) {
+  const detail = useNzbInfoItem(listItem?.id);
+  const item = listItem && (detail.data ?? listItem);
+
   return (
     <Dialog onOpenChange={(open) => !open && onClose()} open={Boolean(item)}>
       <DialogContent className="max-h-[80vh] max-w-2xl overflow-y-auto">
@@ -506,6 +555,14 @@ function NzbInfoDetailDialog({
                 <div className="text-muted-foreground font-medium">Size</div>
                 <div className="mt-1">{prettyBytes(item.size)}</div>
               </div>
+              <div>
+                <div className="text-muted-foreground font-medium">
+                  Downloaded
+                </div>
+                <div className="mt-1">
+                  {prettyBytes(item.downloaded_bytes)}
+                </div>
+              </div>
               <div>
                 <div className="text-muted-foreground font-medium">
                   Streamable
@@ -98,6 +98,9 @@ const columns: ColumnDef<UsenetServer>[] = [
   col.accessor("priority", {
     header: "Priority",
   }),
+  col.accessor("weight", {
+    header: "Weight",
+  }),
   col.accessor("is_backup", {
     cell: ({ getValue }) => {
       const isBackup = getValue();
@@ -262,6 +265,7 @@ const usenetServerSchema = z.object({
   tls: z.boolean(),
   tls_skip_verify: z.boolean(),
   username: z.string(),
+  weight: z.coerce.number<number>().int().min(1, "Weight must be at least 1"),
 });
 
 const priorityOptions = [
@@ -304,6 +308,7 @@ function UsenetServerFormSheet({
       tls: editItem?.tls ?? true,
       tls_skip_verify: editItem?.tls_skip_verify ?? false,
       username: editItem?.username ?? "",
+      weight: editItem?.weight ?? 1,
     },
     onSubmit: async ({ value }) => {
       value = usenetServerSchema.parse(value);
@@ -320,6 +325,7 @@ function UsenetServerFormSheet({
           tls: value.tls,
           tls_skip_verify: value.tls_skip_verify,
           username: value.username,
+          weight: value.weight,
         });
         toast.success("Updated successfully!");
       } else {
@@ -334,6 +340,7 @@ function UsenetServerFormSheet({
           tls: value.tls,
           tls_skip_verify: value.tls_skip_verify,
           username: value.username,
+          weight: value.weight,
         });
         toast.success("Created successfully!");
       }
@@ -453,6 +460,9 @@ function UsenetServerFormSheet({
                   />
                 )}
               </form.AppField>
+              <form.AppField name="weight">
+                {(field) => <field.Input label="Weight" type="number" />}
+              </form.AppField>
               <form.AppField name="is_backup">
                 {(field) => <field.Checkbox label="Backup" />}
               </form.AppField>
@@ -16,6 +16,8 @@ type Cache[V any] interface {
 }
 
 type CacheConfig struct {
+	Compress   CacheCompression // only for DiskBacked
+	Dir        string           // overrides base cache directory
 	DiskBacked bool
 	Lifetime   time.Duration
 	MaxSize    int64
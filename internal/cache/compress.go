@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type CacheCompression string
+
+const (
+	CacheCompressionNone CacheCompression = ""
+	CacheCompressionGzip CacheCompression = "gzip"
+	CacheCompressionZstd CacheCompression = "zstd"
+)
+
+var getZstdEncoder = sync.OnceValue(func() *zstd.Encoder {
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		panic(err)
+	}
+	return encoder
+})
+
+var getZstdDecoder = sync.OnceValue(func() *zstd.Decoder {
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		panic(err)
+	}
+	return decoder
+})
+
+func compress(compression CacheCompression, data []byte) ([]byte, error) {
+	switch compression {
+	case CacheCompressionNone:
+		return data, nil
+	case CacheCompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case CacheCompressionZstd:
+		return getZstdEncoder().EncodeAll(data, nil), nil
+	default:
+		return nil, fmt.Errorf("cache: unsupported compression: %s", compression)
+	}
+}
+
+func decompress(compression CacheCompression, data []byte) ([]byte, error) {
+	switch compression {
+	case CacheCompressionNone:
+		return data, nil
+	case CacheCompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	case CacheCompressionZstd:
+		return getZstdDecoder().DecodeAll(data, nil)
+	default:
+		return nil, fmt.Errorf("cache: unsupported compression: %s", compression)
+	}
+}
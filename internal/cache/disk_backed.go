@@ -22,6 +22,7 @@ type diskBackedCacheMeta struct {
 type diskBackedCache[V any] struct {
 	name     string
 	lifetime time.Duration
+	compress CacheCompression
 	dir      string
 	filePath string
 	otter    *otter.Cache[string, diskBackedCacheMeta]
@@ -32,7 +33,8 @@ func newDiskBackedCache[V any](conf *CacheConfig) Cache[V] {
 		conf.MaxSize = 1024 * 1024 * 1024 // 1 GB
 	}
 
-	dir := filepath.Join(cacheDir, conf.Name)
+	baseDir := conf.getBaseDir()
+	dir := filepath.Join(baseDir, conf.Name)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		panic(err)
 	}
@@ -53,8 +55,9 @@ func newDiskBackedCache[V any](conf *CacheConfig) Cache[V] {
 	cache := &diskBackedCache[V]{
 		name:     conf.Name,
 		lifetime: conf.Lifetime,
+		compress: conf.Compress,
 		dir:      dir,
-		filePath: filepath.Join(cacheDir, conf.Name+".gob"),
+		filePath: filepath.Join(baseDir, conf.Name+".gob"),
 		otter:    otterCache,
 	}
 
@@ -97,6 +100,12 @@ func (c *diskBackedCache[V]) persist() error {
 	return otter.SaveCacheToFile(c.otter, c.filePath)
 }
 
+// Flush writes the cache index to disk, so that the files on disk are not
+// orphaned if the process does not exit gracefully.
+func (c *diskBackedCache[V]) Flush() error {
+	return flushPersistentCache(c)
+}
+
 func (c *diskBackedCache[V]) GetName() string {
 	return c.name
 }
@@ -115,8 +124,15 @@ func (c *diskBackedCache[V]) AddWithLifetime(key string, value V, lifetime time.
 		return err
 	}
 
+	data, err := compress(c.compress, buf.Bytes())
+	if err != nil {
+		return err
+	}
+
 	size := int64(1)
-	if sizer, ok := any(value).(cacheSizer); ok {
+	if c.compress != CacheCompressionNone {
+		size = int64(len(data))
+	} else if sizer, ok := any(value).(cacheSizer); ok {
 		size = sizer.CacheSize()
 	} else if sizer, ok := any(&value).(cacheSizer); ok {
 		size = sizer.CacheSize()
@@ -126,7 +142,7 @@ func (c *diskBackedCache[V]) AddWithLifetime(key string, value V, lifetime time.
 		c.otter.SetExpiresAfter(key, lifetime)
 	}
 
-	if err := os.WriteFile(c.getFilePath(key), buf.Bytes(), 0644); err != nil {
+	if err := os.WriteFile(c.getFilePath(key), data, 0644); err != nil {
 		return err
 	}
 
@@ -139,6 +155,11 @@ func (c *diskBackedCache[V]) Get(key string, value *V) bool {
 		c.otter.Invalidate(key)
 		return false
 	}
+	data, err = decompress(c.compress, data)
+	if err != nil {
+		c.otter.Invalidate(key)
+		return false
+	}
 	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(value); err != nil {
 		return false
 	}
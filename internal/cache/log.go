@@ -0,0 +1,15 @@
+package cache
+
+import (
+	"context"
+	"sync"
+
+	"github.com/MunifTanjim/stremthru/internal/logger/log"
+)
+
+// getLog is the scoped logger of the package. It can not use logger.Scoped,
+// the logger package depends on this one, so it is created on first use,
+// after the logger has set up the default handler.
+var getLog = sync.OnceValue(func() *log.Logger {
+	return log.New(context.Background(), "scope", "cache")
+})
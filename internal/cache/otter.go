@@ -1,6 +1,8 @@
 package cache
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
@@ -18,17 +20,29 @@ var persistentCaches []persistentCache
 var persistentCachesMu sync.Mutex
 
 type persistentCache interface {
+	GetName() string
 	load()
 	persist() error
 }
 
-func persistCaches() {
+// FlushPersistentCaches writes the index of every persistent cache to disk.
+func FlushPersistentCaches() error {
 	persistentCachesMu.Lock()
 	defer persistentCachesMu.Unlock()
 
+	var errs []error
 	for _, c := range persistentCaches {
-		c.persist()
+		if err := c.persist(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.GetName(), err))
+		}
 	}
+	return errors.Join(errs...)
+}
+
+func flushPersistentCache(c persistentCache) error {
+	persistentCachesMu.Lock()
+	defer persistentCachesMu.Unlock()
+	return c.persist()
 }
 
 func registerPersistentCache(c persistentCache) {
@@ -38,20 +52,31 @@ func registerPersistentCache(c persistentCache) {
 }
 
 func ClosePersistentCaches() {
-	persistCaches()
+	if err := FlushPersistentCaches(); err != nil {
+		getLog().Error("failed to flush persistent caches", "error", err)
+	}
 }
 
 var cacheDir = filepath.Join(config.DataDir, "cache")
 
+func (conf *CacheConfig) getBaseDir() string {
+	if conf.Dir != "" {
+		return conf.Dir
+	}
+	return cacheDir
+}
+
 func init() {
 	if err := os.MkdirAll(cacheDir, 0755); err != nil {
 		panic(err)
 	}
 
 	go func() {
-		ticker := time.NewTicker(10 * time.Second)
+		ticker := time.NewTicker(config.CachePersistInterval)
 		for range ticker.C {
-			persistCaches()
+			if err := FlushPersistentCaches(); err != nil {
+				getLog().Warn("failed to flush persistent caches", "error", err)
+			}
 		}
 	}()
 }
@@ -116,7 +141,7 @@ func newOtterCache[V any](conf *CacheConfig) Cache[V] {
 		return newDiskBackedCache[V](conf)
 	}
 
-	filePath := filepath.Join(cacheDir, conf.Name+".gob")
+	filePath := filepath.Join(conf.getBaseDir(), conf.Name+".gob")
 
 	opts := &otter.Options[string, V]{}
 
@@ -138,6 +163,11 @@ func newOtterCache[V any](conf *CacheConfig) Cache[V] {
 	}
 
 	if conf.Persist {
+		if conf.Dir != "" {
+			if err := os.MkdirAll(conf.Dir, 0755); err != nil {
+				panic(err)
+			}
+		}
 		cache.load()
 		registerPersistentCache(cache)
 	}
@@ -82,6 +82,12 @@ func (cache *RedisCache[V]) Remove(key string) {
 	cache.c.Delete(context.Background(), cache.name+":"+key)
 }
 
+// NewRedisCache creates a cache in Redis regardless of the value type, e.g.
+// to share it across instances. It panics if Redis is not available.
+func NewRedisCache[V any](conf *CacheConfig) Cache[V] {
+	return newRedisCache[V](conf)
+}
+
 func newRedisCache[V any](conf *CacheConfig) *RedisCache[V] {
 	redisClient := redis.GetClient()
 	if redisClient == nil {
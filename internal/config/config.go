@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"runtime"
 	"slices"
 	"strconv"
 	"strings"
@@ -55,6 +56,7 @@ var defaultValueByEnv = map[string]map[string]string{
 	},
 	"": {
 		"STREMTHRU_BASE_URL":                               "http://localhost:8080",
+		"STREMTHRU_CACHE_PERSIST_INTERVAL":                 "10s",
 		"STREMTHRU_CONTENT_PROXY_CONNECTION_LIMIT":         "*:0",
 		"STREMTHRU_DATABASE_URI":                           "sqlite://./data/stremthru.db",
 		"STREMTHRU_DATA_DIR":                               "./data",
@@ -86,12 +88,37 @@ var defaultValueByEnv = map[string]map[string]string{
 		"STREMTHRU_STREMIO_WRAP_PUBLIC_MAX_UPSTREAM_COUNT": "5",
 		"STREMTHRU_STREMIO_WRAP_PUBLIC_MAX_STORE_COUNT":    "3",
 		"STREMTHRU_IP_CHECKER":                             "aws",
+		"STREMTHRU_NEWZ_ALLOW_SOLID_SEQUENTIAL":            "false",
+		"STREMTHRU_NEWZ_AVAILABILITY_SAMPLE_SIZE":          "3",
+		"STREMTHRU_NEWZ_CONTENT_RESOLVE_TIMEOUT":           "60s",
+		"STREMTHRU_NEWZ_DECODE_CONCURRENCY":                strconv.Itoa(runtime.GOMAXPROCS(0)),
+		"STREMTHRU_NEWZ_FIRST_SEGMENT_FALLBACK":            "0",
+		"STREMTHRU_NEWZ_FIRST_SEGMENT_TIMEOUT":             "15s",
+		"STREMTHRU_NEWZ_INSPECT_CONCURRENCY":               "1",
+		"STREMTHRU_NEWZ_INSPECT_HEALTH_SAMPLE":             "10",
+		"STREMTHRU_NEWZ_INSPECT_VERIFY_CRC_TIMEOUT":        "0",
 		"STREMTHRU_NEWZ_MAX_CONNECTION_PER_STREAM":         "8",
+		"STREMTHRU_NEWZ_MAX_STREAM_PER_USER":               "0",
 		"STREMTHRU_NEWZ_NZB_FILE_CACHE_SIZE":               "512MB",
 		"STREMTHRU_NEWZ_NZB_FILE_CACHE_TTL":                "24h",
 		"STREMTHRU_NEWZ_NZB_FILE_MAX_SIZE":                 "50MB",
+		"STREMTHRU_NEWZ_NZB_MAX_FILES":                     "5000",
+		"STREMTHRU_NEWZ_NZB_MAX_SEGMENTS":                  "1000000",
+		"STREMTHRU_NEWZ_NZB_UPLOAD_DUPLICATE":              "replace",
+		"STREMTHRU_NEWZ_SEGMENT_CACHE_BACKEND":             "disk",
+		"STREMTHRU_NEWZ_SEGMENT_CACHE_LIFETIME":            "24h",
 		"STREMTHRU_NEWZ_SEGMENT_CACHE_SIZE":                "10GB",
+		"STREMTHRU_NEWZ_SEGMENT_FETCH_TIMEOUT":             "30s",
+		"STREMTHRU_NEWZ_SEGMENT_MAX_SIZE":                  "16MB",
+		"STREMTHRU_NEWZ_STREAM_BUFFER_INITIAL_SIZE":        "16MB",
 		"STREMTHRU_NEWZ_STREAM_BUFFER_SIZE":                "200MB",
+		"STREMTHRU_NEWZ_STREAM_IDLE_TIMEOUT":               "5m",
+		"STREMTHRU_NEWZ_STREAM_MEMORY_LIMIT":               "0",
+		"STREMTHRU_NEWZ_STREAM_RATE_LIMIT":                 "0",
+		"STREMTHRU_NEWZ_STREAM_RETRY_COUNT":                "3",
+		"STREMTHRU_NEWZ_VIDEO_MIN_SIZE":                    "0",
+		"STREMTHRU_NEWZ_VIDEO_SELECT":                      "largest",
+		"STREMTHRU_NEWZ_WARM_UP_SIZE":                      "32MB",
 		"STREMTHRU_NEWZ_NZB_LINK_TYPE":                     "*:proxy",
 	},
 }
@@ -409,6 +436,7 @@ type Config struct {
 	HasPeer                     bool
 	PullPeerURL                 string
 	RedisURI                    string
+	CachePersistInterval        time.Duration
 	DatabaseURI                 string
 	Feature                     FeatureConfig
 	Version                     string
@@ -659,6 +687,7 @@ var config = func() Config {
 		HasPeer:                     len(peerUrl) > 0,
 		PullPeerURL:                 pullPeerUrl,
 		RedisURI:                    getEnv("STREMTHRU_REDIS_URI"),
+		CachePersistInterval:        mustParseDuration("cache persist interval", getEnv("STREMTHRU_CACHE_PERSIST_INTERVAL"), time.Second),
 		DatabaseURI:                 databaseUri,
 		Feature:                     feature,
 		Version:                     "0.97.1", // x-release-please-version
@@ -692,6 +721,7 @@ var PeerFlag = config.PeerFlag
 var HasPeer = config.HasPeer
 var PullPeerURL = config.PullPeerURL
 var RedisURI = config.RedisURI
+var CachePersistInterval = config.CachePersistInterval
 var DatabaseURI = config.DatabaseURI
 var Feature = config.Feature
 var Version = config.Version
@@ -1093,12 +1123,110 @@ func PrintConfig(state *AppState) {
 
 	if Feature.HasVault() {
 		l.Println(" Newz:")
+		if Newz.AllowSolidSequential {
+			l.Println(" allow solid sequential: true")
+		}
+		l.Println("    availability sample: " + strconv.Itoa(Newz.AvailabilitySampleSize))
+		if Newz.ContentResolveTimeout > 0 {
+			l.Println("content resolve timeout: " + Newz.ContentResolveTimeout.String())
+		}
+		if Newz.DecodeConcurrency > 0 {
+			l.Println("     decode concurrency: " + strconv.Itoa(Newz.DecodeConcurrency))
+		}
+		if Newz.FirstSegmentFallback > 0 {
+			l.Println(" first segment fallback: " + strconv.Itoa(Newz.FirstSegmentFallback))
+		}
+		l.Println("  first segment timeout: " + Newz.FirstSegmentTimeout.String())
+		l.Println("    inspect concurrency: " + strconv.Itoa(Newz.InspectConcurrency))
+		if Newz.InspectCRCTimeout > 0 {
+			l.Println("     inspect verify crc: " + Newz.InspectCRCTimeout.String())
+		}
+		if Newz.InspectHealthSample > 0 {
+			l.Println("  inspect health sample: " + strconv.Itoa(Newz.InspectHealthSample))
+		}
 		l.Println("   max conn. per stream: " + strconv.Itoa(Newz.MaxConnectionPerStream))
+		if Newz.MaxStreamPerUser > 0 {
+			l.Println("   max stream per user: " + strconv.Itoa(Newz.MaxStreamPerUser))
+		}
+		if Newz.NZBFileCacheDir != "" {
+			l.Println("     nzb file cache dir: " + Newz.NZBFileCacheDir)
+		}
 		l.Println("    nzb file cache size: " + util.ToSize(Newz.NZBFileCacheSize))
 		l.Println("     nzb file cache ttl: " + Newz.NZBFileCacheTTL.String())
 		l.Println("      nzb file max size: " + util.ToSize(Newz.NZBFileMaxSize))
-		l.Println("     segment cache size: " + util.ToSize(Newz.SegmentCacheSize))
+		if Newz.NZBFollowNested {
+			l.Println("      nzb follow nested: " + strconv.FormatBool(Newz.NZBFollowNested))
+		}
+		if len(Newz.NZBInfoMaxAge) > 0 {
+			maxAges := make([]string, 0, len(Newz.NZBInfoMaxAge))
+			for status, maxAge := range Newz.NZBInfoMaxAge {
+				maxAges = append(maxAges, status+":"+maxAge.String())
+			}
+			slices.Sort(maxAges)
+			l.Println("       nzb info max age: " + strings.Join(maxAges, ", "))
+		}
+		if Newz.NZBMaxFiles > 0 {
+			l.Println("          nzb max files: " + strconv.Itoa(Newz.NZBMaxFiles))
+		}
+		if Newz.NZBMaxSegments > 0 {
+			l.Println("       nzb max segments: " + strconv.Itoa(Newz.NZBMaxSegments))
+		}
+		if Newz.NZBUploadDuplicate != "replace" {
+			l.Println("   nzb upload duplicate: " + Newz.NZBUploadDuplicate)
+		}
+		if Newz.SeekLinearFallback {
+			l.Println("   seek linear fallback: " + strconv.FormatBool(Newz.SeekLinearFallback))
+		}
+		l.Println("  segment cache backend: " + Newz.SegmentCacheBackend)
+		if Newz.SegmentCacheBackend == "disk" {
+			if Newz.SegmentCacheDir != "" {
+				l.Println("      segment cache dir: " + Newz.SegmentCacheDir)
+			}
+			l.Println("     segment cache size: " + util.ToSize(Newz.SegmentCacheSize))
+		} else {
+			l.Println(" segment cache lifetime: " + Newz.SegmentCacheLifetime.String())
+		}
+		if Newz.SegmentCacheNamespace != "" {
+			l.Println("segment cache namespace: " + Newz.SegmentCacheNamespace)
+		}
+		l.Println("  segment fetch timeout: " + Newz.SegmentFetchTimeout.String())
+		if Newz.SegmentMaxSize > 0 {
+			l.Println("       segment max size: " + util.ToSize(Newz.SegmentMaxSize))
+		}
 		l.Println("     stream buffer size: " + util.ToSize(Newz.StreamBufferSize))
+		if Newz.StreamBufferInitSize > 0 && Newz.StreamBufferInitSize < Newz.StreamBufferSize {
+			l.Println("stream buffer init size: " + util.ToSize(Newz.StreamBufferInitSize))
+		}
+		if Newz.StreamIdleTimeout > 0 {
+			l.Println("    stream idle timeout: " + Newz.StreamIdleTimeout.String())
+		}
+		if Newz.StreamMemoryLimit > 0 {
+			l.Println("    stream memory limit: " + util.ToSize(Newz.StreamMemoryLimit))
+		}
+		if Newz.StreamRateLimit > 0 {
+			l.Println("      stream rate limit: " + util.ToSize(Newz.StreamRateLimit) + "/s")
+		}
+		l.Println("     stream retry count: " + strconv.Itoa(Newz.StreamRetryCount))
+		if Newz.StreamStrictSize {
+			l.Println("     stream strict size: " + strconv.FormatBool(Newz.StreamStrictSize))
+		}
+		if Newz.VideoContentType != "" {
+			l.Println("     video content type: " + Newz.VideoContentType)
+		}
+		if Newz.VideoExcludeSample {
+			l.Println("   video exclude sample: " + strconv.FormatBool(Newz.VideoExcludeSample))
+		}
+		if len(Newz.VideoExtensionAllow) > 0 {
+			l.Println("  video extension allow: " + strings.Join(Newz.VideoExtensionAllow, ", "))
+		}
+		if len(Newz.VideoExtensionDeny) > 0 {
+			l.Println("   video extension deny: " + strings.Join(Newz.VideoExtensionDeny, ", "))
+		}
+		if Newz.VideoMinSize.Bytes > 0 || Newz.VideoMinSize.Percent > 0 {
+			l.Println("         video min size: " + Newz.VideoMinSize.String())
+		}
+		l.Println("           video select: " + Newz.VideoSelect)
+		l.Println("           warm up size: " + util.ToSize(Newz.WarmUpSize))
 		l.Println()
 	}
 
@@ -1118,5 +1246,9 @@ func PrintConfig(state *AppState) {
 	l.Println("   " + DataDir)
 	l.Println()
 
+	l.Println(" Cache Persist Interval:")
+	l.Println("   " + CachePersistInterval.String())
+	l.Println()
+
 	l.Print("========================\n\n")
 }
@@ -1,11 +1,16 @@
 package config
 
 import (
+	"log"
 	"net/http"
+	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/MunifTanjim/stremthru/internal/util"
+	"github.com/MunifTanjim/stremthru/store"
 )
 
 type NZBLinkMode string
@@ -62,8 +67,24 @@ const (
 type newzIndexerRequestHeaderByType map[NewzIndexerRequestQueryType]http.Header
 
 type newzIndexerRequestHeaderMap struct {
-	Query newzIndexerRequestHeaderByType
-	Grab  http.Header
+	Query      newzIndexerRequestHeaderByType
+	Grab       http.Header
+	GrabByHost map[string]http.Header
+}
+
+// GetGrab returns the headers for NZB file download requests to hostname,
+// with the host specific headers merged over the default ones.
+func (m newzIndexerRequestHeaderMap) GetGrab(hostname string) http.Header {
+	header := m.Grab.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	if h, ok := m.GrabByHost[strings.ToLower(hostname)]; ok {
+		for k, v := range h {
+			header[k] = slices.Clone(v)
+		}
+	}
+	return header
 }
 
 func (mbt newzIndexerRequestHeaderByType) Get(queryType NewzIndexerRequestQueryType) http.Header {
@@ -77,16 +98,142 @@ func (mbt newzIndexerRequestHeaderByType) Get(queryType NewzIndexerRequestQueryT
 }
 
 type newzConfig struct {
+	AllowSolidSequential   bool // serve the only video of a solid rar forward-only
+	AvailabilitySampleSize int  // segments checked per file by the availability check
+	ContentResolveTimeout  time.Duration
+	DecodeConcurrency      int
+	FirstSegmentFallback   int
+	FirstSegmentTimeout    time.Duration
 	IndexerRequestHeader   newzIndexerRequestHeaderMap
+	InspectConcurrency     int
+	InspectCRCTimeout      time.Duration // 0 disables the crc verification
+	InspectHealthSample    int           // 0 disables the health check
 	MaxConnectionPerStream int
+	MaxStreamPerUser       int
+	NZBFileCacheDir        string
 	NZBFileCacheSize       int64
 	NZBFileCacheTTL        time.Duration
 	NZBFileMaxSize         int64
+	NZBFollowNested        bool
+	NZBInfoMaxAge          map[string]time.Duration // by status, `*` for any
+	NZBMaxFiles            int
+	NZBMaxSegments         int
+	NZBUploadDuplicate     string
+	SeekLinearFallback     bool
+	SegmentCacheBackend    string
+	SegmentCacheDir        string
+	SegmentCacheLifetime   time.Duration
 	SegmentCacheSize       int64
+	SegmentCacheNamespace  string
+	SegmentFetchTimeout    time.Duration
+	SegmentMaxSize         int64
 	StreamBufferSize       int64
+	StreamBufferInitSize   int64
+	StreamIdleTimeout      time.Duration
+	StreamMemoryLimit      int64 // across all the streams, 0 means unlimited
+	StreamRateLimit        int64
+	StreamRetryCount       int
+	StreamStrictSize       bool
+	VideoContentType       string
+	VideoExcludeSample     bool
+	VideoExtensionAllow    []string
+	VideoExtensionDeny     []string
+	VideoMinSize           newzVideoMinSize
+	VideoSelect            string
+	WarmUpSize             int64
+}
+
+func parseNewzVideoExtensions(blob string) []string {
+	exts := []string{}
+	for _, ext := range strings.FieldsFunc(blob, func(c rune) bool {
+		return c == ','
+	}) {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		exts = append(exts, ext)
+	}
+	return exts
+}
+
+// newzVideoMinSize is the size below which a video is considered a sample,
+// either in bytes or as a percent of the total size.
+type newzVideoMinSize struct {
+	Bytes   int64
+	Percent float64
+}
+
+// Get returns the minimum size in bytes, for content of the given total size.
+func (s newzVideoMinSize) Get(totalSize int64) int64 {
+	if s.Percent > 0 {
+		return int64(float64(totalSize) * s.Percent / 100)
+	}
+	return s.Bytes
+}
+
+func (s newzVideoMinSize) String() string {
+	if s.Percent > 0 {
+		return strconv.FormatFloat(s.Percent, 'f', -1, 64) + "%"
+	}
+	return util.ToSize(s.Bytes)
 }
 
-func parseNewzIndexerRequestHeader(queryHeaderBlob, grabHeaderBlob string) newzIndexerRequestHeaderMap {
+func parseNewzVideoMinSize(value string) newzVideoMinSize {
+	value = strings.TrimSpace(value)
+	if percent, ok := strings.CutSuffix(value, "%"); ok {
+		p, err := strconv.ParseFloat(percent, 64)
+		if err != nil || p < 0 || p > 100 {
+			panic("invalid newz video min size: " + value)
+		}
+		return newzVideoMinSize{Percent: p}
+	}
+	bytes := util.ToBytes(value)
+	if bytes < 0 {
+		panic("invalid newz video min size: " + value)
+	}
+	return newzVideoMinSize{Bytes: bytes}
+}
+
+func parseNewzNZBInfoMaxAge(blob string) map[string]time.Duration {
+	maxAgeByStatus := map[string]time.Duration{}
+	for _, entry := range strings.FieldsFunc(blob, func(c rune) bool {
+		return c == ','
+	}) {
+		status, age, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || status == "" {
+			panic("invalid newz nzb info max age: " + entry)
+		}
+		if status != "*" && !store.NewzStatus(status).IsValid() {
+			panic("invalid newz nzb info max age status: " + status)
+		}
+		maxAgeByStatus[status] = mustParseDuration("newz nzb info max age", age, time.Hour)
+	}
+	return maxAgeByStatus
+}
+
+// validateNewzSegmentCacheBackend panics for an unusable backend, and returns
+// a warning for the settings the backend ignores.
+func validateNewzSegmentCacheBackend(backend string, hasRedis, hasSize bool) string {
+	switch backend {
+	case "disk":
+	case "redis":
+		if !hasRedis {
+			panic("newz segment cache backend 'redis' requires STREMTHRU_REDIS_URI")
+		}
+		if hasSize {
+			return "STREMTHRU_NEWZ_SEGMENT_CACHE_SIZE is ignored with the redis segment cache backend, bound the Redis memory with maxmemory and an eviction policy instead"
+		}
+	default:
+		panic("invalid newz segment cache backend: " + backend)
+	}
+	return ""
+}
+
+func parseNewzIndexerRequestHeader(queryHeaderBlob, grabHeaderBlob, grabHostHeaderBlob string) newzIndexerRequestHeaderMap {
 	chromeHeaderBlob := util.MustDecodeBase64("VXNlci1BZ2VudDogTW96aWxsYS81LjAgKE1hY2ludG9zaDsgSW50ZWwgTWFjIE9TIFggMTBfMTVfNykgQXBwbGVXZWJLaXQvNTM3LjM2IChLSFRNTCwgbGlrZSBHZWNrbykgQ2hyb21lLzE0My4wLjAuMCBTYWZhcmkvNTM3LjM2CkFjY2VwdDogdGV4dC9odG1sLGFwcGxpY2F0aW9uL3hodG1sK3htbCxhcHBsaWNhdGlvbi94bWw7cT0wLjksaW1hZ2UvYXZpZixpbWFnZS93ZWJwLGltYWdlL2FwbmcsKi8qO3E9MC44LGFwcGxpY2F0aW9uL3NpZ25lZC1leGNoYW5nZTt2PWIzO3E9MC43CkFjY2VwdC1MYW5ndWFnZTogZW4tVVMsZW47cT0wLjkKUHJpb3JpdHk6IHU9MCwgaQpTZWMtQ2gtVWE6ICJHb29nbGUgQ2hyb21lIjt2PSIxNDMiLCAiQ2hyb21pdW0iO3Y9IjE0MyIsICJOb3QgQShCcmFuZCI7dj0iMjQiClNlYy1DaC1VYS1Nb2JpbGU6ID8wClNlYy1DaC1VYS1QbGF0Zm9ybTogIm1hY09TIgpTZWMtRmV0Y2gtRGVzdDogZG9jdW1lbnQKU2VjLUZldGNoLU1vZGU6IG5hdmlnYXRlClNlYy1GZXRjaC1TaXRlOiBzYW1lLXNpdGUKU2VjLUZldGNoLVVzZXI6ID8xClVwZ3JhZGUtSW5zZWN1cmUtUmVxdWVzdHM6IDE=")
 	presetQueryHeaderBlob := map[string]string{
 		"chrome":   chromeHeaderBlob,
@@ -104,37 +251,33 @@ func parseNewzIndexerRequestHeader(queryHeaderBlob, grabHeaderBlob string) newzI
 		Query: newzIndexerRequestHeaderByType{
 			"*": http.Header{},
 		},
-		Grab: http.Header{},
+		Grab:       http.Header{},
+		GrabByHost: map[string]http.Header{},
 	}
 
-	parseNewzIndexerRequestHeaderBlob := func(blob string, presets map[string]string, supportQueryType bool, setHeader func(queryType NewzIndexerRequestQueryType, key string, value string)) {
-		currQueryType := NewzIndexerRequestQueryTypeAny
+	// parseSection validates the name of a [section], it is nil if sections are not supported.
+	parseNewzIndexerRequestHeaderBlob := func(blob string, presets map[string]string, sectionKind string, defaultSection string, parseSection func(name string) string, setHeader func(section string, key string, value string)) {
+		currSection := defaultSection
 		for line := range strings.SplitSeq(blob, "\n") {
 			line = strings.TrimSpace(line)
 
 			if line == "" {
-				if supportQueryType {
-					currQueryType = ""
+				if parseSection != nil {
+					currSection = ""
 				}
 				continue
 			}
 
 			if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
-				if !supportQueryType {
-					panic("newz header: query type not supported")
-				}
-				queryTypeVal := strings.TrimSuffix(strings.TrimPrefix(line, "["), "]")
-				switch queryType := NewzIndexerRequestQueryType(queryTypeVal); queryType {
-				case NewzIndexerRequestQueryTypeTV, NewzIndexerRequestQueryTypeMovie, "*":
-					currQueryType = queryType
-				default:
-					panic("newz header: invalid query type: " + currQueryType)
+				if parseSection == nil {
+					panic("newz header: " + sectionKind + " not supported")
 				}
+				currSection = parseSection(strings.TrimSuffix(strings.TrimPrefix(line, "["), "]"))
 				continue
 			}
 
-			if supportQueryType && currQueryType == "" {
-				panic("newz header: missing query type after empty line")
+			if parseSection != nil && currSection == "" {
+				panic("newz header: missing " + sectionKind)
 			}
 
 			if strings.HasPrefix(line, ":") && strings.HasSuffix(line, ":") {
@@ -142,7 +285,7 @@ func parseNewzIndexerRequestHeader(queryHeaderBlob, grabHeaderBlob string) newzI
 				if presetBlob, ok := presets[presetName]; ok {
 					for header := range strings.SplitSeq(presetBlob, "\n") {
 						if k, v, ok := strings.Cut(header, ": "); ok {
-							setHeader(currQueryType, k, v)
+							setHeader(currSection, k, v)
 						}
 					}
 				} else {
@@ -152,33 +295,110 @@ func parseNewzIndexerRequestHeader(queryHeaderBlob, grabHeaderBlob string) newzI
 			}
 
 			if k, v, ok := strings.Cut(line, ": "); ok {
-				setHeader(currQueryType, k, v)
+				setHeader(currSection, k, v)
 			}
 		}
 	}
 
-	parseNewzIndexerRequestHeaderBlob(queryHeaderBlob, presetQueryHeaderBlob, true, func(queryType NewzIndexerRequestQueryType, key, value string) {
+	parseNewzIndexerRequestHeaderBlob(queryHeaderBlob, presetQueryHeaderBlob, "query type", string(NewzIndexerRequestQueryTypeAny), func(name string) string {
+		switch queryType := NewzIndexerRequestQueryType(name); queryType {
+		case NewzIndexerRequestQueryTypeTV, NewzIndexerRequestQueryTypeMovie, "*":
+			return name
+		default:
+			panic("newz header: invalid query type: " + name)
+		}
+	}, func(section, key, value string) {
+		queryType := NewzIndexerRequestQueryType(section)
 		if _, ok := indexerRequestHeader.Query[queryType]; !ok {
 			indexerRequestHeader.Query[queryType] = http.Header{}
 		}
 		indexerRequestHeader.Query[queryType].Set(key, value)
 	})
 
-	parseNewzIndexerRequestHeaderBlob(grabHeaderBlob, presetGrabHeaderBlob, false, func(_ NewzIndexerRequestQueryType, key, value string) {
+	parseNewzIndexerRequestHeaderBlob(grabHeaderBlob, presetGrabHeaderBlob, "query type", "", nil, func(_, key, value string) {
 		indexerRequestHeader.Grab.Set(key, value)
 	})
+
+	parseNewzIndexerRequestHeaderBlob(grabHostHeaderBlob, presetGrabHeaderBlob, "hostname", "", func(name string) string {
+		hostname := strings.ToLower(strings.TrimSpace(name))
+		if hostname == "" {
+			panic("newz header: empty hostname")
+		}
+		return hostname
+	}, func(hostname, key, value string) {
+		if _, ok := indexerRequestHeader.GrabByHost[hostname]; !ok {
+			indexerRequestHeader.GrabByHost[hostname] = http.Header{}
+		}
+		indexerRequestHeader.GrabByHost[hostname].Set(key, value)
+	})
 	return indexerRequestHeader
 }
 
 var Newz = func() newzConfig {
 	newz := newzConfig{
-		IndexerRequestHeader:   parseNewzIndexerRequestHeader(getEnv("STREMTHRU_NEWZ_QUERY_HEADER"), getEnv("STREMTHRU_NEWZ_GRAB_HEADER")),
+		AllowSolidSequential:   getEnv("STREMTHRU_NEWZ_ALLOW_SOLID_SEQUENTIAL") == "true",
+		AvailabilitySampleSize: max(util.MustParseInt(getEnv("STREMTHRU_NEWZ_AVAILABILITY_SAMPLE_SIZE")), 1),
+		ContentResolveTimeout:  mustParseDuration("newz content resolve timeout", getEnv("STREMTHRU_NEWZ_CONTENT_RESOLVE_TIMEOUT")),
+		DecodeConcurrency:      max(util.MustParseInt(getEnv("STREMTHRU_NEWZ_DECODE_CONCURRENCY")), 0),
+		FirstSegmentFallback:   max(util.MustParseInt(getEnv("STREMTHRU_NEWZ_FIRST_SEGMENT_FALLBACK")), 0),
+		FirstSegmentTimeout:    mustParseDuration("newz first segment timeout", getEnv("STREMTHRU_NEWZ_FIRST_SEGMENT_TIMEOUT")),
+		IndexerRequestHeader:   parseNewzIndexerRequestHeader(getEnv("STREMTHRU_NEWZ_QUERY_HEADER"), getEnv("STREMTHRU_NEWZ_GRAB_HEADER"), getEnv("STREMTHRU_NEWZ_GRAB_HOST_HEADER")),
+		InspectConcurrency:     max(util.MustParseInt(getEnv("STREMTHRU_NEWZ_INSPECT_CONCURRENCY")), 1),
+		InspectCRCTimeout:      mustParseDuration("newz inspect verify crc timeout", getEnv("STREMTHRU_NEWZ_INSPECT_VERIFY_CRC_TIMEOUT")),
+		InspectHealthSample:    max(util.MustParseInt(getEnv("STREMTHRU_NEWZ_INSPECT_HEALTH_SAMPLE")), 0),
 		MaxConnectionPerStream: util.MustParseInt(getEnv("STREMTHRU_NEWZ_MAX_CONNECTION_PER_STREAM")),
+		MaxStreamPerUser:       max(util.MustParseInt(getEnv("STREMTHRU_NEWZ_MAX_STREAM_PER_USER")), 0),
+		NZBFileCacheDir:        getEnv("STREMTHRU_NEWZ_NZB_FILE_CACHE_DIR"),
 		NZBFileCacheSize:       util.ToBytes(getEnv("STREMTHRU_NEWZ_NZB_FILE_CACHE_SIZE")),
 		NZBFileCacheTTL:        mustParseDuration("newz nzb file cache ttl", getEnv("STREMTHRU_NEWZ_NZB_FILE_CACHE_TTL"), 6*time.Hour),
 		NZBFileMaxSize:         util.ToBytes(getEnv("STREMTHRU_NEWZ_NZB_FILE_MAX_SIZE")),
+		NZBFollowNested:        getEnv("STREMTHRU_NEWZ_NZB_FOLLOW_NESTED") == "true",
+		NZBInfoMaxAge:          parseNewzNZBInfoMaxAge(getEnv("STREMTHRU_NEWZ_NZB_INFO_MAX_AGE")),
+		NZBMaxFiles:            max(util.MustParseInt(getEnv("STREMTHRU_NEWZ_NZB_MAX_FILES")), 0),
+		NZBMaxSegments:         max(util.MustParseInt(getEnv("STREMTHRU_NEWZ_NZB_MAX_SEGMENTS")), 0),
+		NZBUploadDuplicate:     getEnv("STREMTHRU_NEWZ_NZB_UPLOAD_DUPLICATE"),
+		SeekLinearFallback:     getEnv("STREMTHRU_NEWZ_SEEK_LINEAR_FALLBACK") == "true",
+		SegmentCacheBackend:    getEnv("STREMTHRU_NEWZ_SEGMENT_CACHE_BACKEND"),
+		SegmentCacheDir:        getEnv("STREMTHRU_NEWZ_SEGMENT_CACHE_DIR"),
+		SegmentCacheLifetime:   mustParseDuration("newz segment cache lifetime", getEnv("STREMTHRU_NEWZ_SEGMENT_CACHE_LIFETIME"), time.Minute),
 		SegmentCacheSize:       util.ToBytes(getEnv("STREMTHRU_NEWZ_SEGMENT_CACHE_SIZE")),
+		SegmentCacheNamespace:  getEnv("STREMTHRU_NEWZ_SEGMENT_CACHE_NAMESPACE"),
+		SegmentFetchTimeout:    mustParseDuration("newz segment fetch timeout", getEnv("STREMTHRU_NEWZ_SEGMENT_FETCH_TIMEOUT")),
+		SegmentMaxSize:         max(util.ToBytes(getEnv("STREMTHRU_NEWZ_SEGMENT_MAX_SIZE")), 0),
 		StreamBufferSize:       util.ToBytes(getEnv("STREMTHRU_NEWZ_STREAM_BUFFER_SIZE")),
+		StreamBufferInitSize:   max(util.ToBytes(getEnv("STREMTHRU_NEWZ_STREAM_BUFFER_INITIAL_SIZE")), 0),
+		StreamIdleTimeout:      mustParseDuration("newz stream idle timeout", getEnv("STREMTHRU_NEWZ_STREAM_IDLE_TIMEOUT")),
+		StreamMemoryLimit:      max(util.ToBytes(getEnv("STREMTHRU_NEWZ_STREAM_MEMORY_LIMIT")), 0),
+		StreamRateLimit:        max(util.ToBytes(getEnv("STREMTHRU_NEWZ_STREAM_RATE_LIMIT")), 0),
+		StreamRetryCount:       max(util.MustParseInt(getEnv("STREMTHRU_NEWZ_STREAM_RETRY_COUNT")), 0),
+		StreamStrictSize:       getEnv("STREMTHRU_NEWZ_STREAM_STRICT_SIZE") == "true",
+		VideoContentType:       strings.ToLower(strings.TrimSpace(getEnv("STREMTHRU_NEWZ_VIDEO_CONTENT_TYPE"))),
+		VideoExcludeSample:     getEnv("STREMTHRU_NEWZ_VIDEO_EXCLUDE_SAMPLE") == "true",
+		VideoExtensionAllow:    parseNewzVideoExtensions(getEnv("STREMTHRU_NEWZ_VIDEO_EXTENSION_ALLOW")),
+		VideoExtensionDeny:     parseNewzVideoExtensions(getEnv("STREMTHRU_NEWZ_VIDEO_EXTENSION_DENY")),
+		VideoMinSize:           parseNewzVideoMinSize(getEnv("STREMTHRU_NEWZ_VIDEO_MIN_SIZE")),
+		VideoSelect:            getEnv("STREMTHRU_NEWZ_VIDEO_SELECT"),
+		WarmUpSize:             max(util.ToBytes(getEnv("STREMTHRU_NEWZ_WARM_UP_SIZE")), 0),
+	}
+
+	switch newz.VideoSelect {
+	case "largest", "name", "runtime":
+	default:
+		panic("invalid newz video select: " + newz.VideoSelect)
+	}
+
+	switch newz.NZBUploadDuplicate {
+	case "replace", "link", "merge":
+	default:
+		panic("invalid newz nzb upload duplicate: " + newz.NZBUploadDuplicate)
+	}
+
+	if warning := validateNewzSegmentCacheBackend(newz.SegmentCacheBackend, getEnv("STREMTHRU_REDIS_URI") != "", os.Getenv("STREMTHRU_NEWZ_SEGMENT_CACHE_SIZE") != ""); warning != "" {
+		log.Println("WARNING: " + warning)
+	}
+
+	if newz.VideoContentType != "" && !strings.HasPrefix(newz.VideoContentType, "video/") {
+		panic("invalid newz video content type: " + newz.VideoContentType)
 	}
 
 	return newz
@@ -2,7 +2,9 @@ package config
 
 import (
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -11,7 +13,7 @@ type ParseNewzQueryHeaderTestSuite struct {
 }
 
 func (s *ParseNewzQueryHeaderTestSuite) parse(queryBlob string) newzIndexerRequestHeaderMap {
-	return parseNewzIndexerRequestHeader(queryBlob, "")
+	return parseNewzIndexerRequestHeader(queryBlob, "", "")
 }
 
 func (s *ParseNewzQueryHeaderTestSuite) TestMultipleHeadersWithoutQueryType() {
@@ -58,7 +60,7 @@ type ParseNewzGrabHeaderTestSuite struct {
 }
 
 func (s *ParseNewzGrabHeaderTestSuite) parse(grabBlob string) newzIndexerRequestHeaderMap {
-	return parseNewzIndexerRequestHeader("", grabBlob)
+	return parseNewzIndexerRequestHeader("", grabBlob, "")
 }
 
 func (s *ParseNewzGrabHeaderTestSuite) TestMultipleHeaders() {
@@ -81,3 +83,86 @@ func (s *ParseNewzGrabHeaderTestSuite) TestQueryTypePanics() {
 func TestParseNewzGrabHeader(t *testing.T) {
 	suite.Run(t, new(ParseNewzGrabHeaderTestSuite))
 }
+
+type ParseNewzGrabHostHeaderTestSuite struct {
+	suite.Suite
+}
+
+func (s *ParseNewzGrabHostHeaderTestSuite) parse(grabBlob, grabHostBlob string) newzIndexerRequestHeaderMap {
+	return parseNewzIndexerRequestHeader("", grabBlob, grabHostBlob)
+}
+
+func (s *ParseNewzGrabHostHeaderTestSuite) TestMultipleHosts() {
+	result := s.parse("", "[Indexer-A.example.com]\nX-Api-Key: a\n\n[indexer-b.example.com]\n:nzbget:")
+	s.Equal("a", result.GrabByHost["indexer-a.example.com"].Get("X-Api-Key"))
+	s.Contains(result.GrabByHost["indexer-b.example.com"].Get("User-Agent"), "nzbget")
+}
+
+func (s *ParseNewzGrabHostHeaderTestSuite) TestGetGrabMergesOverDefault() {
+	result := s.parse(":sabnzbd:\nAccept: */*", "[indexer.example.com]\nUser-Agent: Custom\nX-Api-Key: key")
+
+	h := result.GetGrab("Indexer.Example.com")
+	s.Equal("Custom", h.Get("User-Agent"))
+	s.Equal("key", h.Get("X-Api-Key"))
+	s.Equal("*/*", h.Get("Accept"))
+
+	h = result.GetGrab("other.example.com")
+	s.Contains(h.Get("User-Agent"), "SABnzbd")
+	s.Empty(h.Get("X-Api-Key"))
+
+	s.Empty(result.Grab.Get("X-Api-Key"))
+}
+
+func (s *ParseNewzGrabHostHeaderTestSuite) TestMissingHostnamePanics() {
+	s.Panics(func() {
+		s.parse("", "User-Agent: Custom")
+	})
+}
+
+func TestParseNewzGrabHostHeader(t *testing.T) {
+	suite.Run(t, new(ParseNewzGrabHostHeaderTestSuite))
+}
+
+func TestParseNewzVideoExtensions(t *testing.T) {
+	assert.Equal(t, []string{".ogm", ".divx", ".m2ts"}, parseNewzVideoExtensions(" .ogm, DIVX,,.M2TS "))
+	assert.Equal(t, []string{}, parseNewzVideoExtensions(""))
+}
+
+func TestParseNewzNZBInfoMaxAge(t *testing.T) {
+	assert.Equal(t, map[string]time.Duration{
+		"*":      2160 * time.Hour,
+		"failed": 168 * time.Hour,
+	}, parseNewzNZBInfoMaxAge("*:2160h, failed:168h"))
+	assert.Equal(t, map[string]time.Duration{}, parseNewzNZBInfoMaxAge(""))
+	assert.Panics(t, func() {
+		parseNewzNZBInfoMaxAge("failed")
+	})
+	assert.Panics(t, func() {
+		parseNewzNZBInfoMaxAge("fialed:168h")
+	})
+}
+
+func TestParseNewzVideoMinSize(t *testing.T) {
+	assert.Equal(t, int64(200*1024*1024), parseNewzVideoMinSize("200MB").Get(1))
+	assert.Equal(t, int64(0), parseNewzVideoMinSize("0").Get(1000))
+	assert.Equal(t, int64(100), parseNewzVideoMinSize("10%").Get(1000))
+	assert.Equal(t, "10%", parseNewzVideoMinSize("10%").String())
+	assert.Panics(t, func() {
+		parseNewzVideoMinSize("150%")
+	})
+	assert.Panics(t, func() {
+		parseNewzVideoMinSize("big")
+	})
+}
+
+func TestValidateNewzSegmentCacheBackend(t *testing.T) {
+	assert.Equal(t, "", validateNewzSegmentCacheBackend("disk", false, true))
+	assert.Equal(t, "", validateNewzSegmentCacheBackend("redis", true, false))
+	assert.Contains(t, validateNewzSegmentCacheBackend("redis", true, true), "maxmemory")
+	assert.Panics(t, func() {
+		validateNewzSegmentCacheBackend("redis", false, false)
+	})
+	assert.Panics(t, func() {
+		validateNewzSegmentCacheBackend("memory", true, false)
+	})
+}
@@ -0,0 +1,28 @@
+package dash_api
+
+import (
+	"net/http"
+
+	"github.com/MunifTanjim/stremthru/internal/cache"
+)
+
+func handleFlushCache(w http.ResponseWriter, r *http.Request) {
+	if err := cache.FlushPersistentCaches(); err != nil {
+		SendError(w, r, err)
+		return
+	}
+	SendData(w, r, 204, nil)
+}
+
+func AddCacheEndpoints(router *http.ServeMux) {
+	authed := EnsureAuthed
+
+	router.HandleFunc("/cache/flush", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			handleFlushCache(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+}
@@ -12,5 +12,7 @@ var ErrorInternalServerError = server.ErrorInternalServerError
 var ErrorLocked = server.ErrorLocked
 var ErrorMethodNotAllowed = server.ErrorMethodNotAllowed
 var ErrorNotFound = server.ErrorNotFound
+var ErrorTooManyRequests = server.ErrorTooManyRequests
 var ErrorUnauthorized = server.ErrorUnauthorized
+var ErrorUnprocessableEntity = server.ErrorUnprocessableEntity
 var ErrorUnsupportedMediaType = server.ErrorUnsupportedMediaType
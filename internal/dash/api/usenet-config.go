@@ -8,8 +8,9 @@ import (
 )
 
 type UsenetConfigIndexerRequestHeader struct {
-	Query map[string]map[string]string `json:"query"`
-	Grab  map[string]string            `json:"grab"`
+	Query      map[string]map[string]string `json:"query"`
+	Grab       map[string]string            `json:"grab"`
+	GrabByHost map[string]map[string]string `json:"grab_by_host"`
 }
 
 type UsenetConfig struct {
@@ -37,6 +38,10 @@ func handleGetUsenetConfig(w http.ResponseWriter, r *http.Request) {
 	for qt, h := range config.Newz.IndexerRequestHeader.Query {
 		queryHeaders[string(qt)] = flattenHeader(h)
 	}
+	grabHostHeaders := make(map[string]map[string]string, len(config.Newz.IndexerRequestHeader.GrabByHost))
+	for hostname, h := range config.Newz.IndexerRequestHeader.GrabByHost {
+		grabHostHeaders[hostname] = flattenHeader(h)
+	}
 
 	data := UsenetConfig{
 		NZBCacheSize:           util.ToSize(config.Newz.NZBFileCacheSize),
@@ -46,8 +51,9 @@ func handleGetUsenetConfig(w http.ResponseWriter, r *http.Request) {
 		StreamBufferSize:       util.ToSize(config.Newz.StreamBufferSize),
 		MaxConnectionPerStream: config.Newz.MaxConnectionPerStream,
 		IndexerRequestHeader: UsenetConfigIndexerRequestHeader{
-			Query: queryHeaders,
-			Grab:  flattenHeader(config.Newz.IndexerRequestHeader.Grab),
+			Query:      queryHeaders,
+			Grab:       flattenHeader(config.Newz.IndexerRequestHeader.Grab),
+			GrabByHost: grabHostHeaders,
 		},
 	}
 	SendData(w, r, 200, data)
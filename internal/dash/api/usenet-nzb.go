@@ -1,19 +1,32 @@
 package dash_api
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/MunifTanjim/stremthru/internal/cache"
 	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/server"
 	usenetmanager "github.com/MunifTanjim/stremthru/internal/usenet/manager"
 	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
 	"github.com/MunifTanjim/stremthru/internal/usenet/nzb_info"
 	usenet_pool "github.com/MunifTanjim/stremthru/internal/usenet/pool"
 	"github.com/MunifTanjim/stremthru/internal/util"
+	"github.com/MunifTanjim/stremthru/store"
+	"github.com/MunifTanjim/stremthru/store/stremthru"
 )
 
 type NzbSegmentResponse struct {
@@ -32,10 +45,19 @@ type NzbFileResponse struct {
 	Segments []NzbSegmentResponse `json:"segments"`
 }
 
+type NzbArchiveGroupResponse struct {
+	BaseName  string   `json:"base_name"`
+	Type      string   `json:"type"`
+	Files     []string `json:"files"`
+	Volumes   []int    `json:"volumes"`
+	TotalSize int64    `json:"total_size"`
+}
+
 type NzbParseResponse struct {
-	Meta  map[string]string `json:"meta"`
-	Size  int64             `json:"size"`
-	Files []NzbFileResponse `json:"files"`
+	Meta   map[string]string         `json:"meta"`
+	Size   int64                     `json:"size"`
+	Files  []NzbFileResponse         `json:"files"`
+	Groups []NzbArchiveGroupResponse `json:"groups"`
 }
 
 func toNzbParseResponse(parsed *nzb.NZB) NzbParseResponse {
@@ -68,11 +90,31 @@ func toNzbParseResponse(parsed *nzb.NZB) NzbParseResponse {
 		}
 	}
 
+	archiveGroups := usenet_pool.GroupNZBArchiveFiles(parsed.Files)
+	groups := make([]NzbArchiveGroupResponse, len(archiveGroups))
+	for i, group := range archiveGroups {
+		groups[i] = NzbArchiveGroupResponse{
+			BaseName:  group.BaseName,
+			Type:      group.FileType.String(),
+			Files:     group.Files,
+			Volumes:   group.Volumes,
+			TotalSize: group.TotalSize,
+		}
+	}
+
 	return NzbParseResponse{
-		Meta:  head,
-		Size:  parsed.TotalSize(),
-		Files: files,
+		Meta:   head,
+		Size:   parsed.TotalSize(),
+		Files:  files,
+		Groups: groups,
+	}
+}
+
+func checkNZBLimits(nzbDoc *nzb.NZB) string {
+	if err := usenet_pool.CheckNZBLimits(nzbDoc); err != nil {
+		return err.Error()
 	}
+	return ""
 }
 
 func handleParseNZB(w http.ResponseWriter, r *http.Request) {
@@ -117,20 +159,148 @@ func handleParseNZB(w http.ResponseWriter, r *http.Request) {
 		SendError(w, r, err)
 		return
 	}
+	if msg := checkNZBLimits(parsed); msg != "" {
+		ErrorUnprocessableEntity(r).WithMessage(msg).Send(w, r)
+		return
+	}
 
 	SendData(w, r, 200, toNzbParseResponse(parsed))
 }
 
+const nzbParseBatchMaxFiles = 20
+
+type NzbParseBatchItemResponse struct {
+	Filename string            `json:"filename"`
+	Data     *NzbParseResponse `json:"data,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+func parseNZBFileHeader(fileHeader *multipart.FileHeader) (*NzbParseResponse, string) {
+	if limit := config.Newz.NZBFileMaxSize; limit > 0 && fileHeader.Size > limit {
+		return nil, fmt.Sprintf("nzb file too large: %s (max %s)", util.ToSize(fileHeader.Size), util.ToSize(limit))
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, err.Error()
+	}
+	defer file.Close()
+
+	parsed, err := nzb.Parse(file)
+	if err != nil {
+		return nil, err.Error()
+	}
+	if msg := checkNZBLimits(parsed); msg != "" {
+		return nil, msg
+	}
+
+	data := toNzbParseResponse(parsed)
+	return &data, ""
+}
+
+func handleParseNZBBatch(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	if !strings.Contains(contentType, "multipart/form-data") {
+		ErrorUnsupportedMediaType(r).Send(w, r)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, config.Newz.NZBFileMaxSize*nzbParseBatchMaxFiles)
+	if err := r.ParseMultipartForm(util.ToBytes("10MB")); err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if r.MultipartForm.File == nil {
+		ErrorBadRequest(r).WithMessage("missing file").Send(w, r)
+		return
+	}
+	fileHeaders := r.MultipartForm.File["file"]
+	if len(fileHeaders) == 0 {
+		ErrorBadRequest(r).WithMessage("missing file").Send(w, r)
+		return
+	}
+	if len(fileHeaders) > nzbParseBatchMaxFiles {
+		ErrorBadRequest(r).WithMessage(fmt.Sprintf("too many files: %d (max %d)", len(fileHeaders), nzbParseBatchMaxFiles)).Send(w, r)
+		return
+	}
+
+	items := make([]NzbParseBatchItemResponse, len(fileHeaders))
+	for i, fileHeader := range fileHeaders {
+		data, errMsg := parseNZBFileHeader(fileHeader)
+		items[i] = NzbParseBatchItemResponse{
+			Filename: fileHeader.Filename,
+			Data:     data,
+			Error:    errMsg,
+		}
+	}
+
+	SendData(w, r, 200, items)
+}
+
+type NZBContentFileReasonResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
 type NZBContentFileResponse struct {
-	Type       string                   `json:"type"`
-	Name       string                   `json:"name"`
-	Alias      string                   `json:"alias,omitempty"`
-	Size       int64                    `json:"size"`
-	Streamable bool                     `json:"streamable"`
-	Errors     []string                 `json:"errors,omitempty"`
-	Files      []NZBContentFileResponse `json:"files,omitempty"`
-	Parts      []NZBContentFileResponse `json:"parts,omitempty"`
-	Volume     int                      `json:"volume,omitempty"`
+	Type       string                         `json:"type"`
+	Name       string                         `json:"name"`
+	Alias      string                         `json:"alias,omitempty"`
+	Size       int64                          `json:"size"`
+	Streamable bool                           `json:"streamable"`
+	Disc       bool                           `json:"disc,omitempty"`
+	CRC        string                         `json:"crc,omitempty"`
+	Errors     []string                       `json:"errors,omitempty"`
+	Reasons    []NZBContentFileReasonResponse `json:"reasons,omitempty"`
+	Files      []NZBContentFileResponse       `json:"files,omitempty"`
+	Parts      []NZBContentFileResponse       `json:"parts,omitempty"`
+	Volume     int                            `json:"volume,omitempty"`
+}
+
+type NZBEpisodeResponse struct {
+	Episodes []int  `json:"episodes,omitempty"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+}
+
+type NZBSeasonResponse struct {
+	Season   int                  `json:"season"`
+	Episodes []NZBEpisodeResponse `json:"episodes"`
+}
+
+type NZBSeasonsResponse struct {
+	Items []NZBSeasonResponse  `json:"items"`
+	Other []NZBEpisodeResponse `json:"other"`
+}
+
+func toNZBEpisodeResponses(items []usenet_pool.NZBContentEpisode) []NZBEpisodeResponse {
+	resp := make([]NZBEpisodeResponse, len(items))
+	for i, item := range items {
+		resp[i] = NZBEpisodeResponse{
+			Episodes: item.Episodes,
+			Name:     item.Name,
+			Path:     item.Path,
+		}
+	}
+	return resp
+}
+
+func toNZBSeasonsResponse(files []usenet_pool.NZBContentFile) *NZBSeasonsResponse {
+	grouped := usenet_pool.GroupNZBContentBySeason(files)
+	if grouped == nil {
+		return nil
+	}
+	resp := &NZBSeasonsResponse{
+		Items: make([]NZBSeasonResponse, len(grouped.Seasons)),
+		Other: toNZBEpisodeResponses(grouped.Other),
+	}
+	for i, season := range grouped.Seasons {
+		resp.Items[i] = NZBSeasonResponse{
+			Season:   season.Season,
+			Episodes: toNZBEpisodeResponses(season.Episodes),
+		}
+	}
+	return resp
 }
 
 type NZBResponse struct {
@@ -140,17 +310,27 @@ type NZBResponse struct {
 	Size       int64                    `json:"size"`
 	FileCount  int                      `json:"file_count"`
 	Password   string                   `json:"password"`
+	Providers  []string                 `json:"providers"`
 	URL        string                   `json:"url"`
 	Files      []NZBContentFileResponse `json:"files"`
+	Seasons    *NZBSeasonsResponse      `json:"seasons,omitempty"`
 	Streamable bool                     `json:"streamable"`
 	Cached     bool                     `json:"cached"`
 	User       string                   `json:"user"`
 	Date       string                   `json:"date"`
 	Status     string                   `json:"status"`
+	Downloaded int64                    `json:"downloaded_bytes"`
+	Health     *NZBHealthResponse       `json:"health,omitempty"`
 	CreatedAt  string                   `json:"created_at"`
 	UpdatedAt  string                   `json:"updated_at"`
 }
 
+type NZBHealthResponse struct {
+	Score   float64 `json:"score"`
+	Sampled int     `json:"sampled"`
+	Failed  int     `json:"failed"`
+}
+
 func toNZBContentFileResponse(file usenet_pool.NZBContentFile) NZBContentFileResponse {
 	resp := NZBContentFileResponse{
 		Type:       string(file.Type),
@@ -158,9 +338,20 @@ func toNZBContentFileResponse(file usenet_pool.NZBContentFile) NZBContentFileRes
 		Alias:      file.Alias,
 		Size:       file.Size,
 		Streamable: file.Streamable,
+		Disc:       file.Disc,
+		CRC:        string(file.CRC),
 		Errors:     file.Errors,
 		Volume:     file.Volume,
 	}
+	if len(file.Reasons) > 0 {
+		resp.Reasons = make([]NZBContentFileReasonResponse, len(file.Reasons))
+		for i, reason := range file.Reasons {
+			resp.Reasons[i] = NZBContentFileReasonResponse{
+				Code:    string(reason.Code),
+				Message: reason.Message,
+			}
+		}
+	}
 	if len(file.Files) > 0 {
 		resp.Files = make([]NZBContentFileResponse, len(file.Files))
 		for i, f := range file.Files {
@@ -188,6 +379,14 @@ func toNZBResponse(info *nzb_info.NZBInfo) NZBResponse {
 	if !info.Date.IsZero() {
 		date = info.Date.Format(time.RFC3339)
 	}
+	var health *NZBHealthResponse
+	if info.HealthSampled > 0 {
+		health = &NZBHealthResponse{
+			Score:   info.HealthScore(),
+			Sampled: info.HealthSampled,
+			Failed:  info.HealthFailed,
+		}
+	}
 	return NZBResponse{
 		Id:         info.Id,
 		Hash:       info.Hash,
@@ -195,13 +394,17 @@ func toNZBResponse(info *nzb_info.NZBInfo) NZBResponse {
 		Size:       info.Size,
 		FileCount:  info.FileCount,
 		Password:   info.Password,
+		Providers:  info.Providers,
 		URL:        info.URL,
 		Files:      contentFiles,
+		Seasons:    toNZBSeasonsResponse(info.ContentFiles.Data),
 		Streamable: info.Streamable,
 		Cached:     nzb_info.IsNZBFileCached(info.Hash),
 		User:       info.User,
 		Date:       date,
 		Status:     info.Status,
+		Downloaded: info.Downloaded,
+		Health:     health,
 		CreatedAt:  info.CAt.Format(time.RFC3339),
 		UpdatedAt:  info.UAt.Format(time.RFC3339),
 	}
@@ -222,6 +425,22 @@ func handleGetNZBs(w http.ResponseWriter, r *http.Request) {
 	SendData(w, r, 200, data)
 }
 
+func handleGetNZB(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	info, err := nzb_info.GetById(id)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if info == nil {
+		ErrorNotFound(r).WithMessage("nzb info not found").Send(w, r)
+		return
+	}
+
+	SendData(w, r, 200, toNZBResponse(info))
+}
+
 func handleDeleteNZB(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 
@@ -245,6 +464,77 @@ func handleDeleteNZB(w http.ResponseWriter, r *http.Request) {
 	SendData(w, r, 204, nil)
 }
 
+type UpdateNZBRequest struct {
+	Password  *string   `json:"password"`
+	Providers *[]string `json:"providers"`
+	Requeue   bool      `json:"requeue"`
+}
+
+func handleUpdateNZB(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	info, err := nzb_info.GetById(id)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if info == nil {
+		ErrorNotFound(r).WithMessage("nzb info not found").Send(w, r)
+		return
+	}
+
+	request := &UpdateNZBRequest{}
+	if err := ReadRequestBodyJSON(r, request); err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	if request.Password != nil {
+		info.Password = *request.Password
+	}
+
+	if request.Providers != nil && len(*request.Providers) > 0 {
+		pool, err := usenetmanager.GetPool()
+		if err != nil {
+			SendError(w, r, err)
+			return
+		}
+		for _, providerId := range *request.Providers {
+			if pool == nil || !pool.HasProvider(providerId) {
+				ErrorBadRequest(r).WithMessage("unknown provider: "+providerId).Send(w, r)
+				return
+			}
+		}
+	}
+
+	if err := nzb_info.Upsert(info); err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	if request.Providers != nil {
+		if err := nzb_info.UpdateProviders(info.Hash, *request.Providers); err != nil {
+			SendError(w, r, err)
+			return
+		}
+	}
+
+	if request.Requeue {
+		if _, err := nzb_info.QueueJob(info.User, info.Name, info.URL, "", 0, info.Password); err != nil {
+			SendError(w, r, err)
+			return
+		}
+	}
+
+	info, err = nzb_info.GetById(id)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	SendData(w, r, 200, toNZBResponse(info))
+}
+
 func handleGetNZBXML(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 
@@ -266,14 +556,13 @@ func handleGetNZBXML(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/xml")
 	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, nzbFile.Name))
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(nzbFile.Blob)))
-	w.WriteHeader(http.StatusOK)
-	w.Write(nzbFile.Blob)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+
+	// handles Last-Modified / If-Modified-Since
+	http.ServeContent(w, r, nzbFile.Name, nzbFile.Mod, bytes.NewReader(nzbFile.Blob))
 }
 
 func handleUploadNZB(w http.ResponseWriter, r *http.Request) {
-	ctx := GetReqCtx(r)
-
 	contentType := r.Header.Get("Content-Type")
 	if !strings.Contains(contentType, "multipart/form-data") {
 		ErrorUnsupportedMediaType(r).Send(w, r)
@@ -312,24 +601,204 @@ func handleUploadNZB(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	uploaded, err := queueUploadedNZB(r, blob, fileHeader.Filename, r.FormValue("name"))
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	resp := NZBUploadResponse{Duplicate: uploaded.duplicate}
+	if uploaded.queueItem != nil {
+		queueItem := toNzbQueueItemResponse(uploaded.queueItem)
+		resp.NZBQueueItemResponse = &queueItem
+	}
+	if r.FormValue("probe_encryption") == "true" {
+		resp.Encryption = probeUploadedNZBEncryption(r, uploaded.nzbDoc)
+	}
+
+	SendData(w, r, 200, resp)
+}
+
+// max time spent on probing the archive headers during upload
+const nzbUploadEncryptionProbeTimeout = 5 * time.Second
+
+type NZBEncryptionProbeResponse struct {
+	Name             string `json:"name"`
+	PasswordRequired bool   `json:"password_required"`
+	HeaderEncrypted  bool   `json:"header_encrypted"`
+	Warning          string `json:"warning,omitempty"`
+}
+
+type NZBUploadDuplicateResponse struct {
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	Action        string `json:"action"` // linked or merged
+	AddedSegments int    `json:"added_segments,omitempty"`
+}
+
+// NZBUploadResponse has no queue item if the upload is linked to the existing
+// nzb.
+type NZBUploadResponse struct {
+	*NZBQueueItemResponse
+	Encryption *NZBEncryptionProbeResponse `json:"encryption,omitempty"`
+	Duplicate  *NZBUploadDuplicateResponse `json:"duplicate,omitempty"`
+}
+
+// probeUploadedNZBEncryption is best-effort, returns nil if the probe is
+// not conclusive.
+func probeUploadedNZBEncryption(r *http.Request, nzbDoc *nzb.NZB) *NZBEncryptionProbeResponse {
+	pool, err := usenetmanager.GetPool()
+	if err != nil || pool == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), nzbUploadEncryptionProbeTimeout)
+	defer cancel()
+
+	result, err := pool.ProbeArchiveEncryption(ctx, nzbDoc)
+	if err != nil {
+		GetReqCtx(r).Log.Warn("upload - failed to probe archive encryption", "error", err)
+		return nil
+	}
+	if result == nil {
+		return nil
+	}
+
+	resp := &NZBEncryptionProbeResponse{
+		Name:             result.Name,
+		PasswordRequired: result.PasswordRequired,
+		HeaderEncrypted:  result.HeaderEncrypted,
+	}
+	switch {
+	case result.HeaderEncrypted:
+		resp.Warning = "archive is encrypted, password required to list and stream files"
+	case result.PasswordRequired:
+		resp.Warning = "archive is encrypted, password required to stream files"
+	}
+	return resp
+}
+
+type uploadedNZB struct {
+	queueItem *nzb_info.JobEntry // nil if linked to the existing nzb
+	nzbDoc    *nzb.NZB
+	duplicate *NZBUploadDuplicateResponse
+}
+
+// mergeUploadedNZB merges the segments of the uploaded nzb and the existing
+// one with the same files, into whichever of them has more segments.
+func mergeUploadedNZB(r *http.Request, existing *nzb_info.NZBInfo, nzbDoc *nzb.NZB) (*nzb.NZB, int, error) {
+	existingFile := nzb_info.GetCachedNZBFile(existing.Hash)
+	if existingFile == nil {
+		GetReqCtx(r).Log.Warn("upload - existing nzb file not cached, replacing it", "id", existing.Id)
+		return nzbDoc, 0, nil
+	}
+	existingDoc, err := nzb.ParseBytes(existingFile.Blob)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	merged, other := existingDoc, nzbDoc
+	if nzbDoc.SegmentCount() > existingDoc.SegmentCount() {
+		merged, other = nzbDoc, existingDoc
+	}
+	added := merged.MergeSegments(other)
+	return merged, added, nil
+}
+
+// getUploadedNZBId returns the id for an uploaded nzb. Nzbs uploaded before
+// the id was hashed by content keep their old id, so that re-uploading them
+// still finds the existing nzb.
+func getUploadedNZBId(nzbDoc *nzb.NZB) (string, error) {
+	nzbId := nzbDoc.HashByContent()
+	legacyId := nzbDoc.HashByFileBoundarySegmentIds()
+	if legacyId == nzbId {
+		return nzbId, nil
+	}
+	if info, err := nzb_info.GetById(nzbId); err != nil || info != nil {
+		return nzbId, err
+	}
+	info, err := nzb_info.GetById(legacyId)
+	if err != nil {
+		return "", err
+	}
+	if info != nil {
+		return legacyId, nil
+	}
+	return nzbId, nil
+}
+
+// queueUploadedNZB caches the uploaded nzb and queues it for inspection.
+// An upload with the same files as an existing nzb is handled according to
+// the configured duplicate mode. Client side problems are reported as
+// *server.APIError.
+func queueUploadedNZB(r *http.Request, blob []byte, filename, name string) (*uploadedNZB, error) {
+	ctx := GetReqCtx(r)
+
+	blob, err := nzb.Decompress(blob, config.Newz.NZBFileMaxSize)
+	if err != nil {
+		if errors.Is(err, nzb.ErrDecompressedTooLarge) {
+			return nil, ErrorUnprocessableEntity(r).WithMessage(err.Error())
+		}
+		return nil, ErrorBadRequest(r).WithMessage("failed to decompress nzb: " + err.Error())
+	}
+
 	nzbDoc, err := nzb.ParseBytes(blob)
 	if err != nil {
 		if parseErr, ok := err.(*nzb.ParseError); ok {
-			ErrorBadRequest(r).WithMessage(parseErr.Error()).Send(w, r)
-			return
+			return nil, ErrorBadRequest(r).WithMessage(parseErr.Error())
+		}
+		return nil, err
+	}
+	if msg := checkNZBLimits(nzbDoc); msg != "" {
+		return nil, ErrorUnprocessableEntity(r).WithMessage(msg)
+	}
+
+	nzbId, err := getUploadedNZBId(nzbDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing *nzb_info.NZBInfo
+	if config.Newz.NZBUploadDuplicate != "replace" {
+		existing, err = nzb_info.GetById(nzbId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	uploaded := &uploadedNZB{nzbDoc: nzbDoc}
+	if existing != nil {
+		uploaded.duplicate = &NZBUploadDuplicateResponse{
+			Id:   existing.Id,
+			Name: existing.Name,
+		}
+		switch config.Newz.NZBUploadDuplicate {
+		case "link":
+			uploaded.duplicate.Action = "linked"
+			return uploaded, nil
+		case "merge":
+			merged, added, err := mergeUploadedNZB(r, existing, nzbDoc)
+			if err != nil {
+				return nil, err
+			}
+			if merged != nzbDoc || added > 0 {
+				if blob, err = merged.Bytes(); err != nil {
+					return nil, err
+				}
+			}
+			uploaded.nzbDoc = merged
+			uploaded.duplicate.Action = "merged"
+			uploaded.duplicate.AddedSegments = added
 		}
-		SendError(w, r, err)
-		return
 	}
 
-	nzbId := nzbDoc.HashByFileBoundarySegmentIds()
 	link := config.BaseURL.JoinPath("/v0/newznab/getnzb/", nzbId)
 	linkQuery := link.Query()
 	apikey := util.Base64Encode(ctx.Session.User + ":" + config.Auth.GetPassword(ctx.Session.User))
 	linkQuery.Set("apikey", apikey)
 	link.RawQuery = linkQuery.Encode()
 
-	filename := fileHeader.Filename
+	filename = nzb.TrimGzipExt(filename)
 	if !strings.HasSuffix(filename, ".nzb") {
 		filename += ".nzb"
 	}
@@ -342,12 +811,20 @@ func handleUploadNZB(w http.ResponseWriter, r *http.Request) {
 	}
 
 	hash := nzb_info.HashNZBFileLink(nzbFile.Link)
+	user, password := ctx.Session.User, ""
+	if existing != nil {
+		// update the existing nzb in place
+		nzbFile.Link = existing.URL
+		hash = existing.Hash
+		user, password = existing.User, existing.Password
+		if name == "" {
+			name = existing.Name
+		}
+	}
 	if err := nzb_info.CacheNZBFile(hash, nzbFile); err != nil {
-		SendError(w, r, err)
-		return
+		return nil, err
 	}
 
-	name := r.FormValue("name")
 	if name == "" {
 		name = filename
 	}
@@ -356,119 +833,1304 @@ func handleUploadNZB(w http.ResponseWriter, r *http.Request) {
 		Id:        nzbId,
 		Hash:      hash,
 		Name:      name,
-		Size:      nzbDoc.TotalSize(),
-		FileCount: nzbDoc.FileCount(),
-		Password:  "",
+		Size:      uploaded.nzbDoc.TotalSize(),
+		FileCount: uploaded.nzbDoc.FileCount(),
+		Password:  password,
 		URL:       nzbFile.Link,
-		User:      ctx.Session.User,
+		User:      user,
 		Status:    "queued",
 	}); err != nil {
-		SendError(w, r, err)
-		return
+		return nil, err
 	}
 
-	queueId, err := nzb_info.QueueJob(ctx.Session.User, name, nzbFile.Link, "", 0, "")
+	queueId, err := nzb_info.QueueJob(user, name, nzbFile.Link, "", 0, password)
 	if err != nil {
-		SendError(w, r, err)
-		return
+		return nil, err
 	}
 
-	queueItem, err := nzb_info.GetJobById(queueId)
+	uploaded.queueItem, err = nzb_info.GetJobById(queueId)
 	if err != nil {
-		SendError(w, r, err)
-		return
+		return nil, err
 	}
+	return uploaded, nil
+}
 
-	SendData(w, r, 200, toNzbQueueItemResponse(queueItem))
+const (
+	nzbUploadBatchMaxFiles   = 20
+	nzbUploadBatchMaxEntries = 200
+)
+
+type NzbUploadBatchItemResponse struct {
+	Filename  string                      `json:"filename"`
+	Data      *NZBQueueItemResponse       `json:"data,omitempty"`
+	Duplicate *NZBUploadDuplicateResponse `json:"duplicate,omitempty"`
+	Error     string                      `json:"error,omitempty"`
 }
 
-func handleRequeueNZB(w http.ResponseWriter, r *http.Request) {
-	id := r.PathValue("id")
+type nzbUploadBatchEntry struct {
+	filename string
+	blob     []byte
+	err      string
+}
 
-	info, err := nzb_info.GetById(id)
+func isZipFile(filename string, blob []byte) bool {
+	return strings.HasSuffix(strings.ToLower(filename), ".zip") || bytes.HasPrefix(blob, []byte("PK\x03\x04"))
+}
+
+func isNZBFilename(filename string) bool {
+	filename = strings.ToLower(filename)
+	return strings.HasSuffix(filename, ".nzb") || strings.HasSuffix(filename, ".nzb.gz")
+}
+
+// listNZBZipEntries lists the nzb files in a zip archive, without
+// decompressing them. Entries that are not nzb files are ignored.
+func listNZBZipEntries(blob []byte, maxEntries int) ([]*zip.File, error) {
+	zr, err := zip.NewReader(bytes.NewReader(blob), int64(len(blob)))
 	if err != nil {
-		SendError(w, r, err)
-		return
+		return nil, err
 	}
-	if info == nil {
-		ErrorNotFound(r).WithMessage("nzb info not found").Send(w, r)
-		return
+
+	files := []*zip.File{}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") || !isNZBFilename(f.Name) {
+			continue
+		}
+		if len(files) == maxEntries {
+			return nil, fmt.Errorf("too many entries in zip (max %d)", maxEntries)
+		}
+		files = append(files, f)
 	}
+	return files, nil
+}
 
-	queueId, err := nzb_info.QueueJob(info.User, info.Name, info.URL, "", 0, info.Password)
+// readNZBZipEntry decompresses a single zip entry, reading at most maxSize
+// bytes of it regardless of the size its header claims.
+func readNZBZipEntry(f *zip.File, maxSize int64) nzbUploadBatchEntry {
+	entry := nzbUploadBatchEntry{filename: f.Name}
+	if f.UncompressedSize64 > uint64(maxSize) {
+		entry.err = fmt.Sprintf("nzb file too large (max %s)", util.ToSize(maxSize))
+		return entry
+	}
+	blob, err := func() ([]byte, error) {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(io.LimitReader(rc, maxSize+1))
+	}()
 	if err != nil {
-		SendError(w, r, err)
-		return
+		entry.err = "failed to read entry: " + err.Error()
+	} else if int64(len(blob)) > maxSize {
+		entry.err = fmt.Sprintf("nzb file too large (max %s)", util.ToSize(maxSize))
+	} else {
+		entry.blob = blob
 	}
+	return entry
+}
 
-	queueItem, err := nzb_info.GetJobById(queueId)
-	if err != nil {
+// handleUploadNZBBatch accepts multiple "file" fields, each being either an
+// nzb file or a zip of nzb files, and queues every nzb found.
+func handleUploadNZBBatch(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	if !strings.Contains(contentType, "multipart/form-data") {
+		ErrorUnsupportedMediaType(r).Send(w, r)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, config.Newz.NZBFileMaxSize*nzbUploadBatchMaxFiles)
+	if err := r.ParseMultipartForm(util.ToBytes("10MB")); err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if r.MultipartForm.File == nil {
+		ErrorBadRequest(r).WithMessage("missing file").Send(w, r)
+		return
+	}
+	fileHeaders := r.MultipartForm.File["file"]
+	if len(fileHeaders) == 0 {
+		ErrorBadRequest(r).WithMessage("missing file").Send(w, r)
+		return
+	}
+	if len(fileHeaders) > nzbUploadBatchMaxFiles {
+		ErrorBadRequest(r).WithMessage(fmt.Sprintf("too many files: %d (max %d)", len(fileHeaders), nzbUploadBatchMaxFiles)).Send(w, r)
+		return
+	}
+
+	// only the zip listings are read upfront, each entry is decompressed
+	// right before it is queued so that a single one is held in memory.
+	type uploadedFile struct {
+		filename string
+		blob     []byte
+		zipFiles []*zip.File
+	}
+	files := make([]uploadedFile, 0, len(fileHeaders))
+	entryCount := 0
+	for _, fileHeader := range fileHeaders {
+		blob, err := func() ([]byte, error) {
+			file, err := fileHeader.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer file.Close()
+			return io.ReadAll(file)
+		}()
+		if err != nil {
+			SendError(w, r, err)
+			return
+		}
+
+		file := uploadedFile{filename: fileHeader.Filename}
+		if !isZipFile(fileHeader.Filename, blob) {
+			file.blob = blob
+			entryCount++
+		} else {
+			file.zipFiles, err = listNZBZipEntries(blob, nzbUploadBatchMaxEntries-entryCount)
+			if err != nil {
+				ErrorBadRequest(r).WithMessage(fileHeader.Filename+": "+err.Error()).Send(w, r)
+				return
+			}
+			entryCount += len(file.zipFiles)
+		}
+		if entryCount > nzbUploadBatchMaxEntries {
+			ErrorBadRequest(r).WithMessage(fmt.Sprintf("too many nzb files (max %d)", nzbUploadBatchMaxEntries)).Send(w, r)
+			return
+		}
+		files = append(files, file)
+	}
+	if entryCount == 0 {
+		ErrorBadRequest(r).WithMessage("no nzb file found").Send(w, r)
+		return
+	}
+
+	items := make([]NzbUploadBatchItemResponse, 0, entryCount)
+	queueEntry := func(entry nzbUploadBatchEntry) {
+		item := NzbUploadBatchItemResponse{Filename: entry.filename}
+		defer func() { items = append(items, item) }()
+		if entry.err != "" {
+			item.Error = entry.err
+			return
+		}
+		uploaded, err := queueUploadedNZB(r, entry.blob, filepath.Base(entry.filename), "")
+		if err != nil {
+			var apiErr *server.APIError
+			if errors.As(err, &apiErr) {
+				item.Error = apiErr.Message
+			} else {
+				item.Error = err.Error()
+			}
+			return
+		}
+		item.Duplicate = uploaded.duplicate
+		if uploaded.queueItem != nil {
+			data := toNzbQueueItemResponse(uploaded.queueItem)
+			item.Data = &data
+		}
+	}
+	for _, file := range files {
+		if file.zipFiles == nil {
+			queueEntry(nzbUploadBatchEntry{filename: file.filename, blob: file.blob})
+			continue
+		}
+		for _, f := range file.zipFiles {
+			queueEntry(readNZBZipEntry(f, config.Newz.NZBFileMaxSize))
+		}
+	}
+
+	SendData(w, r, 200, items)
+}
+
+func handleRequeueNZB(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	info, err := nzb_info.GetById(id)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if info == nil {
+		ErrorNotFound(r).WithMessage("nzb info not found").Send(w, r)
+		return
+	}
+
+	queueId, err := nzb_info.QueueJob(info.User, info.Name, info.URL, "", 0, info.Password)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	queueItem, err := nzb_info.GetJobById(queueId)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	SendData(w, r, 200, toNzbQueueItemResponse(queueItem))
+}
+
+type InspectNZBRequest struct {
+	Password *string `json:"password"`
+}
+
+func handleInspectNZB(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	info, err := nzb_info.GetById(id)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if info == nil {
+		ErrorNotFound(r).WithMessage("nzb info not found").Send(w, r)
+		return
+	}
+
+	request := &InspectNZBRequest{}
+	if r.ContentLength != 0 {
+		if err := ReadRequestBodyJSON(r, request); err != nil {
+			SendError(w, r, err)
+			return
+		}
+	}
+
+	password := info.Password
+	if request.Password != nil {
+		password = *request.Password
+	}
+
+	if err := nzb_info.Inspect(r.Context(), info, password); err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	info, err = nzb_info.GetById(id)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	SendData(w, r, 200, toNZBResponse(info))
+}
+
+func handleCancelNZB(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	info, err := nzb_info.GetById(id)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if info == nil {
+		ErrorNotFound(r).WithMessage("nzb info not found").Send(w, r)
+		return
+	}
+
+	if !nzb_info.Cancel(info.Hash) {
+		ErrorBadRequest(r).WithMessage("no running inspection").Send(w, r)
+		return
+	}
+
+	info.Status = string(store.NewzStatusCancelled)
+	SendData(w, r, 200, toNZBResponse(info))
+}
+
+// maximum length of the `match` pattern for streaming the largest matching file
+const maxStreamMatchPatternLength = 256
+
+// maximum number of newsgroups in the `groups` override for streaming
+const maxStreamGroupsOverride = 10
+
+func handleStreamNZBFile(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	info, err := nzb_info.GetById(id)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if info == nil {
+		ErrorNotFound(r).WithMessage("nzb info not found").Send(w, r)
+		return
+	}
+
+	serveNZBStream(w, r, &nzbStreamSource{
+		URL:          info.URL,
+		Name:         info.Name,
+		Password:     info.Password,
+		ContentFiles: info.ContentFiles.Data,
+		Hash:         info.Hash,
+		Providers:    info.Providers,
+		Path:         r.PathValue("path"),
+	})
+}
+
+// handleStreamNZBURL streams from the nzb at the given url, without storing
+// it, i.e. it is not queued and does not show up in the nzb list.
+func handleStreamNZBURL(w http.ResponseWriter, r *http.Request) {
+	link := r.URL.Query().Get("url")
+	if link == "" {
+		ErrorBadRequest(r).WithMessage("missing url").Send(w, r)
+		return
+	}
+	if u, err := url.Parse(link); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		ErrorBadRequest(r).WithMessage("invalid url").Send(w, r)
+		return
+	}
+
+	serveNZBStream(w, r, &nzbStreamSource{
+		URL:       link,
+		Password:  r.URL.Query().Get("password"),
+		Path:      r.URL.Query().Get("path"),
+		Stateless: true,
+	})
+}
+
+type nzbStreamSource struct {
+	URL          string
+	Name         string
+	Password     string
+	ContentFiles []usenet_pool.NZBContentFile
+	Hash         string   // downloaded bytes are attributed to it
+	Providers    []string // provider allowlist, any if empty
+	Path         string
+	Stateless    bool // not stored, so the nzb limits are checked here
+}
+
+func serveNZBStream(w http.ResponseWriter, r *http.Request, src *nzbStreamSource) {
+	ctx := GetReqCtx(r)
+
+	var err error
+
+	sample := r.URL.Query().Get("sample") == "1"
+	partial := r.URL.Query().Get("partial") == "1"
+	cachedOnly := r.URL.Query().Get("cached_only") == "1"
+	noCache := r.URL.Query().Get("no_cache") == "1"
+	if cachedOnly && noCache {
+		ErrorBadRequest(r).WithMessage("cached_only and no_cache are mutually exclusive").Send(w, r)
+		return
+	}
+	compressed := r.URL.Query().Get("compressed") == "1"
+	download := r.URL.Query().Get("download") == "1"
+	contentType := r.URL.Query().Get("content_type")
+	if contentType != "" && !usenet_pool.IsContentTypeOverrideAllowed(contentType) {
+		ErrorBadRequest(r).WithMessage("unsupported content_type: "+contentType).Send(w, r)
+		return
+	}
+	videoContentType := r.URL.Query().Get("video_content_type")
+	if videoContentType != "" && !usenet_pool.IsContentTypeOverrideAllowed(videoContentType) {
+		ErrorBadRequest(r).WithMessage("unsupported video_content_type: "+videoContentType).Send(w, r)
+		return
+	}
+
+	var match *regexp.Regexp
+	if pattern := r.URL.Query().Get("match"); pattern != "" {
+		if len(pattern) > maxStreamMatchPatternLength {
+			ErrorBadRequest(r).WithMessage(fmt.Sprintf("match pattern too long, max %d characters", maxStreamMatchPatternLength)).Send(w, r)
+			return
+		}
+		match, err = regexp.Compile(pattern)
+		if err != nil {
+			ErrorBadRequest(r).WithMessage("invalid match pattern: "+err.Error()).Send(w, r)
+			return
+		}
+	}
+
+	var groups []string
+	if v := r.URL.Query().Get("groups"); v != "" {
+		groups = strings.Split(v, ",")
+		if len(groups) > maxStreamGroupsOverride {
+			ErrorBadRequest(r).WithMessage(fmt.Sprintf("too many groups, max %d", maxStreamGroupsOverride)).Send(w, r)
+			return
+		}
+		for i := range groups {
+			groups[i] = strings.TrimSpace(groups[i])
+			if !usenet_pool.IsValidNewsgroup(groups[i]) {
+				ErrorBadRequest(r).WithMessage("invalid group: "+groups[i]).Send(w, r)
+				return
+			}
+		}
+	}
+	keepGroups := r.URL.Query().Get("keep_groups") == "1"
+
+	path := src.Path
+	if path == "" && !sample && match == nil {
+		ErrorBadRequest(r).WithMessage("missing path").Send(w, r)
+		return
+	}
+
+	var nzbFile *nzb_info.NZBFile
+	if src.Stateless {
+		nzbFile, err = nzb_info.FetchNZBFileWithoutQueue(src.URL, src.Name, ctx.Log)
+	} else {
+		nzbFile, err = nzb_info.FetchNZBFile(src.URL, src.Name, ctx.Log)
+	}
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	nzbDoc, err := nzb.ParseBytes(nzbFile.Blob)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if src.Stateless {
+		if msg := checkNZBLimits(nzbDoc); msg != "" {
+			ErrorUnprocessableEntity(r).WithMessage(msg).Send(w, r)
+			return
+		}
+	}
+
+	pool, err := usenetmanager.GetPool()
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if pool == nil {
+		ErrorBadRequest(r).WithMessage("no NNTP providers configured").Send(w, r)
+		return
+	}
+
+	streamConfig := &usenet_pool.StreamConfig{
+		Password:             src.Password,
+		ContentFiles:         src.ContentFiles,
+		NZBHash:              src.Hash,
+		RateLimitBytesPerSec: config.Newz.StreamRateLimit,
+		AllowPartial:         partial,
+		CachedOnly:           cachedOnly,
+		BypassCache:          noCache,
+		CacheStats:           &usenet_pool.CacheStats{},
+		AllowCompressed:      compressed,
+		VideoContentType:     videoContentType,
+		GroupsOverride:       groups,
+		KeepFileGroups:       keepGroups,
+		ProviderAllowlist:    src.Providers,
+	}
+
+	releaseStream, err := usenetmanager.AcquireUserStream(ctx.Session.User, r.URL.RequestURI())
+	if err != nil {
+		ErrorTooManyRequests(r).WithMessage(err.Error()).Send(w, r)
+		return
+	}
+	defer releaseStream()
+
+	streamCtx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	var stream *usenet_pool.Stream
+	if sample {
+		stream, err = pool.StreamSampleFile(streamCtx, nzbDoc, streamConfig)
+	} else if match != nil {
+		stream, err = pool.StreamLargestFileMatching(streamCtx, nzbDoc, match, streamConfig)
+	} else {
+		stream, err = pool.StreamByContentPath(streamCtx, nzbDoc, path, streamConfig)
+	}
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	content := usenet_pool.NewIdleWatchdog(stream, config.Newz.StreamIdleTimeout, func() {
+		ctx.Log.Warn("closing idle stream", "path", stream.Path)
+		cancel()
+	})
+	defer content.Close()
+
+	if contentType == "" {
+		contentType = stream.ContentType
+	}
+	w.Header().Set("Content-Type", contentType)
+	if download {
+		w.Header().Set("Content-Disposition", server.ContentDisposition("attachment", stream.Name))
+	} else {
+		w.Header().Set("Content-Disposition", server.ContentDisposition("inline", stream.Name))
+	}
+	w.Header().Set(server.HEADER_STREMTHRU_CONTENT_PATH, stream.Path)
+	if stream.Truncated {
+		w.Header().Set(server.HEADER_STREMTHRU_TRUNCATED, "1")
+	}
+
+	getCacheStatus := func() string {
+		return streamConfig.CacheStats.Count().Status()
+	}
+	if stream.ForwardOnly {
+		server.ServeForwardOnlyContent(w, r, stream.Size, content, getCacheStatus)
+		return
+	}
+
+	w.Header().Set("Content-Length", strconv.FormatInt(stream.Size, 10))
+	w.Header().Set("Accept-Ranges", "bytes")
+	server.ServeContentWithCacheStatus(w, r, stream.Name, nzbFile.Mod, content, getCacheStatus)
+}
+
+func handleDownloadAllNZB(w http.ResponseWriter, r *http.Request) {
+	ctx := GetReqCtx(r)
+
+	id := r.PathValue("id")
+
+	info, err := nzb_info.GetById(id)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if info == nil {
+		ErrorNotFound(r).WithMessage("nzb info not found").Send(w, r)
+		return
+	}
+
+	paths := usenet_pool.GetStreamableVideoContentPaths(info.ContentFiles.Data)
+	if len(paths) == 0 {
+		ErrorNotFound(r).WithMessage("no streamable video found").Send(w, r)
+		return
+	}
+
+	nzbFile, err := nzb_info.FetchNZBFile(info.URL, info.Name, ctx.Log)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	nzbDoc, err := nzb.ParseBytes(nzbFile.Blob)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	pool, err := usenetmanager.GetPool()
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if pool == nil {
+		ErrorBadRequest(r).WithMessage("no NNTP providers configured").Send(w, r)
+		return
+	}
+
+	streamConfig := &usenet_pool.StreamConfig{
+		Password:             info.Password,
+		ContentFiles:         info.ContentFiles.Data,
+		NZBHash:              info.Hash,
+		RateLimitBytesPerSec: config.Newz.StreamRateLimit,
+		ProviderAllowlist:    info.Providers,
+	}
+
+	releaseStream, err := usenetmanager.AcquireUserStream(ctx.Session.User, r.URL.RequestURI())
+	if err != nil {
+		ErrorTooManyRequests(r).WithMessage(err.Error()).Send(w, r)
+		return
+	}
+	defer releaseStream()
+
+	streamCtx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	var zw *zip.Writer
+	var lastErr error
+	for _, path := range paths {
+		stream, err := pool.StreamByContentPath(streamCtx, nzbDoc, path, streamConfig)
+		if err != nil {
+			ctx.Log.Warn("download all - failed to open stream, skipping", "error", err, "path", path)
+			lastErr = err
+			continue
+		}
+
+		if zw == nil {
+			w.Header().Set("Content-Type", "application/zip")
+			w.Header().Set("Content-Disposition", server.ContentDisposition("attachment", info.Name+".zip"))
+			zw = zip.NewWriter(w)
+		}
+
+		content := usenet_pool.NewIdleWatchdog(stream, config.Newz.StreamIdleTimeout, func() {
+			ctx.Log.Warn("download all - closing idle stream", "path", stream.Path)
+			cancel()
+		})
+		err = func() error {
+			defer content.Close()
+			entry, err := zw.CreateHeader(&zip.FileHeader{
+				Name:               strings.ReplaceAll(stream.Path, "::", "/"),
+				Method:             zip.Store,
+				Modified:           nzbFile.Mod,
+				UncompressedSize64: uint64(stream.Size),
+			})
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(entry, content)
+			return err
+		}()
+		if err != nil {
+			// response is already partially written, nothing to recover
+			ctx.Log.Error("download all - failed to write zip entry", "error", err, "path", path)
+			return
+		}
+	}
+
+	if zw == nil {
+		SendError(w, r, lastErr)
+		return
+	}
+	if err := zw.Close(); err != nil {
+		ctx.Log.Error("download all - failed to finish zip", "error", err)
+	}
+}
+
+// getNZBPlaylistEntryFilename returns the filename of the innermost part of
+// the content path.
+func getNZBPlaylistEntryFilename(contentPath string) string {
+	name := contentPath
+	if idx := strings.LastIndex(name, "::"); idx != -1 {
+		name = name[idx+2:]
+	}
+	return filepath.Base(name)
+}
+
+// getNZBPlaylistEntryTitle derives a readable title for the playlist entry
+// from the file name, with season/episode info when it can be parsed.
+func getNZBPlaylistEntryTitle(contentPath string) string {
+	name := getNZBPlaylistEntryFilename(contentPath)
+
+	pttr, err := util.ParseTorrentTitle(name)
+	if err != nil || pttr.Title == "" {
+		return name
+	}
+
+	title := pttr.Title
+	if len(pttr.Seasons) > 0 && len(pttr.Episodes) > 0 {
+		title += fmt.Sprintf(" S%02dE%02d", pttr.Seasons[0], pttr.Episodes[0])
+	} else if len(pttr.Episodes) > 0 {
+		title += fmt.Sprintf(" E%02d", pttr.Episodes[0])
+	} else if pttr.Year != "" {
+		title += " (" + pttr.Year + ")"
+	}
+	return title
+}
+
+func handleGetNZBPlaylist(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	info, err := nzb_info.GetById(id)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if info == nil {
+		ErrorNotFound(r).WithMessage("nzb info not found").Send(w, r)
+		return
+	}
+
+	paths := usenet_pool.GetStreamableVideoContentPaths(info.ContentFiles.Data)
+	if len(paths) == 0 {
+		ErrorNotFound(r).WithMessage("no streamable video found").Send(w, r)
+		return
+	}
+
+	user := GetReqCtx(r).Session.User
+	playlist, err := buildNZBPlaylist(paths, func(path string) (string, error) {
+		return stremthru.CreateNewzStreamLink(user, info.Hash, path, getNZBPlaylistEntryFilename(path))
+	})
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "audio/x-mpegurl")
+	w.Header().Set("Content-Disposition", server.ContentDisposition("inline", info.Name+".m3u8"))
+	w.WriteHeader(http.StatusOK)
+	w.Write(playlist)
+}
+
+// buildNZBPlaylist lists the content paths as m3u8 entries, linked by
+// getLink.
+func buildNZBPlaylist(paths []string, getLink func(path string) (string, error)) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("#EXTM3U\n")
+	for _, path := range paths {
+		link, err := getLink(path)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString("#EXTINF:-1," + getNZBPlaylistEntryTitle(path) + "\n")
+		buf.WriteString(link + "\n")
+	}
+	return buf.Bytes(), nil
+}
+
+type NZBAttachmentResponse struct {
+	Name        string `json:"name"`
+	MimeType    string `json:"mime_type"`
+	Description string `json:"description,omitempty"`
+	Size        int64  `json:"size"`
+}
+
+type NZBAttachmentsResponse struct {
+	Path        string                  `json:"path"`
+	Attachments []NZBAttachmentResponse `json:"attachments"`
+}
+
+// getNZBWithInfo loads the nzb info by the "id" path param, along with the
+// parsed NZB file.
+func getNZBWithInfo(r *http.Request) (*nzb_info.NZBInfo, *nzb.NZB, error) {
+	ctx := GetReqCtx(r)
+
+	id := r.PathValue("id")
+
+	info, err := nzb_info.GetById(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info == nil {
+		return nil, nil, ErrorNotFound(r).WithMessage("nzb info not found")
+	}
+
+	nzbFile, err := nzb_info.FetchNZBFile(info.URL, info.Name, ctx.Log)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nzbDoc, err := nzb.ParseBytes(nzbFile.Blob)
+	if err != nil {
+		return nil, nil, err
+	}
+	return info, nzbDoc, nil
+}
+
+func getNZBPool(r *http.Request) (*usenet_pool.Pool, error) {
+	pool, err := usenetmanager.GetPool()
+	if err != nil {
+		return nil, err
+	}
+	if pool == nil {
+		return nil, ErrorBadRequest(r).WithMessage("no NNTP providers configured")
+	}
+	return pool, nil
+}
+
+// getNZBContext restricts the segments fetched with the request context to
+// the providers of the nzb.
+func getNZBContext(r *http.Request, info *nzb_info.NZBInfo) context.Context {
+	return usenet_pool.WithProviderAllowlist(r.Context(), info.Providers)
+}
+
+func getNZBStreamConfig(info *nzb_info.NZBInfo) *usenet_pool.StreamConfig {
+	return &usenet_pool.StreamConfig{
+		Password:          info.Password,
+		ContentFiles:      info.ContentFiles.Data,
+		NZBHash:           info.Hash,
+		ProviderAllowlist: info.Providers,
+	}
+}
+
+// openNZBVideo resolves the video by the "path" query param, or the largest
+// file when missing.
+func openNZBVideo(r *http.Request, info *nzb_info.NZBInfo, nzbDoc *nzb.NZB) (*usenet_pool.Stream, error) {
+	pool, err := getNZBPool(r)
+	if err != nil {
+		return nil, err
+	}
+
+	streamConfig := getNZBStreamConfig(info)
+	if path := r.URL.Query().Get("path"); path != "" {
+		return pool.StreamByContentPath(r.Context(), nzbDoc, path, streamConfig)
+	}
+	return pool.StreamLargestFile(r.Context(), nzbDoc, streamConfig)
+}
+
+// openNZBVideoAttachments opens the video and reads its matroska attachments.
+func openNZBVideoAttachments(r *http.Request) (*usenet_pool.Stream, []usenet_pool.MKVAttachment, error) {
+	info, nzbDoc, err := getNZBWithInfo(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stream, err := openNZBVideo(r, info, nzbDoc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attachments, err := usenet_pool.ReadMKVAttachments(stream.ReadSeekCloser, stream.Size)
+	if err != nil {
+		stream.Close()
+		if errors.Is(err, usenet_pool.ErrNotMatroska) {
+			return nil, nil, ErrorUnprocessableEntity(r).WithMessage(err.Error())
+		}
+		return nil, nil, err
+	}
+	return stream, attachments, nil
+}
+
+func handleGetNZBAttachments(w http.ResponseWriter, r *http.Request) {
+	stream, attachments, err := openNZBVideoAttachments(r)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	defer stream.Close()
+
+	items := make([]NZBAttachmentResponse, len(attachments))
+	for i, att := range attachments {
+		items[i] = NZBAttachmentResponse{
+			Name:        att.Name,
+			MimeType:    att.MimeType,
+			Description: att.Description,
+			Size:        att.Size,
+		}
+	}
+
+	SendData(w, r, 200, NZBAttachmentsResponse{
+		Path:        stream.Path,
+		Attachments: items,
+	})
+}
+
+func handleGetNZBAttachment(w http.ResponseWriter, r *http.Request) {
+	ctx := GetReqCtx(r)
+
+	stream, attachments, err := openNZBVideoAttachments(r)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	defer stream.Close()
+
+	name := r.PathValue("name")
+	idx := slices.IndexFunc(attachments, func(att usenet_pool.MKVAttachment) bool {
+		return att.Name == name
+	})
+	if idx == -1 {
+		ErrorNotFound(r).WithMessage("attachment not found").Send(w, r)
+		return
+	}
+	att := attachments[idx]
+
+	if _, err := stream.Seek(att.Offset, io.SeekStart); err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	contentType := att.MimeType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(att.Size, 10))
+	w.Header().Set("Content-Disposition", server.ContentDisposition("attachment", att.Name))
+	w.Header().Set(server.HEADER_STREMTHRU_CONTENT_PATH, stream.Path)
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.CopyN(w, stream, att.Size); err != nil {
+		// response is already partially written, nothing to recover
+		ctx.Log.Error("failed to write attachment", "error", err, "name", att.Name)
+	}
+}
+
+type NZBMediaInfoResponse struct {
+	Path       string  `json:"path"`
+	Container  string  `json:"container"`
+	VideoCodec string  `json:"video_codec"`
+	Width      int     `json:"width"`
+	Height     int     `json:"height"`
+	Duration   float64 `json:"duration"` // in seconds, 0 if unknown
+}
+
+// nzbMediaInfoCache holds the media info by the nzb content and the requested
+// path, which is stable for the same content.
+var nzbMediaInfoCache = cache.NewCache[NZBMediaInfoResponse](&cache.CacheConfig{
+	Name:     "newz_nzb_media_info",
+	Lifetime: 7 * 24 * time.Hour,
+})
+
+// handleGetNZBMediaInfo reads the container headers of the video, without
+// streaming the rest of it.
+func handleGetNZBMediaInfo(w http.ResponseWriter, r *http.Request) {
+	info, nzbDoc, err := getNZBWithInfo(r)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	cacheKey := nzbDoc.HashByContent() + ":" + r.URL.Query().Get("path")
+	var res NZBMediaInfoResponse
+	if nzbMediaInfoCache.Get(cacheKey, &res) {
+		SendData(w, r, 200, res)
+		return
+	}
+
+	stream, err := openNZBVideo(r, info, nzbDoc)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	defer stream.Close()
+
+	mediaInfo, err := usenet_pool.ReadMediaInfo(stream.ReadSeekCloser, stream.Size)
+	if err != nil {
+		if errors.Is(err, usenet_pool.ErrUnsupportedContainer) {
+			ErrorUnprocessableEntity(r).WithMessage(err.Error()).Send(w, r)
+			return
+		}
+		SendError(w, r, err)
+		return
+	}
+
+	res = NZBMediaInfoResponse{
+		Path:       stream.Path,
+		Container:  mediaInfo.Container,
+		VideoCodec: mediaInfo.VideoCodec,
+		Width:      mediaInfo.Width,
+		Height:     mediaInfo.Height,
+		Duration:   mediaInfo.Duration.Seconds(),
+	}
+	if err := nzbMediaInfoCache.Add(cacheKey, res); err != nil {
+		GetReqCtx(r).Log.Warn("failed to cache media info", "error", err)
+	}
+	SendData(w, r, 200, res)
+}
+
+// handleGetNZBSegment fetches a single segment and responds with its decoded
+// bytes, to help figuring out which article of a post is broken.
+func handleGetNZBSegment(w http.ResponseWriter, r *http.Request) {
+	fileIdx, err := strconv.Atoi(r.PathValue("fileIndex"))
+	if err != nil {
+		ErrorBadRequest(r).WithMessage("invalid file index").Send(w, r)
+		return
+	}
+	segmentNumber, err := strconv.Atoi(r.PathValue("segmentNumber"))
+	if err != nil {
+		ErrorBadRequest(r).WithMessage("invalid segment number").Send(w, r)
+		return
+	}
+
+	info, nzbDoc, err := getNZBWithInfo(r)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	if fileIdx < 0 || fileIdx >= len(nzbDoc.Files) {
+		ErrorNotFound(r).WithMessage("file not found").Send(w, r)
+		return
+	}
+	file := &nzbDoc.Files[fileIdx]
+	segmentIdx := slices.IndexFunc(file.Segments, func(s nzb.Segment) bool {
+		return s.Number == segmentNumber
+	})
+	if segmentIdx == -1 {
+		ErrorNotFound(r).WithMessage("segment not found").Send(w, r)
+		return
+	}
+
+	pool, err := getNZBPool(r)
+	if err != nil {
 		SendError(w, r, err)
 		return
 	}
 
-	SendData(w, r, 200, toNzbQueueItemResponse(queueItem))
+	data, err := pool.FetchSegment(getNZBContext(r, info), &file.Segments[segmentIdx], file.Groups)
+	if err != nil {
+		if usenet_pool.IsCRCMismatchError(err) {
+			w.Header().Set(server.HEADER_STREMTHRU_SEGMENT_CRC, "mismatch")
+			ErrorUnprocessableEntity(r).WithMessage("segment crc mismatch").WithCause(err).Send(w, r)
+			return
+		}
+		SendError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data.Body)))
+	w.Header().Set("Content-Disposition", server.ContentDisposition("attachment", fmt.Sprintf("%s.%d.bin", file.Name(), segmentNumber)))
+	w.Header().Set(server.HEADER_STREMTHRU_SEGMENT_BYTE_RANGE, fmt.Sprintf("%d-%d", data.ByteRange.Start, data.ByteRange.End-1))
+	w.Header().Set(server.HEADER_STREMTHRU_SEGMENT_CRC, "ok")
+	w.Header().Set(server.HEADER_STREMTHRU_SEGMENT_FILE_SIZE, strconv.FormatInt(data.FileSize, 10))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data.Body)
 }
 
-func handleStreamNZBFile(w http.ResponseWriter, r *http.Request) {
+const nzbWarmUpTimeout = 5 * time.Minute
+
+type NZBWarmUpResponse struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// handleWarmUpNZB resolves the streamable file and pre-fetches its first bytes
+// into the segment cache in background, so that playback starts faster.
+func handleWarmUpNZB(w http.ResponseWriter, r *http.Request) {
 	ctx := GetReqCtx(r)
 
 	id := r.PathValue("id")
 
-	info, err := nzb_info.GetById(id)
+	size := config.Newz.WarmUpSize
+	if v := r.URL.Query().Get("size"); v != "" {
+		n := util.ToBytes(v)
+		if n <= 0 {
+			ErrorBadRequest(r).WithMessage("invalid size").Send(w, r)
+			return
+		}
+		size = min(n, size)
+	}
+	if size <= 0 {
+		ErrorBadRequest(r).WithMessage("warm up is disabled").Send(w, r)
+		return
+	}
+
+	info, nzbDoc, err := getNZBWithInfo(r)
 	if err != nil {
 		SendError(w, r, err)
 		return
 	}
-	if info == nil {
-		ErrorNotFound(r).WithMessage("nzb info not found").Send(w, r)
+
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		if paths := usenet_pool.GetStreamableVideoContentPaths(info.ContentFiles.Data); len(paths) > 0 {
+			path = paths[0]
+		}
+	}
+
+	pool, err := getNZBPool(r)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	streamConfig := getNZBStreamConfig(info)
+
+	releaseStream, err := usenetmanager.AcquireUserStream(ctx.Session.User, r.URL.RequestURI())
+	if err != nil {
+		ErrorTooManyRequests(r).WithMessage(err.Error()).Send(w, r)
+		return
+	}
+
+	// the stream outlives the request, so it can not use the request context
+	warmUpCtx, cancel := context.WithTimeout(context.Background(), nzbWarmUpTimeout)
+
+	var stream *usenet_pool.Stream
+	if path != "" {
+		stream, err = pool.StreamByContentPath(warmUpCtx, nzbDoc, path, streamConfig)
+	} else {
+		stream, err = pool.StreamLargestFile(warmUpCtx, nzbDoc, streamConfig)
+	}
+	if err != nil {
+		cancel()
+		releaseStream()
+		SendError(w, r, err)
 		return
 	}
 
-	path := r.PathValue("path")
+	size = min(size, stream.Size)
+	log := ctx.Log
+	go func() {
+		defer releaseStream()
+		defer cancel()
+		defer stream.Close()
+
+		start := time.Now()
+		n, err := io.CopyN(io.Discard, stream, size)
+		if err != nil && err != io.EOF {
+			log.Warn("warm up - failed to pre-fetch", "error", err, "id", id, "path", stream.Path, "bytes", n)
+			return
+		}
+		log.Debug("warm up - done", "id", id, "path", stream.Path, "bytes", n, "duration", time.Since(start))
+	}()
+
+	SendData(w, r, 202, NZBWarmUpResponse{
+		Path: stream.Path,
+		Size: size,
+	})
+}
+
+type NZBArchiveEntryResponse struct {
+	Name       string `json:"name"`
+	Size       int64  `json:"size"`
+	Streamable bool   `json:"streamable"`
+}
+
+func handleListNZBArchive(w http.ResponseWriter, r *http.Request) {
+	// `{path...}` must be the last segment, so the `/list` suffix is matched here
+	path, ok := strings.CutSuffix(r.PathValue("path"), "/list")
+	if !ok {
+		ErrorNotFound(r).Send(w, r)
+		return
+	}
 	if path == "" {
 		ErrorBadRequest(r).WithMessage("missing path").Send(w, r)
 		return
 	}
 
-	nzbFile, err := nzb_info.FetchNZBFile(info.URL, info.Name, ctx.Log)
+	info, nzbDoc, err := getNZBWithInfo(r)
 	if err != nil {
 		SendError(w, r, err)
 		return
 	}
 
-	nzbDoc, err := nzb.ParseBytes(nzbFile.Blob)
+	pool, err := getNZBPool(r)
 	if err != nil {
 		SendError(w, r, err)
 		return
 	}
 
-	pool, err := usenetmanager.GetPool()
+	entries, err := pool.ListArchiveByContentPath(r.Context(), nzbDoc, path, getNZBStreamConfig(info))
 	if err != nil {
 		SendError(w, r, err)
 		return
 	}
-	if pool == nil {
-		ErrorBadRequest(r).WithMessage("no NNTP providers configured").Send(w, r)
+
+	items := make([]NZBArchiveEntryResponse, len(entries))
+	for i, e := range entries {
+		items[i] = NZBArchiveEntryResponse{
+			Name:       e.Name,
+			Size:       e.Size,
+			Streamable: e.Streamable,
+		}
+	}
+
+	SendData(w, r, 200, items)
+}
+
+type NZBAvailabilityResponse struct {
+	Available bool                           `json:"available"`
+	Files     []usenet_pool.FileAvailability `json:"files"`
+}
+
+const maxNZBAvailabilitySampleSize = 100
+
+func handleCheckNZBAvailability(w http.ResponseWriter, r *http.Request) {
+	sampleSize := 0
+	if v := r.URL.Query().Get("sample_size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxNZBAvailabilitySampleSize {
+			ErrorBadRequest(r).WithMessage("invalid sample_size").Send(w, r)
+			return
+		}
+		sampleSize = n
+	}
+
+	info, nzbDoc, err := getNZBWithInfo(r)
+	if err != nil {
+		SendError(w, r, err)
 		return
 	}
 
-	streamConfig := &usenet_pool.StreamConfig{
-		Password:     info.Password,
-		ContentFiles: info.ContentFiles.Data,
+	pool, err := getNZBPool(r)
+	if err != nil {
+		SendError(w, r, err)
+		return
 	}
-	stream, err := pool.StreamByContentPath(r.Context(), nzbDoc, path, streamConfig)
+
+	availability, err := pool.CheckAvailability(getNZBContext(r, info), nzbDoc, &usenet_pool.CheckAvailabilityConfig{
+		SampleSize: sampleSize,
+	})
 	if err != nil {
 		SendError(w, r, err)
 		return
 	}
-	defer stream.Close()
 
-	w.Header().Set("Content-Type", stream.ContentType)
-	w.Header().Set("Content-Length", strconv.FormatInt(stream.Size, 10))
-	w.Header().Set("Accept-Ranges", "bytes")
+	SendData(w, r, 200, NZBAvailabilityResponse{
+		Available: availability.IsAvailable(),
+		Files:     availability.Files,
+	})
+}
+
+const maxNZBTraceSampleSize = 100
+
+func handleTraceNZBFetch(w http.ResponseWriter, r *http.Request) {
+	ctx := GetReqCtx(r)
+
+	sampleSize := 0
+	if v := r.URL.Query().Get("sample_size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxNZBTraceSampleSize {
+			ErrorBadRequest(r).WithMessage("invalid sample_size").Send(w, r)
+			return
+		}
+		sampleSize = n
+	}
+
+	info, nzbDoc, err := getNZBWithInfo(r)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	pool, err := getNZBPool(r)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	trace, err := pool.TraceFetch(getNZBContext(r, info), nzbDoc, &usenet_pool.FetchTraceConfig{
+		Id:         ctx.RequestId,
+		SampleSize: sampleSize,
+	})
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	SendData(w, r, 200, trace)
+}
+
+const maxNZBBenchmarkReadSize = 256 * 1024 * 1024
+
+// handleBenchmarkNZBStream test-streams the NZB and reports the time to
+// resolve the stream, the time to the first byte and the throughput.
+func handleBenchmarkNZBStream(w http.ResponseWriter, r *http.Request) {
+	ctx := GetReqCtx(r)
+
+	var readSize int64
+	if v := r.URL.Query().Get("size"); v != "" {
+		n := util.ToBytes(v)
+		if n <= 0 || n > maxNZBBenchmarkReadSize {
+			ErrorBadRequest(r).WithMessage("invalid size").Send(w, r)
+			return
+		}
+		readSize = n
+	}
+
+	info, nzbDoc, err := getNZBWithInfo(r)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	pool, err := getNZBPool(r)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	releaseStream, err := usenetmanager.AcquireUserStream(ctx.Session.User, r.URL.RequestURI())
+	if err != nil {
+		ErrorTooManyRequests(r).WithMessage(err.Error()).Send(w, r)
+		return
+	}
+	defer releaseStream()
+
+	result, err := pool.BenchmarkStream(r.Context(), nzbDoc, &usenet_pool.StreamBenchmarkConfig{
+		ContentPath:  r.URL.Query().Get("path"),
+		ReadSize:     readSize,
+		StreamConfig: getNZBStreamConfig(info),
+	})
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	SendData(w, r, 200, result)
+}
+
+func handleGetNZBCachedSegments(w http.ResponseWriter, r *http.Request) {
+	_, nzbDoc, err := getNZBWithInfo(r)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	pool, err := getNZBPool(r)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
 
-	http.ServeContent(w, r, stream.Name, nzbFile.Mod, stream)
+	SendData(w, r, 200, pool.GetCachedSegments(nzbDoc))
 }
 
 func AddUsenetNZBEndpoints(router *http.ServeMux) {
@@ -482,6 +2144,14 @@ func AddUsenetNZBEndpoints(router *http.ServeMux) {
 			ErrorMethodNotAllowed(r).Send(w, r)
 		}
 	}))
+	router.HandleFunc("/usenet/nzb/parse-batch", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			handleParseNZBBatch(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
 	router.HandleFunc("/usenet/nzb/upload", authed(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodPost:
@@ -491,6 +2161,14 @@ func AddUsenetNZBEndpoints(router *http.ServeMux) {
 		}
 	}))
 
+	router.HandleFunc("/usenet/nzb/upload-batch", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			handleUploadNZBBatch(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
 	router.HandleFunc("/usenet/nzb", authed(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
@@ -499,8 +2177,20 @@ func AddUsenetNZBEndpoints(router *http.ServeMux) {
 			ErrorMethodNotAllowed(r).Send(w, r)
 		}
 	}))
+	router.HandleFunc("/usenet/nzb/stream", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleStreamNZBURL(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
 	router.HandleFunc("/usenet/nzb/{id}", authed(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
+		case http.MethodGet:
+			handleGetNZB(w, r)
+		case http.MethodPatch:
+			handleUpdateNZB(w, r)
 		case http.MethodDelete:
 			handleDeleteNZB(w, r)
 		default:
@@ -515,6 +2205,54 @@ func AddUsenetNZBEndpoints(router *http.ServeMux) {
 			ErrorMethodNotAllowed(r).Send(w, r)
 		}
 	}))
+	router.HandleFunc("/usenet/nzb/{id}/inspect", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			handleInspectNZB(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+	router.HandleFunc("/usenet/nzb/{id}/cancel", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			handleCancelNZB(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+	router.HandleFunc("/usenet/nzb/{id}/trace", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleTraceNZBFetch(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+	router.HandleFunc("/usenet/nzb/{id}/benchmark", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleBenchmarkNZBStream(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+	router.HandleFunc("/usenet/nzb/{id}/cache", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleGetNZBCachedSegments(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+	router.HandleFunc("/usenet/nzb/{id}/availability", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleCheckNZBAvailability(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
 	router.HandleFunc("/usenet/nzb/{id}/xml", authed(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
@@ -523,6 +2261,70 @@ func AddUsenetNZBEndpoints(router *http.ServeMux) {
 			ErrorMethodNotAllowed(r).Send(w, r)
 		}
 	}))
+	router.HandleFunc("/usenet/nzb/{id}/download-all", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleDownloadAllNZB(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+	router.HandleFunc("/usenet/nzb/{id}/playlist.m3u8", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleGetNZBPlaylist(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+	router.HandleFunc("/usenet/nzb/{id}/attachments", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleGetNZBAttachments(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+	router.HandleFunc("/usenet/nzb/{id}/attachments/{name}", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleGetNZBAttachment(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+	router.HandleFunc("/usenet/nzb/{id}/mediainfo", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleGetNZBMediaInfo(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+	router.HandleFunc("/usenet/nzb/{id}/segment/{fileIndex}/{segmentNumber}", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleGetNZBSegment(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+	router.HandleFunc("/usenet/nzb/{id}/warm", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			handleWarmUpNZB(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+	router.HandleFunc("/usenet/nzb/{id}/archive/{path...}", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleListNZBArchive(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
 	router.HandleFunc("/usenet/nzb/{id}/download/{path...}", authed(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
@@ -0,0 +1,160 @@
+package dash_api
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/db/dbtest"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb_info"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testZipEntry struct {
+	name    string
+	content string
+}
+
+func createTestZip(t *testing.T, method uint16, entries ...testZipEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: entry.name, Method: method})
+		require.NoError(t, err)
+		_, err = w.Write([]byte(entry.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestListNZBZipEntries(t *testing.T) {
+	blob := createTestZip(t, zip.Deflate,
+		testZipEntry{"a.nzb", "<nzb/>"},
+		testZipEntry{"readme.txt", "hello"},
+		testZipEntry{"__MACOSX/._b.nzb", "junk"},
+		testZipEntry{"dir/b.NZB.gz", "gzipped"},
+	)
+
+	files, err := listNZBZipEntries(blob, 10)
+	require.NoError(t, err)
+	names := []string{}
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"a.nzb", "dir/b.NZB.gz"}, names)
+
+	t.Run("too many entries", func(t *testing.T) {
+		_, err := listNZBZipEntries(blob, 1)
+		assert.ErrorContains(t, err, "too many entries in zip (max 1)")
+	})
+
+	t.Run("not a zip", func(t *testing.T) {
+		_, err := listNZBZipEntries([]byte("<nzb/>"), 10)
+		assert.Error(t, err)
+	})
+}
+
+func TestReadNZBZipEntry(t *testing.T) {
+	content := "<nzb>0123456789</nzb>"
+
+	t.Run("ok", func(t *testing.T) {
+		files, err := listNZBZipEntries(createTestZip(t, zip.Deflate, testZipEntry{"a.nzb", content}), 10)
+		require.NoError(t, err)
+		entry := readNZBZipEntry(files[0], 1024)
+		assert.Empty(t, entry.err)
+		assert.Equal(t, "a.nzb", entry.filename)
+		assert.Equal(t, content, string(entry.blob))
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		files, err := listNZBZipEntries(createTestZip(t, zip.Deflate, testZipEntry{"a.nzb", content}), 10)
+		require.NoError(t, err)
+		entry := readNZBZipEntry(files[0], 8)
+		assert.Nil(t, entry.blob)
+		assert.Contains(t, entry.err, "nzb file too large")
+	})
+
+	t.Run("understated size", func(t *testing.T) {
+		files, err := listNZBZipEntries(createTestZip(t, zip.Deflate, testZipEntry{"a.nzb", content}), 10)
+		require.NoError(t, err)
+		// a crafted header can not make it read past the claimed size
+		files[0].UncompressedSize64 = 1
+		entry := readNZBZipEntry(files[0], 8)
+		assert.Nil(t, entry.blob)
+		assert.Contains(t, entry.err, "failed to read entry")
+	})
+
+	t.Run("corrupt", func(t *testing.T) {
+		blob := createTestZip(t, zip.Store, testZipEntry{"a.nzb", content})
+		i := bytes.Index(blob, []byte(content))
+		require.GreaterOrEqual(t, i, 0)
+		blob[i] ^= 0xff
+
+		files, err := listNZBZipEntries(blob, 10)
+		require.NoError(t, err)
+		entry := readNZBZipEntry(files[0], 1024)
+		assert.Nil(t, entry.blob)
+		assert.Contains(t, entry.err, "failed to read entry")
+	})
+}
+
+func TestBuildNZBPlaylist(t *testing.T) {
+	paths := []string{"Show.S01E01.1080p.mkv", "Show.S01.rar::Show.S01E02.1080p.mkv"}
+	playlist, err := buildNZBPlaylist(paths, func(path string) (string, error) {
+		return "http://localhost/v0/store/newz/stream/token/" + getNZBPlaylistEntryFilename(path), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "#EXTM3U\n"+
+		"#EXTINF:-1,Show S01E01\n"+
+		"http://localhost/v0/store/newz/stream/token/Show.S01E01.1080p.mkv\n"+
+		"#EXTINF:-1,Show S01E02\n"+
+		"http://localhost/v0/store/newz/stream/token/Show.S01E02.1080p.mkv\n", string(playlist))
+
+	t.Run("link error", func(t *testing.T) {
+		_, err := buildNZBPlaylist(paths, func(path string) (string, error) {
+			return "", assert.AnError
+		})
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+}
+
+func TestGetUploadedNZBId(t *testing.T) {
+	dbtest.Open(t)
+
+	nzbDoc := &nzb.NZB{Files: []nzb.File{
+		{Segments: []nzb.Segment{{MessageId: "b@test.com", Number: 1}}},
+		{Segments: []nzb.Segment{{MessageId: "a@test.com", Number: 1}}},
+	}}
+	contentId := nzbDoc.HashByContent()
+	legacyId := nzbDoc.HashByFileBoundarySegmentIds()
+	require.NotEqual(t, contentId, legacyId)
+
+	createTestNZBInfo := func(id string) {
+		link := "https://example.com/" + id + ".nzb"
+		require.NoError(t, nzb_info.Upsert(&nzb_info.NZBInfo{
+			Id:     id,
+			Hash:   nzb_info.HashNZBFileLink(link),
+			Name:   id,
+			URL:    link,
+			Status: "queued",
+		}))
+	}
+
+	nzbId, err := getUploadedNZBId(nzbDoc)
+	require.NoError(t, err)
+	assert.Equal(t, contentId, nzbId)
+
+	createTestNZBInfo(legacyId)
+	nzbId, err = getUploadedNZBId(nzbDoc)
+	require.NoError(t, err)
+	assert.Equal(t, legacyId, nzbId, "existing nzb keeps its old id")
+
+	createTestNZBInfo(contentId)
+	nzbId, err = getUploadedNZBId(nzbDoc)
+	require.NoError(t, err)
+	assert.Equal(t, contentId, nzbId)
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	usenetmanager "github.com/MunifTanjim/stremthru/internal/usenet/manager"
+	usenet_pool "github.com/MunifTanjim/stremthru/internal/usenet/pool"
 )
 
 func handleGetUsenetPoolInfo(w http.ResponseWriter, r *http.Request) {
@@ -44,6 +45,32 @@ func handleRebuildUsenetPool(w http.ResponseWriter, r *http.Request) {
 	SendData(w, r, 200, info)
 }
 
+type UsenetHealthResponse struct {
+	TotalProviders int                          `json:"total_providers"`
+	Providers      []usenet_pool.ProviderHealth `json:"providers"`
+}
+
+func handleGetUsenetHealth(w http.ResponseWriter, r *http.Request) {
+	pool, err := usenetmanager.GetPool()
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if pool == nil || pool.CountProviders() == 0 {
+		SendData(w, r, 200, UsenetHealthResponse{
+			Providers: []usenet_pool.ProviderHealth{},
+		})
+		return
+	}
+
+	providers := pool.CheckProvidersHealth(r.Context())
+
+	SendData(w, r, 200, UsenetHealthResponse{
+		TotalProviders: len(providers),
+		Providers:      providers,
+	})
+}
+
 func AddUsenetPoolEndpoints(router *http.ServeMux) {
 	authed := EnsureAuthed
 
@@ -56,6 +83,15 @@ func AddUsenetPoolEndpoints(router *http.ServeMux) {
 		}
 	}))
 
+	router.HandleFunc("/usenet/health", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleGetUsenetHealth(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+
 	router.HandleFunc("/usenet/pool/rebuild", authed(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodPost:
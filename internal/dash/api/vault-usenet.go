@@ -20,6 +20,7 @@ type UsenetServerResponse struct {
 	TLSSkipVerify  bool   `json:"tls_skip_verify"`
 	Priority       int    `json:"priority"`
 	IsBackup       bool   `json:"is_backup"`
+	Weight         int    `json:"weight"`
 	MaxConnections int    `json:"max_connections"`
 	Disabled       bool   `json:"disabled"`
 	CreatedAt      string `json:"created_at"`
@@ -37,6 +38,7 @@ func toUsenetServerResponse(item *usenet_server.UsenetServer) UsenetServerRespon
 		TLSSkipVerify:  item.TLSSkipVerify,
 		Priority:       item.Priority,
 		IsBackup:       item.IsBackup,
+		Weight:         item.Weight,
 		MaxConnections: item.MaxConnections,
 		Disabled:       item.Disabled,
 		CreatedAt:      item.CAt.Format(time.RFC3339),
@@ -69,6 +71,7 @@ type CreateUsenetServerRequest struct {
 	TLSSkipVerify  bool   `json:"tls_skip_verify"`
 	Priority       int    `json:"priority"`
 	IsBackup       bool   `json:"is_backup"`
+	Weight         int    `json:"weight"`
 	MaxConnections int    `json:"max_connections"`
 }
 
@@ -109,6 +112,10 @@ func handleCreateUsenetServer(w http.ResponseWriter, r *http.Request) {
 		request.MaxConnections = 10
 	}
 
+	if request.Weight <= 0 {
+		request.Weight = 1
+	}
+
 	server, err := usenet_server.NewUsenetServer(
 		request.Name,
 		request.Host,
@@ -119,6 +126,7 @@ func handleCreateUsenetServer(w http.ResponseWriter, r *http.Request) {
 		request.TLSSkipVerify,
 		request.Priority,
 		request.IsBackup,
+		request.Weight,
 		request.MaxConnections,
 	)
 	if err != nil {
@@ -161,6 +169,7 @@ type UpdateUsenetServerRequest struct {
 	TLSSkipVerify  *bool  `json:"tls_skip_verify"`
 	Priority       *int   `json:"priority"`
 	IsBackup       *bool  `json:"is_backup"`
+	Weight         *int   `json:"weight"`
 	MaxConnections *int   `json:"max_connections"`
 }
 
@@ -219,6 +228,9 @@ func handleUpdateUsenetServer(w http.ResponseWriter, r *http.Request) {
 	if request.IsBackup != nil {
 		server.IsBackup = *request.IsBackup
 	}
+	if request.Weight != nil {
+		server.Weight = max(*request.Weight, 1)
+	}
 	if request.MaxConnections != nil {
 		server.MaxConnections = *request.MaxConnections
 		if server.MaxConnections == 0 {
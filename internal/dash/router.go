@@ -32,6 +32,7 @@ func AddEndpoints(mux *http.ServeMux) {
 	router.HandleFunc("/stats/torrents", authed(dash_api.HandleGetTorrentsStats))
 	router.HandleFunc("/stats/server", authed(dash_api.HandleGetServerStats))
 
+	dash_api.AddCacheEndpoints(router)
 	dash_api.AddIMDBEndpoints(router)
 	dash_api.AddWorkerEndpoints(router)
 	dash_api.AddTorznabIndexerSyncInfoEndpoints(router)
@@ -176,15 +176,32 @@ func Ping() {
 }
 
 func Open() *DB {
-	switch connUri.Dialect {
+	return open(connUri)
+}
+
+// OpenAt opens the database at the given uri instead of the configured one,
+// e.g. a scratch database for tests. It must be of the configured dialect.
+func OpenAt(connectionUri string) *DB {
+	uri, err := ParseConnectionURI(connectionUri)
+	if err != nil {
+		log.Fatalf("[db] failed to parse uri: %v\n", err)
+	}
+	if uri.Dialect != Dialect {
+		log.Fatalf("[db] dialect mismatch: %v, expected %v\n", uri.Dialect, Dialect)
+	}
+	return open(uri)
+}
+
+func open(uri ConnectionURI) *DB {
+	switch uri.Dialect {
 	case DBDialectSQLite:
-		database, err := sql.Open(connUri.DriverName, connUri.DSN(dsnModifiers...))
+		database, err := sql.Open(uri.DriverName, uri.DSN(dsnModifiers...))
 		if err != nil {
 			log.Fatalf("[db] failed to open: %v\n", err)
 		}
 		db.DB = database
 	case DBDialectPostgres:
-		pool, err := pgxpool.New(context.Background(), connUri.DSN())
+		pool, err := pgxpool.New(context.Background(), uri.DSN())
 		if err != nil {
 			log.Fatalf("[db] failed to create connection pool: %v\n", err)
 		}
@@ -195,7 +212,7 @@ func Open() *DB {
 		}
 	}
 
-	db.URI = connUri
+	db.URI = uri
 
 	return db
 }
@@ -0,0 +1,41 @@
+// Package dbtest provides a migrated scratch database for tests.
+package dbtest
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/db"
+	"github.com/MunifTanjim/stremthru/migrations"
+	"github.com/pressly/goose/v3"
+)
+
+// Open points the db package at a fresh sqlite database with the schema
+// migrated, closed at the end of the test. Tests using it can not run in
+// parallel.
+func Open(t testing.TB) {
+	t.Helper()
+
+	if db.Dialect != db.DBDialectSQLite {
+		t.Skip("dbtest: only sqlite is supported")
+	}
+
+	database := db.OpenAt("sqlite://" + filepath.ToSlash(filepath.Join(t.TempDir(), "stremthru.db")))
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	goose.SetBaseFS(migrations.FS)
+	goose.SetTableName("db_migration_version")
+	goose.SetLogger(goose.NopLogger())
+	if err := goose.SetDialect("sqlite"); err != nil {
+		t.Fatalf("dbtest: failed to set dialect: %v", err)
+	}
+	if err := goose.Up(database.DB, "sqlite"); err != nil {
+		if strings.Contains(err.Error(), "no such module: fts5") {
+			t.Skip("dbtest: sqlite without fts5, run with --tags fts5")
+		}
+		t.Fatalf("dbtest: failed to run migrations: %v", err)
+	}
+}
@@ -16,8 +16,17 @@ import (
 	"github.com/MunifTanjim/stremthru/internal/znab"
 )
 
+// getNewznabAPIKey reads the apikey from the query, falling back to the
+// X-Api-Key header sent by some newznab clients.
+func getNewznabAPIKey(r *http.Request) string {
+	if apiKey := r.URL.Query().Get("apikey"); apiKey != "" {
+		return apiKey
+	}
+	return r.Header.Get(server.HEADER_X_API_KEY)
+}
+
 func isNewznabRequestAuthed(r *http.Request) bool {
-	apiKey := r.URL.Query().Get("apikey")
+	apiKey := getNewznabAPIKey(r)
 	if apiKey == "" || strings.ContainsRune(apiKey, ':') {
 		return false
 	}
@@ -31,6 +40,8 @@ func isNewznabRequestAuthed(r *http.Request) bool {
 }
 
 func handleNewznab(w http.ResponseWriter, r *http.Request) {
+	server.GetReqCtx(r).RedactURLQueryParams(r, "apikey")
+
 	t := r.URL.Query().Get("t")
 
 	if t == "" {
@@ -71,7 +82,7 @@ func handleNewznab(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		nzbLinkQuery := url.Values{
-			"apikey": {r.URL.Query().Get("apikey")},
+			"apikey": {getNewznabAPIKey(r)},
 			"t":      {"get"},
 		}
 		for i := range items {
@@ -138,6 +149,8 @@ func handleNewznabGet(w http.ResponseWriter, r *http.Request, o string) {
 }
 
 func handleNewznabGetNZB(w http.ResponseWriter, r *http.Request) {
+	server.GetReqCtx(r).RedactURLQueryParams(r, "apikey")
+
 	if !isNewznabRequestAuthed(r) {
 		server.ErrorForbidden(r).Send(w, r)
 		return
@@ -307,6 +307,42 @@ func SetEntriesProcessing(name string, keys []string) error {
 	return err
 }
 
+var query_touch_entry = fmt.Sprintf(
+	`UPDATE %s SET %s = %s WHERE %s = ? AND %s = ? AND %s = '%s'`,
+	TableName,
+	Column.UpdatedAt, db.CurrentTimestamp,
+	Column.Name,
+	Column.Key,
+	Column.Status, EntryStatusProcessing,
+)
+
+// TouchEntry bumps the last updated time of an entry being processed, as the
+// heartbeat of its worker.
+func TouchEntry(name, key string) error {
+	_, err := db.Exec(query_touch_entry, name, key)
+	return err
+}
+
+var query_requeue_stale_entries = fmt.Sprintf(
+	`UPDATE %s SET %s = '%s', %s = %s WHERE %s = ? AND %s = '%s' AND %s < ?`,
+	TableName,
+	Column.Status, EntryStatusQueued,
+	Column.UpdatedAt, db.CurrentTimestamp,
+	Column.Name,
+	Column.Status, EntryStatusProcessing,
+	Column.UpdatedAt,
+)
+
+// RequeueStaleEntries puts back the entries left processing without a
+// heartbeat since the given time, e.g. by a restart.
+func RequeueStaleEntries(name string, before time.Time) (int64, error) {
+	result, err := db.Exec(query_requeue_stale_entries, name, db.Timestamp{Time: before})
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 var query_get_entry_by_key = fmt.Sprintf(
 	`SELECT %s FROM %s WHERE %s = ? AND %s = ?`,
 	strings.Join(columns, ", "),
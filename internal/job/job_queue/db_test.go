@@ -0,0 +1,67 @@
+package job_queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/db"
+	"github.com/MunifTanjim/stremthru/internal/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setEntryUpdatedAt(t *testing.T, name, key string, updatedAt time.Time) {
+	t.Helper()
+	_, err := db.Exec("UPDATE "+TableName+" SET "+Column.UpdatedAt+" = ? WHERE "+Column.Name+" = ? AND "+Column.Key+" = ?", db.Timestamp{Time: updatedAt}, name, key)
+	require.NoError(t, err)
+}
+
+func getEntry(t *testing.T, name, key string) *JobQueueEntry[string] {
+	t.Helper()
+	entry, err := GetEntryByKey[string](name, key)
+	require.NoError(t, err)
+	require.NotNil(t, entry)
+	return entry
+}
+
+func TestTouchEntry(t *testing.T) {
+	dbtest.Open(t)
+
+	name := "test"
+	old := time.Now().Add(-time.Hour)
+	for _, key := range []string{"processing", "queued"} {
+		require.NoError(t, QueueEntry(name, key, key, time.Now(), 0))
+		setEntryUpdatedAt(t, name, key, old)
+	}
+	require.NoError(t, SetEntriesProcessing(name, []string{"processing"}))
+	setEntryUpdatedAt(t, name, "processing", old)
+
+	require.NoError(t, TouchEntry(name, "processing"))
+	require.NoError(t, TouchEntry(name, "queued"))
+
+	assert.WithinDuration(t, time.Now(), getEntry(t, name, "processing").UpdatedAt.Time, time.Minute)
+	// only the entries being processed have a heartbeat
+	assert.WithinDuration(t, old, getEntry(t, name, "queued").UpdatedAt.Time, time.Second)
+}
+
+func TestRequeueStaleEntries(t *testing.T) {
+	dbtest.Open(t)
+
+	name := "test"
+	for _, key := range []string{"stale", "fresh"} {
+		require.NoError(t, QueueEntry(name, key, key, time.Now(), 0))
+	}
+	require.NoError(t, QueueEntry("other", "stale", "stale", time.Now(), 0))
+	require.NoError(t, SetEntriesProcessing(name, []string{"stale", "fresh"}))
+	require.NoError(t, SetEntriesProcessing("other", []string{"stale"}))
+	setEntryUpdatedAt(t, name, "stale", time.Now().Add(-time.Hour))
+	setEntryUpdatedAt(t, "other", "stale", time.Now().Add(-time.Hour))
+
+	count, err := RequeueStaleEntries(name, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	assert.Equal(t, string(EntryStatusQueued), getEntry(t, name, "stale").Status)
+	assert.Equal(t, string(EntryStatusProcessing), getEntry(t, name, "fresh").Status)
+	assert.Equal(t, string(EntryStatusProcessing), getEntry(t, "other", "stale").Status)
+}
@@ -2,6 +2,7 @@ package job_queue
 
 import (
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/MunifTanjim/stremthru/internal/db"
@@ -15,6 +16,47 @@ type PersistentJobQueue[T any] struct {
 	backoffDelay time.Duration
 	maxRetry     int
 	disabled     bool
+
+	dequeueMu sync.Mutex
+}
+
+// EntryHeartbeatInterval is how often an entry being processed is touched,
+// for telling apart the ones left without a worker.
+const EntryHeartbeatInterval = 30 * time.Second
+
+// Heartbeat calls touch every interval until the returned func is called,
+// passing its errors to onError. Once it returns, touch is not called again.
+func Heartbeat(interval time.Duration, touch func() error, onError func(err error)) func() {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				if err := touch(); err != nil {
+					onError(err)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// heartbeat touches the entry until the returned func is called.
+func (q *PersistentJobQueue[T]) heartbeat(key string) func() {
+	return Heartbeat(EntryHeartbeatInterval, func() error {
+		return TouchEntry(q.name, key)
+	}, func(err error) {
+		log.Error("JobQueue heartbeat failed", "error", err, "name", q.name, "key", key)
+	})
 }
 
 func exponentialBackoff(errorCount int, delay time.Duration) time.Duration {
@@ -47,9 +89,24 @@ func (q *PersistentJobQueue[T]) IsEmpty() bool {
 	return !exists
 }
 
+func (q *PersistentJobQueue[T]) dequeue() (*JobQueueEntry[T], error) {
+	q.dequeueMu.Lock()
+	defer q.dequeueMu.Unlock()
+
+	entry, err := GetFirstEntry[T](q.name)
+	if err != nil || entry == nil {
+		return entry, err
+	}
+	if err := SetEntriesProcessing(q.name, []string{entry.Key}); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Process is safe to call concurrently, each entry is handed to a single caller.
 func (q *PersistentJobQueue[T]) Process(f func(item T) error) {
 	for {
-		entry, err := GetFirstEntry[T](q.name)
+		entry, err := q.dequeue()
 		if err != nil {
 			log.Error("JobQueue dequeue failed", "error", err, "name", q.name)
 			return
@@ -57,11 +114,10 @@ func (q *PersistentJobQueue[T]) Process(f func(item T) error) {
 		if entry == nil {
 			return
 		}
-		if err := SetEntriesProcessing(q.name, []string{entry.Key}); err != nil {
-			log.Error("JobQueue set processing failed", "error", err, "name", q.name, "key", entry.Key)
-			return
-		}
-		if err := f(entry.Payload.Data); err != nil {
+		stopHeartbeat := q.heartbeat(entry.Key)
+		err = f(entry.Payload.Data)
+		stopHeartbeat()
+		if err != nil {
 			var delayed *ErrJobQueueItemDelayed
 			if errors.As(err, &delayed) {
 				processAfter := time.Now().Add(delayed.RetryAfter)
@@ -0,0 +1,31 @@
+package job_queue
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeartbeat(t *testing.T) {
+	var touched, failed atomic.Int32
+	stop := Heartbeat(time.Millisecond, func() error {
+		if touched.Add(1)%2 == 0 {
+			return errors.New("touch failed")
+		}
+		return nil
+	}, func(err error) {
+		failed.Add(1)
+	})
+
+	assert.Eventually(t, func() bool {
+		return failed.Load() >= 2
+	}, time.Second, time.Millisecond)
+	stop()
+
+	count := touched.Load()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, count, touched.Load())
+}
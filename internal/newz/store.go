@@ -1,8 +1,11 @@
 package newz
 
 import (
+	"context"
+	"io"
 	"mime/multipart"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -269,58 +272,178 @@ func handleStoreNewzLinkGenerate(w http.ResponseWriter, r *http.Request) {
 	server.SendData(w, r, 200, data)
 }
 
+// no path separators, quotes or control characters, and not a dotfile
+var safeStreamFilenameRegex = regexp.MustCompile(`^[^./\\"\x00-\x1f\x7f][^/\\"\x00-\x1f\x7f]{0,254}$`)
+
+// getStreamFilename returns the filename from the url, if it is safe and has
+// a video extension with a known content type. Players may rely on it, so it
+// is preferred over the detected one.
+func getStreamFilename(r *http.Request) (filename string, contentType string) {
+	filename = r.PathValue("filename")
+	if !safeStreamFilenameRegex.MatchString(filename) || !usenet_pool.IsVideoFilename(filename) {
+		return "", ""
+	}
+	contentType = usenet_pool.GetContentType(filename)
+	if contentType == "application/octet-stream" {
+		return "", ""
+	}
+	return filename, contentType
+}
+
 func handleStoreNewzStreamFile(w http.ResponseWriter, r *http.Request) {
 	ctx := server.GetReqCtx(r)
 	ctx.RedactURLPathValues(r, "token")
 
 	token := r.PathValue("token")
 
-	_, id, path, err := stremthru.UnwrapNewzStreamToken(token)
+	user, id, path, err := stremthru.UnwrapNewzStreamToken(token)
 	if err != nil {
 		server.SendError(w, r, err)
 		return
 	}
 
-	nzbInfo, err := nzb_info.GetByHash(id)
-	if err != nil {
-		server.SendError(w, r, err)
+	partial := r.URL.Query().Get("partial") == "1"
+	download := r.URL.Query().Get("download") == "1"
+	contentType := r.URL.Query().Get("content_type")
+	if contentType != "" && !usenet_pool.IsContentTypeOverrideAllowed(contentType) {
+		server.ErrorBadRequest(r).WithMessage("unsupported content_type: "+contentType).Send(w, r)
 		return
 	}
-	nzbFile, err := nzb_info.FetchNZBFile(nzbInfo.URL, nzbInfo.Name, ctx.Log)
-	if err != nil {
-		server.SendError(w, r, err)
-		return
-	}
-	nzbDoc, err := nzb.ParseBytes(nzbFile.Blob)
-	if err != nil {
-		server.SendError(w, r, err)
-		return
+	cacheKey := token
+	if partial {
+		cacheKey += "?partial"
 	}
 
-	pool, err := usenetmanager.GetPool()
+	releaseStream, err := usenetmanager.AcquireUserStream(user, cacheKey)
 	if err != nil {
-		server.SendError(w, r, err)
-		return
-	}
-	if pool == nil {
-		server.ErrorBadRequest(r).WithMessage("no NNTP providers configured").Send(w, r)
+		server.ErrorTooManyRequests(r).WithMessage(err.Error()).Send(w, r)
 		return
 	}
+	defer releaseStream()
 
-	streamConfig := &usenet_pool.StreamConfig{
-		Password:     nzbInfo.Password,
-		ContentFiles: nzbInfo.ContentFiles.Data,
-	}
-	stream, err := pool.StreamByContentPath(r.Context(), nzbDoc, path, streamConfig)
-	if err != nil {
-		server.SendError(w, r, err)
+	cs := resolvedStreamCache.acquire(cacheKey)
+	if cs == nil {
+		nzbInfo, err := nzb_info.GetByHash(id)
+		if err != nil {
+			server.SendError(w, r, err)
+			return
+		}
+		nzbFile, err := nzb_info.FetchNZBFile(nzbInfo.URL, nzbInfo.Name, ctx.Log)
+		if err != nil {
+			server.SendError(w, r, err)
+			return
+		}
+		nzbDoc, err := nzb.ParseBytes(nzbFile.Blob)
+		if err != nil {
+			server.SendError(w, r, err)
+			return
+		}
+
+		pool, err := usenetmanager.GetPool()
+		if err != nil {
+			server.SendError(w, r, err)
+			return
+		}
+		if pool == nil {
+			server.ErrorBadRequest(r).WithMessage("no NNTP providers configured").Send(w, r)
+			return
+		}
+
+		streamConfig := &usenet_pool.StreamConfig{
+			Password:             nzbInfo.Password,
+			ContentFiles:         nzbInfo.ContentFiles.Data,
+			NZBHash:              nzbInfo.Hash,
+			RateLimitBytesPerSec: config.Newz.StreamRateLimit,
+			ProviderAllowlist:    nzbInfo.Providers,
+			AllowPartial:         partial,
+			CacheStats:           &usenet_pool.CacheStats{},
+			PrefetchGroup:        &usenet_pool.PrefetchGroup{},
+		}
+		// the stream outlives this request, it is canceled on eviction
+		streamCtx, cancel := context.WithCancel(context.Background())
+		stream, err := pool.StreamByContentPath(streamCtx, nzbDoc, path, streamConfig)
+		if err != nil {
+			cancel()
+			server.SendError(w, r, err)
+			return
+		}
+		cs = resolvedStreamCache.add(cacheKey, stream, streamConfig.CacheStats, streamConfig.PrefetchGroup, nzbFile.Mod, cancel)
+	}
+	defer resolvedStreamCache.release(cs)
+
+	stream := cs.stream
+	// the cached stream is reused across requests, only count this one
+	cacheCount := cs.cacheStats.Count()
+
+	// the cached stream is closed on release, a stalled client evicts it and
+	// cancels its fetches, instead of holding it
+	content := usenet_pool.NewIdleWatchdog(unclosableStream{stream}, config.Newz.StreamIdleTimeout, func() {
+		ctx.Log.Warn("evicting idle cached stream", "path", stream.Path)
+		resolvedStreamCache.evict(cs)
+		cs.cancel()
+	})
+	defer content.Close()
+
+	filename, filenameContentType := getStreamFilename(r)
+	if contentType == "" {
+		contentType = filenameContentType
+	}
+	if contentType == "" {
+		contentType = stream.ContentType
+	}
+	w.Header().Set("Content-Type", contentType)
+	if filename == "" {
+		filename = stream.Name
+	}
+	if download {
+		w.Header().Set("Content-Disposition", server.ContentDisposition("attachment", filename))
+	} else {
+		w.Header().Set("Content-Disposition", server.ContentDisposition("inline", filename))
+	}
+	w.Header().Set(server.HEADER_STREMTHRU_CONTENT_PATH, stream.Path)
+	if stream.Truncated {
+		w.Header().Set(server.HEADER_STREMTHRU_TRUNCATED, "1")
+	}
+
+	if stream.ForwardOnly {
+		// consumed by this request, it can not be reused
+		defer resolvedStreamCache.evict(cs)
+		server.ServeForwardOnlyContent(w, r, stream.Size, content, func() string {
+			return cs.cacheStats.Count().Sub(cacheCount).Status()
+		})
 		return
 	}
-	defer stream.Close()
 
-	w.Header().Set("Content-Type", stream.ContentType)
 	w.Header().Set("Content-Length", strconv.FormatInt(stream.Size, 10))
 	w.Header().Set("Accept-Ranges", "bytes")
+	tracker := &readErrorTracker{ReadSeeker: content}
+	server.ServeContentWithCacheStatus(w, r, stream.Name, cs.modTime, tracker, func() string {
+		return cs.cacheStats.Count().Sub(cacheCount).Status()
+	})
+	if tracker.err != nil {
+		ctx.Log.Warn("evicting cached stream after read error", "error", tracker.err)
+		resolvedStreamCache.evict(cs)
+	}
+}
+
+// unclosableStream leaves closing the stream to its owner.
+type unclosableStream struct {
+	io.ReadSeeker
+}
+
+func (unclosableStream) Close() error {
+	return nil
+}
 
-	http.ServeContent(w, r, stream.Name, nzbFile.Mod, stream)
+type readErrorTracker struct {
+	io.ReadSeeker
+	err error
+}
+
+func (t *readErrorTracker) Read(p []byte) (int, error) {
+	n, err := t.ReadSeeker.Read(p)
+	if err != nil && err != io.EOF {
+		t.err = err
+	}
+	return n, err
 }
@@ -0,0 +1,125 @@
+package newz
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	usenet_pool "github.com/MunifTanjim/stremthru/internal/usenet/pool"
+)
+
+const streamCacheIdleTimeout = 30 * time.Second
+
+// cachedStream keeps a resolved stream open across range requests for the
+// same token, so seeking does not re-open the archive every time.
+type cachedStream struct {
+	token      string
+	stream     *usenet_pool.Stream
+	cacheStats *usenet_pool.CacheStats
+	prefetch   *usenet_pool.PrefetchGroup
+	modTime    time.Time
+	cancel     context.CancelFunc
+	timer      *time.Timer
+	inUse      bool
+	evicted    bool
+}
+
+func (cs *cachedStream) close() {
+	cs.stream.Close()
+	cs.cancel()
+}
+
+type streamCache struct {
+	mu      sync.Mutex
+	streams map[string]*cachedStream
+}
+
+var resolvedStreamCache = &streamCache{
+	streams: map[string]*cachedStream{},
+}
+
+// acquire returns the cached stream for token for exclusive use, or nil if
+// there is none or it is already being used by another request.
+func (c *streamCache) acquire(token string) *cachedStream {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cs, ok := c.streams[token]
+	if !ok || cs.inUse {
+		return nil
+	}
+	cs.inUse = true
+	cs.timer.Stop()
+	return cs
+}
+
+// add stores a freshly resolved stream, already acquired by the caller. If
+// another stream is cached for the same token, the new one is not cached and
+// gets closed on release.
+func (c *streamCache) add(token string, stream *usenet_pool.Stream, cacheStats *usenet_pool.CacheStats, prefetch *usenet_pool.PrefetchGroup, modTime time.Time, cancel context.CancelFunc) *cachedStream {
+	cs := &cachedStream{
+		token:      token,
+		stream:     stream,
+		cacheStats: cacheStats,
+		prefetch:   prefetch,
+		modTime:    modTime,
+		cancel:     cancel,
+		inUse:      true,
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.streams[token]; ok {
+		cs.evicted = true
+		return cs
+	}
+	cs.timer = time.AfterFunc(streamCacheIdleTimeout, func() {
+		c.evictIdle(cs)
+	})
+	cs.timer.Stop()
+	c.streams[token] = cs
+	return cs
+}
+
+// release makes the stream available to the next request. While it sits
+// idle, its read-ahead is paused, so it does not keep fetching segments
+// for no one.
+func (c *streamCache) release(cs *cachedStream) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cs.inUse = false
+	if cs.evicted {
+		cs.close()
+		return
+	}
+	cs.prefetch.Pause()
+	cs.timer.Reset(streamCacheIdleTimeout)
+}
+
+// evict removes the stream from the cache, closing it once it is released.
+func (c *streamCache) evict(cs *cachedStream) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.streams[cs.token] == cs {
+		delete(c.streams, cs.token)
+		cs.timer.Stop()
+	}
+	cs.evicted = true
+}
+
+func (c *streamCache) evictIdle(cs *cachedStream) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cs.inUse || cs.evicted {
+		return
+	}
+	if c.streams[cs.token] == cs {
+		delete(c.streams, cs.token)
+	}
+	cs.evicted = true
+	cs.close()
+}
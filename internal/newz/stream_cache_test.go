@@ -0,0 +1,126 @@
+package newz
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	usenet_pool "github.com/MunifTanjim/stremthru/internal/usenet/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testStream struct {
+	io.ReadSeeker
+	closed int
+}
+
+func (s *testStream) Close() error {
+	s.closed++
+	return nil
+}
+
+func newTestStreamCache() *streamCache {
+	return &streamCache{streams: map[string]*cachedStream{}}
+}
+
+func addTestStream(t *testing.T, c *streamCache, token string) (*cachedStream, *testStream, *bool) {
+	t.Helper()
+
+	rsc := &testStream{ReadSeeker: strings.NewReader("data")}
+	canceled := false
+	cs := c.add(token, &usenet_pool.Stream{ReadSeekCloser: rsc}, &usenet_pool.CacheStats{}, &usenet_pool.PrefetchGroup{}, time.Time{}, func() {
+		canceled = true
+	})
+	t.Cleanup(func() {
+		if cs.timer != nil {
+			cs.timer.Stop()
+		}
+	})
+	return cs, rsc, &canceled
+}
+
+func TestStreamCache(t *testing.T) {
+	t.Run("AcquireAfterRelease", func(t *testing.T) {
+		c := newTestStreamCache()
+		cs, rsc, _ := addTestStream(t, c, "a")
+
+		assert.Nil(t, c.acquire("a"), "in use by the request that added it")
+
+		c.release(cs)
+		assert.False(t, cs.inUse)
+		assert.Same(t, cs, c.acquire("a"))
+		assert.True(t, cs.inUse)
+		assert.Nil(t, c.acquire("a"), "in use by the request that acquired it")
+		assert.Equal(t, 0, rsc.closed)
+	})
+
+	t.Run("AcquireMissing", func(t *testing.T) {
+		c := newTestStreamCache()
+		assert.Nil(t, c.acquire("a"))
+	})
+
+	t.Run("AddDuplicate", func(t *testing.T) {
+		c := newTestStreamCache()
+		cs, _, _ := addTestStream(t, c, "a")
+		dup, dupRsc, dupCanceled := addTestStream(t, c, "a")
+
+		assert.True(t, dup.evicted)
+		assert.Same(t, cs, c.streams["a"])
+
+		c.release(dup)
+		assert.Equal(t, 1, dupRsc.closed)
+		assert.True(t, *dupCanceled)
+	})
+
+	t.Run("EvictClosesOnRelease", func(t *testing.T) {
+		c := newTestStreamCache()
+		cs, rsc, canceled := addTestStream(t, c, "a")
+
+		c.evict(cs)
+		assert.NotContains(t, c.streams, "a")
+		assert.Equal(t, 0, rsc.closed, "still in use")
+
+		c.release(cs)
+		assert.Equal(t, 1, rsc.closed)
+		assert.True(t, *canceled)
+		assert.Nil(t, c.acquire("a"))
+	})
+
+	t.Run("EvictKeepsReplacement", func(t *testing.T) {
+		c := newTestStreamCache()
+		cs, _, _ := addTestStream(t, c, "a")
+		c.evict(cs)
+		c.release(cs)
+
+		replacement, _, _ := addTestStream(t, c, "a")
+		c.evict(cs)
+		assert.Same(t, replacement, c.streams["a"])
+	})
+
+	t.Run("ExpireIdle", func(t *testing.T) {
+		c := newTestStreamCache()
+		cs, rsc, canceled := addTestStream(t, c, "a")
+		c.release(cs)
+
+		c.evictIdle(cs)
+		assert.NotContains(t, c.streams, "a")
+		assert.Equal(t, 1, rsc.closed)
+		assert.True(t, *canceled)
+
+		c.evictIdle(cs)
+		assert.Equal(t, 1, rsc.closed, "closed only once")
+	})
+
+	t.Run("ExpireSkipsInUse", func(t *testing.T) {
+		c := newTestStreamCache()
+		cs, rsc, _ := addTestStream(t, c, "a")
+		c.release(cs)
+		require.Same(t, cs, c.acquire("a"))
+
+		c.evictIdle(cs)
+		assert.Contains(t, c.streams, "a")
+		assert.Equal(t, 0, rsc.closed)
+	})
+}
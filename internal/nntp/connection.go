@@ -42,7 +42,8 @@ func (c *ConnectionConfig) setDefaults() {
 }
 
 type Connection struct {
-	conn *textproto.Conn
+	conn    *textproto.Conn
+	netConn net.Conn
 
 	connected     bool
 	authenticated bool
@@ -138,6 +139,7 @@ func (c *Connection) Connect(ctx context.Context, config *ConnectionConfig) erro
 	}
 
 	c.conn = textproto.NewConn(conn)
+	c.netConn = conn
 
 	code, message, err := c.conn.ReadCodeLine(StatusPostingAllowed)
 	if err != nil {
@@ -193,6 +195,15 @@ func (c *Connection) Close() error {
 	return r.Err()
 }
 
+// SetDeadline sets the read and write deadline of the underlying network
+// connection. A zero value for t means no deadline.
+func (c *Connection) SetDeadline(t time.Time) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+	return c.netConn.SetDeadline(t)
+}
+
 func (c *Connection) ensureConnected() error {
 	if c.connected {
 		return nil
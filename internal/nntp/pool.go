@@ -192,7 +192,7 @@ func (p *Pool) handleConnectionFailure(errs ...error) {
 		if currentState == PoolStateOnline {
 			p.destroyAllIdles()
 		}
-		p.scheduleReconnect()
+		p.ScheduleReconnect()
 	}
 }
 
@@ -225,7 +225,9 @@ func (p *Pool) doReconnect(ctx context.Context) bool {
 	return true
 }
 
-func (p *Pool) scheduleReconnect() {
+// ScheduleReconnect starts the reconnection loop with backoff for an offline
+// pool, unless one is already running.
+func (p *Pool) ScheduleReconnect() {
 	if !p.reconnectScheduled.CompareAndSwap(false, true) {
 		return
 	}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// cacheStatusWriter holds back the headers till the first body write, so
+// that the X-Cache header covers the content read for it.
+type cacheStatusWriter struct {
+	http.ResponseWriter
+	getStatus   func() string
+	statusCode  int
+	wroteHeader bool
+}
+
+func (w *cacheStatusWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader || w.statusCode != 0 {
+		return
+	}
+	w.statusCode = statusCode
+}
+
+func (w *cacheStatusWriter) Write(p []byte) (int, error) {
+	w.flushHeader()
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *cacheStatusWriter) flushHeader() {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.Header().Set(HEADER_X_CACHE, w.getStatus())
+	if w.statusCode != 0 {
+		w.ResponseWriter.WriteHeader(w.statusCode)
+	}
+}
+
+// ServeContentWithCacheStatus is http.ServeContent with the X-Cache header
+// set from getStatus, evaluated right before the headers are sent.
+func ServeContentWithCacheStatus(w http.ResponseWriter, r *http.Request, name string, modtime time.Time, content io.ReadSeeker, getStatus func() string) {
+	cw := &cacheStatusWriter{ResponseWriter: w, getStatus: getStatus}
+	http.ServeContent(cw, r, name, modtime, content)
+	cw.flushHeader()
+}
+
+// ServeForwardOnlyContent serves content that can't seek, e.g. decompressed
+// on the fly, in full. Range and conditional requests are not supported.
+func ServeForwardOnlyContent(w http.ResponseWriter, r *http.Request, size int64, content io.Reader, getStatus func() string) {
+	cw := &cacheStatusWriter{ResponseWriter: w, getStatus: getStatus}
+	cw.Header().Set("Accept-Ranges", "none")
+	cw.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	cw.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		io.CopyN(cw, content, size)
+	}
+	cw.flushHeader()
+}
@@ -6,12 +6,19 @@ const (
 	HEADER_REQUEST_ID                    = "Request-ID"
 	HEADER_STREMTHRU_AUTHENTICATE        = "X-StremThru-Authenticate"
 	HEADER_STREMTHRU_AUTHORIZATION       = "X-StremThru-Authorization"
+	HEADER_STREMTHRU_CONTENT_PATH        = "X-StremThru-Content-Path"
 	HEADER_STREMTHRU_INSTANCE_ID         = "X-StremThru-Instance-ID"
 	HEADER_STREMTHRU_ORIGIN_INSTANCE_ID  = "X-StremThru-Origin-Instance-ID"
 	HEADER_STREMTHRU_PEER_TOKEN          = "X-StremThru-Peer-Token"
+	HEADER_STREMTHRU_SEGMENT_BYTE_RANGE  = "X-StremThru-Segment-Byte-Range"
+	HEADER_STREMTHRU_SEGMENT_CRC         = "X-StremThru-Segment-CRC"
+	HEADER_STREMTHRU_SEGMENT_FILE_SIZE   = "X-StremThru-Segment-File-Size"
 	HEADER_STREMTHRU_STORE_AUTHORIZATION = "X-StremThru-Store-Authorization"
 	HEADER_STREMTHRU_STORE_NAME          = "X-StremThru-Store-Name"
+	HEADER_STREMTHRU_TRUNCATED           = "X-StremThru-Truncated"
 	HEADER_STREMTHRU_VERSION             = "X-StremThru-Version"
 	HEADER_USER_AGENT                    = "User-Agent"
 	HEADER_WWW_AUTHENTICATE              = "WWW-Authenticate"
+	HEADER_X_API_KEY                     = "X-Api-Key"
+	HEADER_X_CACHE                       = "X-Cache"
 )
@@ -35,8 +35,36 @@ const (
 	ErrorCodeStoreMagnetInvalid ErrorCode = "STORE_MAGNET_INVALID"
 	ErrorCodeStoreNameInvalid   ErrorCode = "STORE_NAME_INVALID"
 	ErrorCodeStoreServerDown    ErrorCode = "STORE_SERVER_DOWN"
+
+	ErrorCodeArchiveSolid     ErrorCode = "ARCHIVE_SOLID"
+	ErrorCodeArticleMissing   ErrorCode = "ARTICLE_MISSING"
+	ErrorCodeEmptyFile        ErrorCode = "EMPTY_FILE"
+	ErrorCodeFetchTimeout     ErrorCode = "FETCH_TIMEOUT"
+	ErrorCodeFileTooLarge     ErrorCode = "FILE_TOO_LARGE"
+	ErrorCodeNoProviders      ErrorCode = "NO_PROVIDERS"
+	ErrorCodeNoStreamable     ErrorCode = "NO_STREAMABLE_CONTENT"
+	ErrorCodePasswordRequired ErrorCode = "PASSWORD_REQUIRED"
+	ErrorCodeSegmentNotCached ErrorCode = "SEGMENT_NOT_CACHED"
 )
 
+// codedError is an error from outside this package carrying one of the codes
+// above, e.g. from the usenet pool.
+type codedError interface {
+	error
+	Code() string
+}
+
+func errorFromCodedError(r *http.Request, err error, ce codedError) *APIError {
+	code := ErrorCode(ce.Code())
+	statusCode, ok := statusCodeByErrorCode[code]
+	if !ok {
+		statusCode = http.StatusInternalServerError
+	}
+	e := NewAPIError(statusCode, err.Error(), code)
+	e.InjectRequest(r)
+	return e.WithCause(err)
+}
+
 type ErrorDomain = string
 
 var (
@@ -208,12 +236,24 @@ func ErrorUnsupportedMediaType(r *http.Request) *APIError {
 	return err
 }
 
+func ErrorUnprocessableEntity(r *http.Request) *APIError {
+	err := NewAPIError(http.StatusUnprocessableEntity, "Unprocessable Entity", ErrorCodeUnprocessableEntity)
+	err.InjectRequest(r)
+	return err
+}
+
 func ErrorLocked(r *http.Request) *APIError {
 	err := NewAPIError(http.StatusLocked, "Locked", ErrorCodeLocked)
 	err.InjectRequest(r)
 	return err
 }
 
+func ErrorTooManyRequests(r *http.Request) *APIError {
+	err := NewAPIError(http.StatusTooManyRequests, "Too Many Requests", ErrorCodeTooManyRequests)
+	err.InjectRequest(r)
+	return err
+}
+
 func ErrorInternalServerError(r *http.Request) *APIError {
 	err := NewAPIError(http.StatusInternalServerError, "Internal Server Error", ErrorCodeInternalServerError)
 	err.InjectRequest(r)
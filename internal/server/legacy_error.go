@@ -165,6 +165,15 @@ var statusCodeByErrorCode = map[ErrorCode]int{
 
 	ErrorCodeStoreMagnetInvalid: http.StatusBadRequest,
 	ErrorCodeStoreNameInvalid:   http.StatusBadRequest,
+
+	ErrorCodeArchiveSolid:     http.StatusUnprocessableEntity,
+	ErrorCodeArticleMissing:   http.StatusNotFound,
+	ErrorCodeEmptyFile:        http.StatusUnprocessableEntity,
+	ErrorCodeFetchTimeout:     http.StatusGatewayTimeout,
+	ErrorCodeFileTooLarge:     http.StatusRequestEntityTooLarge,
+	ErrorCodeNoProviders:      http.StatusServiceUnavailable,
+	ErrorCodeNoStreamable:     http.StatusUnprocessableEntity,
+	ErrorCodePasswordRequired: http.StatusUnprocessableEntity,
 }
 
 func (e *LegacyError) Pack(r *http.Request) {
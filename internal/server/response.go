@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"mime"
 	"net/http"
+	"path"
 	"strings"
 )
 
@@ -59,7 +61,11 @@ func SendError(w http.ResponseWriter, r *http.Request, err error) {
 			e.meta["store_name"] = err.StoreName
 		}
 	} else if !errors.As(err, &e) {
-		e = ErrorInternalServerError(r).WithCause(err)
+		if ce := codedError(nil); errors.As(err, &ce) {
+			e = errorFromCodedError(r, err, ce)
+		} else {
+			e = ErrorInternalServerError(r).WithCause(err)
+		}
 	}
 
 	if e.Errors == nil {
@@ -98,3 +104,14 @@ func ReadRequestBodyJSON[T any](r *http.Request, payload T) error {
 
 	return ErrorInternalServerError(r).WithMessage("failed to decode body").WithCause(err)
 }
+
+// ContentDisposition formats the Content-Disposition header value, with the
+// base name of filename escaped as needed, e.g. for quotes or non-ASCII
+// characters.
+func ContentDisposition(dispositionType string, filename string) string {
+	filename = path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	if v := mime.FormatMediaType(dispositionType, map[string]string{"filename": filename}); v != "" {
+		return v
+	}
+	return dispositionType
+}
@@ -1,6 +1,7 @@
 package stremio_newz
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/url"
@@ -174,7 +175,7 @@ func handlePlaybackFromStore(w http.ResponseWriter, r *http.Request, ud *UserDat
 			case store.NewzStatusQueued, store.NewzStatusDownloading, store.NewzStatusProcessing:
 				strem.error_level = logger.LevelWarn
 				strem.error_video = store_video.StoreVideoNameDownloading
-			case store.NewzStatusFailed, store.NewzStatusInvalid, store.NewzStatusUnknown:
+			case store.NewzStatusFailed, store.NewzStatusCancelled, store.NewzStatusInvalid, store.NewzStatusUnknown:
 				strem.error_level = logger.LevelWarn
 				strem.error_video = store_video.StoreVideoNameDownloadFailed
 			}
@@ -323,7 +324,7 @@ func handleStreamFromUsenet(w http.ResponseWriter, r *http.Request, ud *UserData
 				strem.error_level = logger.LevelWarn
 				strem.error_log = "newz not ready"
 				strem.error_video = store_video.StoreVideoNameDownloading
-			case store.NewzStatusFailed, store.NewzStatusInvalid, store.NewzStatusUnknown:
+			case store.NewzStatusFailed, store.NewzStatusCancelled, store.NewzStatusInvalid, store.NewzStatusUnknown:
 				strem.error_level = logger.LevelWarn
 				strem.error_log = "newz failed"
 				strem.error_video = store_video.StoreVideoNameDownloadFailed
@@ -398,8 +399,11 @@ func handleStreamFromUsenet(w http.ResponseWriter, r *http.Request, ud *UserData
 		return &usenetStremResult{
 			contentPath: file.GetPath(),
 			streamConfig: &usenet_pool.StreamConfig{
-				Password:     info.Password,
-				ContentFiles: info.ContentFiles.Data,
+				Password:             info.Password,
+				ContentFiles:         info.ContentFiles.Data,
+				NZBHash:              info.Hash,
+				RateLimitBytesPerSec: config.Newz.StreamRateLimit,
+				ProviderAllowlist:    info.Providers,
 			},
 			nzbDoc:     nzbDoc,
 			nzbFileMod: nzbFile.Mod,
@@ -414,19 +418,51 @@ func handleStreamFromUsenet(w http.ResponseWriter, r *http.Request, ud *UserData
 		return
 	}
 
-	stream, err := pool.StreamByContentPath(r.Context(), strem.nzbDoc, strem.contentPath, strem.streamConfig)
+	// anonymous users are told apart by their ip
+	streamUser := ctx.ProxyAuthUser
+	if streamUser == "" {
+		streamUser = "ip:" + ctx.ClientIP
+	}
+	releaseStream, err := usenetmanager.AcquireUserStream(streamUser, cacheKey+":"+strem.contentPath)
+	if err != nil {
+		log.Warn("too many open streams", "error", err)
+		redirectToStaticVideo(w, r, "", store_video.StoreVideoName429)
+		return
+	}
+	defer releaseStream()
+
+	streamCtx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	streamConfig := *strem.streamConfig
+	cacheStats := &usenet_pool.CacheStats{}
+	streamConfig.CacheStats = cacheStats
+	stream, err := pool.StreamByContentPath(streamCtx, strem.nzbDoc, strem.contentPath, &streamConfig)
 	if err != nil {
 		log.Error("failed to create usenet stream", "error", err)
 		redirectToStaticVideo(w, r, "", store_video.StoreVideoName500)
 		return
 	}
-	defer stream.Close()
+	content := usenet_pool.NewIdleWatchdog(stream, config.Newz.StreamIdleTimeout, func() {
+		log.Warn("closing idle usenet stream", "path", stream.Path)
+		cancel()
+	})
+	defer content.Close()
 
 	w.Header().Set("Content-Type", stream.ContentType)
+	w.Header().Set(server.HEADER_STREMTHRU_CONTENT_PATH, stream.Path)
+
+	getCacheStatus := func() string {
+		return cacheStats.Count().Status()
+	}
+	if stream.ForwardOnly {
+		server.ServeForwardOnlyContent(w, r, stream.Size, content, getCacheStatus)
+		return
+	}
+
 	w.Header().Set("Content-Length", strconv.FormatInt(stream.Size, 10))
 	w.Header().Set("Accept-Ranges", "bytes")
-
-	http.ServeContent(w, r, stream.Name, strem.nzbFileMod, stream)
+	server.ServeContentWithCacheStatus(w, r, stream.Name, strem.nzbFileMod, content, getCacheStatus)
 }
 
 func handlePlayback(w http.ResponseWriter, r *http.Request) {
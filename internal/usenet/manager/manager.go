@@ -20,7 +20,10 @@ var globalManager = &Manager{
 }
 
 var getSegmentCache = sync.OnceValue(func() usenet_pool.SegmentCache {
-	return usenet_pool.NewSegmentCache(config.Newz.SegmentCacheSize)
+	if config.Newz.SegmentCacheBackend == "redis" {
+		return usenet_pool.NewRedisSegmentCache(config.Newz.SegmentCacheLifetime)
+	}
+	return usenet_pool.NewSegmentCache(config.Newz.SegmentCacheSize, config.Newz.SegmentCacheDir)
 })
 
 type Manager struct {
@@ -219,6 +222,8 @@ func (m *Manager) createEmptyPool() (*usenet_pool.Pool, error) {
 		Log:          m.log,
 		Providers:    []usenet_pool.ProviderConfig{},
 		SegmentCache: getSegmentCache(),
+
+		SegmentCacheNamespace: config.Newz.SegmentCacheNamespace,
 	})
 }
 
@@ -251,6 +256,7 @@ func (m *Manager) createPoolFromServers(servers []usenet_server.UsenetServer) (*
 			},
 			Priority: s.Priority,
 			IsBackup: s.IsBackup,
+			Weight:   s.Weight,
 		})
 	}
 
@@ -263,6 +269,8 @@ func (m *Manager) createPoolFromServers(servers []usenet_server.UsenetServer) (*
 		Log:          m.log,
 		Providers:    providers,
 		SegmentCache: getSegmentCache(),
+
+		SegmentCacheNamespace: config.Newz.SegmentCacheNamespace,
 	})
 }
 
@@ -286,6 +294,7 @@ func (m *Manager) createProviderConfig(server *usenet_server.UsenetServer) (*use
 		},
 		Priority: server.Priority,
 		IsBackup: server.IsBackup,
+		Weight:   server.Weight,
 	}, nil
 }
 
@@ -0,0 +1,73 @@
+package usenetmanager
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+)
+
+var ErrTooManyStreams = errors.New("too many open streams")
+
+// streamLimiter caps the streams a user can have open at once, so that a
+// single user can not hog all the provider connections.
+//
+// A player opens a stream with several parallel and successive range
+// requests, so the requests are counted by the stream they are for, and
+// only a new stream is held to the limit.
+type streamLimiter struct {
+	limit   int // 0 means unlimited
+	mu      sync.Mutex
+	streams map[string]map[string]int // user -> stream key -> open requests
+}
+
+func (l *streamLimiter) acquire(user, streamKey string) (release func(), err error) {
+	if l.limit <= 0 || user == "" {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	streams := l.streams[user]
+	if _, ok := streams[streamKey]; !ok && len(streams) >= l.limit {
+		return nil, fmt.Errorf("%w, limit is %d", ErrTooManyStreams, l.limit)
+	}
+	if streams == nil {
+		streams = map[string]int{}
+		l.streams[user] = streams
+	}
+	streams[streamKey]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			if streams[streamKey] <= 1 {
+				delete(streams, streamKey)
+			} else {
+				streams[streamKey]--
+			}
+			if len(streams) == 0 {
+				delete(l.streams, user)
+			}
+		})
+	}, nil
+}
+
+var userStreamLimiter = &streamLimiter{
+	limit:   config.Newz.MaxStreamPerUser,
+	streams: map[string]map[string]int{},
+}
+
+// AcquireUserStream reserves the stream identified by streamKey for the user,
+// failing with ErrTooManyStreams if the user is at the limit with other
+// streams. Requests for an already open stream share its reservation. An
+// empty user is not limited, so the caller must identify anonymous users
+// some other way. The returned release must be called once the request is
+// done.
+func AcquireUserStream(user, streamKey string) (release func(), err error) {
+	return userStreamLimiter.acquire(user, streamKey)
+}
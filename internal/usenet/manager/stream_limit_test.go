@@ -0,0 +1,70 @@
+package usenetmanager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamLimiter(t *testing.T) {
+	t.Run("Unlimited", func(t *testing.T) {
+		l := &streamLimiter{streams: map[string]map[string]int{}}
+		for range 10 {
+			_, err := l.acquire("alice", "movie")
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("Limited", func(t *testing.T) {
+		l := &streamLimiter{limit: 2, streams: map[string]map[string]int{}}
+
+		release1, err := l.acquire("alice", "movie1")
+		require.NoError(t, err)
+		_, err = l.acquire("alice", "movie2")
+		require.NoError(t, err)
+
+		_, err = l.acquire("alice", "movie3")
+		assert.ErrorIs(t, err, ErrTooManyStreams)
+
+		_, err = l.acquire("bob", "movie3")
+		assert.NoError(t, err, "limit is per user")
+
+		release1()
+		release1()
+		_, err = l.acquire("alice", "movie3")
+		assert.NoError(t, err)
+		_, err = l.acquire("alice", "movie4")
+		assert.ErrorIs(t, err, ErrTooManyStreams, "release is idempotent")
+	})
+
+	t.Run("SameStream", func(t *testing.T) {
+		l := &streamLimiter{limit: 1, streams: map[string]map[string]int{}}
+
+		// parallel range requests of a single playback
+		release1, err := l.acquire("alice", "movie1")
+		require.NoError(t, err)
+		release2, err := l.acquire("alice", "movie1")
+		require.NoError(t, err)
+
+		_, err = l.acquire("alice", "movie2")
+		assert.ErrorIs(t, err, ErrTooManyStreams)
+
+		release1()
+		_, err = l.acquire("alice", "movie2")
+		assert.ErrorIs(t, err, ErrTooManyStreams, "stream is open until its last request is done")
+
+		release2()
+		_, err = l.acquire("alice", "movie2")
+		assert.NoError(t, err)
+	})
+
+	t.Run("EmptyUser", func(t *testing.T) {
+		l := &streamLimiter{limit: 1, streams: map[string]map[string]int{}}
+		for _, key := range []string{"movie1", "movie2"} {
+			_, err := l.acquire("", key)
+			require.NoError(t, err)
+		}
+		assert.Empty(t, l.streams)
+	})
+}
@@ -0,0 +1,51 @@
+package nzb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"strings"
+)
+
+var ErrDecompressedTooLarge = errors.New("decompressed nzb too large")
+
+func IsGzip(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b
+}
+
+// Decompress returns the gunzipped data if it is gzip compressed, otherwise
+// the data as is. If maxSize is positive, the decompressed data is limited
+// to maxSize bytes.
+func Decompress(data []byte, maxSize int64) ([]byte, error) {
+	if !IsGzip(data) {
+		return data, nil
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	var r io.Reader = zr
+	if maxSize > 0 {
+		r = io.LimitReader(zr, maxSize+1)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if maxSize > 0 && int64(len(out)) > maxSize {
+		return nil, ErrDecompressedTooLarge
+	}
+	return out, nil
+}
+
+// TrimGzipExt strips the trailing .gz from a .nzb.gz filename.
+func TrimGzipExt(filename string) string {
+	if strings.HasSuffix(strings.ToLower(filename), ".nzb.gz") {
+		return filename[:len(filename)-len(".gz")]
+	}
+	return filename
+}
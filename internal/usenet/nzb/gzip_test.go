@@ -0,0 +1,49 @@
+package nzb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	data := []byte(`<?xml version="1.0" encoding="UTF-8"?><nzb></nzb>`)
+
+	t.Run("plain", func(t *testing.T) {
+		out, err := Decompress(data, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, data, out)
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		compressed := gzipBytes(t, data)
+		assert.True(t, IsGzip(compressed))
+		out, err := Decompress(compressed, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, data, out)
+	})
+
+	t.Run("gzip too large", func(t *testing.T) {
+		_, err := Decompress(gzipBytes(t, data), int64(len(data)-1))
+		assert.ErrorIs(t, err, ErrDecompressedTooLarge)
+	})
+}
+
+func TestTrimGzipExt(t *testing.T) {
+	assert.Equal(t, "a.nzb", TrimGzipExt("a.nzb.gz"))
+	assert.Equal(t, "a.NZB", TrimGzipExt("a.NZB.GZ"))
+	assert.Equal(t, "a.nzb", TrimGzipExt("a.nzb"))
+	assert.Equal(t, "a.gz", TrimGzipExt("a.gz"))
+}
@@ -0,0 +1,73 @@
+package nzb
+
+import (
+	"bytes"
+	"encoding/xml"
+	"slices"
+)
+
+// MergeSegments fills the segments missing from the files of n with the ones
+// of the same files in other, matched by their boundary segment ids. The
+// groups of the matched files are merged too. Returns the number of segments
+// added.
+func (n *NZB) MergeSegments(other *NZB) int {
+	otherByBoundary := make(map[string]*File, len(other.Files))
+	for i := range other.Files {
+		if boundary := other.Files[i].boundary(); boundary != "" {
+			otherByBoundary[boundary] = &other.Files[i]
+		}
+	}
+
+	added := 0
+	for i := range n.Files {
+		f := &n.Files[i]
+		of, ok := otherByBoundary[f.boundary()]
+		if !ok {
+			continue
+		}
+
+		for _, group := range of.Groups {
+			if !slices.Contains(f.Groups, group) {
+				f.Groups = append(f.Groups, group)
+			}
+		}
+
+		numbers := make(map[int]struct{}, len(f.Segments))
+		for _, segment := range f.Segments {
+			numbers[segment.Number] = struct{}{}
+		}
+		fileAdded := 0
+		for _, segment := range of.Segments {
+			if _, ok := numbers[segment.Number]; !ok {
+				numbers[segment.Number] = struct{}{}
+				f.Segments = append(f.Segments, segment)
+				fileAdded++
+			}
+		}
+		if fileAdded == 0 {
+			continue
+		}
+		slices.SortStableFunc(f.Segments, func(a, b Segment) int {
+			return a.Number - b.Number
+		})
+		f.totalSize = 0
+		f.messageIds = nil
+		added += fileAdded
+	}
+	return added
+}
+
+const xmlns = "http://www.newzbin.com/DTD/2003/nzb"
+
+// Bytes encodes the nzb back to xml.
+func (n *NZB) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(n); err != nil {
+		return nil, err
+	}
+	// the root element is encoded without the namespace
+	blob := bytes.Replace(buf.Bytes(), []byte("<nzb>"), []byte(`<nzb xmlns="`+xmlns+`">`), 1)
+	return append([]byte(xml.Header), blob...), nil
+}
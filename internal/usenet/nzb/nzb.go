@@ -2,6 +2,7 @@ package nzb
 
 import (
 	"bytes"
+	"cmp"
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/xml"
@@ -9,6 +10,7 @@ import (
 	"slices"
 	"strings"
 
+	"github.com/MunifTanjim/stremthru/internal/util"
 	"golang.org/x/net/html/charset"
 )
 
@@ -98,8 +100,10 @@ func Parse(r io.Reader) (*NZB, error) {
 
 	nzb.ParseFileSubject()
 
+	// without numbering in the subjects, the files are ordered by name.
+	// archive volumes are ordered separately, when grouped.
 	slices.SortStableFunc(nzb.Files, func(a, b File) int {
-		return a.number - b.number
+		return cmp.Or(a.number-b.number, util.CompareNatural(a.name, b.name))
 	})
 
 	for i := range nzb.Files {
@@ -127,6 +131,13 @@ func (n *NZB) FileCount() int {
 	return len(n.Files)
 }
 
+func (n *NZB) SegmentCount() (count int) {
+	for i := range n.Files {
+		count += n.Files[i].SegmentCount()
+	}
+	return count
+}
+
 func (n *NZB) GetMeta(metaType string) string {
 	if n.Head == nil {
 		return ""
@@ -181,6 +192,19 @@ func (f *File) SegmentCount() int {
 	return len(f.Segments)
 }
 
+// boundary joins the message ids of the first and the last segments, which
+// identify the file across nzbs listing the same post.
+func (f *File) boundary() string {
+	if len(f.Segments) == 0 {
+		return ""
+	}
+	boundary := strings.TrimSpace(f.Segments[0].MessageId)
+	if last := len(f.Segments) - 1; last > 0 {
+		boundary += strings.TrimSpace(f.Segments[last].MessageId)
+	}
+	return boundary
+}
+
 func (n *NZB) HashByFileBoundarySegmentIds() string {
 	h := md5.New()
 	for i := range n.Files {
@@ -194,3 +218,22 @@ func (n *NZB) HashByFileBoundarySegmentIds() string {
 	}
 	return hex.EncodeToString(h.Sum(nil))
 }
+
+// HashByContent is like HashByFileBoundarySegmentIds, but the files are
+// sorted by their boundary segment ids first. So the same content yields
+// the same hash regardless of the file order in the nzb.
+func (n *NZB) HashByContent() string {
+	boundaries := make([]string, 0, len(n.Files))
+	for i := range n.Files {
+		if boundary := n.Files[i].boundary(); boundary != "" {
+			boundaries = append(boundaries, boundary)
+		}
+	}
+	slices.Sort(boundaries)
+
+	h := md5.New()
+	for _, boundary := range boundaries {
+		io.WriteString(h, boundary)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
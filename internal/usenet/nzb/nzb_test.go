@@ -40,6 +40,7 @@ func TestParse(t *testing.T) {
 
 	assert.Equal(t, 2, nzb.FileCount())
 	assert.Equal(t, int64(1250000), nzb.TotalSize())
+	assert.Equal(t, 3, nzb.SegmentCount())
 
 	assert.Equal(t, "My Test File", nzb.GetMeta("title"))
 	assert.Equal(t, "secret123", nzb.GetMeta("password"))
@@ -72,6 +73,33 @@ func TestParse_WithoutHead(t *testing.T) {
 	assert.Empty(t, nzb.GetMeta("title"))
 }
 
+func TestParse_OrdersUnnumberedFilesByName(t *testing.T) {
+	nzbData := `<?xml version="1.0" encoding="UTF-8"?>
+<nzb>
+  <file poster="user@test.com" date="1000000000" subject="&quot;Show.E10.mkv&quot; yEnc (1/1)">
+    <groups><group>alt.binaries.test</group></groups>
+    <segments><segment bytes="100" number="1">e10@test.com</segment></segments>
+  </file>
+  <file poster="user@test.com" date="1000000000" subject="&quot;Show.E2.mkv&quot; yEnc (1/1)">
+    <groups><group>alt.binaries.test</group></groups>
+    <segments><segment bytes="100" number="1">e2@test.com</segment></segments>
+  </file>
+  <file poster="user@test.com" date="1000000000" subject="&quot;show.e1.mkv&quot; yEnc (1/1)">
+    <groups><group>alt.binaries.test</group></groups>
+    <segments><segment bytes="100" number="1">e1@test.com</segment></segments>
+  </file>
+</nzb>`
+
+	nzb, err := ParseBytes([]byte(nzbData))
+	assert.NoError(t, err)
+
+	names := []string{}
+	for i := range nzb.Files {
+		names = append(names, nzb.Files[i].Name())
+	}
+	assert.Equal(t, []string{"show.e1.mkv", "Show.E2.mkv", "Show.E10.mkv"}, names)
+}
+
 func TestParse_MalformedXML(t *testing.T) {
 	nzbData := `<?xml version="1.0" encoding="UTF-8"?>
 <nzb>
@@ -113,3 +141,76 @@ func TestMessageIds_Ordering(t *testing.T) {
 		"msg-id-3@example.com",
 	}, msgIds)
 }
+
+func TestHashByContent(t *testing.T) {
+	fileA := File{Subject: "a", Segments: []Segment{{Number: 1, MessageId: "a-1@test"}, {Number: 2, MessageId: "a-2@test"}}}
+	fileB := File{Subject: "b", Segments: []Segment{{Number: 1, MessageId: "b-1@test"}}}
+
+	n1 := &NZB{Files: []File{fileA, fileB}}
+	n2 := &NZB{Files: []File{fileB, fileA}}
+
+	assert.Equal(t, n1.HashByContent(), n2.HashByContent())
+	assert.NotEqual(t, n1.HashByFileBoundarySegmentIds(), n2.HashByFileBoundarySegmentIds())
+
+	n3 := &NZB{Files: []File{fileA}}
+	assert.NotEqual(t, n1.HashByContent(), n3.HashByContent())
+}
+
+func TestMergeSegments(t *testing.T) {
+	partial := &NZB{Files: []File{
+		{Subject: "a", Groups: []string{"alt.binaries.a"}, Segments: []Segment{
+			{Number: 1, Bytes: 10, MessageId: "a-1@test"},
+			{Number: 3, Bytes: 10, MessageId: "a-3@test"},
+		}},
+		{Subject: "b", Segments: []Segment{{Number: 1, Bytes: 10, MessageId: "b-1@test"}}},
+	}}
+	complete := &NZB{Files: []File{
+		{Subject: "a", Groups: []string{"alt.binaries.b"}, Segments: []Segment{
+			{Number: 1, Bytes: 10, MessageId: "a-1@test"},
+			{Number: 2, Bytes: 10, MessageId: "a-2@test"},
+			{Number: 3, Bytes: 10, MessageId: "a-3@test"},
+		}},
+		{Subject: "c", Segments: []Segment{{Number: 1, Bytes: 10, MessageId: "c-1@test"}}},
+	}}
+
+	assert.Equal(t, int64(20), partial.Files[0].Size())
+	assert.Equal(t, 1, partial.MergeSegments(complete))
+
+	file := partial.Files[0]
+	assert.Equal(t, []string{"a-1@test", "a-2@test", "a-3@test"}, file.MessageIds())
+	assert.Equal(t, int64(30), file.Size())
+	assert.Equal(t, []string{"alt.binaries.a", "alt.binaries.b"}, file.Groups)
+	assert.Equal(t, 1, partial.Files[1].SegmentCount())
+	assert.Equal(t, 2, partial.FileCount())
+
+	assert.Equal(t, 0, partial.MergeSegments(complete))
+}
+
+func TestBytes(t *testing.T) {
+	nzbData := `<?xml version="1.0" encoding="UTF-8"?>
+<nzb xmlns="http://www.newzbin.com/DTD/2003/nzb">
+  <head><meta type="title">Test</meta></head>
+  <file poster="user@test.com" date="1000000000" subject="Test - &quot;movie.mkv&quot; yEnc (1/2)">
+    <groups><group>alt.binaries.test</group></groups>
+    <segments>
+      <segment bytes="100" number="1">seg-1@test</segment>
+      <segment bytes="50" number="2">seg-2@test</segment>
+    </segments>
+  </file>
+</nzb>`
+
+	n, err := ParseBytes([]byte(nzbData))
+	assert.NoError(t, err)
+
+	blob, err := n.Bytes()
+	assert.NoError(t, err)
+	assert.Contains(t, string(blob), `<nzb xmlns="http://www.newzbin.com/DTD/2003/nzb">`)
+
+	decoded, err := ParseBytes(blob)
+	assert.NoError(t, err)
+	assert.Equal(t, "Test", decoded.GetMeta("title"))
+	assert.Equal(t, n.HashByFileBoundarySegmentIds(), decoded.HashByFileBoundarySegmentIds())
+	assert.Equal(t, "movie.mkv", decoded.Files[0].Name())
+	assert.Equal(t, int64(150), decoded.TotalSize())
+	assert.Equal(t, []string{"alt.binaries.test"}, decoded.Files[0].Groups)
+}
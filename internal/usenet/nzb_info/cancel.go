@@ -0,0 +1,70 @@
+package nzb_info
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// errInspectionCancelled is the cause of an inspection cancelled via Cancel.
+var errInspectionCancelled = errors.New("nzb inspection cancelled")
+
+type inspection struct {
+	id     uint64
+	cancel context.CancelCauseFunc
+}
+
+var inspections = struct {
+	sync.Mutex
+	nextId uint64
+	byHash map[string]inspection
+}{
+	byHash: map[string]inspection{},
+}
+
+// trackInspection derives a cancellable context for the inspection of the
+// given hash. The returned func must be called once the inspection is over.
+func trackInspection(ctx context.Context, hash string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	inspections.Lock()
+	if prev, ok := inspections.byHash[hash]; ok {
+		prev.cancel(nil)
+	}
+	inspections.nextId++
+	id := inspections.nextId
+	inspections.byHash[hash] = inspection{id: id, cancel: cancel}
+	inspections.Unlock()
+
+	return ctx, func() {
+		inspections.Lock()
+		if curr, ok := inspections.byHash[hash]; ok && curr.id == id {
+			delete(inspections.byHash, hash)
+		}
+		inspections.Unlock()
+		cancel(nil)
+	}
+}
+
+// isInspectionCancelled reports whether the inspection was cancelled via
+// Cancel, and not by its parent context or a newer inspection of the same
+// hash.
+func isInspectionCancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errInspectionCancelled)
+}
+
+// Cancel signals the running inspection for the given hash to stop.
+// Returns false if no inspection is running.
+func Cancel(hash string) bool {
+	inspections.Lock()
+	curr, ok := inspections.byHash[hash]
+	if ok {
+		delete(inspections.byHash, hash)
+	}
+	inspections.Unlock()
+
+	if ok {
+		curr.cancel(errInspectionCancelled)
+	}
+	return ok
+}
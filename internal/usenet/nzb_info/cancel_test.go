@@ -0,0 +1,52 @@
+package nzb_info
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCancel(t *testing.T) {
+	t.Run("Explicit", func(t *testing.T) {
+		ctx, done := trackInspection(t.Context(), t.Name())
+		defer done()
+
+		assert.True(t, Cancel(t.Name()))
+		assert.ErrorIs(t, ctx.Err(), context.Canceled)
+		assert.True(t, isInspectionCancelled(ctx))
+		assert.False(t, Cancel(t.Name()), "no longer running")
+	})
+
+	t.Run("ParentCanceled", func(t *testing.T) {
+		parent, cancel := context.WithCancel(t.Context())
+		ctx, done := trackInspection(parent, t.Name())
+		defer done()
+
+		cancel()
+		assert.ErrorIs(t, ctx.Err(), context.Canceled)
+		assert.False(t, isInspectionCancelled(ctx))
+	})
+
+	t.Run("Superseded", func(t *testing.T) {
+		prevCtx, prevDone := trackInspection(t.Context(), t.Name())
+		ctx, done := trackInspection(t.Context(), t.Name())
+		defer done()
+
+		assert.ErrorIs(t, prevCtx.Err(), context.Canceled)
+		assert.False(t, isInspectionCancelled(prevCtx))
+
+		prevDone()
+		assert.NoError(t, ctx.Err(), "done of the superseded inspection")
+		assert.True(t, Cancel(t.Name()))
+		assert.True(t, isInspectionCancelled(ctx))
+	})
+
+	t.Run("Done", func(t *testing.T) {
+		ctx, done := trackInspection(t.Context(), t.Name())
+		done()
+
+		assert.False(t, Cancel(t.Name()))
+		assert.False(t, isInspectionCancelled(ctx))
+	})
+}
@@ -3,44 +3,54 @@ package nzb_info
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/MunifTanjim/stremthru/internal/db"
 	usenet_pool "github.com/MunifTanjim/stremthru/internal/usenet/pool"
+	"github.com/MunifTanjim/stremthru/internal/util"
 	"github.com/rs/xid"
 )
 
 const TableName = "nzb_info"
 
 var Column = struct {
-	Id         string
-	Hash       string
-	Name       string
-	Size       string
-	FileCount  string
-	Password   string
-	URL        string
-	Files      string
-	Streamable string
-	User       string
-	Date       string
-	Status     string
-	CAt        string
-	UAt        string
+	Id            string
+	Hash          string
+	Name          string
+	Size          string
+	FileCount     string
+	Password      string
+	URL           string
+	Files         string
+	Streamable    string
+	User          string
+	Date          string
+	Status        string
+	Downloaded    string
+	HealthSampled string
+	HealthFailed  string
+	Providers     string
+	CAt           string
+	UAt           string
 }{
-	Id:         "id",
-	Hash:       "hash",
-	Name:       "name",
-	Size:       "size",
-	FileCount:  "file_count",
-	Password:   "password",
-	URL:        "url",
-	Files:      "files",
-	Streamable: "streamable",
-	User:       "user",
-	Date:       "date",
-	Status:     "status",
-	CAt:        "cat",
-	UAt:        "uat",
+	Id:            "id",
+	Hash:          "hash",
+	Name:          "name",
+	Size:          "size",
+	FileCount:     "file_count",
+	Password:      "password",
+	URL:           "url",
+	Files:         "files",
+	Streamable:    "streamable",
+	User:          "user",
+	Date:          "date",
+	Status:        "status",
+	Downloaded:    "downloaded_bytes",
+	HealthSampled: "health_sampled",
+	HealthFailed:  "health_failed",
+	Providers:     "providers",
+	CAt:           "cat",
+	UAt:           "uat",
 }
 
 var columns = []string{
@@ -56,31 +66,48 @@ var columns = []string{
 	Column.User,
 	Column.Date,
 	Column.Status,
+	Column.Downloaded,
+	Column.HealthSampled,
+	Column.HealthFailed,
+	Column.Providers,
 	Column.CAt,
 	Column.UAt,
 }
 
 type NZBInfo struct {
-	Id           string
-	Hash         string
-	Name         string
-	Size         int64
-	FileCount    int
-	Password     string
-	URL          string
-	ContentFiles db.JSONB[[]usenet_pool.NZBContentFile]
-	Streamable   bool
-	User         string
-	Date         db.Timestamp
-	Status       string
-	CAt          db.Timestamp
-	UAt          db.Timestamp
+	Id            string
+	Hash          string
+	Name          string
+	Size          int64
+	FileCount     int
+	Password      string
+	URL           string
+	ContentFiles  db.JSONB[[]usenet_pool.NZBContentFile]
+	Streamable    bool
+	User          string
+	Date          db.Timestamp
+	Status        string
+	Downloaded    int64
+	HealthSampled int               // segments sampled for the health score
+	HealthFailed  int               // sampled segments missing or failing the crc check
+	Providers     db.JSONStringList // provider ids its segments are fetched from, any if empty
+	CAt           db.Timestamp
+	UAt           db.Timestamp
+}
+
+// HealthScore is the fraction of the sampled segments that were intact, or
+// -1 if the health was not checked.
+func (info *NZBInfo) HealthScore() float64 {
+	if info.HealthSampled == 0 {
+		return -1
+	}
+	return 1 - float64(info.HealthFailed)/float64(info.HealthSampled)
 }
 
 var query_upsert = fmt.Sprintf(
-	`INSERT INTO %s (%s) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (%s) DO UPDATE SET %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = %s`,
+	`INSERT INTO %s (%s) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (%s) DO UPDATE SET %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = %s`,
 	TableName,
-	db.JoinColumnNames(Column.Id, Column.Hash, Column.Name, Column.Size, Column.FileCount, Column.Password, Column.URL, Column.Files, Column.Streamable, Column.User, Column.Date, Column.Status),
+	db.JoinColumnNames(Column.Id, Column.Hash, Column.Name, Column.Size, Column.FileCount, Column.Password, Column.URL, Column.Files, Column.Streamable, Column.User, Column.Date, Column.Status, Column.HealthSampled, Column.HealthFailed),
 	Column.Hash,
 	Column.Name, Column.Name,
 	Column.Size, Column.Size,
@@ -91,6 +118,8 @@ var query_upsert = fmt.Sprintf(
 	Column.Streamable, Column.Streamable,
 	Column.Date, Column.Date,
 	Column.Status, Column.Status,
+	Column.HealthSampled, Column.HealthSampled,
+	Column.HealthFailed, Column.HealthFailed,
 	Column.UAt, db.CurrentTimestamp,
 )
 
@@ -111,6 +140,8 @@ func Upsert(info *NZBInfo) error {
 		info.User,
 		info.Date,
 		info.Status,
+		info.HealthSampled,
+		info.HealthFailed,
 	)
 	return err
 }
@@ -123,11 +154,55 @@ var query_update_status = fmt.Sprintf(
 	Column.Hash,
 )
 
+var query_update_providers = fmt.Sprintf(
+	`UPDATE %s SET %s = ?, %s = %s WHERE %s = ?`,
+	TableName,
+	Column.Providers,
+	Column.UAt, db.CurrentTimestamp,
+	Column.Hash,
+)
+
+// UpdateProviders sets the provider allowlist, it is kept apart from Upsert
+// so that a re-inspection does not reset it.
+func UpdateProviders(hash string, providers []string) error {
+	if providers == nil {
+		providers = []string{}
+	}
+	_, err := db.Exec(query_update_providers, db.JSONStringList(providers), hash)
+	return err
+}
+
 func UpdateStatus(hash string, status string) error {
 	_, err := db.Exec(query_update_status, status, hash)
 	return err
 }
 
+var query_touch = fmt.Sprintf(
+	`UPDATE %s SET %s = %s WHERE %s = ?`,
+	TableName,
+	Column.UAt, db.CurrentTimestamp,
+	Column.Hash,
+)
+
+// Touch bumps the last updated time, as the heartbeat of a running
+// inspection.
+func Touch(hash string) error {
+	_, err := db.Exec(query_touch, hash)
+	return err
+}
+
+var query_add_downloaded = fmt.Sprintf(
+	`UPDATE %s SET %s = %s + ? WHERE %s = ?`,
+	TableName,
+	Column.Downloaded, Column.Downloaded,
+	Column.Hash,
+)
+
+func AddDownloaded(hash string, bytes int64) error {
+	_, err := db.Exec(query_add_downloaded, bytes, hash)
+	return err
+}
+
 var query_get_by_id = fmt.Sprintf(
 	`SELECT %s FROM %s WHERE %s = ?`,
 	db.JoinColumnNames(columns...),
@@ -138,7 +213,7 @@ var query_get_by_id = fmt.Sprintf(
 func GetById(id string) (*NZBInfo, error) {
 	row := db.QueryRow(query_get_by_id, id)
 	info := NZBInfo{}
-	if err := row.Scan(&info.Id, &info.Hash, &info.Name, &info.Size, &info.FileCount, &info.Password, &info.URL, &info.ContentFiles, &info.Streamable, &info.User, &info.Date, &info.Status, &info.CAt, &info.UAt); err != nil {
+	if err := row.Scan(&info.Id, &info.Hash, &info.Name, &info.Size, &info.FileCount, &info.Password, &info.URL, &info.ContentFiles, &info.Streamable, &info.User, &info.Date, &info.Status, &info.Downloaded, &info.HealthSampled, &info.HealthFailed, &info.Providers, &info.CAt, &info.UAt); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
@@ -157,7 +232,7 @@ var query_get_by_hash = fmt.Sprintf(
 func GetByHash(hash string) (*NZBInfo, error) {
 	row := db.QueryRow(query_get_by_hash, hash)
 	info := NZBInfo{}
-	if err := row.Scan(&info.Id, &info.Hash, &info.Name, &info.Size, &info.FileCount, &info.Password, &info.URL, &info.ContentFiles, &info.Streamable, &info.User, &info.Date, &info.Status, &info.CAt, &info.UAt); err != nil {
+	if err := row.Scan(&info.Id, &info.Hash, &info.Name, &info.Size, &info.FileCount, &info.Password, &info.URL, &info.ContentFiles, &info.Streamable, &info.User, &info.Date, &info.Status, &info.Downloaded, &info.HealthSampled, &info.HealthFailed, &info.Providers, &info.CAt, &info.UAt); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
@@ -183,7 +258,40 @@ func GetAll() ([]NZBInfo, error) {
 	infos := []NZBInfo{}
 	for rows.Next() {
 		info := NZBInfo{}
-		if err := rows.Scan(&info.Id, &info.Hash, &info.Name, &info.Size, &info.FileCount, &info.Password, &info.URL, &info.ContentFiles, &info.Streamable, &info.User, &info.Date, &info.Status, &info.CAt, &info.UAt); err != nil {
+		if err := rows.Scan(&info.Id, &info.Hash, &info.Name, &info.Size, &info.FileCount, &info.Password, &info.URL, &info.ContentFiles, &info.Streamable, &info.User, &info.Date, &info.Status, &info.Downloaded, &info.HealthSampled, &info.HealthFailed, &info.Providers, &info.CAt, &info.UAt); err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return infos, nil
+}
+
+var query_get_by_status_updated_before = fmt.Sprintf(
+	`SELECT %s FROM %s WHERE %s = ? AND %s < ?`,
+	db.JoinColumnNames(columns...),
+	TableName,
+	Column.Status,
+	Column.UAt,
+)
+
+// GetByStatusUpdatedBefore returns the records with the given status, last
+// updated before the given time.
+func GetByStatusUpdatedBefore(status string, before time.Time) ([]NZBInfo, error) {
+	rows, err := db.Query(query_get_by_status_updated_before, status, db.Timestamp{Time: before})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	infos := []NZBInfo{}
+	for rows.Next() {
+		info := NZBInfo{}
+		if err := rows.Scan(&info.Id, &info.Hash, &info.Name, &info.Size, &info.FileCount, &info.Password, &info.URL, &info.ContentFiles, &info.Streamable, &info.User, &info.Date, &info.Status, &info.Downloaded, &info.HealthSampled, &info.HealthFailed, &info.Providers, &info.CAt, &info.UAt); err != nil {
 			return nil, err
 		}
 		infos = append(infos, info)
@@ -206,3 +314,70 @@ func DeleteById(id string) error {
 	_, err := db.Exec(query_delete_by_id, id)
 	return err
 }
+
+var query_get_stale = fmt.Sprintf(
+	`SELECT %s, %s FROM %s WHERE %s < ?`,
+	Column.Id,
+	Column.URL,
+	TableName,
+	Column.UAt,
+)
+
+var query_get_stale_by_status = fmt.Sprintf(
+	`SELECT %s, %s FROM %s WHERE %s = ? AND %s < ?`,
+	Column.Id,
+	Column.URL,
+	TableName,
+	Column.Status,
+	Column.UAt,
+)
+
+// GetStale returns the url by id of the records last updated before the
+// given time, with the given status or any status for `*`.
+func GetStale(status string, before time.Time) (map[string]string, error) {
+	var rows *sql.Rows
+	var err error
+	if status == "*" {
+		rows, err = db.Query(query_get_stale, db.Timestamp{Time: before})
+	} else {
+		rows, err = db.Query(query_get_stale_by_status, status, db.Timestamp{Time: before})
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	urlById := map[string]string{}
+	for rows.Next() {
+		var id, url string
+		if err := rows.Scan(&id, &url); err != nil {
+			return nil, err
+		}
+		urlById[id] = url
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return urlById, nil
+}
+
+var query_delete_by_ids = fmt.Sprintf(
+	`DELETE FROM %s WHERE %s IN `,
+	TableName,
+	Column.Id,
+)
+
+func DeleteByIds(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	query := query_delete_by_ids + "(" + util.RepeatJoin("?", len(ids), ",") + ")"
+	_, err := db.Exec(query, args...)
+	return err
+}
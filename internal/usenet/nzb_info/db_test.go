@@ -0,0 +1,110 @@
+package nzb_info
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/db"
+	"github.com/MunifTanjim/stremthru/internal/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTestNZBInfo adds a record for the link, last updated at the given
+// time.
+func createTestNZBInfo(t *testing.T, link string, status string, updatedAt time.Time) *NZBInfo {
+	t.Helper()
+	info := &NZBInfo{
+		Hash:   HashNZBFileLink(link),
+		Name:   link,
+		URL:    link,
+		Status: status,
+	}
+	require.NoError(t, Upsert(info))
+	setUpdatedAt(t, info.Hash, updatedAt)
+	return info
+}
+
+func setUpdatedAt(t *testing.T, hash string, updatedAt time.Time) {
+	t.Helper()
+	_, err := db.Exec("UPDATE "+TableName+" SET "+Column.UAt+" = ? WHERE "+Column.Hash+" = ?", db.Timestamp{Time: updatedAt}, hash)
+	require.NoError(t, err)
+}
+
+func getTestNZBInfo(t *testing.T, hash string) *NZBInfo {
+	t.Helper()
+	info, err := GetByHash(hash)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	return info
+}
+
+func TestTouch(t *testing.T) {
+	dbtest.Open(t)
+
+	info := createTestNZBInfo(t, "https://example.com/a.nzb", "downloading", time.Now().Add(-time.Hour))
+	require.NoError(t, Touch(info.Hash))
+	assert.WithinDuration(t, time.Now(), getTestNZBInfo(t, info.Hash).UAt.Time, time.Minute)
+}
+
+func TestGetByStatusUpdatedBefore(t *testing.T) {
+	dbtest.Open(t)
+
+	stale := createTestNZBInfo(t, "https://example.com/stale.nzb", "downloading", time.Now().Add(-time.Hour))
+	createTestNZBInfo(t, "https://example.com/fresh.nzb", "downloading", time.Now())
+	createTestNZBInfo(t, "https://example.com/done.nzb", "downloaded", time.Now().Add(-time.Hour))
+
+	infos, err := GetByStatusUpdatedBefore("downloading", time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	require.Len(t, infos, 1)
+	assert.Equal(t, stale.Hash, infos[0].Hash)
+}
+
+func TestGetStale(t *testing.T) {
+	dbtest.Open(t)
+
+	old := time.Now().Add(-time.Hour)
+	staleFailed := createTestNZBInfo(t, "https://example.com/stale-failed.nzb", "failed", old)
+	staleDone := createTestNZBInfo(t, "https://example.com/stale-done.nzb", "downloaded", old)
+	createTestNZBInfo(t, "https://example.com/fresh-failed.nzb", "failed", time.Now())
+
+	// created long ago, but updated since
+	recreated := createTestNZBInfo(t, "https://example.com/recreated.nzb", "downloaded", time.Now())
+	_, err := db.Exec("UPDATE "+TableName+" SET "+Column.CAt+" = ? WHERE "+Column.Hash+" = ?", db.Timestamp{Time: old}, recreated.Hash)
+	require.NoError(t, err)
+
+	before := time.Now().Add(-time.Minute)
+
+	t.Run("Status", func(t *testing.T) {
+		urlById, err := GetStale("failed", before)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{staleFailed.Id: staleFailed.URL}, urlById)
+	})
+
+	t.Run("AnyStatus", func(t *testing.T) {
+		urlById, err := GetStale("*", before)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			staleFailed.Id: staleFailed.URL,
+			staleDone.Id:   staleDone.URL,
+		}, urlById)
+	})
+}
+
+func TestDeleteByIds(t *testing.T) {
+	dbtest.Open(t)
+
+	a := createTestNZBInfo(t, "https://example.com/a.nzb", "downloaded", time.Now())
+	b := createTestNZBInfo(t, "https://example.com/b.nzb", "downloaded", time.Now())
+	c := createTestNZBInfo(t, "https://example.com/c.nzb", "downloaded", time.Now())
+
+	require.NoError(t, DeleteByIds(nil))
+	require.NoError(t, DeleteByIds([]string{a.Id, c.Id}))
+
+	for _, info := range []*NZBInfo{a, c} {
+		deleted, err := GetByHash(info.Hash)
+		require.NoError(t, err)
+		assert.Nil(t, deleted)
+	}
+	getTestNZBInfo(t, b.Hash)
+}
@@ -0,0 +1,37 @@
+package nzb_info
+
+import (
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/job"
+	usenetmanager "github.com/MunifTanjim/stremthru/internal/usenet/manager"
+)
+
+const downloadSchedulerId = "record-nzb-download"
+
+var _ = job.NewScheduler(&job.SchedulerConfig[struct{}]{
+	Id:           downloadSchedulerId,
+	Title:        "Record NZB Download",
+	Interval:     5 * time.Minute,
+	RunExclusive: true,
+	Disabled:     !config.Feature.HasVault(),
+	Executor: func(j *job.Scheduler[struct{}]) error {
+		log := j.Logger()
+
+		pool, err := usenetmanager.GetPool()
+		if err != nil {
+			return err
+		}
+		if pool == nil {
+			return nil
+		}
+
+		for hash, bytes := range pool.DrainDownloadedBytes() {
+			if err := AddDownloaded(hash, bytes); err != nil {
+				log.Error("failed to record downloaded bytes", "error", err, "hash", hash, "bytes", bytes)
+			}
+		}
+		return nil
+	},
+})
@@ -0,0 +1,41 @@
+package nzb_info
+
+import (
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/cache"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+	usenet_pool "github.com/MunifTanjim/stremthru/internal/usenet/pool"
+	"github.com/MunifTanjim/stremthru/internal/util"
+)
+
+// inspectionCache holds the content of the streamable nzbs, which depends only
+// on the nzb content and the password. Failures are not cached, those can be
+// transient.
+var inspectionCache = cache.NewCache[[]usenet_pool.NZBContentFile](&cache.CacheConfig{
+	Name:     "newz_nzb_inspection",
+	Lifetime: 24 * time.Hour,
+})
+
+// getInspectionCacheKey includes the password, so the content is inspected
+// again when it changes.
+func getInspectionCacheKey(nzbDoc *nzb.NZB, password string) string {
+	return nzbDoc.HashByContent() + ":" + util.MD5Hash(password)
+}
+
+func getCachedInspection(key string) *usenet_pool.NZBContent {
+	var files []usenet_pool.NZBContentFile
+	if !inspectionCache.Get(key, &files) {
+		return nil
+	}
+	return &usenet_pool.NZBContent{Files: files, Streamable: true}
+}
+
+func cacheInspection(key string, content *usenet_pool.NZBContent) {
+	if !content.Streamable {
+		return
+	}
+	if err := inspectionCache.Add(key, content.Files); err != nil {
+		log.Warn("failed to cache nzb inspection", "error", err)
+	}
+}
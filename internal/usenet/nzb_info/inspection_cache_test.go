@@ -0,0 +1,65 @@
+package nzb_info
+
+import (
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+	usenet_pool "github.com/MunifTanjim/stremthru/internal/usenet/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createTestInspectionNZB(t *testing.T) *nzb.NZB {
+	t.Helper()
+
+	return &nzb.NZB{Files: []nzb.File{{
+		Segments: []nzb.Segment{
+			{MessageId: t.Name() + "-1@test.com", Number: 1},
+			{MessageId: t.Name() + "-2@test.com", Number: 2},
+		},
+	}}}
+}
+
+func TestInspectionCache(t *testing.T) {
+	content := &usenet_pool.NZBContent{
+		Files: []usenet_pool.NZBContentFile{
+			{Type: usenet_pool.NZBContentFileTypeVideo, Name: "video.mkv", Size: 1024, Streamable: true},
+		},
+		Streamable: true,
+	}
+
+	t.Run("Hit", func(t *testing.T) {
+		nzbDoc := createTestInspectionNZB(t)
+		key := getInspectionCacheKey(nzbDoc, "")
+		assert.Nil(t, getCachedInspection(key))
+
+		cacheInspection(key, content)
+
+		cached := getCachedInspection(getInspectionCacheKey(createTestInspectionNZB(t), ""))
+		require.NotNil(t, cached)
+		assert.True(t, cached.Streamable)
+		assert.Equal(t, content.Files, cached.Files)
+	})
+
+	t.Run("PasswordChange", func(t *testing.T) {
+		nzbDoc := createTestInspectionNZB(t)
+		cacheInspection(getInspectionCacheKey(nzbDoc, "old"), content)
+
+		assert.NotNil(t, getCachedInspection(getInspectionCacheKey(nzbDoc, "old")))
+		assert.Nil(t, getCachedInspection(getInspectionCacheKey(nzbDoc, "new")))
+		assert.Nil(t, getCachedInspection(getInspectionCacheKey(nzbDoc, "")))
+	})
+
+	t.Run("NotStreamable", func(t *testing.T) {
+		nzbDoc := createTestInspectionNZB(t)
+		key := getInspectionCacheKey(nzbDoc, "")
+
+		cacheInspection(key, &usenet_pool.NZBContent{
+			Files: []usenet_pool.NZBContentFile{
+				{Type: usenet_pool.NZBContentFileTypeVideo, Name: "video.mkv", Size: 1024},
+			},
+			Err: usenet_pool.ErrNoStreamableContent,
+		})
+		assert.Nil(t, getCachedInspection(key))
+	})
+}
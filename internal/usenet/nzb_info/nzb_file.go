@@ -2,6 +2,7 @@ package nzb_info
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"mime"
@@ -14,6 +15,9 @@ import (
 	"github.com/MunifTanjim/stremthru/internal/cache"
 	"github.com/MunifTanjim/stremthru/internal/config"
 	"github.com/MunifTanjim/stremthru/internal/logger"
+	"github.com/MunifTanjim/stremthru/internal/server"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+	usenet_pool "github.com/MunifTanjim/stremthru/internal/usenet/pool"
 	"github.com/MunifTanjim/stremthru/internal/util"
 	"golang.org/x/sync/singleflight"
 )
@@ -59,6 +63,8 @@ func (f *NZBFile) ToFileHeader() (*multipart.FileHeader, error) {
 
 var nzbFileCache = cache.NewCache[NZBFile](&cache.CacheConfig{
 	Name:       "newz_nzb",
+	Compress:   cache.CacheCompressionZstd,
+	Dir:        config.Newz.NZBFileCacheDir,
 	Lifetime:   config.Newz.NZBFileCacheTTL,
 	DiskBacked: true,
 	MaxSize:    config.Newz.NZBFileCacheSize,
@@ -96,13 +102,90 @@ func cleanNZBFileLink(link string) string {
 
 var nzbFileFetchSG singleflight.Group
 
+var ErrNZBFileTooLarge = usenet_pool.NewError(server.ErrorCodeFileTooLarge, "file too large")
+
 var nzbFileFetcher = func() *http.Client {
 	client := config.GetHTTPClient(config.TUNNEL_TYPE_AUTO)
 	client.Timeout = 60 * time.Second
 	return client
 }()
 
-func fetchNZBFile(link string, name string, log *logger.Logger, onFetch func(*NZBFile)) (*NZBFile, error) {
+// downloadNZBFile fetches the nzb file from the link, without caching it.
+func downloadNZBFile(link string, name string, log *logger.Logger) (*NZBFile, error) {
+	clink := cleanNZBFileLink(link)
+	req, err := http.NewRequest("GET", link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = config.Newz.IndexerRequestHeader.GetGrab(req.URL.Hostname())
+	res, err := nzbFileFetcher.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || 300 <= res.StatusCode {
+		return nil, fmt.Errorf("failed to fetch nzb: status %d", res.StatusCode)
+	}
+
+	if res.ContentLength > config.Newz.NZBFileMaxSize {
+		return nil, fmt.Errorf("%w: %d bytes (max %d)", ErrNZBFileTooLarge, res.ContentLength, config.Newz.NZBFileMaxSize)
+	}
+
+	blob, err := io.ReadAll(io.LimitReader(res.Body, config.Newz.NZBFileMaxSize+1024))
+	if err != nil {
+		if log != nil {
+			log.Error("fetch nzb - failed", "error", err, "link", clink)
+		}
+		return nil, err
+	}
+	if size := int64(len(blob)); size > config.Newz.NZBFileMaxSize {
+		return nil, fmt.Errorf("%w: %d+ bytes (max %d)", ErrNZBFileTooLarge, size, config.Newz.NZBFileMaxSize)
+	}
+	if len(blob) == 0 {
+		return nil, fmt.Errorf("empty response body")
+	}
+	// gzipped nzb, either as .nzb.gz or with un-decoded Content-Encoding
+	if nzb.IsGzip(blob) {
+		blob, err = nzb.Decompress(blob, config.Newz.NZBFileMaxSize)
+		if errors.Is(err, nzb.ErrDecompressedTooLarge) {
+			return nil, fmt.Errorf("%w: decompressed size exceeds %d bytes", ErrNZBFileTooLarge, config.Newz.NZBFileMaxSize)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress nzb: %w", err)
+		}
+	}
+	if log != nil {
+		log.Debug("fetch nzb - completed", "link", clink)
+	}
+
+	if name == "" {
+		name = "unknown.nzb"
+	}
+	filename := name
+	if cd := res.Header.Get("Content-Disposition"); cd != "" {
+		_, params, _ := mime.ParseMediaType(cd)
+		if fn := params["filename"]; fn != "" {
+			filename = nzb.TrimGzipExt(fn)
+		}
+	}
+	if filename == name {
+		if fn := nzb.TrimGzipExt(path.Base(link)); strings.HasSuffix(fn, ".nzb") {
+			filename = fn
+		}
+	}
+	if !strings.HasSuffix(filename, ".nzb") {
+		filename += ".nzb"
+	}
+	return &NZBFile{
+		Blob: blob,
+		Name: filename,
+		Link: link,
+		Mod:  time.Now(),
+	}, nil
+}
+
+func fetchNZBFile(link string, name string, log *logger.Logger, stateless bool, onFetch func(*NZBFile)) (*NZBFile, error) {
 	clink := cleanNZBFileLink(link)
 	cacheKey := HashNZBFileLink(link)
 	var nzbFile NZBFile
@@ -120,7 +203,13 @@ func fetchNZBFile(link string, name string, log *logger.Logger, onFetch func(*NZ
 		if log != nil {
 			log.Debug("fetch nzb - cache miss", "link", clink)
 		}
-		file, err, _ := nzbFileFetchSG.Do(cacheKey, func() (ret any, err error) {
+		// a stateless fetch is neither cached nor queued, so it must not be
+		// joined by a regular one that expects both.
+		fetchKey := cacheKey
+		if stateless {
+			fetchKey = "stateless:" + cacheKey
+		}
+		file, err, _ := nzbFileFetchSG.Do(fetchKey, func() (ret any, err error) {
 			defer func() {
 				if err == nil {
 					return
@@ -130,75 +219,21 @@ func fetchNZBFile(link string, name string, log *logger.Logger, onFetch func(*NZ
 				}
 			}()
 
-			req, err := http.NewRequest("GET", link, nil)
-			if err != nil {
-				return nil, err
-			}
-			req.Header = config.Newz.IndexerRequestHeader.Grab.Clone()
-			res, err := nzbFileFetcher.Do(req)
+			file, err := downloadNZBFile(link, name, log)
 			if err != nil {
 				return nil, err
 			}
-			defer res.Body.Close()
-
-			if res.StatusCode < 200 || 300 <= res.StatusCode {
-				return nil, fmt.Errorf("failed to fetch nzb: status %d", res.StatusCode)
-			}
-
-			if res.ContentLength > config.Newz.NZBFileMaxSize {
-				return nil, fmt.Errorf("file too large: %d bytes (max %d)", res.ContentLength, config.Newz.NZBFileMaxSize)
+			if stateless {
+				return *file, nil
 			}
-
-			blob, err := io.ReadAll(io.LimitReader(res.Body, config.Newz.NZBFileMaxSize+1024))
-			if err != nil {
-				if log != nil {
-					log.Error("fetch nzb - failed", "error", err, "link", clink)
-				}
-				return nil, err
-			}
-			if size := int64(len(blob)); size > config.Newz.NZBFileMaxSize {
-				return nil, fmt.Errorf("file too large: %d+ bytes (max %d)", size, config.Newz.NZBFileMaxSize)
-			}
-			if len(blob) == 0 {
-				return nil, fmt.Errorf("empty response body")
-			}
-			if log != nil {
-				log.Debug("fetch nzb - completed", "link", clink)
-			}
-
-			if name == "" {
-				name = "unknown.nzb"
-			}
-			filename := name
-			if cd := res.Header.Get("Content-Disposition"); cd != "" {
-				_, params, _ := mime.ParseMediaType(cd)
-				if fn := params["filename"]; fn != "" {
-					filename = fn
-				}
-			}
-			if filename == name {
-				if fn := path.Base(link); strings.HasSuffix(fn, ".nzb") {
-					filename = fn
-				}
-			}
-			if !strings.HasSuffix(filename, ".nzb") {
-				filename += ".nzb"
-			}
-			file := NZBFile{
-				Blob: blob,
-				Name: filename,
-				Link: link,
-				Mod:  time.Now(),
-			}
-			err = nzbFileCache.Add(cacheKey, file)
-			if err != nil {
+			if err := nzbFileCache.Add(cacheKey, *file); err != nil {
 				if log != nil {
 					log.Warn("fetch nzb - failed to cache", "error", err, "link", clink)
 				}
 			} else if onFetch != nil {
-				onFetch(&file)
+				onFetch(file)
 			}
-			return file, nil
+			return *file, nil
 		})
 		if err != nil {
 			if log != nil {
@@ -212,11 +247,17 @@ func fetchNZBFile(link string, name string, log *logger.Logger, onFetch func(*NZ
 }
 
 func FetchNZBFile(link string, name string, log *logger.Logger) (*NZBFile, error) {
-	return fetchNZBFile(link, name, log, func(n *NZBFile) {
+	return fetchNZBFile(link, name, log, false, func(n *NZBFile) {
 		QueueJob("", n.Name, n.Link, "", 0, "")
 	})
 }
 
+// FetchNZBFileWithoutQueue is FetchNZBFile, without caching or queueing the
+// fetched nzb, so no NZBInfo is created for it.
+func FetchNZBFileWithoutQueue(link string, name string, log *logger.Logger) (*NZBFile, error) {
+	return fetchNZBFile(link, name, log, true, nil)
+}
+
 func CacheNZBFile(hash string, file NZBFile) error {
 	return nzbFileCache.Add(hash, file)
 }
@@ -0,0 +1,44 @@
+package nzb_info
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchNZBFileWithoutQueue(t *testing.T) {
+	blob := []byte(`<?xml version="1.0" encoding="utf-8"?><nzb xmlns="http://www.newzbin.com/DTD/2003/nzb"></nzb>`)
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write(blob)
+	}))
+	defer server.Close()
+
+	link := server.URL + "/" + t.Name() + ".nzb"
+	t.Cleanup(func() { DeleteNZBFile(link) })
+
+	nzbFile, err := FetchNZBFileWithoutQueue(link, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, blob, nzbFile.Blob)
+	assert.False(t, IsNZBFileCached(HashNZBFileLink(link)))
+
+	queued := 0
+	onFetch := func(*NZBFile) { queued++ }
+
+	nzbFile, err = fetchNZBFile(link, "", nil, false, onFetch)
+	require.NoError(t, err)
+	assert.Equal(t, blob, nzbFile.Blob)
+	assert.Equal(t, 1, queued, "stateless fetch must not prevent queueing")
+	assert.True(t, IsNZBFileCached(HashNZBFileLink(link)))
+	assert.Equal(t, int32(2), requests.Load())
+
+	_, err = fetchNZBFile(link, "", nil, false, onFetch)
+	require.NoError(t, err)
+	assert.Equal(t, 1, queued, "cached nzb is not queued again")
+	assert.Equal(t, int32(2), requests.Load())
+}
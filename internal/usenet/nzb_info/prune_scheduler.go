@@ -0,0 +1,56 @@
+package nzb_info
+
+import (
+	"slices"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/job"
+)
+
+const pruneSchedulerId = "prune-nzb-info"
+
+var _ = job.NewScheduler(&job.SchedulerConfig[struct{}]{
+	Id:           pruneSchedulerId,
+	Title:        "Prune NZB Info",
+	Interval:     6 * time.Hour,
+	RunExclusive: true,
+	Disabled:     !config.Feature.HasVault() || len(config.Newz.NZBInfoMaxAge) == 0,
+	Executor: func(j *job.Scheduler[struct{}]) error {
+		log := j.Logger()
+
+		for status, maxAge := range config.Newz.NZBInfoMaxAge {
+			count, err := Prune(status, time.Now().Add(-maxAge))
+			if err != nil {
+				log.Error("failed to prune nzb info", "error", err, "status", status, "max_age", maxAge.String())
+				continue
+			}
+			log.Info("pruned nzb info", "status", status, "max_age", maxAge.String(), "count", count)
+		}
+		return nil
+	},
+})
+
+// Prune deletes the records that went stale before the given time, along
+// with their cached NZB files.
+func Prune(status string, before time.Time) (int, error) {
+	urlById, err := GetStale(status, before)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make([]string, 0, len(urlById))
+	for id := range urlById {
+		ids = append(ids, id)
+	}
+
+	for chunk := range slices.Chunk(ids, 500) {
+		if err := DeleteByIds(chunk); err != nil {
+			return 0, err
+		}
+		for _, id := range chunk {
+			DeleteNZBFile(urlById[id])
+		}
+	}
+	return len(ids), nil
+}
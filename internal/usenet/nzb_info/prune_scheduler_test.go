@@ -0,0 +1,34 @@
+package nzb_info
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrune(t *testing.T) {
+	dbtest.Open(t)
+
+	old := time.Now().Add(-time.Hour)
+	stale := createTestNZBInfo(t, "https://example.com/stale.nzb", "failed", old)
+	other := createTestNZBInfo(t, "https://example.com/other.nzb", "downloaded", old)
+	fresh := createTestNZBInfo(t, "https://example.com/fresh.nzb", "failed", time.Now())
+
+	count, err := Prune("failed", time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	pruned, err := GetByHash(stale.Hash)
+	require.NoError(t, err)
+	assert.Nil(t, pruned)
+	getTestNZBInfo(t, other.Hash)
+	getTestNZBInfo(t, fresh.Hash)
+
+	count, err = Prune("*", time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	getTestNZBInfo(t, fresh.Hash)
+}
@@ -2,13 +2,18 @@ package nzb_info
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"time"
 
+	"github.com/MunifTanjim/stremthru/internal/config"
 	"github.com/MunifTanjim/stremthru/internal/db"
 	"github.com/MunifTanjim/stremthru/internal/job"
+	"github.com/MunifTanjim/stremthru/internal/job/job_queue"
 	"github.com/MunifTanjim/stremthru/internal/logger"
 	usenetmanager "github.com/MunifTanjim/stremthru/internal/usenet/manager"
 	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+	usenet_pool "github.com/MunifTanjim/stremthru/internal/usenet/pool"
 	"github.com/MunifTanjim/stremthru/store"
 )
 
@@ -16,14 +21,21 @@ const schedulerId = "process-nzb"
 
 var log = logger.Scoped("job/" + schedulerId)
 
+// staleAfter is how long an inspection can go without a heartbeat before it
+// is taken for interrupted, e.g. by a restart.
+const staleAfter = 3 * job_queue.EntryHeartbeatInterval
+
 var scheduler = job.NewScheduler(&job.SchedulerConfig[JobData]{
-	Id:           schedulerId,
-	Title:        "Process NZB",
-	RunExclusive: true,
-	Queue:        queue,
+	Id:                schedulerId,
+	Title:             "Process NZB",
+	RunAtStartupAfter: 30 * time.Second,
+	RunExclusive:      true,
+	Queue:             queue,
 	Executor: func(j *job.Scheduler[JobData]) error {
-		j.JobQueue().Process(func(data JobData) error {
-			nzbFile, err := fetchNZBFile(data.URL, data.Name, log, nil)
+		requeueInterrupted()
+
+		process := func(data JobData) error {
+			nzbFile, err := fetchNZBFile(data.URL, data.Name, log, false, nil)
 			if err != nil {
 				return err
 			}
@@ -70,34 +82,177 @@ var scheduler = job.NewScheduler(&job.SchedulerConfig[JobData]{
 				Status:    string(store.NewzStatusDownloading),
 			}
 
-			if err := Upsert(info); err != nil {
+			// kept across re-adds, it is only set through UpdateProviders
+			if existing, err := GetByHash(hash); err != nil {
 				return err
+			} else if existing != nil {
+				info.Providers = existing.Providers
+				// kept if the health check fails
+				info.HealthSampled = existing.HealthSampled
+				info.HealthFailed = existing.HealthFailed
 			}
 
-			pool, err := usenetmanager.GetPool()
-			if err != nil {
-				return err
-			}
-			content, err := pool.InspectNZBContent(context.Background(), nzbDoc, password)
-			if err != nil {
-				log.Warn("failed to inspect nzb content", "error", err)
-				UpdateStatus(hash, string(store.NewzStatusFailed))
+			if err := Upsert(info); err != nil {
 				return err
 			}
-			info.ContentFiles.Data = content.Files
-			info.Streamable = content.Streamable
-			if content.Streamable {
-				info.Status = string(store.NewzStatusDownloaded)
-			} else {
-				info.Status = string(store.NewzStatusFailed)
-			}
 
-			return Upsert(info)
-		})
+			return inspectContent(context.Background(), info, nzbDoc, true)
+		}
+
+		var wg sync.WaitGroup
+		for range config.Newz.InspectConcurrency {
+			wg.Go(func() {
+				j.JobQueue().Process(process)
+			})
+		}
+		wg.Wait()
 		return nil
 	},
 	ShouldSkip: func() bool {
 		pool, err := usenetmanager.GetPool()
-		return err != nil || pool.CountProviders() == 0
+		// re-evaluated on every run, so it resumes once the providers are back online
+		return err != nil || pool == nil || pool.CountOnlineProviders() == 0
 	},
 })
+
+// requeueInterrupted puts back the inspections left without a worker, going
+// by their heartbeat, so that they are not stuck in `downloading`.
+func requeueInterrupted() {
+	before := time.Now().Add(-staleAfter)
+
+	count, err := job_queue.RequeueStaleEntries(JobQueueName, before)
+	if err != nil {
+		log.Error("failed to requeue interrupted jobs", "error", err)
+	} else if count > 0 {
+		log.Info("requeued interrupted jobs", "count", count)
+	}
+
+	infos, err := GetByStatusUpdatedBefore(string(store.NewzStatusDownloading), before)
+	if err != nil {
+		log.Error("failed to get interrupted nzb info", "error", err)
+		return
+	}
+	for i := range infos {
+		info := &infos[i]
+		err := queue.Queue(JobData{
+			Name:     info.Name,
+			URL:      info.URL,
+			Password: info.Password,
+			User:     info.User,
+		})
+		if err != nil {
+			log.Error("failed to requeue interrupted nzb", "error", err, "hash", info.Hash)
+			continue
+		}
+		if err := UpdateStatus(info.Hash, string(store.NewzStatusQueued)); err != nil {
+			log.Error("failed to update status", "error", err, "hash", info.Hash)
+		}
+		log.Info("requeued interrupted nzb", "hash", info.Hash)
+	}
+}
+
+// heartbeat touches the record until the returned func is called, so that
+// the running inspection is not taken for interrupted.
+func heartbeat(hash string) func() {
+	return job_queue.Heartbeat(job_queue.EntryHeartbeatInterval, func() error {
+		return Touch(hash)
+	}, func(err error) {
+		log.Warn("failed to touch nzb info", "error", err, "hash", hash)
+	})
+}
+
+// inspectContent records the content of the nzb, reusing the result of an
+// earlier inspection of the same content and password if useCache is set.
+// The health is checked either way, it changes as the articles expire.
+func inspectContent(ctx context.Context, info *NZBInfo, nzbDoc *nzb.NZB, useCache bool) error {
+	pool, err := usenetmanager.GetPool()
+	if err != nil {
+		return err
+	}
+
+	ctx, done := trackInspection(ctx, info.Hash)
+	defer done()
+	ctx = usenet_pool.WithProviderAllowlist(ctx, info.Providers)
+
+	stopHeartbeat := heartbeat(info.Hash)
+	defer stopHeartbeat()
+
+	cacheKey := getInspectionCacheKey(nzbDoc, info.Password)
+
+	var content *usenet_pool.NZBContent
+	if useCache {
+		content = getCachedInspection(cacheKey)
+	}
+	if content != nil {
+		log.Debug("nzb inspection - cache hit", "hash", info.Hash)
+	} else {
+		content, err = pool.InspectNZBContent(ctx, nzbDoc, info.Password)
+		if isInspectionCancelled(ctx) {
+			log.Info("nzb inspection cancelled", "hash", info.Hash)
+			info.Status = string(store.NewzStatusCancelled)
+			return Upsert(info)
+		}
+		if err := ctx.Err(); err != nil {
+			// stopped by the caller or a newer inspection, the status is theirs to set
+			return err
+		}
+		if err != nil {
+			log.Warn("failed to inspect nzb content", "error", err)
+			UpdateStatus(info.Hash, string(store.NewzStatusFailed))
+			return err
+		}
+		cacheInspection(cacheKey, content)
+	}
+	checkHealth(ctx, pool, info, nzbDoc)
+	info.ContentFiles.Data = content.Files
+	info.Streamable = content.Streamable
+	switch {
+	case content.Streamable:
+		info.Status = string(store.NewzStatusDownloaded)
+	case errors.Is(content.Err, usenet_pool.ErrNoStreamableContent):
+		log.Info("nzb has no streamable content", "hash", info.Hash)
+		info.Status = string(store.NewzStatusInvalid)
+	default:
+		info.Status = string(store.NewzStatusFailed)
+	}
+
+	return Upsert(info)
+}
+
+// checkHealth records the share of a bounded sample of segments that are
+// missing or corrupt, keeping the earlier result if it fails.
+func checkHealth(ctx context.Context, pool *usenet_pool.Pool, info *NZBInfo, nzbDoc *nzb.NZB) {
+	if config.Newz.InspectHealthSample == 0 {
+		return
+	}
+	health, err := pool.CheckHealth(ctx, nzbDoc, nil)
+	if err != nil {
+		log.Warn("failed to check nzb health", "error", err, "hash", info.Hash)
+		return
+	}
+	info.HealthSampled = health.Sampled
+	info.HealthFailed = health.Failed()
+}
+
+// Inspect re-runs the content inspection for an existing NZB with the given
+// password, reusing the cached NZB file when available.
+func Inspect(ctx context.Context, info *NZBInfo, password string) error {
+	nzbFile, err := fetchNZBFile(info.URL, info.Name, log, false, nil)
+	if err != nil {
+		return err
+	}
+
+	nzbDoc, err := nzb.ParseBytes(nzbFile.Blob)
+	if err != nil {
+		return err
+	}
+
+	info.Password = password
+	info.Status = string(store.NewzStatusDownloading)
+	if err := Upsert(info); err != nil {
+		return err
+	}
+
+	// explicitly requested, so inspected afresh
+	return inspectContent(ctx, info, nzbDoc, false)
+}
@@ -0,0 +1,42 @@
+package nzb_info
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/db"
+	"github.com/MunifTanjim/stremthru/internal/db/dbtest"
+	"github.com/MunifTanjim/stremthru/internal/job/job_queue"
+	"github.com/MunifTanjim/stremthru/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequeueInterrupted(t *testing.T) {
+	dbtest.Open(t)
+
+	stale := createTestNZBInfo(t, "https://example.com/stale.nzb", string(store.NewzStatusDownloading), time.Now().Add(-time.Hour))
+	fresh := createTestNZBInfo(t, "https://example.com/fresh.nzb", string(store.NewzStatusDownloading), time.Now())
+
+	for _, info := range []*NZBInfo{stale, fresh} {
+		require.NoError(t, queue.Queue(JobData{Name: info.Name, URL: info.URL}))
+		require.NoError(t, job_queue.SetEntriesProcessing(JobQueueName, []string{info.Hash}))
+	}
+	_, err := db.Exec("UPDATE "+job_queue.TableName+" SET "+job_queue.Column.UpdatedAt+" = ? WHERE "+job_queue.Column.Key+" = ?", db.Timestamp{Time: time.Now().Add(-time.Hour)}, stale.Hash)
+	require.NoError(t, err)
+
+	requeueInterrupted()
+
+	assert.Equal(t, string(store.NewzStatusQueued), getTestNZBInfo(t, stale.Hash).Status)
+	assert.Equal(t, string(store.NewzStatusDownloading), getTestNZBInfo(t, fresh.Hash).Status)
+
+	job, err := GetJobById(stale.Hash)
+	require.NoError(t, err)
+	require.NotNil(t, job)
+	assert.Equal(t, string(job_queue.EntryStatusQueued), job.Status)
+
+	job, err = GetJobById(fresh.Hash)
+	require.NoError(t, err)
+	require.NotNil(t, job)
+	assert.Equal(t, string(job_queue.EntryStatusProcessing), job.Status)
+}
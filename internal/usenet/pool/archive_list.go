@@ -0,0 +1,96 @@
+package usenet_pool
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+)
+
+type ArchiveEntry struct {
+	Name       string
+	Size       int64
+	Streamable bool
+}
+
+// ListArchiveByContentPath opens the archive at contentPath, walking into
+// nested archives as needed, and lists its entries without opening them.
+// Solid and compressed archives are listed too, with their entries marked
+// as not streamable.
+func (p *Pool) ListArchiveByContentPath(
+	ctx context.Context,
+	nzbDoc *nzb.NZB,
+	contentPath string,
+	config *StreamConfig,
+) ([]ArchiveEntry, error) {
+	pathParts := strings.Split(strings.Trim(contentPath, "/"), "::")
+	for i := range pathParts {
+		pathParts[i] = strings.TrimPrefix(pathParts[i], "/")
+	}
+
+	if len(pathParts) == 0 || pathParts[0] == "" {
+		return nil, fmt.Errorf("invalid content path: %s", strings.Join(pathParts, "::"))
+	}
+
+	if config == nil {
+		config = &StreamConfig{}
+	}
+	ctx = withStreamConfig(ctx, config)
+
+	name := pathParts[0]
+	file, contentFile := findFileByName(nzbDoc, config.ContentFiles, name)
+	if file == nil {
+		return nil, fmt.Errorf("no file matching '%s' found", name)
+	}
+
+	archive, _, err := p.openArchiveHeaders(ctx, nzbDoc, file, name, contentFile, config)
+	if err != nil {
+		return nil, err
+	}
+	archives := []Archive{archive}
+	defer func() {
+		for i := len(archives) - 1; i >= 0; i-- {
+			archives[i].Close()
+		}
+	}()
+
+	files, err := archive.GetFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get archive files: %w", err)
+	}
+
+	for _, part := range pathParts[1:] {
+		targetName := strings.Trim(part, "/")
+		idx := -1
+		for i := range files {
+			if strings.EqualFold(files[i].Name(), targetName) {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 {
+			return nil, fmt.Errorf("no file matching '%s' found in archive", targetName)
+		}
+		innerArchive, _, err := openInnerArchiveHeaders(files, files[idx])
+		if err != nil {
+			return nil, err
+		}
+		archives = append(archives, innerArchive)
+
+		files, err = innerArchive.GetFiles()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get archive files: %w", err)
+		}
+	}
+
+	entries := make([]ArchiveEntry, len(files))
+	for i, f := range files {
+		entries[i] = ArchiveEntry{
+			Name:       f.Name(),
+			Size:       f.Size(),
+			Streamable: f.IsStreamable() && f.Size() > 0,
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,110 @@
+package usenet_pool
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/logger"
+	"github.com/MunifTanjim/stremthru/internal/nntp/nntptest"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testNZBFile struct {
+	name string
+	data []byte
+}
+
+// createTestNZBServer serves each file as a single segment, returning the
+// pool and the nzb to fetch them with.
+func createTestNZBServer(t *testing.T, files ...testNZBFile) (*Pool, *nzb.NZB) {
+	t.Helper()
+
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.SetResponse("GROUP alt.binaries.test", "211 1 1 1 alt.binaries.test")
+	nzbFiles := make([]nzb.File, len(files))
+	for i, f := range files {
+		messageId := fmt.Sprintf("file%d@test", i+1)
+		encoded := encodeYenc(f.data, f.name, 1, 1, int64(len(f.data)), 1)
+		server.SetResponse("BODY <"+messageId+">", "222 0 <"+messageId+">", []string{string(encoded)})
+		nzbFiles[i] = nzb.File{
+			Subject:  fmt.Sprintf(`Test - "%s" yEnc (1/1)`, f.name),
+			Segments: []nzb.Segment{{MessageId: messageId, Bytes: int64(len(encoded)), Number: 1}},
+		}
+	}
+	server.Start(t)
+
+	return createTestPool(t, server), createTestNZB(nzbFiles...)
+}
+
+func TestListArchiveByContentPath(t *testing.T) {
+	usenetPool := &Pool{Log: logger.Scoped("test/usenet/pool")}
+
+	nzbDoc := createTestNZB(nzb.File{
+		Subject: `Test - "archive.rar" yEnc (1/1)`,
+		Segments: []nzb.Segment{
+			{MessageId: "archive@test.com", Bytes: 100, Number: 1},
+		},
+	})
+
+	t.Run("InvalidPath", func(t *testing.T) {
+		_, err := usenetPool.ListArchiveByContentPath(t.Context(), nzbDoc, "/", nil)
+		assert.ErrorContains(t, err, "invalid content path")
+	})
+
+	t.Run("FileNotFound", func(t *testing.T) {
+		_, err := usenetPool.ListArchiveByContentPath(t.Context(), nzbDoc, "/missing.rar", nil)
+		assert.ErrorContains(t, err, "no file matching 'missing.rar' found")
+	})
+}
+
+func TestListArchiveByContentPathEntries(t *testing.T) {
+	video := bytes.Repeat([]byte("0123456789"), 10)
+
+	t.Run("Stored", func(t *testing.T) {
+		usenetPool, nzbDoc := createTestNZBServer(t, testNZBFile{"movie.rar", buildRAR4ArchiveFiles(
+			rar4TestFile{name: "movie.mkv", data: video},
+			rar4TestFile{name: "movie.nfo", data: []byte("nfo")},
+		)})
+
+		entries, err := usenetPool.ListArchiveByContentPath(t.Context(), nzbDoc, "/movie.rar", nil)
+		require.NoError(t, err)
+		assert.Equal(t, []ArchiveEntry{
+			{Name: "movie.mkv", Size: int64(len(video)), Streamable: true},
+			{Name: "movie.nfo", Size: 3, Streamable: true},
+		}, entries)
+	})
+
+	solid := buildRAR4ArchiveFiles(
+		rar4TestFile{name: "movie.nfo", data: []byte("nfo")},
+		rar4TestFile{name: "movie.mkv", data: video, flags: rar4FileFlagSolid},
+	)
+	// only the entries after the first one depend on the previous ones
+	solidEntries := []ArchiveEntry{
+		{Name: "movie.nfo", Size: 3, Streamable: true},
+		{Name: "movie.mkv", Size: int64(len(video)), Streamable: false},
+	}
+
+	t.Run("Solid", func(t *testing.T) {
+		usenetPool, nzbDoc := createTestNZBServer(t, testNZBFile{"movie.rar", solid})
+
+		entries, err := usenetPool.ListArchiveByContentPath(t.Context(), nzbDoc, "/movie.rar", nil)
+		require.NoError(t, err)
+		assert.Equal(t, solidEntries, entries)
+
+		_, err = usenetPool.StreamByContentPath(t.Context(), nzbDoc, "/movie.rar::movie.mkv", nil)
+		assert.ErrorIs(t, err, ErrArchiveSolid)
+	})
+
+	t.Run("NestedSolid", func(t *testing.T) {
+		usenetPool, nzbDoc := createTestNZBServer(t, testNZBFile{"outer.rar", buildRAR4ArchiveFiles(
+			rar4TestFile{name: "inner.rar", data: solid},
+		)})
+
+		entries, err := usenetPool.ListArchiveByContentPath(t.Context(), nzbDoc, "/outer.rar::inner.rar", nil)
+		require.NoError(t, err)
+		assert.Equal(t, solidEntries, entries)
+	})
+}
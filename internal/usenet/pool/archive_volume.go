@@ -2,9 +2,14 @@ package usenet_pool
 
 import (
 	"cmp"
+	"io"
+	"path/filepath"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
+
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
 )
 
 type archiveVolume struct {
@@ -30,6 +35,15 @@ func stripTrailingNumbers(filename string) string {
 	return filename
 }
 
+func getTrailingNumber(filename string) int {
+	if loc := trailingNumbersRegex.FindStringIndex(filename); loc != nil {
+		if n, err := strconv.Atoi(filename[loc[0]+1:]); err == nil {
+			return n
+		}
+	}
+	return -1
+}
+
 func getArchiveBaseName(filename string) (baseName string, fileType FileType) {
 	lower := strings.ToLower(filename)
 
@@ -53,6 +67,10 @@ func getArchiveBaseName(filename string) (baseName string, fileType FileType) {
 		return filename[:len(filename)-len(matches[0])], FileType7z
 	}
 
+	if matches := zipRegex.FindStringSubmatch(lower); len(matches) > 0 {
+		return filename[:len(filename)-len(matches[0])], FileTypeZIP
+	}
+
 	return "", FileTypePlain
 }
 
@@ -75,6 +93,8 @@ func getFileVolume[T simpleFile](f T, fileType FileType) int {
 		return GetRARVolumeNumber(f.Name())
 	case FileType7z:
 		return Get7zVolumeNumber(f.Name())
+	case FileTypeZIP:
+		return GetZIPVolumeNumber(f.Name())
 	default:
 		return -1
 	}
@@ -143,3 +163,144 @@ func groupArchiveVolumes[T simpleFile](
 
 	return result
 }
+
+type NZBArchiveGroup struct {
+	BaseName  string
+	FileType  FileType
+	Files     []string
+	Volumes   []int
+	TotalSize int64
+}
+
+// GroupNZBArchiveFiles groups the archive volumes of an NZB by their
+// parsed names, ordered by volume number. Non-archive files are skipped.
+func GroupNZBArchiveFiles(files []nzb.File) []NZBArchiveGroup {
+	nzbFiles := make([]*nzb.File, len(files))
+	for i := range files {
+		nzbFiles[i] = &files[i]
+	}
+
+	groups := groupArchiveVolumes(nzbFiles)
+	result := make([]NZBArchiveGroup, len(groups))
+	for i := range groups {
+		group := &groups[i]
+		names := make([]string, len(group.Files))
+		for j, f := range group.Files {
+			names[j] = f.Name()
+		}
+		result[i] = NZBArchiveGroup{
+			BaseName:  group.BaseName,
+			FileType:  group.FileType,
+			Files:     names,
+			Volumes:   group.Volumes,
+			TotalSize: group.TotalSize,
+		}
+	}
+	return result
+}
+
+func generateArchiveVolumeName(fileType FileType, base string, volume int) string {
+	switch fileType {
+	case FileTypeRAR:
+		return GenerateRARVolumeName(base, volume)
+	case FileType7z:
+		return Generate7zVolumeName(base, volume)
+	case FileTypeZIP:
+		return base + ".zip"
+	default:
+		return base
+	}
+}
+
+type typedInnerArchiveFile struct {
+	ArchiveFile
+	fileType FileType
+	volume   int
+}
+
+func (f *typedInnerArchiveFile) FileType() FileType {
+	return f.fileType
+}
+
+func (f *typedInnerArchiveFile) Volume() int {
+	return f.volume
+}
+
+func sniffArchiveFileType(f ArchiveFile) FileType {
+	if !f.IsStreamable() {
+		return FileTypePlain
+	}
+	r, err := f.Open()
+	if err != nil {
+		return FileTypePlain
+	}
+	defer r.Close()
+	header := make([]byte, len(magicBytesRAR5))
+	n, _ := io.ReadFull(r, header)
+	return DetectFileType(header[:n], f.Name())
+}
+
+// typeAliasedArchiveParts detects obfuscated inner archive volumes, e.g.
+// "abc.001", "abc.002", by the magic bytes of the first one, and tags them
+// with type and volume so that groupArchiveVolumes can coalesce them.
+func typeAliasedArchiveParts(files []ArchiveFile) []ArchiveFile {
+	candidates := map[string][]int{}
+	for i, f := range files {
+		name := f.Name()
+		if isVideoFile(name) || DetectArchiveFileTypeByExtension(name) != FileTypePlain {
+			continue
+		}
+		if baseName := stripTrailingNumbers(name); baseName != name {
+			candidates[baseName] = append(candidates[baseName], i)
+		}
+	}
+	if len(candidates) == 0 {
+		return files
+	}
+
+	result := slices.Clone(files)
+	for _, indices := range candidates {
+		slices.SortStableFunc(indices, func(a, b int) int {
+			return cmp.Compare(getTrailingNumber(files[a].Name()), getTrailingNumber(files[b].Name()))
+		})
+		fileType := sniffArchiveFileType(files[indices[0]])
+		if fileType != FileTypeRAR && fileType != FileType7z {
+			continue
+		}
+		for volume, idx := range indices {
+			result[idx] = &typedInnerArchiveFile{
+				ArchiveFile: files[idx],
+				fileType:    fileType,
+				volume:      volume,
+			}
+		}
+	}
+	return result
+}
+
+type aliasedArchiveFile struct {
+	ArchiveFile
+	alias string
+}
+
+func (f *aliasedArchiveFile) Name() string {
+	return f.alias
+}
+
+// getArchiveGroupFiles returns the files of the group along with the name of
+// the first volume. Aliased volumes are exposed under synthetic names, so that
+// the archive readers can discover the sibling volumes.
+func getArchiveGroupFiles(group *archiveVolumeGroup[ArchiveFile]) ([]ArchiveFile, string) {
+	if !group.Aliased {
+		return group.Files, filepath.Base(group.Files[0].Name())
+	}
+	baseName := filepath.Base(group.BaseName)
+	files := make([]ArchiveFile, len(group.Files))
+	for i, f := range group.Files {
+		files[i] = &aliasedArchiveFile{
+			ArchiveFile: f,
+			alias:       generateArchiveVolumeName(group.FileType, baseName, group.Volumes[i]),
+		}
+	}
+	return files, files[0].Name()
+}
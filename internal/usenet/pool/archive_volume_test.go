@@ -0,0 +1,157 @@
+package usenet_pool
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testSimpleFile struct {
+	name string
+	size int64
+}
+
+func (f testSimpleFile) Name() string {
+	return f.name
+}
+
+func (f testSimpleFile) Size() int64 {
+	return f.size
+}
+
+func TestGroupArchiveVolumes(t *testing.T) {
+	t.Run("7zFiveVolumeSplit", func(t *testing.T) {
+		files := []testSimpleFile{
+			{"movie.7z.003", 100},
+			{"movie.7z.005", 40},
+			{"movie.7z.001", 100},
+			{"movie.7z.004", 100},
+			{"movie.7z.002", 100},
+		}
+
+		groups := groupArchiveVolumes(files)
+		require.Len(t, groups, 1)
+
+		group := groups[0]
+		assert.Equal(t, "movie", group.BaseName)
+		assert.Equal(t, FileType7z, group.FileType)
+		assert.Equal(t, int64(440), group.TotalSize)
+		assert.Equal(t, []int{0, 1, 2, 3, 4}, group.Volumes)
+
+		names := make([]string, len(group.Files))
+		for i, f := range group.Files {
+			names[i] = f.Name()
+		}
+		assert.Equal(t, []string{"movie.7z.001", "movie.7z.002", "movie.7z.003", "movie.7z.004", "movie.7z.005"}, names)
+	})
+
+	t.Run("7zSingleAndSplitShareGroup", func(t *testing.T) {
+		files := []testSimpleFile{
+			{"movie.7z.002", 100},
+			{"movie.7z", 100},
+		}
+
+		groups := groupArchiveVolumes(files)
+		require.Len(t, groups, 1)
+		assert.Equal(t, "movie.7z", groups[0].Files[0].Name())
+		assert.Equal(t, "movie.7z.002", groups[0].Files[1].Name())
+	})
+}
+
+type testDataArchiveFile struct {
+	name string
+	data []byte
+}
+
+func (f *testDataArchiveFile) Name() string       { return f.name }
+func (f *testDataArchiveFile) Size() int64        { return int64(len(f.data)) }
+func (f *testDataArchiveFile) PackedSize() int64  { return int64(len(f.data)) }
+func (f *testDataArchiveFile) IsStreamable() bool { return true }
+
+func (f *testDataArchiveFile) Open() (io.ReadSeekCloser, error) {
+	return nopReadSeekCloser{bytes.NewReader(f.data)}, nil
+}
+
+func TestGroupAliasedInnerArchiveVolumes(t *testing.T) {
+	t.Run("7zThreeParts", func(t *testing.T) {
+		files := []ArchiveFile{
+			&testDataArchiveFile{"a1b2c3.003", makeTestBytes(40)},
+			&testDataArchiveFile{"a1b2c3.001", append(append([]byte{}, magicBytes7Zip...), makeTestBytes(94)...)},
+			&testDataArchiveFile{"sample.mkv", makeTestBytes(10)},
+			&testDataArchiveFile{"a1b2c3.002", makeTestBytes(100)},
+		}
+
+		groups := groupArchiveVolumes(typeAliasedArchiveParts(files))
+		require.Len(t, groups, 1)
+
+		group := &groups[0]
+		assert.True(t, group.Aliased)
+		assert.Equal(t, "a1b2c3", group.BaseName)
+		assert.Equal(t, FileType7z, group.FileType)
+		assert.Equal(t, int64(240), group.TotalSize)
+		assert.Equal(t, []int{0, 1, 2}, group.Volumes)
+
+		names := make([]string, len(group.Files))
+		for i, f := range group.Files {
+			names[i] = f.Name()
+		}
+		assert.Equal(t, []string{"a1b2c3.001", "a1b2c3.002", "a1b2c3.003"}, names)
+
+		archiveFiles, archiveName := getArchiveGroupFiles(group)
+		assert.Equal(t, "a1b2c3.7z.001", archiveName)
+		aliases := make([]string, len(archiveFiles))
+		for i, f := range archiveFiles {
+			aliases[i] = f.Name()
+		}
+		assert.Equal(t, []string{"a1b2c3.7z.001", "a1b2c3.7z.002", "a1b2c3.7z.003"}, aliases)
+
+		afs := NewArchiveFS(archiveFiles)
+		defer afs.Close()
+		info, err := afs.Stat("a1b2c3.7z.003")
+		require.NoError(t, err)
+		assert.Equal(t, int64(40), info.Size())
+	})
+
+	t.Run("NoArchiveMagic", func(t *testing.T) {
+		files := []ArchiveFile{
+			&testDataArchiveFile{"data.001", makeTestBytes(100)},
+			&testDataArchiveFile{"data.002", makeTestBytes(100)},
+		}
+
+		groups := groupArchiveVolumes(typeAliasedArchiveParts(files))
+		assert.Empty(t, groups)
+	})
+}
+
+func TestGroupNZBArchiveFiles(t *testing.T) {
+	nzbDoc := createTestNZB(
+		nzb.File{Subject: `Test - "movie.part2.rar" yEnc (1/1)`, Segments: []nzb.Segment{{MessageId: "2@test", Bytes: 100, Number: 1}}},
+		nzb.File{Subject: `Test - "movie.part1.rar" yEnc (1/1)`, Segments: []nzb.Segment{{MessageId: "1@test", Bytes: 100, Number: 1}}},
+		nzb.File{Subject: `Test - "movie.nfo" yEnc (1/1)`, Segments: []nzb.Segment{{MessageId: "3@test", Bytes: 10, Number: 1}}},
+	)
+
+	groups := GroupNZBArchiveFiles(nzbDoc.Files)
+	require.Len(t, groups, 1)
+	assert.Equal(t, "movie", groups[0].BaseName)
+	assert.Equal(t, FileTypeRAR, groups[0].FileType)
+	assert.Equal(t, []string{"movie.part1.rar", "movie.part2.rar"}, groups[0].Files)
+	assert.Equal(t, int64(200), groups[0].TotalSize)
+}
+
+func TestNewUsenetSevenZipArchive(t *testing.T) {
+	t.Run("PicksFirstSplitVolume", func(t *testing.T) {
+		nzbDoc := createTestNZB(
+			nzb.File{Subject: `Test - "movie.7z.003" yEnc (1/1)`, Segments: []nzb.Segment{{MessageId: "3@test", Bytes: 100, Number: 1}}},
+			nzb.File{Subject: `Test - "movie.7z.001" yEnc (1/1)`, Segments: []nzb.Segment{{MessageId: "1@test", Bytes: 100, Number: 1}}},
+			nzb.File{Subject: `Test - "movie.7z.002" yEnc (1/1)`, Segments: []nzb.Segment{{MessageId: "2@test", Bytes: 100, Number: 1}}},
+		)
+
+		ufs := NewUsenetFS(t.Context(), &UsenetFSConfig{NZB: nzbDoc})
+		archive := NewUsenetSevenZipArchive(ufs)
+		assert.Equal(t, "movie.7z.001", archive.name)
+	})
+}
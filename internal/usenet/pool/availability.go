@@ -0,0 +1,172 @@
+package usenet_pool
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+	"github.com/alitto/pond/v2"
+)
+
+type CheckAvailabilityConfig struct {
+	SampleSize int // segments checked per file (first, last and evenly spaced in between)
+}
+
+type FileAvailability struct {
+	Name      string `json:"name"`
+	Segments  int    `json:"segments"`
+	Sampled   int    `json:"sampled"`
+	Available int    `json:"available"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (fa *FileAvailability) Ratio() float64 {
+	if fa.Sampled == 0 {
+		return 0
+	}
+	return float64(fa.Available) / float64(fa.Sampled)
+}
+
+type NZBAvailability struct {
+	Files []FileAvailability `json:"files"`
+}
+
+func (a *NZBAvailability) IsAvailable() bool {
+	if len(a.Files) == 0 {
+		return false
+	}
+	for i := range a.Files {
+		f := &a.Files[i]
+		if f.Sampled == 0 || f.Available < f.Sampled {
+			return false
+		}
+	}
+	return true
+}
+
+func sampleSegmentIndices(segmentCount, sampleSize int) []int {
+	if segmentCount <= 0 || sampleSize <= 0 {
+		return nil
+	}
+	if sampleSize >= segmentCount {
+		indices := make([]int, segmentCount)
+		for i := range indices {
+			indices[i] = i
+		}
+		return indices
+	}
+	if sampleSize == 1 {
+		return []int{0}
+	}
+
+	indices := make([]int, 0, sampleSize)
+	for i := range sampleSize {
+		idx := i * (segmentCount - 1) / (sampleSize - 1)
+		if len(indices) > 0 && indices[len(indices)-1] == idx {
+			continue
+		}
+		indices = append(indices, idx)
+	}
+	return indices
+}
+
+func (p *Pool) statSegment(ctx context.Context, segment *nzb.Segment, groups []string) (bool, error) {
+	excludeProviders := p.getDisallowedProviders(getProviderAllowlist(ctx))
+	for _, useBackup := range []bool{false, true} {
+		for {
+			conn, err := p.GetConnection(ctx, excludeProviders, math.MaxInt, useBackup)
+			if err != nil {
+				if errors.Is(err, ErrNoProvidersAvailable) {
+					break
+				}
+				return false, err
+			}
+
+			if err := p.ensureConnectionGroup(conn, groups...); err != nil {
+				conn.Release()
+				excludeProviders = append(excludeProviders, conn.ProviderId())
+				p.Log.Trace("stat segment - failed to ensure group", "error", err, "message_id", segment.MessageId, "provider_id", conn.ProviderId())
+				continue
+			}
+
+			if _, _, err := conn.Stat("<" + segment.MessageId + ">"); err != nil {
+				if isArticleNotFoundError(err) {
+					conn.Release()
+					excludeProviders = append(excludeProviders, conn.ProviderId())
+					p.Log.Trace("stat segment - article not found", "message_id", segment.MessageId, "provider_id", conn.ProviderId())
+					continue
+				}
+				conn.Destroy()
+				return false, err
+			}
+
+			conn.Release()
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// CheckAvailability issues STAT for a sample of segments of every file and
+// reports how many of them exist on at least one provider.
+func (p *Pool) CheckAvailability(
+	ctx context.Context,
+	nzbDoc *nzb.NZB,
+	conf *CheckAvailabilityConfig,
+) (*NZBAvailability, error) {
+	if conf == nil {
+		conf = &CheckAvailabilityConfig{}
+	}
+	sampleSize := conf.SampleSize
+	if sampleSize <= 0 {
+		sampleSize = config.Newz.AvailabilitySampleSize
+	}
+
+	if p.CountProviders() == 0 {
+		return nil, ErrNoProvidersConfigured
+	}
+
+	result := &NZBAvailability{
+		Files: make([]FileAvailability, len(nzbDoc.Files)),
+	}
+
+	type statResult struct {
+		fileIdx int
+		found   bool
+		err     error
+	}
+
+	statResults := []*statResult{}
+	statPool := pond.NewPool(config.Newz.MaxConnectionPerStream)
+	for i := range nzbDoc.Files {
+		f := &nzbDoc.Files[i]
+		result.Files[i] = FileAvailability{
+			Name:     f.Name(),
+			Segments: f.SegmentCount(),
+		}
+		for _, idx := range sampleSegmentIndices(f.SegmentCount(), sampleSize) {
+			sr := &statResult{fileIdx: i}
+			statResults = append(statResults, sr)
+			statPool.Submit(func() {
+				sr.found, sr.err = p.statSegment(ctx, &f.Segments[idx], f.Groups)
+			})
+		}
+	}
+	statPool.StopAndWait()
+
+	for _, sr := range statResults {
+		fa := &result.Files[sr.fileIdx]
+		fa.Sampled++
+		if sr.err != nil {
+			fa.Error = sr.err.Error()
+			continue
+		}
+		if sr.found {
+			fa.Available++
+		}
+	}
+
+	return result, nil
+}
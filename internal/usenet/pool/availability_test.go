@@ -0,0 +1,83 @@
+package usenet_pool
+
+import (
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/logger"
+	"github.com/MunifTanjim/stremthru/internal/nntp"
+	"github.com/MunifTanjim/stremthru/internal/nntp/nntptest"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampleSegmentIndices(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		segmentCount int
+		sampleSize   int
+		expected     []int
+	}{
+		{"NoSegments", 0, 3, nil},
+		{"NoSample", 10, 0, nil},
+		{"SingleSample", 10, 1, []int{0}},
+		{"FirstMiddleLast", 11, 3, []int{0, 5, 10}},
+		{"FirstAndLast", 10, 2, []int{0, 9}},
+		{"SampleExceedsSegments", 2, 3, []int{0, 1}},
+		{"EvenlySpaced", 100, 5, []int{0, 24, 49, 74, 99}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, sampleSegmentIndices(tc.segmentCount, tc.sampleSize))
+		})
+	}
+}
+
+func TestNZBAvailability(t *testing.T) {
+	a := &NZBAvailability{
+		Files: []FileAvailability{
+			{Name: "a.mkv", Sampled: 3, Available: 3},
+			{Name: "b.rar", Sampled: 3, Available: 2},
+		},
+	}
+	assert.False(t, a.IsAvailable())
+	assert.InDelta(t, 2.0/3.0, a.Files[1].Ratio(), 0.0001)
+
+	a.Files[1].Available = 3
+	assert.True(t, a.IsAvailable())
+
+	assert.False(t, (&NZBAvailability{}).IsAvailable())
+}
+
+func TestCheckAvailabilityProviderAllowlist(t *testing.T) {
+	newServer := func() *nntptest.Server {
+		server := nntptest.NewServer(t, "200 NNTP Service Ready")
+		server.SetResponse("GROUP alt.test", "211 1 1 1 alt.test")
+		server.SetResponse("STAT <seg@test.com>", "223 1 <seg@test.com>")
+		server.Start(t)
+		return server
+	}
+	disallowedServer := newServer()
+	allowedServer := newServer()
+
+	allowed := nntptest.NewPool(t, allowedServer, &nntp.PoolConfig{})
+	usenetPool := &Pool{
+		Log: logger.Scoped("test/usenet/pool"),
+		providers: []*providerPool{
+			{Pool: nntptest.NewPool(t, disallowedServer, &nntp.PoolConfig{})},
+			{Pool: allowed},
+		},
+	}
+
+	nzbDoc := &nzb.NZB{Files: []nzb.File{{
+		Groups:   []string{"alt.test"},
+		Segments: []nzb.Segment{{MessageId: "seg@test.com", Number: 1}},
+	}}}
+
+	ctx := WithProviderAllowlist(t.Context(), []string{allowed.Id()})
+	availability, err := usenetPool.CheckAvailability(ctx, nzbDoc, nil)
+	require.NoError(t, err)
+	assert.True(t, availability.IsAvailable())
+
+	assert.True(t, allowedServer.GetRequestCommands().HasCommand("STAT <seg@test.com>"))
+	assert.False(t, disallowedServer.GetRequestCommands().HasCommand("STAT <seg@test.com>"))
+}
@@ -0,0 +1,20 @@
+package usenet_pool
+
+import "context"
+
+type bypassCacheContextKey struct{}
+
+// withBypassCache makes the segments fetched using the context skip the
+// segment cache read, so they are fetched afresh from the providers. The
+// fetched segments are still cached.
+func withBypassCache(ctx context.Context, bypassCache bool) context.Context {
+	if !bypassCache {
+		return ctx
+	}
+	return context.WithValue(ctx, bypassCacheContextKey{}, true)
+}
+
+func isBypassCache(ctx context.Context) bool {
+	bypassCache, _ := ctx.Value(bypassCacheContextKey{}).(bool)
+	return bypassCache
+}
@@ -0,0 +1,67 @@
+package usenet_pool
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+const (
+	CacheStatusHit     = "HIT"
+	CacheStatusMiss    = "MISS"
+	CacheStatusPartial = "PARTIAL"
+)
+
+// CacheStats counts the segments served from the segment cache (hits) and
+// fetched from the providers (misses) for a stream.
+type CacheStats struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+type CacheCount struct {
+	Hits   int64
+	Misses int64
+}
+
+func (s *CacheStats) Count() CacheCount {
+	return CacheCount{Hits: s.hits.Load(), Misses: s.misses.Load()}
+}
+
+func (c CacheCount) Sub(o CacheCount) CacheCount {
+	return CacheCount{Hits: c.Hits - o.Hits, Misses: c.Misses - o.Misses}
+}
+
+// Status is HIT if nothing was fetched from the providers, MISS if nothing
+// was served from the segment cache, PARTIAL otherwise.
+func (c CacheCount) Status() string {
+	switch {
+	case c.Misses == 0:
+		return CacheStatusHit
+	case c.Hits == 0:
+		return CacheStatusMiss
+	default:
+		return CacheStatusPartial
+	}
+}
+
+type cacheStatsContextKey struct{}
+
+// withCacheStats records the segments fetched using the context in stats.
+func withCacheStats(ctx context.Context, stats *CacheStats) context.Context {
+	if stats == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, cacheStatsContextKey{}, stats)
+}
+
+func recordCacheHit(ctx context.Context, hit bool) {
+	stats, _ := ctx.Value(cacheStatsContextKey{}).(*CacheStats)
+	if stats == nil {
+		return
+	}
+	if hit {
+		stats.hits.Add(1)
+	} else {
+		stats.misses.Add(1)
+	}
+}
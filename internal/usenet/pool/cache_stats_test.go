@@ -0,0 +1,57 @@
+package usenet_pool
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/logger"
+	"github.com/MunifTanjim/stremthru/internal/nntp"
+	"github.com/MunifTanjim/stremthru/internal/nntp/nntptest"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheCountStatus(t *testing.T) {
+	assert.Equal(t, CacheStatusHit, CacheCount{}.Status())
+	assert.Equal(t, CacheStatusHit, CacheCount{Hits: 3}.Status())
+	assert.Equal(t, CacheStatusMiss, CacheCount{Misses: 3}.Status())
+	assert.Equal(t, CacheStatusPartial, CacheCount{Hits: 1, Misses: 2}.Status())
+
+	count := CacheCount{Hits: 3, Misses: 2}.Sub(CacheCount{Hits: 1, Misses: 2})
+	assert.Equal(t, CacheCount{Hits: 2}, count)
+	assert.Equal(t, CacheStatusHit, count.Status())
+}
+
+func TestFetchSegmentRecordsCacheStats(t *testing.T) {
+	data := []byte("cached segment data")
+	encoded := encodeYenc(data, "test.bin", 1, 1, int64(len(data)), 1)
+
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.SetResponse("GROUP alt.test", "211 1 1 1 alt.test")
+	server.SetResponse(
+		"BODY <stats@test>",
+		"222 0 <stats@test>",
+		strings.Split(strings.TrimSpace(string(encoded)), "\r\n"),
+	)
+	server.Start(t)
+
+	usenetPool := &Pool{
+		Log:          logger.Scoped("test/usenet/pool"),
+		providers:    []*providerPool{{Pool: nntptest.NewPool(t, server, &nntp.PoolConfig{})}},
+		segmentCache: NewSegmentCache(10*1024*1024, ""),
+	}
+
+	stats := &CacheStats{}
+	ctx := withCacheStats(t.Context(), stats)
+	segment := &nzb.Segment{MessageId: "stats@test", Bytes: int64(len(encoded)), Number: 1}
+
+	_, err := usenetPool.fetchSegment(ctx, segment, []string{"alt.test"})
+	require.NoError(t, err)
+	assert.Equal(t, CacheCount{Misses: 1}, stats.Count())
+
+	_, err = usenetPool.fetchSegment(ctx, segment, []string{"alt.test"})
+	require.NoError(t, err)
+	assert.Equal(t, CacheCount{Hits: 1, Misses: 1}, stats.Count())
+	assert.Equal(t, CacheStatusPartial, stats.Count().Status())
+}
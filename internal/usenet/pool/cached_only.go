@@ -0,0 +1,19 @@
+package usenet_pool
+
+import "context"
+
+type cachedOnlyContextKey struct{}
+
+// withCachedOnly restricts the segments fetched using the context to the
+// segment cache, providers are never hit.
+func withCachedOnly(ctx context.Context, cachedOnly bool) context.Context {
+	if !cachedOnly {
+		return ctx
+	}
+	return context.WithValue(ctx, cachedOnlyContextKey{}, true)
+}
+
+func isCachedOnly(ctx context.Context) bool {
+	cachedOnly, _ := ctx.Value(cachedOnlyContextKey{}).(bool)
+	return cachedOnly
+}
@@ -0,0 +1,295 @@
+package usenet_pool
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidDiscTitle = errors.New("usenet: invalid disc title")
+
+// maximum size of a playlist/ifo file read for the title
+const discTitleFileMaxSize = 1024 * 1024
+
+var vtsIFORegex = regexp.MustCompile(`(?i)^(VTS_\d{2})_0\.IFO$`)
+
+// isDiscTitleFile reports whether the file selects a title of a disc
+// structure, i.e. a BluRay playlist (BDMV/PLAYLIST/*.mpls) or a DVD title set
+// (VIDEO_TS/VTS_XX_0.IFO).
+func isDiscTitleFile(name string) bool {
+	name = strings.ReplaceAll(name, "\\", "/")
+	dir := strings.ToUpper(path.Base(path.Dir(name)))
+	base := path.Base(name)
+	switch {
+	case strings.EqualFold(path.Ext(base), ".mpls"):
+		return dir == "PLAYLIST"
+	case vtsIFORegex.MatchString(base):
+		return dir == "VIDEO_TS"
+	}
+	return false
+}
+
+func readDiscTitleFile(f ArchiveFile) ([]byte, error) {
+	if f.Size() > discTitleFileMaxSize {
+		return nil, fmt.Errorf("%w: %s is too large", ErrInvalidDiscTitle, f.Name())
+	}
+	var r io.ReadCloser
+	var err error
+	if !f.IsStreamable() && canDecompress(f) {
+		r, err = f.(decompressibleFile).openDecompressed()
+	} else {
+		r, err = f.Open()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", f.Name(), err)
+	}
+	defer r.Close()
+	return io.ReadAll(io.LimitReader(r, discTitleFileMaxSize))
+}
+
+// parseMPLSClips returns the clip names of the play items of a BluRay
+// playlist, in playback order. In/out points and angles are not considered,
+// the whole primary clips are played.
+func parseMPLSClips(data []byte) ([]string, error) {
+	if len(data) < 12 || !bytes.Equal(data[0:4], []byte("MPLS")) {
+		return nil, fmt.Errorf("%w: missing mpls header", ErrInvalidDiscTitle)
+	}
+
+	pos := int(binary.BigEndian.Uint32(data[8:12]))
+	// length(4) + reserved(2) + number_of_PlayItems(2) + number_of_SubPaths(2)
+	if pos+10 > len(data) {
+		return nil, fmt.Errorf("%w: truncated mpls playlist", ErrInvalidDiscTitle)
+	}
+	itemCount := int(binary.BigEndian.Uint16(data[pos+6 : pos+8]))
+	pos += 10
+
+	clips := make([]string, 0, itemCount)
+	for range itemCount {
+		// length(2) + Clip_Information_file_name(5) + Clip_codec_identifier(4)
+		if pos+11 > len(data) {
+			return nil, fmt.Errorf("%w: truncated mpls play item", ErrInvalidDiscTitle)
+		}
+		length := int(binary.BigEndian.Uint16(data[pos : pos+2]))
+		clips = append(clips, string(data[pos+2:pos+7]))
+		pos += 2 + length
+	}
+
+	if len(clips) == 0 {
+		return nil, fmt.Errorf("%w: mpls playlist has no play items", ErrInvalidDiscTitle)
+	}
+	return clips, nil
+}
+
+// parseVTSIFO validates the header of a DVD title set ifo, which must have
+// the title VOBs.
+func parseVTSIFO(data []byte) error {
+	if len(data) < 0xC8 || !bytes.Equal(data[0:12], []byte("DVDVIDEO-VTS")) {
+		return fmt.Errorf("%w: missing ifo header", ErrInvalidDiscTitle)
+	}
+	if binary.BigEndian.Uint32(data[0xC4:0xC8]) == 0 {
+		return fmt.Errorf("%w: ifo has no title vobs", ErrInvalidDiscTitle)
+	}
+	return nil
+}
+
+// resolveDiscTitle maps the disc title file to the files it is played from.
+func resolveDiscTitle(files []ArchiveFile, titleFile ArchiveFile) (name string, parts []ArchiveFile, err error) {
+	data, err := readDiscTitleFile(titleFile)
+	if err != nil {
+		return "", nil, err
+	}
+
+	titlePath := strings.ReplaceAll(titleFile.Name(), "\\", "/")
+	dir := path.Dir(titlePath)
+	base := path.Base(titlePath)
+
+	fileByPath := make(map[string]ArchiveFile, len(files))
+	for _, f := range files {
+		fileByPath[strings.ToUpper(strings.ReplaceAll(f.Name(), "\\", "/"))] = f
+	}
+
+	if m := vtsIFORegex.FindStringSubmatch(base); m != nil {
+		if err := parseVTSIFO(data); err != nil {
+			return "", nil, err
+		}
+		for i := 1; i <= 9; i++ {
+			f, ok := fileByPath[strings.ToUpper(path.Join(dir, fmt.Sprintf("%s_%d.VOB", m[1], i)))]
+			if !ok {
+				break
+			}
+			parts = append(parts, f)
+		}
+		if len(parts) == 0 {
+			return "", nil, fmt.Errorf("%w: no vob found for %s", ErrInvalidDiscTitle, base)
+		}
+		return m[1] + ".VOB", parts, nil
+	}
+
+	clips, err := parseMPLSClips(data)
+	if err != nil {
+		return "", nil, err
+	}
+	streamDir := path.Join(path.Dir(dir), "STREAM")
+	for _, clip := range clips {
+		f, ok := fileByPath[strings.ToUpper(path.Join(streamDir, clip+".m2ts"))]
+		if !ok {
+			return "", nil, fmt.Errorf("%w: clip %s not found for %s", ErrInvalidDiscTitle, clip, base)
+		}
+		parts = append(parts, f)
+	}
+	return strings.TrimSuffix(base, path.Ext(base)) + ".m2ts", parts, nil
+}
+
+// streamDiscTitle joins the files the disc title is played from into a
+// single stream.
+func streamDiscTitle(files []ArchiveFile, titleFile ArchiveFile) (*Stream, error) {
+	name, parts, err := resolveDiscTitle(files, titleFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var size int64
+	for _, f := range parts {
+		if !f.IsStreamable() {
+			return nil, fmt.Errorf("file %s is not streamable: %w", f.Name(), ErrArchiveSolid)
+		}
+		size += f.Size()
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("%w: %s", ErrEmptyFile, titleFile.Name())
+	}
+
+	r := newJoinedReader(parts)
+	return &Stream{
+		ReadSeekCloser: r,
+		Name:           name,
+		Size:           size,
+		ContentType:    detectContentType(r, name),
+		Path:           titleFile.Name(),
+	}, nil
+}
+
+var _ io.ReadSeekCloser = (*joinedReader)(nil)
+
+// joinedReader reads the files one after another as a single seekable
+// stream. Only the file at the current position is kept open.
+type joinedReader struct {
+	files   []ArchiveFile
+	offsets []int64 // start offset of each file
+	size    int64
+	pos     int64
+	idx     int // index of the opened file, -1 if none
+	r       io.ReadSeekCloser
+	rpos    int64 // position in the opened file
+}
+
+func newJoinedReader(files []ArchiveFile) *joinedReader {
+	jr := &joinedReader{
+		files:   files,
+		offsets: make([]int64, len(files)),
+		idx:     -1,
+	}
+	for i, f := range files {
+		jr.offsets[i] = jr.size
+		jr.size += f.Size()
+	}
+	return jr
+}
+
+func (jr *joinedReader) fileIndex(pos int64) int {
+	for i := len(jr.offsets) - 1; i >= 0; i-- {
+		if pos >= jr.offsets[i] {
+			return i
+		}
+	}
+	return 0
+}
+
+func (jr *joinedReader) open(idx int) error {
+	if jr.idx == idx {
+		return nil
+	}
+	if jr.r != nil {
+		jr.r.Close()
+		jr.r = nil
+		jr.idx = -1
+	}
+	r, err := jr.files[idx].Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", jr.files[idx].Name(), err)
+	}
+	jr.r = r
+	jr.idx = idx
+	jr.rpos = 0
+	return nil
+}
+
+func (jr *joinedReader) Read(p []byte) (int, error) {
+	for jr.pos < jr.size {
+		idx := jr.fileIndex(jr.pos)
+		if err := jr.open(idx); err != nil {
+			return 0, err
+		}
+		offset := jr.pos - jr.offsets[idx]
+		if offset != jr.rpos {
+			if _, err := jr.r.Seek(offset, io.SeekStart); err != nil {
+				return 0, err
+			}
+			jr.rpos = offset
+		}
+		remaining := jr.files[idx].Size() - offset
+		if int64(len(p)) > remaining {
+			p = p[:remaining]
+		}
+		n, err := jr.r.Read(p)
+		jr.pos += int64(n)
+		jr.rpos += int64(n)
+		if n > 0 {
+			return n, nil
+		}
+		if err == io.EOF {
+			if jr.pos < jr.offsets[idx]+jr.files[idx].Size() {
+				return 0, io.ErrUnexpectedEOF
+			}
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return 0, io.EOF
+}
+
+func (jr *joinedReader) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = jr.pos + offset
+	case io.SeekEnd:
+		pos = jr.size + offset
+	default:
+		return jr.pos, errors.New("joined reader: invalid whence")
+	}
+	if pos < 0 {
+		return jr.pos, errors.New("joined reader: negative position")
+	}
+	jr.pos = pos
+	return pos, nil
+}
+
+func (jr *joinedReader) Close() error {
+	if jr.r == nil {
+		return nil
+	}
+	err := jr.r.Close()
+	jr.r = nil
+	jr.idx = -1
+	return err
+}
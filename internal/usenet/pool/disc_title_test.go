@@ -0,0 +1,125 @@
+package usenet_pool
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildTestMPLS(clips ...string) []byte {
+	data := make([]byte, 40)
+	copy(data, "MPLS0200")
+	binary.BigEndian.PutUint32(data[8:12], 40)
+
+	playlist := make([]byte, 10)
+	binary.BigEndian.PutUint16(playlist[6:8], uint16(len(clips)))
+	for _, clip := range clips {
+		item := make([]byte, 20)
+		binary.BigEndian.PutUint16(item[0:2], 18)
+		copy(item[2:7], clip)
+		copy(item[7:11], "M2TS")
+		playlist = append(playlist, item...)
+	}
+	return append(data, playlist...)
+}
+
+func buildTestVTSIFO() []byte {
+	data := make([]byte, 0x100)
+	copy(data, "DVDVIDEO-VTS")
+	binary.BigEndian.PutUint32(data[0xC4:0xC8], 100)
+	return data
+}
+
+func TestIsDiscTitleFile(t *testing.T) {
+	for name, expected := range map[string]bool{
+		"Movie/BDMV/PLAYLIST/00800.mpls": true,
+		"BDMV\\PLAYLIST\\00001.MPLS":     true,
+		"VIDEO_TS/VTS_01_0.IFO":          true,
+		"VIDEO_TS/VIDEO_TS.IFO":          false,
+		"VIDEO_TS/VTS_01_1.VOB":          false,
+		"BDMV/STREAM/00001.m2ts":         false,
+		"00800.mpls":                     false,
+	} {
+		assert.Equal(t, expected, isDiscTitleFile(name), name)
+	}
+}
+
+func TestStreamDiscTitle(t *testing.T) {
+	usenetPool := &Pool{Log: logger.Scoped("test/usenet/pool")}
+
+	t.Run("BluRayPlaylist", func(t *testing.T) {
+		clip1 := bytes.Repeat([]byte{'a'}, 100)
+		clip2 := bytes.Repeat([]byte{'b'}, 50)
+		archive := &testArchive{files: []ArchiveFile{
+			&testDataArchiveFile{name: "Movie/BDMV/PLAYLIST/00800.mpls", data: buildTestMPLS("00002", "00001")},
+			&testDataArchiveFile{name: "Movie/BDMV/STREAM/00001.m2ts", data: clip1},
+			&testDataArchiveFile{name: "Movie/BDMV/STREAM/00002.M2TS", data: clip2},
+			&testDataArchiveFile{name: "Movie/BDMV/STREAM/00003.m2ts", data: []byte("extra")},
+		}}
+
+		stream, err := usenetPool.streamTargetFromArchive(archive, []string{"Movie/BDMV/PLAYLIST/00800.mpls"}, FileTypeRAR, VideoSelectLargest, false)
+		require.NoError(t, err)
+		defer stream.Close()
+
+		assert.Equal(t, "00800.m2ts", stream.Name)
+		assert.Equal(t, "video/mp2t", stream.ContentType)
+		assert.Equal(t, int64(150), stream.Size)
+		assert.Equal(t, "Movie/BDMV/PLAYLIST/00800.mpls", stream.Path)
+
+		data, err := io.ReadAll(stream)
+		require.NoError(t, err)
+		assert.Equal(t, append(clip2, clip1...), data)
+
+		_, err = stream.Seek(45, io.SeekStart)
+		require.NoError(t, err)
+		buf := make([]byte, 10)
+		_, err = io.ReadFull(stream, buf)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("bbbbbaaaaa"), buf)
+	})
+
+	t.Run("BluRayMissingClip", func(t *testing.T) {
+		archive := &testArchive{files: []ArchiveFile{
+			&testDataArchiveFile{name: "BDMV/PLAYLIST/00800.mpls", data: buildTestMPLS("00001")},
+		}}
+		_, err := usenetPool.streamTargetFromArchive(archive, []string{"BDMV/PLAYLIST/00800.mpls"}, FileTypeRAR, VideoSelectLargest, false)
+		assert.ErrorIs(t, err, ErrInvalidDiscTitle)
+	})
+
+	t.Run("DVDTitleSet", func(t *testing.T) {
+		vob1 := append([]byte{0x00, 0x00, 0x01, 0xBA}, bytes.Repeat([]byte{'1'}, 60)...)
+		vob2 := bytes.Repeat([]byte{'2'}, 40)
+		archive := &testArchive{files: []ArchiveFile{
+			&testDataArchiveFile{name: "VIDEO_TS/VTS_01_0.IFO", data: buildTestVTSIFO()},
+			&testDataArchiveFile{name: "VIDEO_TS/VTS_01_0.VOB", data: []byte("menu")},
+			&testDataArchiveFile{name: "VIDEO_TS/VTS_01_1.VOB", data: vob1},
+			&testDataArchiveFile{name: "VIDEO_TS/VTS_01_2.VOB", data: vob2},
+			&testDataArchiveFile{name: "VIDEO_TS/VTS_02_1.VOB", data: []byte("other")},
+		}}
+
+		stream, err := usenetPool.streamTargetFromArchive(archive, []string{"VIDEO_TS/VTS_01_0.IFO"}, FileTypeRAR, VideoSelectLargest, false)
+		require.NoError(t, err)
+		defer stream.Close()
+
+		assert.Equal(t, "VTS_01.VOB", stream.Name)
+		assert.Equal(t, "video/mpeg", stream.ContentType)
+
+		data, err := io.ReadAll(stream)
+		require.NoError(t, err)
+		assert.Equal(t, append(vob1, vob2...), data)
+	})
+
+	t.Run("DVDInvalidIFO", func(t *testing.T) {
+		archive := &testArchive{files: []ArchiveFile{
+			&testDataArchiveFile{name: "VIDEO_TS/VTS_01_0.IFO", data: []byte("garbage")},
+			&testDataArchiveFile{name: "VIDEO_TS/VTS_01_1.VOB", data: []byte("vob")},
+		}}
+		_, err := usenetPool.streamTargetFromArchive(archive, []string{"VIDEO_TS/VTS_01_0.IFO"}, FileTypeRAR, VideoSelectLargest, false)
+		assert.ErrorIs(t, err, ErrInvalidDiscTitle)
+	})
+}
@@ -0,0 +1,53 @@
+package usenet_pool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+type nzbHashContextKey struct{}
+
+// withNZBHash attributes the segments fetched using the context to the NZB.
+func withNZBHash(ctx context.Context, hash string) context.Context {
+	if hash == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, nzbHashContextKey{}, hash)
+}
+
+func getNZBHash(ctx context.Context) string {
+	hash, _ := ctx.Value(nzbHashContextKey{}).(string)
+	return hash
+}
+
+// downloadCounter keeps the bytes fetched from providers per NZB hash. The
+// same NZB can be streamed concurrently, so the counters are atomic.
+type downloadCounter struct {
+	counts sync.Map // hash -> *atomic.Int64
+}
+
+func (c *downloadCounter) add(hash string, n int64) {
+	counter, ok := c.counts.Load(hash)
+	if !ok {
+		counter, _ = c.counts.LoadOrStore(hash, &atomic.Int64{})
+	}
+	counter.(*atomic.Int64).Add(n)
+}
+
+func (c *downloadCounter) drain() map[string]int64 {
+	result := map[string]int64{}
+	c.counts.Range(func(key, value any) bool {
+		if n := value.(*atomic.Int64).Swap(0); n > 0 {
+			result[key.(string)] = n
+		}
+		return true
+	})
+	return result
+}
+
+// DrainDownloadedBytes returns the bytes fetched from providers per NZB hash
+// since the last call.
+func (p *Pool) DrainDownloadedBytes() map[string]int64 {
+	return p.downloaded.drain()
+}
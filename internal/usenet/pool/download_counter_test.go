@@ -0,0 +1,36 @@
+package usenet_pool
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadCounter(t *testing.T) {
+	c := &downloadCounter{}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			for range 100 {
+				c.add("a", 10)
+			}
+		})
+	}
+	c.add("b", 5)
+	wg.Wait()
+
+	assert.Equal(t, map[string]int64{"a": 10000, "b": 5}, c.drain())
+	assert.Empty(t, c.drain())
+
+	c.add("a", 1)
+	assert.Equal(t, map[string]int64{"a": 1}, c.drain())
+}
+
+func TestNZBHashContext(t *testing.T) {
+	ctx := t.Context()
+	assert.Equal(t, "", getNZBHash(ctx))
+	assert.Equal(t, "", getNZBHash(withNZBHash(ctx, "")))
+	assert.Equal(t, "abc", getNZBHash(withNZBHash(ctx, "abc")))
+}
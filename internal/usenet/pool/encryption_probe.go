@@ -0,0 +1,107 @@
+package usenet_pool
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+	"github.com/MunifTanjim/stremthru/internal/util"
+)
+
+type ArchiveEncryption struct {
+	Name             string
+	PasswordRequired bool
+	// file listing itself needs the password
+	HeaderEncrypted bool
+}
+
+// ProbeArchiveEncryption looks at the headers of the largest RAR/7z archive
+// in the NZB to tell if a password is needed. Returns nil if there is no
+// such archive.
+func (p *Pool) ProbeArchiveEncryption(ctx context.Context, nzbDoc *nzb.NZB) (*ArchiveEncryption, error) {
+	var group *NZBArchiveGroup
+	for _, g := range GroupNZBArchiveFiles(nzbDoc.Files) {
+		if g.FileType != FileTypeRAR && g.FileType != FileType7z {
+			continue
+		}
+		if group == nil || g.TotalSize > group.TotalSize {
+			group = &g
+		}
+	}
+	if group == nil {
+		return nil, nil
+	}
+
+	ufs := NewUsenetFS(ctx, &UsenetFSConfig{
+		NZB:               nzbDoc,
+		Pool:              p,
+		SegmentBufferSize: util.ToBytes("1MB"),
+	})
+	defer ufs.Close()
+
+	result := &ArchiveEncryption{Name: group.Files[0]}
+
+	switch group.FileType {
+	case FileTypeRAR:
+		// volumes are checked until one tells, the first may only carry
+		// files in the clear, e.g. an nfo
+		for _, name := range group.Files {
+			encrypted, err := probeRARVolumeEncryption(ufs, name)
+			if errors.Is(err, errRARHeaderEncrypted) {
+				result.PasswordRequired = true
+				result.HeaderEncrypted = true
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if encrypted {
+				result.PasswordRequired = true
+				break
+			}
+		}
+
+	case FileType7z:
+		archive := NewSevenZipArchive(ufs.toAfero(), result.Name)
+		if err := archive.Open(""); err != nil {
+			if errors.Is(err, ErrPasswordRequired) {
+				result.PasswordRequired = true
+				result.HeaderEncrypted = true
+				return result, nil
+			}
+			return nil, err
+		}
+		defer archive.Close()
+		files, err := archive.GetFiles()
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			if f, ok := f.(*Usenet7zFile); ok && f.IsEncrypted() {
+				result.PasswordRequired = true
+				break
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// probeRARVolumeEncryption tells if any file in the rar volume is encrypted.
+// Returns errRARHeaderEncrypted if the headers are encrypted.
+func probeRARVolumeEncryption(fsys fs.FS, name string) (bool, error) {
+	f, size, err := openRARStoredFileVolume(fsys, name)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	encrypted := false
+	hr := &rarHeaderReader{r: f, size: size}
+	err = hr.walkFiles(func(header *rarStoredBlockHeader) bool {
+		encrypted = header.encrypted
+		return encrypted
+	})
+	return encrypted, err
+}
@@ -0,0 +1,96 @@
+package usenet_pool
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/nntp/nntptest"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbeArchiveEncryption(t *testing.T) {
+	content := []byte("0123456789")
+
+	rar5HeaderEncrypted := bytes.Clone(magicBytesRAR5)
+	rar5HeaderEncrypted = appendRAR5Header(rar5HeaderEncrypted, []byte{rar5HeaderTypeEncryption, 0, 0, 0, 0})
+
+	for _, tc := range []struct {
+		name     string
+		data     []byte
+		expected ArchiveEncryption
+	}{
+		{
+			name:     "plain",
+			data:     buildRAR5Volume(0, "movie.mkv", content, false, false),
+			expected: ArchiveEncryption{Name: "movie.rar"},
+		},
+		{
+			name:     "file encrypted",
+			data:     buildRAR4Archive("movie.mkv", content, rar4FileFlagPassword),
+			expected: ArchiveEncryption{Name: "movie.rar", PasswordRequired: true},
+		},
+		{
+			name: "later file encrypted",
+			data: buildRAR4ArchiveFiles(
+				rar4TestFile{name: "movie.nfo", data: []byte("nfo")},
+				rar4TestFile{name: "movie.mkv", data: content, flags: rar4FileFlagPassword},
+			),
+			expected: ArchiveEncryption{Name: "movie.rar", PasswordRequired: true},
+		},
+		{
+			name:     "header encrypted",
+			data:     rar5HeaderEncrypted,
+			expected: ArchiveEncryption{Name: "movie.rar", PasswordRequired: true, HeaderEncrypted: true},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := nntptest.NewServer(t, "200 NNTP Service Ready")
+			size := int64(len(tc.data))
+			server.SetResponse("GROUP alt.binaries.test", "211 1 1 1 alt.binaries.test")
+			server.SetResponse("BODY <msg1@test>", "222 0 <msg1@test>", []string{string(encodeYenc(tc.data, "movie.rar", 1, 1, size, 1))})
+			server.Start(t)
+
+			nzbDoc := createTestNZB(
+				nzb.File{
+					Subject:  `Test - "movie.rar" yEnc (1/1)`,
+					Segments: []nzb.Segment{{MessageId: "msg1@test", Bytes: size, Number: 1}},
+				},
+				nzb.File{
+					Subject:  `Test - "movie.nfo" yEnc (1/1)`,
+					Segments: []nzb.Segment{{MessageId: "msg2@test", Bytes: 10, Number: 1}},
+				},
+			)
+
+			result, err := createTestPool(t, server).ProbeArchiveEncryption(t.Context(), nzbDoc)
+			require.NoError(t, err)
+			require.NotNil(t, result)
+			assert.Equal(t, tc.expected, *result)
+		})
+	}
+
+	t.Run("largest archive, later volume header encrypted", func(t *testing.T) {
+		video := bytes.Repeat(content, 10)
+		usenetPool, nzbDoc := createTestNZBServer(t,
+			testNZBFile{"extras.rar", buildRAR4Archive("extras.mkv", content, 0)},
+			testNZBFile{"movie.part1.rar", buildRAR5Volume(0, "movie.mkv", video, false, true)},
+			testNZBFile{"movie.part2.rar", rar5HeaderEncrypted},
+		)
+
+		result, err := usenetPool.ProbeArchiveEncryption(t.Context(), nzbDoc)
+		require.NoError(t, err)
+		require.NotNil(t, result)
+		assert.Equal(t, ArchiveEncryption{Name: "movie.part1.rar", PasswordRequired: true, HeaderEncrypted: true}, *result)
+	})
+
+	t.Run("no archive", func(t *testing.T) {
+		nzbDoc := createTestNZB(nzb.File{
+			Subject:  `Test - "movie.mkv" yEnc (1/1)`,
+			Segments: []nzb.Segment{{MessageId: "msg1@test", Bytes: 10, Number: 1}},
+		})
+		result, err := (&Pool{}).ProbeArchiveEncryption(t.Context(), nzbDoc)
+		require.NoError(t, err)
+		assert.Nil(t, result)
+	})
+}
@@ -0,0 +1,52 @@
+package usenet_pool
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/MunifTanjim/stremthru/internal/server"
+	"github.com/bodgit/sevenzip"
+	"github.com/nwaples/rardecode/v2"
+)
+
+// Error carries a machine-readable code, so that API clients can branch on
+// the kind of failure instead of the message. The codes are defined along
+// with their status codes in the server package.
+type Error struct {
+	code server.ErrorCode
+	msg  string
+}
+
+func NewError(code server.ErrorCode, msg string) *Error {
+	return &Error{code: code, msg: msg}
+}
+
+func (e *Error) Error() string {
+	return e.msg
+}
+
+func (e *Error) Code() string {
+	return string(e.code)
+}
+
+// GetErrorCode returns the code of the first *Error in err's chain.
+func GetErrorCode(err error) server.ErrorCode {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.code
+	}
+	return ""
+}
+
+func wrapArchiveOpenError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, rardecode.ErrArchiveEncrypted) || errors.Is(err, rardecode.ErrArchivedFileEncrypted) || errors.Is(err, rardecode.ErrBadPassword) {
+		return fmt.Errorf("%w: %w", ErrPasswordRequired, err)
+	}
+	if re := (*sevenzip.ReadError)(nil); errors.As(err, &re) && re.Encrypted {
+		return fmt.Errorf("%w: %w", ErrPasswordRequired, err)
+	}
+	return err
+}
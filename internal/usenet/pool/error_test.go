@@ -0,0 +1,33 @@
+package usenet_pool
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/server"
+	"github.com/nwaples/rardecode/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetErrorCode(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		code server.ErrorCode
+	}{
+		{"ArticleNotFound", fmt.Errorf("failed to fetch: %w", ErrArticleNotFound), server.ErrorCodeArticleMissing},
+		{"SegmentFetchTimeout", fmt.Errorf("%w: %w", ErrSegmentFetchTimeout, errors.New("i/o timeout")), server.ErrorCodeFetchTimeout},
+		{"ArchiveSolid", fmt.Errorf("non-streamable %s archive: %w", FileTypeRAR, ErrArchiveSolid), server.ErrorCodeArchiveSolid},
+		{"RAREncrypted", wrapArchiveOpenError(rardecode.ErrArchiveEncrypted), server.ErrorCodePasswordRequired},
+		{"RARBadPassword", wrapArchiveOpenError(fmt.Errorf("open: %w", rardecode.ErrBadPassword)), server.ErrorCodePasswordRequired},
+		{"Uncoded", errors.New("boom"), ""},
+		{"Nil", nil, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.code, GetErrorCode(tc.err))
+		})
+	}
+
+	assert.ErrorIs(t, wrapArchiveOpenError(rardecode.ErrArchiveEncrypted), rardecode.ErrArchiveEncrypted)
+}
@@ -0,0 +1,140 @@
+package usenet_pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+)
+
+const defaultTraceSampleSize = 10
+
+type SegmentFetchTrace struct {
+	Number     int     `json:"number"`
+	MessageId  string  `json:"message_id"`
+	Provider   string  `json:"provider,omitempty"` // empty for cache hit or shared fetch
+	Cache      string  `json:"cache"`
+	Size       int     `json:"size"`
+	DurationMs float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// FetchTracer collects the timings of the segments fetched with a context
+// it is attached to.
+type FetchTracer struct {
+	Id     string // included in the trace logs, e.g. the request id
+	mu     sync.Mutex
+	traces []SegmentFetchTrace
+}
+
+func (t *FetchTracer) Traces() []SegmentFetchTrace {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]SegmentFetchTrace(nil), t.traces...)
+}
+
+type fetchTracerContextKey struct{}
+
+func WithFetchTracer(ctx context.Context, tracer *FetchTracer) context.Context {
+	if tracer == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, fetchTracerContextKey{}, tracer)
+}
+
+func getFetchTracer(ctx context.Context) *FetchTracer {
+	tracer, _ := ctx.Value(fetchTracerContextKey{}).(*FetchTracer)
+	return tracer
+}
+
+func toDurationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func (p *Pool) traceSegmentFetch(ctx context.Context, segment *nzb.Segment, start time.Time, provider string, cacheHit bool, data *SegmentData, err error) {
+	tracer := getFetchTracer(ctx)
+	if tracer == nil {
+		return
+	}
+
+	trace := SegmentFetchTrace{
+		Number:     segment.Number,
+		MessageId:  segment.MessageId,
+		Provider:   provider,
+		Cache:      CacheStatusMiss,
+		DurationMs: toDurationMs(time.Since(start)),
+	}
+	if cacheHit {
+		trace.Cache = CacheStatusHit
+	}
+	if data != nil {
+		trace.Size = len(data.Body)
+	}
+	if err != nil {
+		trace.Error = err.Error()
+	}
+
+	tracer.mu.Lock()
+	tracer.traces = append(tracer.traces, trace)
+	tracer.mu.Unlock()
+
+	p.Log.Debug("fetch segment - trace", "trace_id", tracer.Id, "segment_num", trace.Number, "message_id", trace.MessageId, "provider_id", trace.Provider, "cache", trace.Cache, "size", trace.Size, "duration_ms", trace.DurationMs, "error", trace.Error)
+}
+
+type FetchTraceConfig struct {
+	Id         string // included in the trace logs
+	SampleSize int    // segments fetched from the largest file (first, last and evenly spaced in between)
+}
+
+type FetchTraceResult struct {
+	File       string              `json:"file"`
+	Segments   []SegmentFetchTrace `json:"segments"`
+	DurationMs float64             `json:"duration_ms"`
+}
+
+// TraceFetch fetches a sample of segments of the largest file one after
+// another, recording the timing of each, to tell apart the provider latency
+// from the streaming overhead.
+func (p *Pool) TraceFetch(
+	ctx context.Context,
+	nzbDoc *nzb.NZB,
+	conf *FetchTraceConfig,
+) (*FetchTraceResult, error) {
+	if conf == nil {
+		conf = &FetchTraceConfig{}
+	}
+	sampleSize := conf.SampleSize
+	if sampleSize <= 0 {
+		sampleSize = defaultTraceSampleSize
+	}
+
+	if p.CountProviders() == 0 {
+		return nil, ErrNoProvidersConfigured
+	}
+
+	largestFileIdx := nzbDoc.GetLargestFileIdx(nil)
+	if largestFileIdx == -1 {
+		return nil, errors.New("NZB has no non-empty files")
+	}
+	file := &nzbDoc.Files[largestFileIdx]
+
+	tracer := &FetchTracer{Id: conf.Id}
+	ctx = WithFetchTracer(ctx, tracer)
+
+	start := time.Now()
+	for _, idx := range sampleSegmentIndices(file.SegmentCount(), sampleSize) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// failures are recorded in the trace
+		p.fetchSegment(ctx, &file.Segments[idx], file.Groups)
+	}
+
+	return &FetchTraceResult{
+		File:       file.Name(),
+		Segments:   tracer.Traces(),
+		DurationMs: toDurationMs(time.Since(start)),
+	}, nil
+}
@@ -0,0 +1,53 @@
+package usenet_pool
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/nntp/nntptest"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceFetch(t *testing.T) {
+	data := makeTestBytes(100)
+	encoded := encodeYenc(data, "movie.mkv", 1, 1, int64(len(data)), 1)
+
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.SetResponse("GROUP alt.binaries.test", "211 1 1 1 alt.binaries.test")
+	server.SetResponse("BODY <seg1@test.com>", "222 0 <seg1@test.com>", strings.Split(strings.TrimSpace(string(encoded)), "\r\n"))
+	server.SetResponse("BODY <seg2@test.com>", "430 No Such Article")
+	server.Start(t)
+
+	usenetPool := createTestPool(t, server)
+	usenetPool.segmentCache = NewSegmentCache(10*1024*1024, "")
+
+	nzbDoc := createTestNZB(nzb.File{
+		Subject: `Test - "movie.mkv" yEnc (1/2)`,
+		Segments: []nzb.Segment{
+			{MessageId: "seg1@test.com", Bytes: int64(len(encoded)), Number: 1},
+			{MessageId: "seg2@test.com", Bytes: int64(len(encoded)), Number: 2},
+		},
+	})
+
+	result, err := usenetPool.TraceFetch(t.Context(), nzbDoc, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "movie.mkv", result.File)
+	require.Len(t, result.Segments, 2)
+
+	assert.Equal(t, "seg1@test.com", result.Segments[0].MessageId)
+	assert.Equal(t, CacheStatusMiss, result.Segments[0].Cache)
+	assert.Equal(t, usenetPool.providers[0].Id(), result.Segments[0].Provider)
+	assert.Equal(t, len(data), result.Segments[0].Size)
+	assert.Empty(t, result.Segments[0].Error)
+
+	assert.Equal(t, "seg2@test.com", result.Segments[1].MessageId)
+	assert.NotEmpty(t, result.Segments[1].Error)
+
+	result, err = usenetPool.TraceFetch(t.Context(), nzbDoc, &FetchTraceConfig{SampleSize: 1})
+	require.NoError(t, err)
+	require.Len(t, result.Segments, 1)
+	assert.Equal(t, CacheStatusHit, result.Segments[0].Cache)
+	assert.Empty(t, result.Segments[0].Provider)
+}
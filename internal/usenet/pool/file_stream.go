@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"sync"
+	"sync/atomic"
 
 	"github.com/MunifTanjim/stremthru/internal/config"
 	"github.com/MunifTanjim/stremthru/internal/logger"
@@ -50,12 +51,28 @@ type FileStream struct {
 	pool       *Pool
 	bufferSize int64
 
+	// adaptive buffer starts at initBufferSize and grows up to bufferSize
+	// while the stream is read sequentially
+	adaptiveBuffer  bool
+	initBufferSize  int64
+	currBufferSize  int64
+	bytesSinceGrown int64
+
 	mu     sync.Mutex
 	ctx    context.Context
 	cancel context.CancelFunc
 
 	position int64
 	stream   *SegmentsStream
+	retries  int
+
+	lastSearch atomic.Pointer[searchResult]
+
+	readersMu sync.Mutex
+	readers   []*positionedReader
+
+	allowPartial bool
+	truncated    bool
 
 	closed bool
 }
@@ -66,11 +83,18 @@ func NewFileStream(
 	file *nzb.File,
 	bufferSize int64,
 ) (*FileStream, error) {
+	adaptiveBuffer := false
+	initBufferSize := bufferSize
 	if bufferSize <= 0 {
 		bufferSize = config.Newz.StreamBufferSize
+		initBufferSize = bufferSize
+		if size := config.Newz.StreamBufferInitSize; size > 0 && size < bufferSize {
+			adaptiveBuffer = true
+			initBufferSize = size
+		}
 	}
 
-	firstSegment, err := pool.fetchFirstSegment(ctx, file)
+	firstSegment, err := pool.fetchFileHeader(ctx, file)
 	if err != nil {
 		return nil, err
 	}
@@ -90,7 +114,7 @@ func NewFileStream(
 
 	ctx, cancel := context.WithCancel(ctx)
 
-	return &FileStream{
+	s := &FileStream{
 		file:             file,
 		fileSize:         fileSize,
 		avgSegmentSize:   avgSegmentSize,
@@ -99,9 +123,15 @@ func NewFileStream(
 		pool:       pool,
 		bufferSize: bufferSize,
 
+		adaptiveBuffer: adaptiveBuffer,
+		initBufferSize: initBufferSize,
+		currBufferSize: initBufferSize,
+
 		ctx:    ctx,
 		cancel: cancel,
-	}, nil
+	}
+	trackPrefetch(ctx, s)
+	return s, nil
 }
 
 func (s *FileStream) Read(p []byte) (n int, err error) {
@@ -116,33 +146,194 @@ func (s *FileStream) Read(p []byte) (n int, err error) {
 		return 0, io.EOF
 	}
 
-	if s.stream == nil {
-		stream, err := s.createSegmentsStream(s.position, s.bufferSize)
-		if err != nil {
-			return 0, err
+	for {
+		if s.stream == nil {
+			stream, err := s.createSegmentsStream(s.position, s.currBufferSize)
+			if err != nil {
+				if s.shouldRetry(err) {
+					s.retries++
+					continue
+				}
+				return 0, err
+			}
+			s.stream = stream
 		}
-		s.stream = stream
+
+		n, err = s.stream.Read(p)
+		s.position += int64(n)
+		s.growBuffer(int64(n))
+		if err == nil || !s.shouldRetry(err) {
+			if err == nil && n > 0 {
+				s.retries = 0
+			}
+			return n, err
+		}
+
+		// rebuild the segments stream from the current position
+		s.stream.Close()
+		s.stream = nil
+		s.retries++
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// isTransientStreamError checks if the stream can be resumed after err,
+// e.g. provider disconnect or timeout.
+func isTransientStreamError(err error) bool {
+	switch {
+	case errors.Is(err, io.EOF),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, ErrArticleNotFound),
+		errors.Is(err, ErrSegmentTooLarge),
+		errors.Is(err, ErrInvalidSegmentPlacement),
+		errors.Is(err, ErrSegmentNotCached),
+		errors.Is(err, ErrNoProvidersConfigured):
+		return false
 	}
+	return true
+}
+
+func (s *FileStream) shouldRetry(err error) bool {
+	if s.retries >= config.Newz.StreamRetryCount || s.ctx.Err() != nil || !isTransientStreamError(err) {
+		return false
+	}
+	fileLog.Warn("file stream - retrying after transient error", "error", err, "position", s.position, "attempt", s.retries+1)
+	return true
+}
+
+// growBuffer doubles the buffer, up to bufferSize, every time a buffer worth
+// of bytes is read sequentially.
+func (s *FileStream) growBuffer(n int64) {
+	if !s.adaptiveBuffer || s.currBufferSize >= s.bufferSize {
+		return
+	}
+
+	s.bytesSinceGrown += n
+	if s.bytesSinceGrown < s.currBufferSize {
+		return
+	}
+
+	newSize := min(s.currBufferSize*2, s.bufferSize)
+	fileLog.Trace("file stream - growing buffer", "old_size", s.currBufferSize, "new_size", newSize)
+	s.stream.growBuffer(newSize - s.currBufferSize)
+	s.currBufferSize = newSize
+	s.bytesSinceGrown = 0
+}
 
-	n, err = s.stream.Read(p)
-	s.position += int64(n)
-	return n, err
+// positionedReader is a segments stream left open after a ReadAt, so that a
+// following ReadAt continuing from pos can reuse it.
+type positionedReader struct {
+	stream *SegmentsStream
+	pos    int64
 }
 
+// number of positioned readers kept open for ReadAt
+const readAtReaderPoolSize = 4
+
 func (s *FileStream) ReadAt(p []byte, off int64) (n int, err error) {
 	if off < 0 || off >= s.fileSize {
 		return 0, io.EOF
 	}
 
+	if r := s.takeReader(off); r != nil {
+		fileLog.Trace("file stream - read at - reusing reader", "offset", off, "size", len(p))
+		return s.readAtWith(r, p)
+	}
+
+	if int64(len(p)) <= s.avgSegmentSize {
+		return s.readAtFromSegments(p, off)
+	}
+
 	// Use at least the requested read size as buffer, plus one extra segment for overhead
 	bufferSize := int64(len(p)) + s.avgSegmentSize
 	stream, err := s.createSegmentsStream(off, bufferSize)
 	if err != nil {
 		return 0, err
 	}
-	defer stream.Close()
 
-	return io.ReadFull(stream, p)
+	return s.readAtWith(&positionedReader{stream: stream, pos: off}, p)
+}
+
+func (s *FileStream) readAtWith(r *positionedReader, p []byte) (n int, err error) {
+	n, err = io.ReadFull(r.stream, p)
+	r.pos += int64(n)
+	if err != nil || r.pos >= s.fileSize {
+		r.stream.Close()
+		return n, err
+	}
+	s.putReader(r)
+	return n, nil
+}
+
+// readAtFromSegments serves small reads directly from the segments, which
+// are usually in the segment cache already, without creating a stream. The
+// parallel range requests landing on the same segment share its fetch.
+func (s *FileStream) readAtFromSegments(p []byte, off int64) (n int, err error) {
+	for n < len(p) {
+		pos := off + int64(n)
+		if pos >= s.fileSize {
+			return n, io.EOF
+		}
+
+		result, err := s.interpolationSearch(pos)
+		if err != nil {
+			return n, fmt.Errorf("failed to find segment for position %d: %w", pos, err)
+		}
+
+		data, err := s.pool.fetchSegment(s.ctx, &s.file.Segments[result.SegmentIndex], s.file.Groups)
+		if err != nil {
+			return n, err
+		}
+
+		start := pos - data.ByteRange.Start
+		if start < 0 || start >= int64(len(data.Body)) {
+			return n, fmt.Errorf("corrupt file: segment %d does not contain position %d", result.SegmentIndex, pos)
+		}
+		n += copy(p[n:], data.Body[start:])
+	}
+	return n, nil
+}
+
+func (s *FileStream) takeReader(pos int64) *positionedReader {
+	s.readersMu.Lock()
+	defer s.readersMu.Unlock()
+
+	for i, r := range s.readers {
+		if r.pos == pos {
+			s.readers = append(s.readers[:i], s.readers[i+1:]...)
+			return r
+		}
+	}
+	return nil
+}
+
+func (s *FileStream) putReader(r *positionedReader) {
+	s.readersMu.Lock()
+	defer s.readersMu.Unlock()
+
+	if s.ctx.Err() != nil {
+		r.stream.Close()
+		return
+	}
+
+	if len(s.readers) >= readAtReaderPoolSize {
+		s.readers[0].stream.Close()
+		s.readers = s.readers[1:]
+	}
+	s.readers = append(s.readers, r)
+}
+
+func (s *FileStream) closeReaders() {
+	s.readersMu.Lock()
+	defer s.readersMu.Unlock()
+
+	for _, r := range s.readers {
+		r.stream.Close()
+	}
+	s.readers = nil
 }
 
 func (s *FileStream) Seek(offset int64, whence int) (int64, error) {
@@ -177,15 +368,89 @@ func (s *FileStream) Seek(offset int64, whence int) (int64, error) {
 			s.stream = nil
 		}
 		s.position = newPos
+		s.currBufferSize = s.initBufferSize
+		s.bytesSinceGrown = 0
 	}
 
 	return s.position, nil
 }
 
+// pausePrefetch drops the segments stream and the positioned readers along
+// with their read-ahead, they are recreated on the next read. It reports
+// whether the stream is still open.
+func (s *FileStream) pausePrefetch() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+
+	if s.stream != nil {
+		s.stream.Close()
+		s.stream = nil
+	}
+	s.closeReaders()
+	s.currBufferSize = s.initBufferSize
+	s.bytesSinceGrown = 0
+	return true
+}
+
 func (s *FileStream) Size() int64 {
 	return s.fileSize
 }
 
+// Truncated reports whether the stream only covers a prefix of the file.
+func (s *FileStream) Truncated() bool {
+	return s.truncated
+}
+
+// enablePartial lets the stream serve the available prefix of the file when
+// its trailing segments are missing on all providers. The size is shrunk to
+// the end of the last available segment, found by binary search.
+func (s *FileStream) enablePartial() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.allowPartial = true
+
+	segments := s.file.Segments
+	lastIdx := len(segments) - 1
+	if lastIdx <= 0 {
+		return nil
+	}
+
+	if _, err := s.pool.fetchSegment(s.ctx, &segments[lastIdx], s.file.Groups); err == nil {
+		return nil
+	} else if !errors.Is(err, ErrArticleNotFound) {
+		return err
+	}
+
+	// the first segment is known to be available
+	lo, hi := 0, lastIdx
+	loData, err := s.pool.fetchSegment(s.ctx, &segments[lo], s.file.Groups)
+	if err != nil {
+		return err
+	}
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		data, err := s.pool.fetchSegment(s.ctx, &segments[mid], s.file.Groups)
+		if err == nil {
+			lo, loData = mid, data
+		} else if errors.Is(err, ErrArticleNotFound) {
+			hi = mid
+		} else {
+			return err
+		}
+	}
+
+	fileLog.Debug("file stream - truncated to available segments", "segment_count", lo+1, "total_segment_count", len(segments), "size", loData.ByteRange.End, "file_size", s.fileSize)
+
+	s.fileSize = loData.ByteRange.End
+	s.truncated = true
+	return nil
+}
+
 func (s *FileStream) Close() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -197,6 +462,7 @@ func (s *FileStream) Close() error {
 	s.closed = true
 
 	s.cancel()
+	s.closeReaders()
 	if s.stream != nil {
 		return s.stream.Close()
 	}
@@ -207,7 +473,7 @@ func (s *FileStream) createSegmentsStream(startPos int64, bufferSize int64) (*Se
 	fileLog.Trace("create segments stream - start", "position", startPos)
 
 	if startPos == 0 {
-		return NewSegmentsStream(s.ctx, s.pool, s.file.Segments, s.file.Groups, bufferSize), nil
+		return newSegmentsStream(s.ctx, s.pool, s.file.Segments, s.file.Groups, bufferSize, s.allowPartial), nil
 	}
 
 	result, err := s.interpolationSearch(startPos)
@@ -217,7 +483,7 @@ func (s *FileStream) createSegmentsStream(startPos int64, bufferSize int64) (*Se
 
 	fileLog.Trace("create segments stream - found segment", "segment_idx", result.SegmentIndex, "byte_range", fmt.Sprintf("[%d, %d)", result.ByteRange.Start, result.ByteRange.End))
 
-	stream := NewSegmentsStream(s.ctx, s.pool, s.file.Segments[result.SegmentIndex:], s.file.Groups, bufferSize)
+	stream := newSegmentsStream(s.ctx, s.pool, s.file.Segments[result.SegmentIndex:], s.file.Groups, bufferSize, s.allowPartial)
 
 	skipBytes := startPos - result.ByteRange.Start
 	if skipBytes > 0 {
@@ -271,7 +537,49 @@ type searchResult struct {
 	ByteRange    ByteRange
 }
 
+// seeks within this many segments of the last search result are searched
+// from there, instead of from the whole file
+const nearbySeekSegmentCount = 8
+
+// segments probed by linearSearch, before giving up
+const linearSearchMaxSegments = 256
+
+var errSearchCorruptFile = errors.New("corrupt file")
+
 func (s *FileStream) interpolationSearch(targetByte int64) (searchResult, error) {
+	result, err := s.search(targetByte)
+	if err != nil && errors.Is(err, errSearchCorruptFile) && config.Newz.SeekLinearFallback {
+		fileLog.Debug("search - falling back to linear search", "error", err, "target_byte", targetByte)
+		result, err = s.linearSearch(targetByte)
+	}
+	if err == nil {
+		s.lastSearch.Store(&result)
+	}
+	return result, err
+}
+
+// nearbySearchBounds returns the search bounds next to the last search result,
+// if the target is close to it.
+func (s *FileStream) nearbySearchBounds(last *searchResult, targetByte int64) (indexRange, byteRange ByteRange, ok bool) {
+	nearbyBytes := nearbySeekSegmentCount * s.avgSegmentSize
+	segmentCount := int64(s.file.SegmentCount())
+
+	if targetByte >= last.ByteRange.End && targetByte-last.ByteRange.End < nearbyBytes {
+		indexRange = ByteRange{Start: int64(last.SegmentIndex + 1), End: segmentCount}
+		byteRange = ByteRange{Start: last.ByteRange.End, End: s.fileSize}
+		return indexRange, byteRange, true
+	}
+
+	if targetByte < last.ByteRange.Start && last.ByteRange.Start-targetByte <= nearbyBytes {
+		indexRange = ByteRange{Start: 0, End: int64(last.SegmentIndex)}
+		byteRange = ByteRange{Start: 0, End: last.ByteRange.Start}
+		return indexRange, byteRange, true
+	}
+
+	return indexRange, byteRange, false
+}
+
+func (s *FileStream) search(targetByte int64) (searchResult, error) {
 	segmentCount := s.file.SegmentCount()
 
 	if segmentCount == 0 {
@@ -285,7 +593,22 @@ func (s *FileStream) interpolationSearch(targetByte int64) (searchResult, error)
 	indexRange := ByteRange{Start: 0, End: int64(segmentCount)}
 	byteRange := ByteRange{Start: 0, End: s.fileSize}
 
-	estimatedIdx := s.estimateSegmentIndex(targetByte)
+	seeded := false
+	if last := s.lastSearch.Load(); last != nil && last.SegmentIndex < segmentCount {
+		if last.ByteRange.Contains(targetByte) {
+			fileLog.Trace("search - found via last result", "segment_idx", last.SegmentIndex, "byte_range", fmt.Sprintf("[%d, %d)", last.ByteRange.Start, last.ByteRange.End))
+			return *last, nil
+		}
+		if ir, br, ok := s.nearbySearchBounds(last, targetByte); ok {
+			indexRange, byteRange, seeded = ir, br, true
+			fileLog.Trace("search - seeded from last result", "last_segment_idx", last.SegmentIndex, "index_range", fmt.Sprintf("[%d, %d)", indexRange.Start, indexRange.End), "byte_range", fmt.Sprintf("[%d, %d)", byteRange.Start, byteRange.End))
+		}
+	}
+
+	estimatedIdx := -1
+	if !seeded {
+		estimatedIdx = s.estimateSegmentIndex(targetByte)
+	}
 	fileLog.Trace("search - started", "target_byte", targetByte, "segment_count", segmentCount, "file_size", s.fileSize, "initial_guess", estimatedIdx)
 	if estimatedIdx >= 0 && estimatedIdx < segmentCount {
 		segmentRange, err := s.getSegmentByteRange(s.ctx, estimatedIdx)
@@ -315,8 +638,8 @@ func (s *FileStream) interpolationSearch(targetByte int64) (searchResult, error)
 
 		// Validate search is possible
 		if !byteRange.Contains(targetByte) || indexRange.Count() <= 0 {
-			return searchResult{}, fmt.Errorf("cannot find byte %d in range [%d, %d)",
-				targetByte, byteRange.Start, byteRange.End)
+			return searchResult{}, fmt.Errorf("%w: cannot find byte %d in range [%d, %d)",
+				errSearchCorruptFile, targetByte, byteRange.Start, byteRange.End)
 		}
 
 		// Estimate segment based on average bytes per segment
@@ -345,8 +668,8 @@ func (s *FileStream) interpolationSearch(targetByte int64) (searchResult, error)
 
 		// Validate segment range is within expected bounds
 		if !byteRange.ContainsRange(segmentRange) {
-			return searchResult{}, fmt.Errorf("corrupt file: segment %d range [%d, %d) outside expected [%d, %d)",
-				guessedIndex, segmentRange.Start, segmentRange.End, byteRange.Start, byteRange.End)
+			return searchResult{}, fmt.Errorf("%w: segment %d range [%d, %d) outside expected [%d, %d)",
+				errSearchCorruptFile, guessedIndex, segmentRange.Start, segmentRange.End, byteRange.Start, byteRange.End)
 		}
 
 		// Check if we found the target
@@ -369,3 +692,51 @@ func (s *FileStream) interpolationSearch(targetByte int64) (searchResult, error)
 		}
 	}
 }
+
+// linearSearch walks consecutive segments, starting from the one estimated
+// using the decoded segment size. It is slower than search, but does not rely
+// on the segments being consistent with the expected byte ranges.
+func (s *FileStream) linearSearch(targetByte int64) (searchResult, error) {
+	segmentCount := s.file.SegmentCount()
+
+	idx := segmentCount - 1
+	if s.avgSegmentSize > 0 {
+		idx = min(int(targetByte/s.avgSegmentSize), segmentCount-1)
+	}
+
+	direction := 0
+	for range min(linearSearchMaxSegments, segmentCount) {
+		select {
+		case <-s.ctx.Done():
+			return searchResult{}, s.ctx.Err()
+		default:
+		}
+
+		segmentRange, err := s.getSegmentByteRange(s.ctx, idx)
+		if err != nil {
+			return searchResult{}, fmt.Errorf("failed to get byte range for segment %d: %w", idx, err)
+		}
+
+		fileLog.Trace("linear search - segment range", "segment_idx", idx, "byte_range", fmt.Sprintf("[%d, %d)", segmentRange.Start, segmentRange.End))
+
+		if segmentRange.Contains(targetByte) {
+			return searchResult{SegmentIndex: idx, ByteRange: segmentRange}, nil
+		}
+
+		step := 1
+		if targetByte < segmentRange.Start {
+			step = -1
+		}
+		if direction != 0 && step != direction {
+			return searchResult{}, fmt.Errorf("%w: byte %d is not in any segment around segment %d", errSearchCorruptFile, targetByte, idx)
+		}
+		direction = step
+
+		idx += step
+		if idx < 0 || idx >= segmentCount {
+			break
+		}
+	}
+
+	return searchResult{}, fmt.Errorf("%w: cannot find byte %d using linear search", errSearchCorruptFile, targetByte)
+}
@@ -1,9 +1,20 @@
 package usenet_pool
 
 import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/logger"
+	"github.com/MunifTanjim/stremthru/internal/nntp"
+	"github.com/MunifTanjim/stremthru/internal/nntp/nntptest"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestByteRange(t *testing.T) {
@@ -57,3 +68,485 @@ func TestByteRange(t *testing.T) {
 		assert.Equal(t, int64(150), r.End)
 	})
 }
+
+type countingSegmentCache struct {
+	data map[string]SegmentData
+	gets int
+}
+
+func (c *countingSegmentCache) Get(messageId string) (SegmentData, bool) {
+	c.gets++
+	data, ok := c.data[messageId]
+	return data, ok
+}
+
+func (c *countingSegmentCache) Set(messageId string, data SegmentData) {}
+func (c *countingSegmentCache) Has(messageId string) bool              { return false }
+
+func TestFileStreamInterpolationSearch(t *testing.T) {
+	const segmentCount = 100
+	const segmentSize = int64(100)
+
+	// encoded sizes are skewed, so that the size based estimate is off
+	cache := &countingSegmentCache{data: map[string]SegmentData{}}
+	segments := make([]nzb.Segment, segmentCount)
+	for i := range segments {
+		messageId := fmt.Sprintf("%d@test.com", i)
+		encodedBytes := int64(50)
+		if i >= segmentCount/2 {
+			encodedBytes = 150
+		}
+		segments[i] = nzb.Segment{MessageId: messageId, Bytes: encodedBytes, Number: i + 1}
+		cache.data[messageId] = SegmentData{
+			ByteRange: NewByteRangeFromSize(int64(i)*segmentSize, segmentSize),
+			FileSize:  segmentCount * segmentSize,
+			Size:      segmentSize,
+		}
+	}
+
+	newStream := func() *FileStream {
+		return &FileStream{
+			file:             &nzb.File{Segments: segments},
+			fileSize:         segmentCount * segmentSize,
+			avgSegmentSize:   segmentSize,
+			segmentSizeRatio: 1,
+			pool:             &Pool{Log: logger.Scoped("test/usenet/pool"), segmentCache: cache},
+			ctx:              t.Context(),
+		}
+	}
+
+	search := func(s *FileStream, targetByte int64) (searchResult, int) {
+		cache.gets = 0
+		result, err := s.interpolationSearch(targetByte)
+		require.NoError(t, err)
+		return result, cache.gets
+	}
+
+	t.Run("SameSegment", func(t *testing.T) {
+		s := newStream()
+		result, _ := search(s, 2050)
+		assert.Equal(t, 20, result.SegmentIndex)
+
+		result, probes := search(s, 2099)
+		assert.Equal(t, 20, result.SegmentIndex)
+		assert.Equal(t, 0, probes)
+	})
+
+	t.Run("NearbyForward", func(t *testing.T) {
+		s := newStream()
+		search(s, 2050)
+
+		result, probes := search(s, 2250)
+		assert.Equal(t, 22, result.SegmentIndex)
+		assert.Equal(t, 1, probes)
+
+		_, freshProbes := search(newStream(), 2250)
+		assert.Greater(t, freshProbes, probes)
+	})
+
+	t.Run("NearbyBackward", func(t *testing.T) {
+		s := newStream()
+		search(s, 2050)
+
+		result, probes := search(s, 1850)
+		assert.Equal(t, 18, result.SegmentIndex)
+		assert.Equal(t, 1, probes)
+	})
+
+	t.Run("FarAway", func(t *testing.T) {
+		s := newStream()
+		search(s, 2050)
+
+		result, _ := search(s, 7050)
+		assert.Equal(t, 70, result.SegmentIndex)
+	})
+}
+
+func TestFileStreamLinearSearchFallback(t *testing.T) {
+	const segmentSize = int64(100)
+	const fileSize = 100 * segmentSize
+
+	// segment 50 is posted twice, so the segment ranges are not consistent
+	// with their index
+	cache := &countingSegmentCache{data: map[string]SegmentData{}}
+	segments := make([]nzb.Segment, 101)
+	for i := range segments {
+		messageId := fmt.Sprintf("%d@test.com", i)
+		start := int64(i) * segmentSize
+		if i > 50 {
+			start -= segmentSize
+		}
+		encodedBytes := segmentSize
+		if i == 0 {
+			// skews the initial guess onto the duplicated segment
+			encodedBytes = 2 * segmentSize
+		}
+		segments[i] = nzb.Segment{MessageId: messageId, Bytes: encodedBytes, Number: i + 1}
+		cache.data[messageId] = SegmentData{
+			ByteRange: NewByteRangeFromSize(start, segmentSize),
+			FileSize:  fileSize,
+			Size:      segmentSize,
+		}
+	}
+
+	newStream := func() *FileStream {
+		return &FileStream{
+			file:             &nzb.File{Segments: segments},
+			fileSize:         fileSize,
+			avgSegmentSize:   fileSize / int64(len(segments)),
+			segmentSizeRatio: 1,
+			pool:             &Pool{Log: logger.Scoped("test/usenet/pool"), segmentCache: cache},
+			ctx:              t.Context(),
+		}
+	}
+
+	prevFallback := config.Newz.SeekLinearFallback
+	t.Cleanup(func() {
+		config.Newz.SeekLinearFallback = prevFallback
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		config.Newz.SeekLinearFallback = false
+		_, err := newStream().interpolationSearch(5150)
+		assert.ErrorIs(t, err, errSearchCorruptFile)
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		config.Newz.SeekLinearFallback = true
+		result, err := newStream().interpolationSearch(5150)
+		require.NoError(t, err)
+		assert.Equal(t, 52, result.SegmentIndex)
+		assert.Equal(t, ByteRange{Start: 5100, End: 5200}, result.ByteRange)
+	})
+}
+
+func TestFileStreamAllowPartial(t *testing.T) {
+	const segmentCount = 5
+	const availableCount = 3
+	const segmentSize = 50
+
+	data := makeTestBytes(segmentCount * segmentSize)
+
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.SetResponse("GROUP alt.test", "211 5 1 5 alt.test")
+
+	segments := make([]nzb.Segment, segmentCount)
+	for i := range segmentCount {
+		msgId := fmt.Sprintf("seg%d@test.com", i+1)
+		encoded := encodeYenc(data[i*segmentSize:(i+1)*segmentSize], "test.mkv", i+1, segmentCount, int64(len(data)), int64(i*segmentSize)+1)
+		if i < availableCount {
+			server.SetResponse("BODY <"+msgId+">", "222 0 <"+msgId+">", strings.Split(strings.TrimSpace(string(encoded)), "\r\n"))
+		} else {
+			server.SetResponse("BODY <"+msgId+">", "430 No Such Article")
+		}
+		segments[i] = nzb.Segment{MessageId: msgId, Bytes: int64(len(encoded)), Number: i + 1}
+	}
+	server.Start(t)
+
+	usenetPool := &Pool{
+		Log:          logger.Scoped("test/usenet/pool"),
+		providers:    []*providerPool{{Pool: nntptest.NewPool(t, server, &nntp.PoolConfig{})}},
+		segmentCache: NewSegmentCache(10*1024*1024, ""),
+	}
+
+	file := &nzb.File{Segments: segments, Groups: []string{"alt.test"}}
+
+	t.Run("Disabled", func(t *testing.T) {
+		stream, err := NewFileStream(t.Context(), usenetPool, file, 0)
+		require.NoError(t, err)
+		defer stream.Close()
+
+		assert.Equal(t, int64(len(data)), stream.Size())
+		_, err = io.ReadAll(stream)
+		assert.ErrorIs(t, err, ErrArticleNotFound)
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		stream, err := NewFileStream(t.Context(), usenetPool, file, 0)
+		require.NoError(t, err)
+		defer stream.Close()
+
+		require.NoError(t, stream.enablePartial())
+		assert.True(t, stream.Truncated())
+		assert.Equal(t, int64(availableCount*segmentSize), stream.Size())
+
+		got, err := io.ReadAll(stream)
+		require.NoError(t, err)
+		assert.Equal(t, data[:availableCount*segmentSize], got)
+	})
+}
+
+func TestFileStreamReadAt(t *testing.T) {
+	const segmentCount = 5
+	const segmentSize = 50
+
+	data := makeTestBytes(segmentCount * segmentSize)
+
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.SetResponse("GROUP alt.test", "211 5 1 5 alt.test")
+
+	segments := make([]nzb.Segment, segmentCount)
+	for i := range segmentCount {
+		msgId := fmt.Sprintf("seg%d@test.com", i+1)
+		encoded := encodeYenc(data[i*segmentSize:(i+1)*segmentSize], "test.mkv", i+1, segmentCount, int64(len(data)), int64(i*segmentSize)+1)
+		server.SetResponse("BODY <"+msgId+">", "222 0 <"+msgId+">", strings.Split(strings.TrimSpace(string(encoded)), "\r\n"))
+		segments[i] = nzb.Segment{MessageId: msgId, Bytes: int64(len(encoded)), Number: i + 1}
+	}
+	server.Start(t)
+
+	usenetPool := &Pool{
+		Log:          logger.Scoped("test/usenet/pool"),
+		providers:    []*providerPool{{Pool: nntptest.NewPool(t, server, &nntp.PoolConfig{})}},
+		segmentCache: NewSegmentCache(10*1024*1024, ""),
+	}
+
+	file := &nzb.File{Segments: segments, Groups: []string{"alt.test"}}
+
+	stream, err := NewFileStream(t.Context(), usenetPool, file, 0)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	t.Run("SmallAcrossSegments", func(t *testing.T) {
+		buf := make([]byte, 30)
+		n, err := stream.ReadAt(buf, 190)
+		require.NoError(t, err)
+		assert.Equal(t, 30, n)
+		assert.Equal(t, data[190:220], buf)
+	})
+
+	t.Run("SmallAtEnd", func(t *testing.T) {
+		buf := make([]byte, 20)
+		n, err := stream.ReadAt(buf, 240)
+		assert.ErrorIs(t, err, io.EOF)
+		assert.Equal(t, 10, n)
+		assert.Equal(t, data[240:], buf[:n])
+	})
+
+	t.Run("ReusesReader", func(t *testing.T) {
+		buf := make([]byte, 60)
+		n, err := stream.ReadAt(buf, 10)
+		require.NoError(t, err)
+		assert.Equal(t, data[10:70], buf[:n])
+		require.Len(t, stream.readers, 1)
+		reader := stream.readers[0]
+		assert.Equal(t, int64(70), reader.pos)
+
+		n, err = stream.ReadAt(buf, 70)
+		require.NoError(t, err)
+		assert.Equal(t, data[70:130], buf[:n])
+		require.Len(t, stream.readers, 1)
+		assert.Same(t, reader, stream.readers[0])
+		assert.Equal(t, int64(130), reader.pos)
+	})
+}
+
+func TestFileStreamAdaptiveBuffer(t *testing.T) {
+	const segmentCount = 10
+	const segmentSize = 50
+
+	originalSize, originalInitSize := config.Newz.StreamBufferSize, config.Newz.StreamBufferInitSize
+	config.Newz.StreamBufferSize = 400
+	config.Newz.StreamBufferInitSize = 100
+	t.Cleanup(func() {
+		config.Newz.StreamBufferSize, config.Newz.StreamBufferInitSize = originalSize, originalInitSize
+	})
+
+	data := makeTestBytes(segmentCount * segmentSize)
+
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.SetResponse("GROUP alt.test", "211 10 1 10 alt.test")
+
+	segments := make([]nzb.Segment, segmentCount)
+	for i := range segmentCount {
+		msgId := fmt.Sprintf("seg%d@test.com", i+1)
+		encoded := encodeYenc(data[i*segmentSize:(i+1)*segmentSize], "test.mkv", i+1, segmentCount, int64(len(data)), int64(i*segmentSize)+1)
+		server.SetResponse("BODY <"+msgId+">", "222 0 <"+msgId+">", strings.Split(strings.TrimSpace(string(encoded)), "\r\n"))
+		segments[i] = nzb.Segment{MessageId: msgId, Bytes: int64(len(encoded)), Number: i + 1}
+	}
+	server.Start(t)
+
+	usenetPool := &Pool{
+		Log:          logger.Scoped("test/usenet/pool"),
+		providers:    []*providerPool{{Pool: nntptest.NewPool(t, server, &nntp.PoolConfig{})}},
+		segmentCache: NewSegmentCache(10*1024*1024, ""),
+	}
+
+	file := &nzb.File{Segments: segments, Groups: []string{"alt.test"}}
+
+	t.Run("Adaptive", func(t *testing.T) {
+		stream, err := NewFileStream(t.Context(), usenetPool, file, 0)
+		require.NoError(t, err)
+		defer stream.Close()
+
+		assert.Equal(t, int64(100), stream.currBufferSize)
+
+		var got []byte
+		buf := make([]byte, segmentSize)
+		for {
+			n, err := stream.Read(buf)
+			got = append(got, buf[:n]...)
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+		}
+		assert.Equal(t, data, got)
+		assert.Equal(t, int64(400), stream.currBufferSize)
+
+		_, err = stream.Seek(0, io.SeekStart)
+		require.NoError(t, err)
+		assert.Equal(t, int64(100), stream.currBufferSize)
+	})
+
+	t.Run("Explicit", func(t *testing.T) {
+		stream, err := NewFileStream(t.Context(), usenetPool, file, 200)
+		require.NoError(t, err)
+		defer stream.Close()
+
+		got, err := io.ReadAll(stream)
+		require.NoError(t, err)
+		assert.Equal(t, data, got)
+		assert.Equal(t, int64(200), stream.currBufferSize)
+	})
+}
+
+// flakySegmentCache misses the given message ids once, so that they are
+// fetched from the provider.
+type flakySegmentCache struct {
+	mu       sync.Mutex
+	data     map[string]SegmentData
+	missOnce map[string]bool
+}
+
+func (c *flakySegmentCache) Get(messageId string) (SegmentData, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.missOnce[messageId] {
+		delete(c.missOnce, messageId)
+		return SegmentData{}, false
+	}
+	data, ok := c.data[messageId]
+	return data, ok
+}
+
+func (c *flakySegmentCache) Set(messageId string, data SegmentData) {}
+func (c *flakySegmentCache) Has(messageId string) bool              { return false }
+
+func TestFileStreamRetry(t *testing.T) {
+	const segmentCount = 5
+	const segmentSize = 50
+
+	originalTimeout := config.Newz.SegmentFetchTimeout
+	originalRetryCount := config.Newz.StreamRetryCount
+	config.Newz.SegmentFetchTimeout = 50 * time.Millisecond
+	t.Cleanup(func() {
+		config.Newz.SegmentFetchTimeout = originalTimeout
+		config.Newz.StreamRetryCount = originalRetryCount
+	})
+
+	data := makeTestBytes(segmentCount * segmentSize)
+
+	// no response for BODY, so the provider fetch times out
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.SetResponse("GROUP alt.test", "211 5 1 5 alt.test")
+	server.Start(t)
+
+	segments := make([]nzb.Segment, segmentCount)
+	for i := range segments {
+		segments[i] = nzb.Segment{MessageId: fmt.Sprintf("seg%d@test.com", i+1), Bytes: segmentSize, Number: i + 1}
+	}
+
+	newCache := func() *flakySegmentCache {
+		cache := &flakySegmentCache{
+			data:     map[string]SegmentData{},
+			missOnce: map[string]bool{"seg3@test.com": true},
+		}
+		for i, segment := range segments {
+			cache.data[segment.MessageId] = SegmentData{
+				Body:      data[i*segmentSize : (i+1)*segmentSize],
+				ByteRange: NewByteRangeFromSize(int64(i*segmentSize), segmentSize),
+				FileSize:  int64(len(data)),
+				Size:      segmentSize,
+			}
+		}
+		return cache
+	}
+
+	newStream := func(t *testing.T) *FileStream {
+		usenetPool := &Pool{
+			Log:          logger.Scoped("test/usenet/pool"),
+			providers:    []*providerPool{{Pool: nntptest.NewPool(t, server, &nntp.PoolConfig{})}},
+			segmentCache: newCache(),
+		}
+		file := &nzb.File{Segments: segments, Groups: []string{"alt.test"}}
+		stream, err := NewFileStream(t.Context(), usenetPool, file, segmentSize)
+		require.NoError(t, err)
+		t.Cleanup(func() { stream.Close() })
+		return stream
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		config.Newz.StreamRetryCount = 0
+		_, err := io.ReadAll(newStream(t))
+		assert.ErrorIs(t, err, ErrSegmentFetchTimeout)
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		config.Newz.StreamRetryCount = 1
+		got, err := io.ReadAll(newStream(t))
+		require.NoError(t, err)
+		assert.Equal(t, data, got)
+	})
+}
+
+func TestFileStreamReadAtSharedFetch(t *testing.T) {
+	const segmentCount = 2
+	const segmentSize = 50
+
+	data := makeTestBytes(segmentCount * segmentSize)
+
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.SetResponse("GROUP alt.test", "211 2 1 2 alt.test")
+
+	segments := make([]nzb.Segment, segmentCount)
+	for i := range segmentCount {
+		msgId := fmt.Sprintf("seg%d@test.com", i+1)
+		encoded := encodeYenc(data[i*segmentSize:(i+1)*segmentSize], "test.mkv", i+1, segmentCount, int64(len(data)), int64(i*segmentSize)+1)
+		server.SetResponse("BODY <"+msgId+">", "222 0 <"+msgId+">", strings.Split(strings.TrimSpace(string(encoded)), "\r\n"))
+		segments[i] = nzb.Segment{MessageId: msgId, Bytes: int64(len(encoded)), Number: i + 1}
+	}
+	server.Start(t)
+
+	usenetPool := &Pool{
+		Log:          logger.Scoped("test/usenet/pool"),
+		providers:    []*providerPool{{Pool: nntptest.NewPool(t, server, &nntp.PoolConfig{})}},
+		segmentCache: NewSegmentCache(10*1024*1024, ""),
+	}
+
+	file := &nzb.File{Segments: segments, Groups: []string{"alt.test"}}
+
+	stream, err := NewFileStream(t.Context(), usenetPool, file, 0)
+	require.NoError(t, err)
+	defer stream.Close()
+	server.ClearRequestCommands()
+
+	const callers = 5
+	var wg sync.WaitGroup
+	for range callers {
+		wg.Go(func() {
+			buf := make([]byte, 20)
+			n, err := stream.ReadAt(buf, 60)
+			assert.NoError(t, err)
+			assert.Equal(t, data[60:80], buf[:n])
+		})
+	}
+	wg.Wait()
+
+	bodies := 0
+	for _, cmd := range server.GetRequestCommands() {
+		if cmd == "BODY <seg2@test.com>" {
+			bodies++
+		}
+	}
+	assert.Equal(t, 1, bodies)
+}
@@ -3,10 +3,12 @@ package usenet_pool
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"path/filepath"
 	"regexp"
 	"strings"
 
+	"github.com/MunifTanjim/stremthru/internal/config"
 	"github.com/MunifTanjim/stremthru/internal/logger"
 )
 
@@ -18,6 +20,7 @@ const (
 	FileTypePlain FileType = iota + 1
 	FileTypeRAR
 	FileType7z
+	FileTypeZIP
 )
 
 func (ft FileType) String() string {
@@ -28,6 +31,8 @@ func (ft FileType) String() string {
 		return "rar"
 	case FileType7z:
 		return "7z"
+	case FileTypeZIP:
+		return "zip"
 	default:
 		return "unknown"
 	}
@@ -39,8 +44,8 @@ var (
 	magicBytes7Zip = []byte{0x37, 0x7A, 0xBC, 0xAF, 0x27, 0x1C}
 )
 
-// RAR patterns: .rar, .r00, .r01, .part01.rar
-var rarRegex = regexp.MustCompile(`(?i)\.r(ar|\d+)$`)
+// RAR patterns: .rar, .r00, .r01, .part01.rar, .cbr
+var rarRegex = regexp.MustCompile(`(?i)\.(r(ar|\d+)|cbr)$`)
 
 // 7z patterns: .7z, .7z.001, .7z.002
 var sevenZipRegex = regexp.MustCompile(`(?i)\.7z(\.\d+)?$`)
@@ -52,16 +57,23 @@ func DetectArchiveFileTypeByExtension(filename string) FileType {
 	if sevenZipRegex.MatchString(filename) {
 		return FileType7z
 	}
+	if zipRegex.MatchString(filename) {
+		return FileTypeZIP
+	}
 	return FileTypePlain
 }
 
+func hasMagicBytesAt(fileBytes []byte, offset int, magicBytes []byte) bool {
+	return len(fileBytes) >= offset+len(magicBytes) && bytes.Equal(fileBytes[offset:offset+len(magicBytes)], magicBytes)
+}
+
 func DetectFileType(fileBytes []byte, filename string) FileType {
-	if bytes.HasPrefix(fileBytes, magicBytesRAR5) || bytes.HasPrefix(fileBytes, magicBytesRAR4) {
+	if hasMagicBytesAt(fileBytes, 0, magicBytesRAR5) || hasMagicBytesAt(fileBytes, 0, magicBytesRAR4) {
 		ftLog.Trace("file type - detected", "filename", filename, "type", FileTypeRAR, "method", "magic_bytes")
 		return FileTypeRAR
 	}
 
-	if bytes.HasPrefix(fileBytes, magicBytes7Zip) {
+	if hasMagicBytesAt(fileBytes, 0, magicBytes7Zip) {
 		ftLog.Trace("file type - detected", "filename", filename, "type", FileType7z, "method", "magic_bytes")
 		return FileType7z
 	}
@@ -71,27 +83,165 @@ func DetectFileType(fileBytes []byte, filename string) FileType {
 	return ft
 }
 
-var isVideoFile = func() func(filename string) bool {
-	videoExtensions := map[string]struct{}{
-		".mkv":  {},
-		".mp4":  {},
-		".avi":  {},
-		".webm": {},
-		".mov":  {},
-		".wmv":  {},
-		".flv":  {},
-		".ts":   {},
-		".m2ts": {},
-		".mpg":  {},
-		".mpeg": {},
-		".m4v":  {},
+// defaultVideoExtensions are always recognized as video, unless denied with
+// STREMTHRU_NEWZ_VIDEO_EXTENSION_DENY. STREMTHRU_NEWZ_VIDEO_EXTENSION_ALLOW
+// adds to these.
+var defaultVideoExtensions = []string{
+	".avi",
+	".flv",
+	".m2ts",
+	".m4v",
+	".mkv",
+	".mov",
+	".mp4",
+	".mpeg",
+	".mpg",
+	".ts",
+	".webm",
+	".wmv",
+}
+
+func newVideoFileMatcher(allow, deny []string) func(filename string) bool {
+	videoExtensions := make(map[string]struct{}, len(defaultVideoExtensions)+len(allow))
+	for _, ext := range defaultVideoExtensions {
+		videoExtensions[ext] = struct{}{}
+	}
+	for _, ext := range allow {
+		videoExtensions[strings.ToLower(ext)] = struct{}{}
+	}
+	for _, ext := range deny {
+		delete(videoExtensions, strings.ToLower(ext))
 	}
 
 	return func(filename string) bool {
 		_, found := videoExtensions[strings.ToLower(filepath.Ext(filename))]
 		return found
 	}
-}()
+}
+
+var isVideoFile = newVideoFileMatcher(config.Newz.VideoExtensionAllow, config.Newz.VideoExtensionDeny)
+
+// IsVideoFilename checks if the filename has a video extension, as allowed by
+// STREMTHRU_NEWZ_VIDEO_EXTENSION_ALLOW and STREMTHRU_NEWZ_VIDEO_EXTENSION_DENY.
+func IsVideoFilename(filename string) bool {
+	return isVideoFile(filename)
+}
+
+var sampleFileRegex = regexp.MustCompile(`(?i)(?:^|[^a-z0-9])sample(?:[^a-z0-9]|$)`)
+
+func isSampleFile(filename string) bool {
+	return sampleFileRegex.MatchString(filename)
+}
+
+// isMainVideoFile checks if the file is a video, that is not a sample when
+// STREMTHRU_NEWZ_VIDEO_EXCLUDE_SAMPLE is enabled.
+func isMainVideoFile(filename string) bool {
+	return isVideoFile(filename) && !(config.Newz.VideoExcludeSample && isSampleFile(filename))
+}
+
+// isDiscStructure reports whether the files are laid out as a DVD (VIDEO_TS)
+// or BluRay (BDMV) disc, where no single file is the playable unit.
+func isDiscStructure(files []ArchiveFile) bool {
+	for _, f := range files {
+		name := strings.ReplaceAll(f.Name(), "\\", "/")
+		for dir := range strings.SplitSeq(name, "/") {
+			switch strings.ToUpper(dir) {
+			case "VIDEO_TS", "BDMV":
+				return true
+			}
+		}
+	}
+	return false
+}
+
+const contentTypeUnknown = "application/octet-stream"
+
+// bytes needed to sniff the content type
+const contentTypeSniffLen = 512
+
+type contentTypeSignature struct {
+	offset      int
+	magicBytes  []byte
+	contentType string
+}
+
+var contentTypeSignatures = []contentTypeSignature{
+	{0, []byte{0x1A, 0x45, 0xDF, 0xA3}, "video/x-matroska"},
+	{4, []byte("ftyp"), "video/mp4"},
+	{8, []byte("AVI "), "video/x-msvideo"},
+	{0, []byte("FLV"), "video/x-flv"},
+	{0, []byte{0x30, 0x26, 0xB2, 0x75, 0x8E, 0x66, 0xCF, 0x11}, "video/x-ms-wmv"},
+	{0, []byte{0x00, 0x00, 0x01, 0xBA}, "video/mpeg"},
+}
+
+// SniffContentType detects the content type of a video container from its
+// first bytes. It returns an empty string if none matches.
+func SniffContentType(fileBytes []byte) string {
+	for _, sig := range contentTypeSignatures {
+		if hasMagicBytesAt(fileBytes, sig.offset, sig.magicBytes) {
+			return sig.contentType
+		}
+	}
+	// MPEG-TS packets are 188 bytes, each starting with a sync byte
+	if len(fileBytes) > 188 && fileBytes[0] == 0x47 && fileBytes[188] == 0x47 {
+		return "video/mp2t"
+	}
+	return ""
+}
+
+// detectContentType uses the extension, falling back to sniffing the first
+// bytes of r when the extension is unknown. r is left at the start.
+func detectContentType(r io.ReadSeeker, filename string) string {
+	contentType := GetContentType(filename)
+	if contentType != contentTypeUnknown {
+		return contentType
+	}
+
+	header := make([]byte, contentTypeSniffLen)
+	var n int
+	if ra, ok := r.(io.ReaderAt); ok {
+		n, _ = ra.ReadAt(header, 0)
+	} else {
+		n, _ = io.ReadFull(r, header)
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			ftLog.Warn("content type - failed to rewind after sniffing", "error", err, "filename", filename)
+		}
+	}
+
+	if sniffed := SniffContentType(header[:n]); sniffed != "" {
+		ftLog.Trace("content type - sniffed", "filename", filename, "content_type", sniffed)
+		return sniffed
+	}
+	return contentType
+}
+
+// getVideoContentType is the content type for a video whose type is unknown
+// from both its extension and its first bytes. Empty means none.
+func (c *StreamConfig) getVideoContentType() string {
+	if c.VideoContentType != "" {
+		return c.VideoContentType
+	}
+	return config.Newz.VideoContentType
+}
+
+// contentTypeOverrides are the content types a client can ask a stream to be
+// served as, instead of the detected one.
+var contentTypeOverrides = map[string]struct{}{
+	"application/octet-stream": {},
+	"video/mp2t":               {},
+	"video/mp4":                {},
+	"video/mpeg":               {},
+	"video/quicktime":          {},
+	"video/webm":               {},
+	"video/x-m4v":              {},
+	"video/x-matroska":         {},
+	"video/x-msvideo":          {},
+}
+
+func IsContentTypeOverrideAllowed(contentType string) bool {
+	_, ok := contentTypeOverrides[contentType]
+	return ok
+}
 
 func GetContentType(filename string) string {
 	lower := strings.ToLower(filename)
@@ -116,14 +266,34 @@ func GetContentType(filename string) string {
 		return "video/mpeg"
 	case strings.HasSuffix(lower, ".m4v"):
 		return "video/x-m4v"
+	case strings.HasSuffix(lower, ".mp3"):
+		return "audio/mpeg"
+	case strings.HasSuffix(lower, ".flac"):
+		return "audio/flac"
+	case strings.HasSuffix(lower, ".m4a"):
+		return "audio/mp4"
+	case strings.HasSuffix(lower, ".iso"):
+		return "application/x-iso9660-image"
+	case strings.HasSuffix(lower, ".jpg"), strings.HasSuffix(lower, ".jpeg"):
+		return "image/jpeg"
+	case strings.HasSuffix(lower, ".png"):
+		return "image/png"
+	case strings.HasSuffix(lower, ".gif"):
+		return "image/gif"
+	case strings.HasSuffix(lower, ".webp"):
+		return "image/webp"
+	case strings.HasSuffix(lower, ".avif"):
+		return "image/avif"
+	case strings.HasSuffix(lower, ".bmp"):
+		return "image/bmp"
 	default:
-		return "application/octet-stream"
+		return contentTypeUnknown
 	}
 }
 
 func IsArchiveFile(filename string) bool {
 	switch ft := DetectArchiveFileTypeByExtension(filename); ft {
-	case FileType7z, FileTypeRAR:
+	case FileType7z, FileTypeRAR, FileTypeZIP:
 		return true
 	default:
 		return false
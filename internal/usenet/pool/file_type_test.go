@@ -1,6 +1,8 @@
 package usenet_pool
 
 import (
+	"bytes"
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -46,6 +48,9 @@ func TestFileTypeDetection(t *testing.T) {
 			{"archive.7z", FileType7z},
 			{"archive.7z.001", FileType7z},
 			{"archive.7z.002", FileType7z},
+			{"comic.cbr", FileTypeRAR},
+			{"archive.zip", FileTypeZIP},
+			{"comic.CBZ", FileTypeZIP},
 			{"unknown.txt", FileTypePlain},
 		}
 
@@ -89,6 +94,7 @@ func TestFileTypeDetection(t *testing.T) {
 			{"archive.part01.rar", 1},
 			{"archive.part02.rar", 2},
 			{"archive.part99.rar", 99},
+			{"comic.cbr", 0},
 			{"notrar.txt", -1},
 			{"archive.zip", -1},
 		}
@@ -107,9 +113,10 @@ func TestFileTypeDetection(t *testing.T) {
 			expected int
 		}{
 			{"archive.7z", 0},
-			{"archive.7z.001", 1},
-			{"archive.7z.002", 2},
-			{"archive.7z.099", 99},
+			{"archive.7z.001", 0},
+			{"archive.7z.002", 1},
+			{"archive.7z.099", 98},
+			{"Archive.7Z.005", 4},
 			{"not7z.txt", -1},
 			{"archive.zip", -1},
 		}
@@ -139,6 +146,14 @@ func TestFileTypeDetection(t *testing.T) {
 			{"movie.mpg", "video/mpeg"},
 			{"movie.mpeg", "video/mpeg"},
 			{"movie.m4v", "video/x-m4v"},
+			{"track.mp3", "audio/mpeg"},
+			{"track.flac", "audio/flac"},
+			{"track.m4a", "audio/mp4"},
+			{"disc.iso", "application/x-iso9660-image"},
+			{"page01.jpg", "image/jpeg"},
+			{"page01.JPEG", "image/jpeg"},
+			{"page01.png", "image/png"},
+			{"page01.webp", "image/webp"},
 			{"unknown.xyz", "application/octet-stream"},
 		}
 
@@ -150,3 +165,81 @@ func TestFileTypeDetection(t *testing.T) {
 		}
 	})
 }
+
+func TestIsDiscStructure(t *testing.T) {
+	assert.True(t, isDiscStructure([]ArchiveFile{
+		&testArchiveFile{name: "Movie/VIDEO_TS/VIDEO_TS.IFO"},
+		&testArchiveFile{name: "Movie/VIDEO_TS/VTS_01_1.VOB"},
+	}))
+	assert.True(t, isDiscStructure([]ArchiveFile{
+		&testArchiveFile{name: `BDMV\STREAM\00001.m2ts`},
+	}))
+	assert.False(t, isDiscStructure([]ArchiveFile{
+		&testArchiveFile{name: "Movie/movie.mkv"},
+		&testArchiveFile{name: "bdmv.nfo"},
+	}))
+}
+
+func TestSniffContentType(t *testing.T) {
+	mp4 := append([]byte{0x00, 0x00, 0x00, 0x20}, []byte("ftypisom")...)
+	avi := append([]byte("RIFF\x00\x00\x00\x00"), []byte("AVI LIST")...)
+	ts := make([]byte, 200)
+	ts[0], ts[188] = 0x47, 0x47
+
+	assert.Equal(t, "video/x-matroska", SniffContentType([]byte{0x1A, 0x45, 0xDF, 0xA3, 0x01}))
+	assert.Equal(t, "video/mp4", SniffContentType(mp4))
+	assert.Equal(t, "video/x-msvideo", SniffContentType(avi))
+	assert.Equal(t, "video/mp2t", SniffContentType(ts))
+	assert.Equal(t, "", SniffContentType([]byte("plain text")))
+	assert.Equal(t, "", SniffContentType(nil))
+}
+
+func TestDetectContentType(t *testing.T) {
+	mkv := append([]byte{0x1A, 0x45, 0xDF, 0xA3}, make([]byte, 1000)...)
+
+	t.Run("KnownExtension", func(t *testing.T) {
+		r := bytes.NewReader(mkv)
+		assert.Equal(t, "video/mp4", detectContentType(r, "movie.mp4"))
+	})
+
+	t.Run("UnknownExtension", func(t *testing.T) {
+		r := bytes.NewReader(mkv)
+		assert.Equal(t, "video/x-matroska", detectContentType(r, "a1b2c3d4"))
+		got, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, mkv, got)
+	})
+
+	t.Run("UnknownContent", func(t *testing.T) {
+		r := bytes.NewReader([]byte("plain text"))
+		assert.Equal(t, contentTypeUnknown, detectContentType(r, "a1b2c3d4"))
+	})
+}
+
+func TestNewVideoFileMatcher(t *testing.T) {
+	isVideo := newVideoFileMatcher([]string{".ogm", ".DIVX"}, []string{".ts"})
+
+	assert.True(t, isVideo("movie.mkv"))
+	assert.True(t, isVideo("movie.MP4"))
+	assert.True(t, isVideo("movie.ogm"))
+	assert.True(t, isVideo("movie.divx"))
+	assert.False(t, isVideo("movie.ts"))
+	assert.False(t, isVideo("movie.nfo"))
+}
+
+func TestIsSampleFile(t *testing.T) {
+	assert.True(t, isSampleFile("movie.sample.mkv"))
+	assert.True(t, isSampleFile("movie-SAMPLE.mkv"))
+	assert.True(t, isSampleFile("Sample/movie.mkv"))
+	assert.True(t, isSampleFile("sample.mkv"))
+	assert.False(t, isSampleFile("movie.mkv"))
+	assert.False(t, isSampleFile("samples.of.life.mkv"))
+}
+
+func TestIsContentTypeOverrideAllowed(t *testing.T) {
+	assert.True(t, IsContentTypeOverrideAllowed("video/mp4"))
+	assert.True(t, IsContentTypeOverrideAllowed("application/octet-stream"))
+	assert.False(t, IsContentTypeOverrideAllowed("text/html"))
+	assert.False(t, IsContentTypeOverrideAllowed("video/mp4; charset=utf-8"))
+	assert.False(t, IsContentTypeOverrideAllowed(""))
+}
@@ -0,0 +1,80 @@
+package usenet_pool
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrStreamNotSeekable = errors.New("usenet: stream is not seekable")
+
+// decompressibleFile is implemented by the archive files that, when not
+// streamable as is, can still be read forward-only through the decompressor.
+type decompressibleFile interface {
+	canDecompress() bool
+	openDecompressed() (io.ReadCloser, error)
+}
+
+func canDecompress(f ArchiveFile) bool {
+	df, ok := f.(decompressibleFile)
+	return ok && df.canDecompress()
+}
+
+var _ io.ReadSeekCloser = (*forwardOnlyReader)(nil)
+
+// forwardOnlyReader reads a decompressed entry from the start. Seeking is
+// only allowed to the current position, e.g. for figuring out the offset.
+type forwardOnlyReader struct {
+	io.ReadCloser
+	size int64
+	pos  int64
+}
+
+func (r *forwardOnlyReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.pos += int64(n)
+	return n, err
+}
+
+func (r *forwardOnlyReader) Seek(offset int64, whence int) (int64, error) {
+	var target int64
+	switch whence {
+	case io.SeekStart:
+		target = offset
+	case io.SeekCurrent:
+		target = r.pos + offset
+	case io.SeekEnd:
+		target = r.size + offset
+	default:
+		return r.pos, errors.New("forward only reader: invalid whence")
+	}
+	if target != r.pos {
+		return r.pos, ErrStreamNotSeekable
+	}
+	return r.pos, nil
+}
+
+// openForwardOnly streams the compressed file through its decompressor. The
+// content type is only guessed from the name, sniffing would need a rewind.
+func openForwardOnly(f ArchiveFile) (*Stream, error) {
+	df, ok := f.(decompressibleFile)
+	if !ok || !df.canDecompress() {
+		return nil, fmt.Errorf("file %s can not be decompressed: %w", f.Name(), ErrArchiveSolid)
+	}
+	r, err := df.openDecompressed()
+	if err != nil {
+		return nil, err
+	}
+	return newForwardOnlyStream(f, r), nil
+}
+
+func newForwardOnlyStream(f ArchiveFile, r io.ReadCloser) *Stream {
+	return &Stream{
+		ReadSeekCloser: &forwardOnlyReader{ReadCloser: r, size: f.Size()},
+		Name:           f.Name(),
+		Size:           f.Size(),
+		ContentType:    GetContentType(f.Name()),
+		Path:           f.Name(),
+		ForwardOnly:    true,
+	}
+}
@@ -0,0 +1,90 @@
+package usenet_pool
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/logger"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCompressedArchiveFile struct {
+	testArchiveFile
+}
+
+func (f *testCompressedArchiveFile) canDecompress() bool { return true }
+
+func (f *testCompressedArchiveFile) openDecompressed() (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(strings.Repeat("x", int(f.size)))), nil
+}
+
+func TestOpenForwardOnly(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 100)
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "movie.zip", buildZIPArchive(t,
+		zipTestEntry{"movie.mkv", zip.Deflate, content},
+		zipTestEntry{"movie.nfo", zip.Store, []byte("nfo")},
+	), 0644))
+
+	archive := NewZIPArchive(fs, "movie.zip")
+	require.NoError(t, archive.Open(""))
+	defer archive.Close()
+
+	files, err := archive.GetFiles()
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.False(t, canDecompress(files[1]))
+
+	stream, err := openForwardOnly(files[0])
+	require.NoError(t, err)
+	defer stream.Close()
+	assert.True(t, stream.ForwardOnly)
+	assert.Equal(t, "video/x-matroska", stream.ContentType)
+
+	pos, err := stream.Seek(0, io.SeekCurrent)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), pos)
+
+	data, err := io.ReadAll(stream)
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+
+	pos, err = stream.Seek(0, io.SeekEnd)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), pos)
+
+	_, err = stream.Seek(0, io.SeekStart)
+	assert.ErrorIs(t, err, ErrStreamNotSeekable)
+}
+
+func TestStreamVideoFromArchiveCompressed(t *testing.T) {
+	usenetPool := &Pool{Log: logger.Scoped("test/usenet/pool")}
+
+	videos := []ArchiveFile{
+		&testCompressedArchiveFile{testArchiveFile{name: "movie.mkv", size: 100}},
+	}
+
+	_, err := usenetPool.streamVideoFromArchive(videos, FileTypeRAR, VideoSelectLargest, 0, false)
+	assert.ErrorIs(t, err, ErrArchiveSolid)
+
+	stream, err := usenetPool.streamVideoFromArchive(videos, FileTypeRAR, VideoSelectLargest, 0, true)
+	require.NoError(t, err)
+	assert.True(t, stream.ForwardOnly)
+	assert.Equal(t, "movie.mkv", stream.Name)
+	stream.Close()
+
+	t.Run("PrefersSeekable", func(t *testing.T) {
+		videos := append(videos, &testArchiveFile{name: "small.mkv", size: 10, streamable: true})
+		stream, err := usenetPool.streamVideoFromArchive(videos, FileTypeRAR, VideoSelectLargest, 0, true)
+		require.NoError(t, err)
+		defer stream.Close()
+		assert.False(t, stream.ForwardOnly)
+		assert.Equal(t, "small.mkv", stream.Name)
+	})
+}
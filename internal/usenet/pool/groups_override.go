@@ -0,0 +1,51 @@
+package usenet_pool
+
+import (
+	"context"
+	"regexp"
+	"slices"
+)
+
+var newsgroupRegex = regexp.MustCompile(`^[A-Za-z0-9+_.-]+$`)
+
+// IsValidNewsgroup reports whether name can be sent in a GROUP command.
+func IsValidNewsgroup(name string) bool {
+	return newsgroupRegex.MatchString(name)
+}
+
+type groupsOverride struct {
+	groups         []string
+	keepFileGroups bool
+}
+
+type groupsOverrideContextKey struct{}
+
+// withGroupsOverride makes the segments fetched using the context use groups
+// in place of the ones of the file, e.g. when the nzb lists dead groups. With
+// keepFileGroups, the file's groups are tried after them.
+func withGroupsOverride(ctx context.Context, groups []string, keepFileGroups bool) context.Context {
+	if len(groups) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, groupsOverrideContextKey{}, &groupsOverride{
+		groups:         groups,
+		keepFileGroups: keepFileGroups,
+	})
+}
+
+func resolveGroups(ctx context.Context, fileGroups []string) []string {
+	override, _ := ctx.Value(groupsOverrideContextKey{}).(*groupsOverride)
+	if override == nil {
+		return fileGroups
+	}
+	if !override.keepFileGroups {
+		return override.groups
+	}
+	groups := slices.Clone(override.groups)
+	for _, group := range fileGroups {
+		if !slices.Contains(groups, group) {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
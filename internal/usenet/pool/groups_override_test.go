@@ -0,0 +1,67 @@
+package usenet_pool
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/nntp/nntptest"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveGroups(t *testing.T) {
+	fileGroups := []string{"alt.binaries.dead", "alt.binaries.live"}
+
+	assert.Equal(t, fileGroups, resolveGroups(t.Context(), fileGroups))
+
+	ctx := withGroupsOverride(t.Context(), []string{"alt.binaries.live"}, false)
+	assert.Equal(t, []string{"alt.binaries.live"}, resolveGroups(ctx, fileGroups))
+
+	ctx = withGroupsOverride(t.Context(), []string{"alt.binaries.live"}, true)
+	assert.Equal(t, []string{"alt.binaries.live", "alt.binaries.dead"}, resolveGroups(ctx, fileGroups))
+}
+
+func TestIsValidNewsgroup(t *testing.T) {
+	assert.True(t, IsValidNewsgroup("alt.binaries.multimedia"))
+	assert.True(t, IsValidNewsgroup("a.b.c+d_e-f"))
+	assert.False(t, IsValidNewsgroup(""))
+	assert.False(t, IsValidNewsgroup("alt.binaries.test\r\nQUIT"))
+	assert.False(t, IsValidNewsgroup("alt binaries"))
+}
+
+func TestStreamGroupsOverride(t *testing.T) {
+	data := makeTestBytes(100)
+	encoded := encodeYenc(data, "movie.mkv", 1, 1, int64(len(data)), 1)
+
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.SetResponse("GROUP alt.binaries.dead", "411 No such group")
+	server.SetResponse("GROUP alt.binaries.live", "211 1 1 1 alt.binaries.live")
+	server.SetResponse("BODY <seg1@test.com>", "222 0 <seg1@test.com>", strings.Split(strings.TrimSpace(string(encoded)), "\r\n"))
+	server.Start(t)
+
+	usenetPool := createTestPool(t, server)
+	usenetPool.segmentCache = NewSegmentCache(10*1024*1024, t.TempDir())
+
+	nzbDoc := &nzb.NZB{Files: []nzb.File{{
+		Subject:  `Test - "movie.mkv" yEnc (1/1)`,
+		Groups:   []string{"alt.binaries.dead"},
+		Segments: []nzb.Segment{{MessageId: "seg1@test.com", Bytes: int64(len(encoded)), Number: 1}},
+	}}}
+	nzbDoc.ParseFileSubject()
+
+	stream, err := usenetPool.StreamLargestFile(t.Context(), nzbDoc, &StreamConfig{
+		GroupsOverride: []string{"alt.binaries.live"},
+	})
+	require.NoError(t, err)
+	defer stream.Close()
+
+	got, err := io.ReadAll(stream)
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+
+	cmds := server.GetRequestCommands()
+	assert.True(t, cmds.HasCommand("GROUP alt.binaries.live"))
+	assert.False(t, cmds.HasCommand("GROUP alt.binaries.dead"))
+}
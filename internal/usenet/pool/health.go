@@ -0,0 +1,69 @@
+package usenet_pool
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/nntp"
+)
+
+const providerHealthCheckTimeout = 10 * time.Second
+
+type ProviderHealth struct {
+	ID                   string         `json:"id"`
+	State                nntp.PoolState `json:"state"`
+	Up                   bool           `json:"up"`
+	LatencyMs            int64          `json:"latency_ms"`
+	AvailableConnections int            `json:"available_connections"`
+	Error                string         `json:"error,omitempty"`
+}
+
+func (p *Pool) checkProviderHealth(ctx context.Context, provider *providerPool) ProviderHealth {
+	health := ProviderHealth{
+		ID:                   provider.Id(),
+		State:                provider.GetState(),
+		AvailableConnections: max(int(provider.MaxSize()-provider.Stat().AcquiredResources()), 0),
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, providerHealthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	conn, err := provider.Acquire(ctx)
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+
+	if _, err := conn.Date(); err != nil {
+		conn.Destroy()
+		health.Error = err.Error()
+		return health
+	}
+	conn.Release()
+
+	health.Up = true
+	health.LatencyMs = time.Since(start).Milliseconds()
+	return health
+}
+
+// CheckProvidersHealth issues a DATE command on a connection from every
+// provider. Failures are reported per provider.
+func (p *Pool) CheckProvidersHealth(ctx context.Context) []ProviderHealth {
+	p.providersMutex.RLock()
+	providers := make([]*providerPool, len(p.providers))
+	copy(providers, p.providers)
+	p.providersMutex.RUnlock()
+
+	result := make([]ProviderHealth, len(providers))
+	var wg sync.WaitGroup
+	for i, provider := range providers {
+		wg.Go(func() {
+			result[i] = p.checkProviderHealth(ctx, provider)
+		})
+	}
+	wg.Wait()
+
+	return result
+}
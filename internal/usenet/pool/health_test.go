@@ -0,0 +1,38 @@
+package usenet_pool
+
+import (
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/logger"
+	"github.com/MunifTanjim/stremthru/internal/nntp"
+	"github.com/MunifTanjim/stremthru/internal/nntp/nntptest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckProvidersHealth(t *testing.T) {
+	upServer := nntptest.NewServer(t, "200 NNTP Service Ready")
+	upServer.Start(t)
+
+	downServer := nntptest.NewServer(t, "502 Service Unavailable")
+	downServer.Start(t)
+
+	usenetPool := &Pool{
+		Log: logger.Scoped("test/usenet/pool"),
+		providers: []*providerPool{
+			{Pool: nntptest.NewPool(t, upServer, &nntp.PoolConfig{MaxSize: 4})},
+			{Pool: nntptest.NewPool(t, downServer, &nntp.PoolConfig{MaxSize: 2})},
+		},
+	}
+
+	result := usenetPool.CheckProvidersHealth(t.Context())
+	require.Len(t, result, 2)
+
+	assert.True(t, result[0].Up)
+	assert.Empty(t, result[0].Error)
+	assert.Equal(t, 4, result[0].AvailableConnections)
+
+	assert.False(t, result[1].Up)
+	assert.NotEmpty(t, result[1].Error)
+	assert.Equal(t, 2, result[1].AvailableConnections)
+}
@@ -0,0 +1,83 @@
+package usenet_pool
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+var _ io.ReadSeekCloser = (*idleWatchdogReadSeekCloser)(nil)
+
+var errStreamIdle = errors.New("stream closed after being idle")
+
+// idleWatchdogReadSeekCloser closes the underlying stream when it is not
+// read for the configured duration, e.g. when the client stalled.
+type idleWatchdogReadSeekCloser struct {
+	rsc       io.ReadSeekCloser
+	mu        sync.Mutex
+	timer     *time.Timer
+	timeout   time.Duration
+	onIdle    func()
+	idle      bool
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewIdleWatchdog wraps the stream, calling onIdle and closing the stream
+// if no read happens within timeout. onIdle is expected to cancel the stream
+// context, so that in-flight fetches are released. A zero timeout disables it.
+func NewIdleWatchdog(stream io.ReadSeekCloser, timeout time.Duration, onIdle func()) io.ReadSeekCloser {
+	if timeout <= 0 {
+		return stream
+	}
+	w := &idleWatchdogReadSeekCloser{
+		rsc:     stream,
+		timeout: timeout,
+		onIdle:  onIdle,
+	}
+	w.timer = time.AfterFunc(timeout, w.fire)
+	return w
+}
+
+func (w *idleWatchdogReadSeekCloser) fire() {
+	if w.onIdle != nil {
+		w.onIdle()
+	}
+	w.mu.Lock()
+	w.idle = true
+	w.mu.Unlock()
+	w.Close()
+}
+
+func (w *idleWatchdogReadSeekCloser) Read(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.idle {
+		return 0, errStreamIdle
+	}
+	n, err := w.rsc.Read(p)
+	w.timer.Reset(w.timeout)
+	return n, err
+}
+
+func (w *idleWatchdogReadSeekCloser) Seek(offset int64, whence int) (int64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.idle {
+		return 0, errStreamIdle
+	}
+	pos, err := w.rsc.Seek(offset, whence)
+	w.timer.Reset(w.timeout)
+	return pos, err
+}
+
+func (w *idleWatchdogReadSeekCloser) Close() error {
+	w.closeOnce.Do(func() {
+		w.timer.Stop()
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.closeErr = w.rsc.Close()
+	})
+	return w.closeErr
+}
@@ -0,0 +1,63 @@
+package usenet_pool
+
+import (
+	"bytes"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type closeTrackingReadSeeker struct {
+	*bytes.Reader
+	closed atomic.Bool
+}
+
+func (r *closeTrackingReadSeeker) Close() error {
+	r.closed.Store(true)
+	return nil
+}
+
+func TestIdleWatchdog(t *testing.T) {
+	t.Run("Disabled", func(t *testing.T) {
+		stream := &closeTrackingReadSeeker{Reader: bytes.NewReader(makeTestBytes(10))}
+		assert.Same(t, stream, NewIdleWatchdog(stream, 0, nil))
+	})
+
+	t.Run("ClosesIdleStream", func(t *testing.T) {
+		stream := &closeTrackingReadSeeker{Reader: bytes.NewReader(makeTestBytes(10))}
+		var idled atomic.Bool
+		w := NewIdleWatchdog(stream, 50*time.Millisecond, func() {
+			idled.Store(true)
+		})
+
+		buf := make([]byte, 4)
+		_, err := w.Read(buf)
+		require.NoError(t, err)
+
+		assert.Eventually(t, stream.closed.Load, time.Second, 10*time.Millisecond)
+		assert.True(t, idled.Load())
+
+		_, err = w.Read(buf)
+		assert.ErrorIs(t, err, errStreamIdle)
+		assert.NoError(t, w.Close())
+	})
+
+	t.Run("ReadsKeepStreamAlive", func(t *testing.T) {
+		stream := &closeTrackingReadSeeker{Reader: bytes.NewReader(makeTestBytes(10))}
+		w := NewIdleWatchdog(stream, 100*time.Millisecond, nil)
+
+		buf := make([]byte, 1)
+		for range 5 {
+			time.Sleep(40 * time.Millisecond)
+			_, err := w.Read(buf)
+			require.NoError(t, err)
+		}
+		assert.False(t, stream.closed.Load())
+
+		require.NoError(t, w.Close())
+		assert.True(t, stream.closed.Load())
+	})
+}
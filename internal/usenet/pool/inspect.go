@@ -5,6 +5,8 @@ import (
 	"context"
 	"errors"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/MunifTanjim/stremthru/internal/config"
 	"github.com/MunifTanjim/stremthru/internal/logger"
@@ -21,6 +23,7 @@ type NZBContentFileType string
 const (
 	NZBContentFileTypeVideo   NZBContentFileType = "video"
 	NZBContentFileTypeArchive NZBContentFileType = "archive"
+	NZBContentFileTypeNZB     NZBContentFileType = "nzb"
 	NZBContentFileTypeOther   NZBContentFileType = "other"
 	NZBContentFileTypeUnknown NZBContentFileType = ""
 )
@@ -28,23 +31,87 @@ const (
 const (
 	NZBContentFileErrorArticleNotFound = "article_not_found"
 	NZBContentFileErrorOpenFailed      = "open_failed"
+	NZBContentFileErrorEmptyFile       = "empty_file"
 )
 
+type NZBContentFileReasonCode string
+
+const (
+	NZBContentFileReasonMissingSegments  NZBContentFileReasonCode = "missing_segments"
+	NZBContentFileReasonFetchFailed      NZBContentFileReasonCode = "fetch_failed"
+	NZBContentFileReasonPasswordRequired NZBContentFileReasonCode = "password_required"
+	NZBContentFileReasonSolidArchive     NZBContentFileReasonCode = "solid_archive"
+	NZBContentFileReasonCompressed       NZBContentFileReasonCode = "compressed"
+	NZBContentFileReasonUnsupportedType  NZBContentFileReasonCode = "unsupported_type"
+	NZBContentFileReasonOpenFailed       NZBContentFileReasonCode = "open_failed"
+	NZBContentFileReasonEmptyFile        NZBContentFileReasonCode = "empty_file"
+	NZBContentFileReasonCRCMismatch      NZBContentFileReasonCode = "crc_mismatch"
+	NZBContentFileReasonNestedNZB        NZBContentFileReasonCode = "nested_nzb"
+)
+
+// NZBContentFileCRC is the result of verifying the file against the CRC in
+// the archive header, empty if not verified.
+type NZBContentFileCRC string
+
+const (
+	NZBContentFileCRCOk         NZBContentFileCRC = "ok"
+	NZBContentFileCRCMismatch   NZBContentFileCRC = "mismatch"
+	NZBContentFileCRCUnverified NZBContentFileCRC = "unverified" // timed out or failed to read
+)
+
+// NZBContentFileReason explains why a file is not streamable.
+type NZBContentFileReason struct {
+	Code    NZBContentFileReasonCode `json:"code"`
+	Message string                   `json:"msg"`
+}
+
 type NZBContentFile struct {
-	Type       NZBContentFileType `json:"t"`
-	Name       string             `json:"n"`
-	Alias      string             `json:"alias,omitempty"`
-	Size       int64              `json:"s"`
-	Volume     int                `json:"vol,omitempty"`
-	Streamable bool               `json:"strm"`
-	Errors     []string           `json:"errs,omitempty"`
-	Files      []NZBContentFile   `json:"files,omitempty"`
-	Parts      []NZBContentFile   `json:"parts,omitempty"`
+	Type       NZBContentFileType     `json:"t"`
+	Name       string                 `json:"n"`
+	Alias      string                 `json:"alias,omitempty"`
+	Size       int64                  `json:"s"`
+	Volume     int                    `json:"vol,omitempty"`
+	Streamable bool                   `json:"strm"`
+	Disc       bool                   `json:"disc,omitempty"`
+	CRC        NZBContentFileCRC      `json:"crc,omitempty"`
+	Errors     []string               `json:"errs,omitempty"`
+	Reasons    []NZBContentFileReason `json:"rsns,omitempty"`
+	Files      []NZBContentFile       `json:"files,omitempty"`
+	Parts      []NZBContentFile       `json:"parts,omitempty"`
+}
+
+func (f *NZBContentFile) addReason(code NZBContentFileReasonCode, message string) {
+	f.Reasons = append(f.Reasons, NZBContentFileReason{Code: code, Message: message})
+}
+
+// addArchiveErrorReason records the reason for failing to open or list
+// the archive, along with the legacy error.
+func (f *NZBContentFile) addArchiveErrorReason(err error) {
+	switch {
+	case errors.Is(err, ErrArticleNotFound):
+		f.Errors = append(f.Errors, NZBContentFileErrorArticleNotFound)
+		f.addReason(NZBContentFileReasonMissingSegments, "archive has missing articles")
+	case errors.Is(err, ErrPasswordRequired):
+		f.Errors = append(f.Errors, NZBContentFileErrorOpenFailed)
+		f.addReason(NZBContentFileReasonPasswordRequired, "archive is encrypted, password required")
+	default:
+		f.Errors = append(f.Errors, NZBContentFileErrorOpenFailed)
+		f.addReason(NZBContentFileReasonOpenFailed, err.Error())
+	}
+}
+
+func (f *NZBContentFile) addArchiveNotStreamableReason(fileType FileType) {
+	if fileType == FileTypeRAR {
+		f.addReason(NZBContentFileReasonSolidArchive, "archive is solid or compressed")
+	} else {
+		f.addReason(NZBContentFileReasonCompressed, "archive is compressed or encrypted")
+	}
 }
 
 type NZBContent struct {
 	Files      []NZBContentFile
 	Streamable bool
+	Err        error // why it is not streamable, when known up front
 }
 
 func classifyNZBContentFileType(filename string) NZBContentFileType {
@@ -64,7 +131,7 @@ func hasStreamableVideoInNZBContentFiles(files []NZBContentFile) bool {
 		if f.Alias != "" {
 			name = f.Alias
 		}
-		isVideo := isVideoFile(name)
+		isVideo := isMainVideoFile(name)
 		if f.Streamable && isVideo {
 			return true
 		}
@@ -75,6 +142,36 @@ func hasStreamableVideoInNZBContentFiles(files []NZBContentFile) bool {
 	return false
 }
 
+// GetStreamableVideoContentPaths returns the content paths of all the
+// streamable videos, including the ones nested inside archives.
+func GetStreamableVideoContentPaths(files []NZBContentFile) []string {
+	var paths []string
+	for i := range files {
+		f := &files[i]
+		name := f.Name
+		if f.Alias != "" {
+			name = f.Alias
+		}
+		if f.Streamable && isMainVideoFile(name) {
+			paths = append(paths, f.Name)
+		}
+		for _, path := range GetStreamableVideoContentPaths(f.Files) {
+			paths = append(paths, joinContentPath(f.Name, path))
+		}
+	}
+	return paths
+}
+
+func hasVideoOrArchive(files []NZBContentFile) bool {
+	return slices.ContainsFunc(files, func(f NZBContentFile) bool {
+		switch f.Type {
+		case NZBContentFileTypeVideo, NZBContentFileTypeArchive:
+			return true
+		}
+		return false
+	})
+}
+
 func isNZBStremable(c *NZBContent) bool {
 	return hasStreamableVideoInNZBContentFiles(c.Files)
 }
@@ -107,6 +204,8 @@ func (f *nzbArchiveFile) Volume() int {
 		return GetRARVolumeNumber(f.Name())
 	case FileType7z:
 		return Get7zVolumeNumber(f.Name())
+	case FileTypeZIP:
+		return GetZIPVolumeNumber(f.Name())
 	default:
 		return -1
 	}
@@ -119,6 +218,8 @@ func (p *Pool) InspectNZBContent(ctx context.Context, nzbDoc *nzb.NZB, password
 	}
 
 	if len(nzbDoc.Files) == 0 {
+		content.Streamable = false
+		content.Err = ErrNoStreamableContent
 		return content, nil
 	}
 
@@ -151,31 +252,50 @@ func (p *Pool) InspectNZBContent(ctx context.Context, nzbDoc *nzb.NZB, password
 	}
 
 	fetchResults := make([]segmentFetchResult, len(needsFetch))
+	for i, f := range needsFetch {
+		fetchResults[i].nzbFile = f
+	}
+
+	// the first segments, needed for type detection, are all queued ahead of
+	// the last ones, so a slow file does not hold up the rest.
 	fetchPool := pond.NewPool(config.Newz.MaxConnectionPerStream)
 	for i, f := range needsFetch {
 		fetchPool.Submit(func() {
-			startSegment, startErr := p.fetchSegment(ctx, &f.Segments[0], f.Groups)
-			var endSegment *SegmentData
-			var endErr error
-			if f.SegmentCount() > 1 {
-				endSegment, endErr = p.fetchSegment(ctx, &f.Segments[len(f.Segments)-1], f.Groups)
+			if err := ctx.Err(); err != nil {
+				fetchResults[i].startErr = err
+				return
 			}
-			fetchResults[i] = segmentFetchResult{
-				nzbFile:      f,
-				startSegment: startSegment,
-				startErr:     startErr,
-				endSegment:   endSegment,
-				endErr:       endErr,
+			fetchResults[i].startSegment, fetchResults[i].startErr = p.fetchSegment(ctx, &f.Segments[0], f.Groups)
+		})
+	}
+	for i, f := range needsFetch {
+		if f.SegmentCount() < 2 {
+			continue
+		}
+		fetchPool.Submit(func() {
+			if err := ctx.Err(); err != nil {
+				fetchResults[i].endErr = err
+				return
 			}
+			fetchResults[i].endSegment, fetchResults[i].endErr = p.fetchSegment(ctx, &f.Segments[len(f.Segments)-1], f.Groups)
 		})
 	}
 	fetchPool.StopAndWait()
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	for _, fr := range fetchResults {
 		filename := fr.nzbFile.Name()
 
 		articleNotFound := errors.Is(fr.startErr, ErrArticleNotFound) || errors.Is(fr.endErr, ErrArticleNotFound)
 
+		if isNZBFile(filename) {
+			content.Files = append(content.Files, p.inspectNestedNZB(ctx, fr.nzbFile, password))
+			continue
+		}
+
 		if isVideoFile(filename) {
 			entry := NZBContentFile{
 				Type:       NZBContentFileTypeVideo,
@@ -186,11 +306,14 @@ func (p *Pool) InspectNZBContent(ctx context.Context, nzbDoc *nzb.NZB, password
 			if articleNotFound {
 				entry.Streamable = false
 				entry.Errors = append(entry.Errors, NZBContentFileErrorArticleNotFound)
+				entry.addReason(NZBContentFileReasonMissingSegments, "article not found")
 			} else if fr.startErr != nil {
 				entry.Streamable = false
+				entry.addReason(NZBContentFileReasonFetchFailed, "failed to fetch first segment")
 				inspectLog.Warn("failed to fetch first segment for video file", "error", fr.startErr, "name", filename)
 			} else if fr.endErr != nil {
 				entry.Streamable = false
+				entry.addReason(NZBContentFileReasonFetchFailed, "failed to fetch last segment")
 				inspectLog.Warn("failed to fetch last segment for video file", "error", fr.endErr, "name", filename)
 			}
 			content.Files = append(content.Files, entry)
@@ -205,6 +328,9 @@ func (p *Pool) InspectNZBContent(ctx context.Context, nzbDoc *nzb.NZB, password
 					Size:       fr.nzbFile.Size(),
 					Streamable: false,
 					Errors:     []string{NZBContentFileErrorArticleNotFound},
+					Reasons: []NZBContentFileReason{
+						{Code: NZBContentFileReasonMissingSegments, Message: "article not found"},
+					},
 				})
 			} else {
 				af := &nzbArchiveFile{
@@ -226,23 +352,28 @@ func (p *Pool) InspectNZBContent(ctx context.Context, nzbDoc *nzb.NZB, password
 		streamable := true
 		var fileType FileType
 		var errs []string
+		var reasons []NZBContentFileReason
 
 		if fr.startErr != nil {
 			inspectLog.Warn("failed to fetch first segment for type detection", "error", fr.startErr, "name", filename)
 			streamable = false
 			if errors.Is(fr.startErr, ErrArticleNotFound) {
 				errs = append(errs, NZBContentFileErrorArticleNotFound)
+				reasons = append(reasons, NZBContentFileReason{Code: NZBContentFileReasonMissingSegments, Message: "article not found"})
+			} else {
+				reasons = append(reasons, NZBContentFileReason{Code: NZBContentFileReasonFetchFailed, Message: "failed to fetch first segment"})
 			}
 		} else {
 			fileType = DetectFileType(fr.startSegment.Body, filename)
 			if fr.endErr != nil && errors.Is(fr.endErr, ErrArticleNotFound) {
 				streamable = false
 				errs = append(errs, NZBContentFileErrorArticleNotFound)
+				reasons = append(reasons, NZBContentFileReason{Code: NZBContentFileReasonMissingSegments, Message: "article not found"})
 			}
 		}
 
 		switch fileType {
-		case FileTypeRAR, FileType7z:
+		case FileTypeRAR, FileType7z, FileTypeZIP:
 			if !streamable {
 				content.Files = append(content.Files, NZBContentFile{
 					Type:       NZBContentFileTypeArchive,
@@ -250,6 +381,7 @@ func (p *Pool) InspectNZBContent(ctx context.Context, nzbDoc *nzb.NZB, password
 					Size:       fr.nzbFile.Size(),
 					Streamable: false,
 					Errors:     errs,
+					Reasons:    reasons,
 				})
 			} else {
 				af := &nzbArchiveFile{
@@ -279,16 +411,28 @@ func (p *Pool) InspectNZBContent(ctx context.Context, nzbDoc *nzb.NZB, password
 				Size:       fr.nzbFile.Size(),
 				Streamable: streamable,
 				Errors:     errs,
+				Reasons:    reasons,
 			})
 		}
 	}
 
 	archiveGroups := groupArchiveVolumes(nzbArchiveFiles)
 
+	nzbName := getNZBNameForAlias(nzbDoc)
+
 	for i := range archiveGroups {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		group := &archiveGroups[i]
 		name := group.Files[0].Name()
 
+		baseName := group.BaseName
+		if nzbName != "" && len(archiveGroups) == 1 {
+			baseName = nzbName
+		}
+
 		entry := NZBContentFile{
 			Type: NZBContentFileTypeArchive,
 			Name: name,
@@ -306,13 +450,7 @@ func (p *Pool) InspectNZBContent(ctx context.Context, nzbDoc *nzb.NZB, password
 			aliases := make(map[string]string, len(group.Files))
 			for i, f := range group.Files {
 				vol := group.Volumes[i]
-				var syntheticName string
-				switch group.FileType {
-				case FileTypeRAR:
-					syntheticName = GenerateRARVolumeName(group.BaseName, vol)
-				case FileType7z:
-					syntheticName = Generate7zVolumeName(group.BaseName, vol)
-				}
+				syntheticName := generateArchiveVolumeName(group.FileType, baseName, vol)
 				aliases[syntheticName] = f.Name()
 				if vol == 0 {
 					archiveName = syntheticName
@@ -346,32 +484,34 @@ func (p *Pool) InspectNZBContent(ctx context.Context, nzbDoc *nzb.NZB, password
 			archive = NewRARArchive(ufs, archiveName)
 		case FileType7z:
 			archive = NewSevenZipArchive(ufs.toAfero(), archiveName)
+		case FileTypeZIP:
+			archive = NewZIPArchive(ufs.toAfero(), archiveName)
 		}
 
 		if err := archive.Open(password); err != nil {
 			inspectLog.Warn("failed to open archive", "error", err, "name", name)
-			if errors.Is(err, ErrArticleNotFound) {
-				entry.Errors = append(entry.Errors, NZBContentFileErrorArticleNotFound)
-			} else {
-				entry.Errors = append(entry.Errors, NZBContentFileErrorOpenFailed)
-			}
+			entry.addArchiveErrorReason(err)
 			content.Files = append(content.Files, entry)
 			ufs.Close()
 			continue
 		}
 
 		entry.Streamable = archive.IsStreamable()
-		if entry.Streamable {
+		if video := getSolidSequentialVideo(archive); !entry.Streamable && video != nil {
+			// crc verification is skipped, it would decompress the whole archive
+			entry.Streamable = true
+			entry.Files = inspectSolidSequentialFiles(archive, video)
+		} else if !entry.Streamable {
+			entry.addArchiveNotStreamableReason(group.FileType)
+		} else {
 			files, err := archive.GetFiles()
 			if err != nil {
 				inspectLog.Warn("failed to get archive files", "name", name, "error", err)
-				if errors.Is(err, ErrArticleNotFound) {
-					entry.Errors = append(entry.Errors, NZBContentFileErrorArticleNotFound)
-				} else {
-					entry.Errors = append(entry.Errors, NZBContentFileErrorOpenFailed)
-				}
+				entry.addArchiveErrorReason(err)
 			} else {
 				entry.Files = p.inspectArchiveFiles(files, password)
+				verifyArchiveFilesCRC(ctx, files, entry.Files)
+				entry.Disc = isDiscStructure(files)
 			}
 		}
 
@@ -380,23 +520,163 @@ func (p *Pool) InspectNZBContent(ctx context.Context, nzbDoc *nzb.NZB, password
 		content.Files = append(content.Files, entry)
 	}
 
+	aliasObfuscatedVideo(content.Files, nzbName)
+
 	content.Streamable = isNZBStremable(content)
+	if !content.Streamable && !hasVideoOrArchive(content.Files) {
+		content.Err = ErrNoStreamableContent
+		if hasNestedNZB(content.Files) {
+			content.Err = ErrNestedNZB
+		}
+	}
 
 	return content, nil
 }
 
+// getNZBNameForAlias returns the human readable name from the NZB meta, used
+// to make obfuscated releases addressable by the title users see.
+func getNZBNameForAlias(nzbDoc *nzb.NZB) string {
+	name := nzbDoc.GetMeta("name")
+	if name == "" {
+		name = nzbDoc.GetMeta("title")
+	}
+	name = strings.TrimSpace(name)
+	return strings.NewReplacer("/", "_", "\\", "_").Replace(name)
+}
+
+// isObfuscatedName checks if the filename, without extension, looks like a
+// random hash rather than a release name.
+func isObfuscatedName(filename string) bool {
+	stem := strings.TrimSuffix(filename, filepath.Ext(filename))
+	if len(stem) < 16 {
+		return false
+	}
+	for _, c := range stem {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// aliasObfuscatedVideo sets an alias based on the NZB name for the only video
+// in the NZB, if its filename is obfuscated. The real name stays canonical.
+func aliasObfuscatedVideo(files []NZBContentFile, nzbName string) {
+	if nzbName == "" {
+		return
+	}
+
+	var video *NZBContentFile
+	for i := range files {
+		f := &files[i]
+		if f.Type != NZBContentFileTypeVideo {
+			continue
+		}
+		if video != nil {
+			return
+		}
+		video = f
+	}
+
+	if video == nil || video.Alias != "" || !isObfuscatedName(video.Name) {
+		return
+	}
+
+	ext := filepath.Ext(video.Name)
+	if strings.EqualFold(filepath.Ext(nzbName), ext) {
+		video.Alias = nzbName
+	} else {
+		video.Alias = nzbName + ext
+	}
+}
+
+// verifyArchiveFilesCRC checks the streamable videos in the RAR archive
+// against their CRC, when enabled with STREMTHRU_NEWZ_INSPECT_VERIFY_CRC_TIMEOUT.
+// Nested archives are not verified.
+func verifyArchiveFilesCRC(ctx context.Context, files []ArchiveFile, entries []NZBContentFile) {
+	timeout := config.Newz.InspectCRCTimeout
+	if timeout <= 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for i := range entries {
+		entry := &entries[i]
+		if entry.Type != NZBContentFileTypeVideo || !entry.Streamable {
+			continue
+		}
+		idx := slices.IndexFunc(files, func(f ArchiveFile) bool {
+			return f.Name() == entry.Name
+		})
+		if idx == -1 {
+			continue
+		}
+		f, ok := files[idx].(*UsenetRARFile)
+		if !ok {
+			continue
+		}
+
+		err := f.VerifyCRC(ctx)
+		switch {
+		case err == nil:
+			entry.CRC = NZBContentFileCRCOk
+		case errors.Is(err, rardecode.ErrBadFileChecksum):
+			entry.CRC = NZBContentFileCRCMismatch
+			entry.Streamable = false
+			entry.addReason(NZBContentFileReasonCRCMismatch, "file does not match the crc in archive header")
+		default:
+			inspectLog.Warn("failed to verify crc", "error", err, "name", entry.Name)
+			entry.CRC = NZBContentFileCRCUnverified
+		}
+	}
+}
+
+// newArchiveContentFile describes a file found inside an archive. Entries
+// with no content (e.g. header-only) are marked non-streamable.
+func newArchiveContentFile(f ArchiveFile) NZBContentFile {
+	entry := NZBContentFile{
+		Type:       classifyNZBContentFileType(f.Name()),
+		Name:       f.Name(),
+		Size:       f.Size(),
+		Streamable: f.IsStreamable(),
+	}
+	if !entry.Streamable {
+		entry.addReason(NZBContentFileReasonCompressed, "file is compressed or stored in a solid block")
+	}
+	if f.Size() <= 0 {
+		entry.Streamable = false
+		entry.Errors = append(entry.Errors, NZBContentFileErrorEmptyFile)
+		entry.addReason(NZBContentFileReasonEmptyFile, "file is empty")
+	}
+	return entry
+}
+
+// inspectSolidSequentialFiles describes the files of a solid archive, with
+// only its video streamable, forward-only.
+func inspectSolidSequentialFiles(archive Archive, video ArchiveFile) []NZBContentFile {
+	files, _ := archive.GetFiles()
+	result := make([]NZBContentFile, len(files))
+	for i, f := range files {
+		result[i] = newArchiveContentFile(f)
+		if f == video {
+			result[i].Streamable = true
+			result[i].Reasons = nil
+		} else if result[i].Streamable {
+			result[i].Streamable = false
+			result[i].addReason(NZBContentFileReasonSolidArchive, "only the video of a solid archive is streamable")
+		}
+	}
+	return result
+}
+
 func (p *Pool) inspectArchiveFiles(files []ArchiveFile, password string) []NZBContentFile {
-	archiveGroups := groupArchiveVolumes(files)
+	archiveGroups := groupArchiveVolumes(typeAliasedArchiveParts(files))
 
 	if len(archiveGroups) == 0 {
 		result := make([]NZBContentFile, len(files))
 		for i, f := range files {
-			result[i] = NZBContentFile{
-				Type:       classifyNZBContentFileType(f.Name()),
-				Name:       f.Name(),
-				Size:       f.Size(),
-				Streamable: f.IsStreamable(),
-			}
+			result[i] = newArchiveContentFile(f)
 		}
 		return result
 	}
@@ -412,12 +692,7 @@ func (p *Pool) inspectArchiveFiles(files []ArchiveFile, password string) []NZBCo
 
 	for _, f := range files {
 		if _, isArchivePart := archiveFileNames[f.Name()]; !isArchivePart {
-			result = append(result, NZBContentFile{
-				Type:       classifyNZBContentFileType(f.Name()),
-				Name:       f.Name(),
-				Size:       f.Size(),
-				Streamable: f.IsStreamable(),
-			})
+			result = append(result, newArchiveContentFile(f))
 		}
 	}
 
@@ -430,14 +705,23 @@ func (p *Pool) inspectArchiveFiles(files []ArchiveFile, password string) []NZBCo
 			Name: name,
 			Size: group.TotalSize,
 		}
+		archiveFiles, archiveName := getArchiveGroupFiles(group)
+		if group.Aliased {
+			entry.Alias = archiveName
+		}
 		for i, f := range group.Files {
-			entry.Parts = append(entry.Parts, NZBContentFile{
+			part := NZBContentFile{
 				Type:       classifyNZBContentFileType(f.Name()),
 				Name:       f.Name(),
 				Size:       f.Size(),
 				Volume:     group.Volumes[i],
 				Streamable: true,
-			})
+			}
+			if group.Aliased {
+				part.Type = NZBContentFileTypeArchive
+				part.Alias = archiveFiles[i].Name()
+			}
+			entry.Parts = append(entry.Parts, part)
 		}
 
 		allStreamable := true
@@ -449,56 +733,50 @@ func (p *Pool) inspectArchiveFiles(files []ArchiveFile, password string) []NZBCo
 		}
 
 		if !allStreamable {
+			entry.addReason(NZBContentFileReasonCompressed, "archive volumes are compressed in the outer archive")
 			result = append(result, entry)
 			continue
 		}
 
-		afs := NewArchiveFS(group.Files)
+		afs := NewArchiveFS(archiveFiles)
 
 		var innerArchive Archive
 		switch group.FileType {
 		case FileTypeRAR:
-			innerArchive = NewRARArchive(afs, filepath.Base(name))
+			innerArchive = NewRARArchive(afs, archiveName)
 		case FileType7z:
-			innerArchive = NewSevenZipArchive(afs.toAfero(), filepath.Base(name))
+			innerArchive = NewSevenZipArchive(afs.toAfero(), archiveName)
+		case FileTypeZIP:
+			innerArchive = NewZIPArchive(afs.toAfero(), archiveName)
 		default:
 			afs.Close()
+			entry.addReason(NZBContentFileReasonUnsupportedType, "unsupported archive type")
 			result = append(result, entry)
 			continue
 		}
 
 		if err := innerArchive.Open(""); err != nil {
 			inspectLog.Warn("failed to open nested archive", "error", err, "name", name)
-			if errors.Is(err, ErrArticleNotFound) {
-				entry.Errors = append(entry.Errors, NZBContentFileErrorArticleNotFound)
-			} else {
-				entry.Errors = append(entry.Errors, NZBContentFileErrorOpenFailed)
-			}
+			entry.addArchiveErrorReason(err)
 			afs.Close()
 			result = append(result, entry)
 			continue
 		}
 
 		entry.Streamable = innerArchive.IsStreamable()
-		if entry.Streamable {
+		if !entry.Streamable {
+			entry.addArchiveNotStreamableReason(group.FileType)
+		} else {
 			if innerFiles, err := innerArchive.GetFiles(); err != nil {
 				inspectLog.Warn("failed to get nested archive files", "error", err, "name", name)
-				if errors.Is(err, ErrArticleNotFound) {
-					entry.Errors = append(entry.Errors, NZBContentFileErrorArticleNotFound)
-				} else {
-					entry.Errors = append(entry.Errors, NZBContentFileErrorOpenFailed)
-				}
+				entry.addArchiveErrorReason(err)
 			} else {
 				innerContentFiles := make([]NZBContentFile, len(innerFiles))
 				for j, f := range innerFiles {
-					innerContentFiles[j] = NZBContentFile{
-						Type:       classifyNZBContentFileType(f.Name()),
-						Name:       f.Name(),
-						Size:       f.Size(),
-						Streamable: f.IsStreamable(),
-					}
+					innerContentFiles[j] = newArchiveContentFile(f)
 				}
 				entry.Files = innerContentFiles
+				entry.Disc = isDiscStructure(innerFiles)
 			}
 		}
 
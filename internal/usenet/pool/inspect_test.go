@@ -0,0 +1,185 @@
+package usenet_pool
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/logger"
+	"github.com/MunifTanjim/stremthru/internal/nntp"
+	"github.com/MunifTanjim/stremthru/internal/nntp/nntptest"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetStreamableVideoContentPaths(t *testing.T) {
+	files := []NZBContentFile{
+		{Type: NZBContentFileTypeVideo, Name: "e01.mkv", Streamable: true},
+		{Type: NZBContentFileTypeVideo, Name: "e02.mkv", Streamable: false},
+		{Type: NZBContentFileTypeOther, Name: "info.nfo", Streamable: true},
+		{
+			Type:       NZBContentFileTypeArchive,
+			Name:       "a1b2c3",
+			Alias:      "season.part01.rar",
+			Streamable: true,
+			Parts: []NZBContentFile{
+				{Type: NZBContentFileTypeArchive, Name: "a1b2c3", Alias: "season.part01.rar", Streamable: true},
+			},
+			Files: []NZBContentFile{
+				{Type: NZBContentFileTypeVideo, Name: "e03.mkv", Streamable: true},
+				{
+					Type:       NZBContentFileTypeArchive,
+					Name:       "inner.7z",
+					Streamable: true,
+					Files: []NZBContentFile{
+						{Type: NZBContentFileTypeVideo, Name: "e04.mkv", Streamable: true},
+					},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, []string{
+		"e01.mkv",
+		"a1b2c3::e03.mkv",
+		"a1b2c3::inner.7z::e04.mkv",
+	}, GetStreamableVideoContentPaths(files))
+}
+
+func TestInspectNZBContentNoStreamableContent(t *testing.T) {
+	content, err := (&Pool{}).InspectNZBContent(t.Context(), createTestNZB(), "")
+	assert.NoError(t, err)
+	assert.False(t, content.Streamable)
+	assert.ErrorIs(t, content.Err, ErrNoStreamableContent)
+
+	assert.False(t, hasVideoOrArchive([]NZBContentFile{
+		{Type: NZBContentFileTypeOther, Name: "movie.nfo"},
+	}))
+	assert.True(t, hasVideoOrArchive([]NZBContentFile{
+		{Type: NZBContentFileTypeOther, Name: "movie.nfo"},
+		{Type: NZBContentFileTypeArchive, Name: "movie.rar"},
+	}))
+}
+
+func TestInspectNZBContentFileOrder(t *testing.T) {
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.SetResponse("GROUP alt.binaries.test", "211 1 1 1 alt.binaries.test")
+
+	data := makeTestBytes(100)
+	files := []nzb.File{}
+	for i := range 6 {
+		name := fmt.Sprintf("file%d.txt", i)
+		segments := []nzb.Segment{}
+		for part := 1; part <= 2; part++ {
+			messageId := fmt.Sprintf("%s.%d@test.com", name, part)
+			encoded := encodeYenc(data, name, part, 2, int64(2*len(data)), int64((part-1)*len(data)+1))
+			if i == 3 && part == 1 {
+				server.SetResponse("BODY <"+messageId+">", "430 No Such Article")
+			} else {
+				server.SetResponse("BODY <"+messageId+">", "222 0 <"+messageId+">", strings.Split(strings.TrimSpace(string(encoded)), "\r\n"))
+			}
+			segments = append(segments, nzb.Segment{MessageId: messageId, Bytes: int64(len(encoded)), Number: part})
+		}
+		files = append(files, nzb.File{Subject: `Test - "` + name + `" yEnc (1/2)`, Segments: segments})
+	}
+	server.Start(t)
+
+	usenetPool := &Pool{
+		Log:          logger.Scoped("test/usenet/pool"),
+		providers:    []*providerPool{{Pool: nntptest.NewPool(t, server, &nntp.PoolConfig{})}},
+		segmentCache: NewSegmentCache(10*1024*1024, t.TempDir()),
+	}
+
+	content, err := usenetPool.InspectNZBContent(t.Context(), createTestNZB(files...), "")
+	require.NoError(t, err)
+	require.Len(t, content.Files, 6)
+	for i, f := range content.Files {
+		assert.Equal(t, fmt.Sprintf("file%d.txt", i), f.Name)
+		assert.Equal(t, i != 3, f.Streamable, f.Name)
+	}
+}
+
+func TestInspectNZBContentDisc(t *testing.T) {
+	usenetPool, nzbDoc := createTestNZBServer(t, testNZBFile{"movie.rar", buildRAR4ArchiveFiles(
+		rar4TestFile{name: "VIDEO_TS/VIDEO_TS.IFO", data: []byte("ifo")},
+		rar4TestFile{name: "VIDEO_TS/VTS_01_1.VOB", data: makeTestBytes(100)},
+	)})
+
+	content, err := usenetPool.InspectNZBContent(t.Context(), nzbDoc, "")
+	require.NoError(t, err)
+	require.Len(t, content.Files, 1)
+	assert.Equal(t, NZBContentFileTypeArchive, content.Files[0].Type)
+	assert.True(t, content.Files[0].Disc)
+	assert.NotEmpty(t, content.Files[0].Files)
+}
+
+func TestNewArchiveContentFile(t *testing.T) {
+	entry := newArchiveContentFile(&testArchiveFile{name: "movie.mkv", size: 100, streamable: true})
+	assert.True(t, entry.Streamable)
+	assert.Empty(t, entry.Errors)
+
+	assert.Empty(t, entry.Reasons)
+
+	entry = newArchiveContentFile(&testArchiveFile{name: "movie.mkv", size: 0, streamable: true})
+	assert.False(t, entry.Streamable)
+	assert.Equal(t, []string{NZBContentFileErrorEmptyFile}, entry.Errors)
+	assert.Len(t, entry.Reasons, 1)
+	assert.Equal(t, NZBContentFileReasonEmptyFile, entry.Reasons[0].Code)
+
+	entry = newArchiveContentFile(&testArchiveFile{name: "movie.mkv", size: 100, streamable: false})
+	assert.False(t, entry.Streamable)
+	assert.Empty(t, entry.Errors)
+	assert.Len(t, entry.Reasons, 1)
+	assert.Equal(t, NZBContentFileReasonCompressed, entry.Reasons[0].Code)
+}
+
+func TestAddArchiveErrorReason(t *testing.T) {
+	for _, tc := range []struct {
+		err   error
+		code  NZBContentFileReasonCode
+		error string
+	}{
+		{fmt.Errorf("open: %w", ErrArticleNotFound), NZBContentFileReasonMissingSegments, NZBContentFileErrorArticleNotFound},
+		{fmt.Errorf("%w: %w", ErrPasswordRequired, errors.New("encrypted")), NZBContentFileReasonPasswordRequired, NZBContentFileErrorOpenFailed},
+		{errors.New("bad header"), NZBContentFileReasonOpenFailed, NZBContentFileErrorOpenFailed},
+	} {
+		entry := NZBContentFile{}
+		entry.addArchiveErrorReason(tc.err)
+		assert.Equal(t, []string{tc.error}, entry.Errors)
+		assert.Len(t, entry.Reasons, 1)
+		assert.Equal(t, tc.code, entry.Reasons[0].Code)
+	}
+}
+
+func TestAliasObfuscatedVideo(t *testing.T) {
+	t.Run("SingleObfuscatedVideo", func(t *testing.T) {
+		files := []NZBContentFile{
+			{Type: NZBContentFileTypeVideo, Name: "a1b2c3d4e5f6a7b8c9d0.mkv"},
+			{Type: NZBContentFileTypeOther, Name: "a1b2c3d4e5f6a7b8c9d0.nfo"},
+		}
+		aliasObfuscatedVideo(files, "Movie.2024.1080p")
+		assert.Equal(t, "Movie.2024.1080p.mkv", files[0].Alias)
+		assert.Equal(t, "a1b2c3d4e5f6a7b8c9d0.mkv", files[0].Name)
+		assert.Empty(t, files[1].Alias)
+	})
+
+	t.Run("NotObfuscated", func(t *testing.T) {
+		files := []NZBContentFile{
+			{Type: NZBContentFileTypeVideo, Name: "Movie.2024.1080p.mkv"},
+		}
+		aliasObfuscatedVideo(files, "Another Name")
+		assert.Empty(t, files[0].Alias)
+	})
+
+	t.Run("MultipleVideos", func(t *testing.T) {
+		files := []NZBContentFile{
+			{Type: NZBContentFileTypeVideo, Name: "a1b2c3d4e5f6a7b8c9d0.mkv"},
+			{Type: NZBContentFileTypeVideo, Name: "f1e2d3c4b5a6f7e8d9c0.mkv"},
+		}
+		aliasObfuscatedVideo(files, "Show.S01")
+		assert.Empty(t, files[0].Alias)
+		assert.Empty(t, files[1].Alias)
+	})
+}
@@ -0,0 +1,59 @@
+package usenet_pool
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+var ErrUnsupportedContainer = errors.New("usenet: unsupported media container")
+
+const (
+	ContainerMatroska = "matroska"
+	ContainerWebM     = "webm"
+	ContainerMP4      = "mp4"
+)
+
+const (
+	VideoCodecH264  = "h264"
+	VideoCodecHEVC  = "hevc"
+	VideoCodecAV1   = "av1"
+	VideoCodecVP8   = "vp8"
+	VideoCodecVP9   = "vp9"
+	VideoCodecMPEG2 = "mpeg2"
+	VideoCodecMPEG4 = "mpeg4"
+)
+
+// MediaInfo is the technical metadata of a video, read from the container
+// headers. VideoCodec is the raw codec id when not known.
+type MediaInfo struct {
+	Container  string        `json:"container"`
+	VideoCodec string        `json:"video_codec"`
+	Width      int           `json:"width"`
+	Height     int           `json:"height"`
+	Duration   time.Duration `json:"duration"`
+}
+
+// ReadMediaInfo reads the metadata of a matroska or mp4 video, only reading
+// the headers it needs, by offset.
+func ReadMediaInfo(r io.ReadSeeker, size int64) (*MediaInfo, error) {
+	info, err := readMKVMediaInfo(r, size)
+	if !errors.Is(err, ErrNotMatroska) {
+		return info, err
+	}
+	info, err = readMP4MediaInfo(r, size)
+	if errors.Is(err, errNotMP4) {
+		return nil, ErrUnsupportedContainer
+	}
+	return info, err
+}
+
+func readAtOffset(r io.ReadSeeker, p []byte, off int64) (int, error) {
+	if ra, ok := r.(io.ReaderAt); ok {
+		return ra.ReadAt(p, off)
+	}
+	if _, err := r.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(r, p)
+}
@@ -0,0 +1,131 @@
+package usenet_pool
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mp4TestBox(typ string, data ...[]byte) []byte {
+	body := bytes.Join(data, nil)
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	box = append(box, typ...)
+	return append(box, body...)
+}
+
+func mp4TrackHeader(width, height uint32) []byte {
+	data := make([]byte, 84)
+	binary.BigEndian.PutUint32(data[76:80], width<<16)
+	binary.BigEndian.PutUint32(data[80:84], height<<16)
+	return data
+}
+
+func mp4Handler(handler string) []byte {
+	data := make([]byte, 24)
+	copy(data[8:12], handler)
+	return data
+}
+
+func mp4SampleDescription(codec string) []byte {
+	data := binary.BigEndian.AppendUint32(make([]byte, 4), 1)
+	return append(data, mp4TestBox(codec, make([]byte, 16))...)
+}
+
+func TestReadMediaInfo(t *testing.T) {
+	t.Run("Matroska", func(t *testing.T) {
+		header := ebmlElement(mkvIdEBML, ebmlElement(mkvIdDocType, []byte("matroska")))
+		info := ebmlElement(mkvIdInfo,
+			ebmlElement(mkvIdDuration, binary.BigEndian.AppendUint64(nil, math.Float64bits(90000))),
+		)
+		tracks := ebmlElement(mkvIdTracks,
+			ebmlElement(mkvIdTrackEntry,
+				ebmlElement(mkvIdTrackType, ebmlUint(2)),
+				ebmlElement(mkvIdCodecID, []byte("A_AAC")),
+			),
+			ebmlElement(mkvIdTrackEntry,
+				ebmlElement(mkvIdTrackType, ebmlUint(1)),
+				ebmlElement(mkvIdCodecID, []byte("V_MPEGH/ISO/HEVC")),
+				ebmlElement(mkvIdVideo,
+					ebmlElement(mkvIdPixelWidth, ebmlUint(3840)),
+					ebmlElement(mkvIdPixelHeight, ebmlUint(2160)),
+				),
+			),
+		)
+		cluster := ebmlElement(mkvIdCluster, make([]byte, 64))
+		data := slices.Concat(header, ebmlElement(mkvIdSegment, info, tracks, cluster))
+
+		mediaInfo, err := ReadMediaInfo(bytes.NewReader(data), int64(len(data)))
+		require.NoError(t, err)
+		assert.Equal(t, &MediaInfo{
+			Container:  ContainerMatroska,
+			VideoCodec: VideoCodecHEVC,
+			Width:      3840,
+			Height:     2160,
+			Duration:   90 * time.Second,
+		}, mediaInfo)
+	})
+
+	t.Run("WebM", func(t *testing.T) {
+		header := ebmlElement(mkvIdEBML, ebmlElement(mkvIdDocType, []byte("webm")))
+		tracks := ebmlElement(mkvIdTracks,
+			ebmlElement(mkvIdTrackEntry,
+				ebmlElement(mkvIdTrackType, ebmlUint(1)),
+				ebmlElement(mkvIdCodecID, []byte("V_VP9")),
+			),
+		)
+		data := slices.Concat(header, ebmlElement(mkvIdSegment, tracks))
+
+		mediaInfo, err := ReadMediaInfo(bytes.NewReader(data), int64(len(data)))
+		require.NoError(t, err)
+		assert.Equal(t, ContainerWebM, mediaInfo.Container)
+		assert.Equal(t, VideoCodecVP9, mediaInfo.VideoCodec)
+	})
+
+	t.Run("MP4", func(t *testing.T) {
+		movieHeader := make([]byte, 20)
+		binary.BigEndian.PutUint32(movieHeader[12:16], 1000)
+		binary.BigEndian.PutUint32(movieHeader[16:20], 5400000)
+
+		track := func(handler, codec string, width, height uint32) []byte {
+			return mp4TestBox("trak",
+				mp4TestBox("tkhd", mp4TrackHeader(width, height)),
+				mp4TestBox("mdia",
+					mp4TestBox("hdlr", mp4Handler(handler)),
+					mp4TestBox("minf", mp4TestBox("stbl", mp4TestBox("stsd", mp4SampleDescription(codec)))),
+				),
+			)
+		}
+		// movie box after the media data, as left by some muxers
+		data := slices.Concat(
+			mp4TestBox("ftyp", []byte("isom"), make([]byte, 4)),
+			mp4TestBox("mdat", make([]byte, 64)),
+			mp4TestBox("moov",
+				mp4TestBox("mvhd", movieHeader),
+				track("soun", "mp4a", 0, 0),
+				track("vide", "avc1", 1920, 1080),
+			),
+		)
+
+		mediaInfo, err := ReadMediaInfo(seekOnlyReader{bytes.NewReader(data)}, int64(len(data)))
+		require.NoError(t, err)
+		assert.Equal(t, &MediaInfo{
+			Container:  ContainerMP4,
+			VideoCodec: VideoCodecH264,
+			Width:      1920,
+			Height:     1080,
+			Duration:   90 * time.Minute,
+		}, mediaInfo)
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		data := []byte("not a video file at all")
+		_, err := ReadMediaInfo(bytes.NewReader(data), int64(len(data)))
+		assert.ErrorIs(t, err, ErrUnsupportedContainer)
+	})
+}
@@ -0,0 +1,121 @@
+package usenet_pool
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// memoryBudget bounds the bytes of the segments in flight across all streams,
+// from dispatch until read. A reservation is granted while the budget is not
+// exhausted, so a single segment larger than the remaining budget does not
+// block forever. The limit is re-evaluated on every acquire.
+type memoryBudget struct {
+	mu      sync.Mutex
+	used    int64
+	waiters []chan struct{}
+	limit   func() int64
+}
+
+func newMemoryBudget(limit func() int64) *memoryBudget {
+	return &memoryBudget{limit: limit}
+}
+
+func (b *memoryBudget) Acquire(ctx context.Context, n int64) error {
+	if b == nil {
+		return nil
+	}
+
+	for {
+		b.mu.Lock()
+		if limit := b.limit(); limit <= 0 || b.used < limit {
+			b.used += n
+			b.mu.Unlock()
+			return nil
+		}
+		ready := make(chan struct{})
+		b.waiters = append(b.waiters, ready)
+		b.mu.Unlock()
+
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			b.mu.Lock()
+			if idx := slices.Index(b.waiters, ready); idx != -1 {
+				b.waiters = slices.Delete(b.waiters, idx, idx+1)
+			}
+			b.mu.Unlock()
+			return ctx.Err()
+		}
+	}
+}
+
+func (b *memoryBudget) Release(n int64) {
+	if b == nil || n == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.used -= n
+	// freed bytes may fit more than one waiter
+	for _, ready := range b.waiters {
+		close(ready)
+	}
+	b.waiters = nil
+}
+
+func (b *memoryBudget) Used() int64 {
+	if b == nil {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.used
+}
+
+// streamMemoryBudget tracks the reservations of a single stream, so that the
+// ones left unread are returned when the stream is closed.
+type streamMemoryBudget struct {
+	budget *memoryBudget
+	mu     sync.Mutex
+	held   int64
+	closed bool
+}
+
+func (sb *streamMemoryBudget) acquire(ctx context.Context, n int64) error {
+	if err := sb.budget.Acquire(ctx, n); err != nil {
+		return err
+	}
+
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	if sb.closed {
+		sb.budget.Release(n)
+		return context.Canceled
+	}
+	sb.held += n
+	return nil
+}
+
+func (sb *streamMemoryBudget) release(n int64) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	n = min(n, sb.held)
+	sb.held -= n
+	sb.budget.Release(n)
+}
+
+func (sb *streamMemoryBudget) releaseAll() {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	sb.closed = true
+	sb.budget.Release(sb.held)
+	sb.held = 0
+}
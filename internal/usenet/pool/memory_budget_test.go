@@ -0,0 +1,121 @@
+package usenet_pool
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/nntp/nntptest"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryBudget(t *testing.T) {
+	t.Run("BlocksWhenExhausted", func(t *testing.T) {
+		b := newMemoryBudget(func() int64 { return 100 })
+
+		require.NoError(t, b.Acquire(t.Context(), 60))
+		// granted while not exhausted, even if it overshoots
+		require.NoError(t, b.Acquire(t.Context(), 60))
+
+		acquired := make(chan struct{})
+		go func() {
+			if b.Acquire(context.Background(), 10) == nil {
+				close(acquired)
+			}
+		}()
+
+		select {
+		case <-acquired:
+			t.Fatal("acquired beyond budget")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		b.Release(60)
+
+		select {
+		case <-acquired:
+		case <-time.After(time.Second):
+			t.Fatal("not woken up after release")
+		}
+		assert.Equal(t, int64(70), b.Used())
+	})
+
+	t.Run("RespectsContextCancellation", func(t *testing.T) {
+		b := newMemoryBudget(func() int64 { return 10 })
+		require.NoError(t, b.Acquire(t.Context(), 10))
+
+		ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, b.Acquire(ctx, 10), context.DeadlineExceeded)
+		assert.Empty(t, b.waiters)
+	})
+
+	t.Run("Unlimited", func(t *testing.T) {
+		b := newMemoryBudget(func() int64 { return 0 })
+		for range 10 {
+			require.NoError(t, b.Acquire(t.Context(), 1000))
+		}
+	})
+
+	t.Run("StreamReleasesUnread", func(t *testing.T) {
+		b := newMemoryBudget(func() int64 { return 100 })
+		sb := &streamMemoryBudget{budget: b}
+
+		require.NoError(t, sb.acquire(t.Context(), 30))
+		require.NoError(t, sb.acquire(t.Context(), 30))
+		sb.release(30)
+		assert.Equal(t, int64(30), b.Used())
+
+		sb.releaseAll()
+		assert.Equal(t, int64(0), b.Used())
+
+		assert.Error(t, sb.acquire(t.Context(), 30))
+		assert.Equal(t, int64(0), b.Used())
+	})
+}
+
+func TestSegmentsStreamMemoryBudget(t *testing.T) {
+	data := makeTestBytes(300)
+
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.SetResponse("GROUP alt.binaries.test", "211 1 1 1 alt.binaries.test")
+	segments := []nzb.Segment{}
+	for i := range 3 {
+		messageId := fmt.Sprintf("seg%d@test.com", i+1)
+		encoded := encodeYenc(data[i*100:(i+1)*100], "movie.mkv", i+1, 3, int64(len(data)), int64(i*100+1))
+		server.SetResponse("BODY <"+messageId+">", "222 0 <"+messageId+">", strings.Split(strings.TrimSpace(string(encoded)), "\r\n"))
+		segments = append(segments, nzb.Segment{MessageId: messageId, Bytes: int64(len(encoded)), Number: i + 1})
+	}
+	server.Start(t)
+
+	usenetPool := createTestPool(t, server)
+	usenetPool.segmentCache = NewSegmentCache(10*1024*1024, t.TempDir())
+	// exhausted by a single segment
+	usenetPool.memoryBudget = newMemoryBudget(func() int64 { return 1 })
+
+	t.Run("ReadAll", func(t *testing.T) {
+		stream := NewSegmentsStream(t.Context(), usenetPool, segments, []string{"alt.binaries.test"}, 10*1024*1024)
+		got, err := io.ReadAll(stream)
+		require.NoError(t, err)
+		assert.Equal(t, data, got)
+		require.NoError(t, stream.Close())
+		assert.Equal(t, int64(0), usenetPool.memoryBudget.Used())
+	})
+
+	t.Run("CloseUnread", func(t *testing.T) {
+		stream := NewSegmentsStream(t.Context(), usenetPool, segments, []string{"alt.binaries.test"}, 10*1024*1024)
+		buf := make([]byte, 10)
+		_, err := io.ReadFull(stream, buf)
+		require.NoError(t, err)
+		require.NoError(t, stream.Close())
+		// the dispatcher may still be returning a reservation
+		assert.Eventually(t, func() bool {
+			return usenetPool.memoryBudget.Used() == 0
+		}, time.Second, 10*time.Millisecond)
+	})
+}
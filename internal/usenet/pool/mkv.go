@@ -0,0 +1,533 @@
+package usenet_pool
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+var ErrNotMatroska = errors.New("usenet: not a matroska file")
+
+// EBML element ids, with the length marker kept
+const (
+	mkvIdEBML            = 0x1A45DFA3
+	mkvIdSegment         = 0x18538067
+	mkvIdSeekHead        = 0x114D9B74
+	mkvIdSeek            = 0x4DBB
+	mkvIdSeekID          = 0x53AB
+	mkvIdSeekPosition    = 0x53AC
+	mkvIdCluster         = 0x1F43B675
+	mkvIdInfo            = 0x1549A966
+	mkvIdTimestampScale  = 0x2AD7B1
+	mkvIdDuration        = 0x4489
+	mkvIdAttachments     = 0x1941A469
+	mkvIdAttachedFile    = 0x61A7
+	mkvIdFileDescription = 0x467E
+	mkvIdFileName        = 0x466E
+	mkvIdFileMimeType    = 0x4660
+	mkvIdFileData        = 0x465C
+	mkvIdFileUID         = 0x46AE
+	mkvIdDocType         = 0x4282
+	mkvIdTracks          = 0x1654AE6B
+	mkvIdTrackEntry      = 0xAE
+	mkvIdTrackType       = 0x83
+	mkvIdCodecID         = 0x86
+	mkvIdVideo           = 0xE0
+	mkvIdPixelWidth      = 0xB0
+	mkvIdPixelHeight     = 0xBA
+)
+
+const mkvTrackTypeVideo = 1
+
+const (
+	// max length of an element header: 4 bytes id + 8 bytes size
+	mkvMaxElementHeaderSize = 12
+	// top-level elements looked at when there is no usable seek head
+	mkvMaxTopLevelScan = 64
+	// string/uint elements larger than this are rejected
+	mkvMaxSmallElementSize = 4096
+)
+
+type MKVAttachment struct {
+	UID         uint64
+	Name        string
+	MimeType    string
+	Description string
+	// position of the attachment data in the file
+	Offset int64
+	Size   int64
+}
+
+type mkvElement struct {
+	id         uint32
+	offset     int64 // offset of the element header
+	dataOffset int64
+	dataSize   int64 // -1 for unknown size
+}
+
+func (e *mkvElement) end() int64 {
+	return e.dataOffset + e.dataSize
+}
+
+type mkvReader struct {
+	r    io.ReadSeeker
+	size int64
+}
+
+func (mr *mkvReader) readAt(p []byte, off int64) (int, error) {
+	return readAtOffset(mr.r, p, off)
+}
+
+func readEBMLVint(b []byte, keepMarker bool) (uint64, int, bool) {
+	if len(b) == 0 || b[0] == 0 {
+		return 0, 0, false
+	}
+	length := 1
+	for mask := byte(0x80); b[0]&mask == 0; mask >>= 1 {
+		length++
+	}
+	if length > len(b) {
+		return 0, 0, false
+	}
+	value := uint64(b[0])
+	if !keepMarker {
+		value &= uint64(0xFF >> length)
+	}
+	for i := 1; i < length; i++ {
+		value = value<<8 | uint64(b[i])
+	}
+	return value, length, true
+}
+
+func (mr *mkvReader) readElement(off int64) (*mkvElement, error) {
+	n := min(int64(mkvMaxElementHeaderSize), mr.size-off)
+	if n < 2 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	buf := make([]byte, n)
+	if _, err := mr.readAt(buf, off); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	id, idLen, ok := readEBMLVint(buf, true)
+	if !ok || idLen > 4 {
+		return nil, fmt.Errorf("mkv: invalid element id at %d", off)
+	}
+	size, sizeLen, ok := readEBMLVint(buf[idLen:], false)
+	if !ok {
+		return nil, fmt.Errorf("mkv: invalid element size at %d", off)
+	}
+
+	el := &mkvElement{
+		id:         uint32(id),
+		offset:     off,
+		dataOffset: off + int64(idLen+sizeLen),
+		dataSize:   int64(size),
+	}
+	// all data bits set means unknown size
+	if size == 1<<(7*sizeLen)-1 {
+		el.dataSize = -1
+	} else if el.end() > mr.size {
+		return nil, fmt.Errorf("mkv: element at %d exceeds file size", off)
+	}
+	return el, nil
+}
+
+func (mr *mkvReader) readData(el *mkvElement) ([]byte, error) {
+	if el.dataSize < 0 || el.dataSize > mkvMaxSmallElementSize {
+		return nil, fmt.Errorf("mkv: unexpected element size at %d", el.offset)
+	}
+	buf := make([]byte, el.dataSize)
+	if _, err := mr.readAt(buf, el.dataOffset); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func decodeEBMLUint(b []byte) uint64 {
+	var value uint64
+	for _, c := range b {
+		value = value<<8 | uint64(c)
+	}
+	return value
+}
+
+// children calls fn for every child of the master element el
+func (mr *mkvReader) children(el *mkvElement, fn func(child *mkvElement) error) error {
+	end := el.end()
+	if el.dataSize < 0 {
+		end = mr.size
+	}
+	for off := el.dataOffset; off < end; {
+		child, err := mr.readElement(off)
+		if err != nil {
+			return err
+		}
+		if err := fn(child); err != nil {
+			return err
+		}
+		if child.dataSize < 0 {
+			return nil
+		}
+		off = child.end()
+	}
+	return nil
+}
+
+// seekPositions returns the positions referenced by the seek head, relative
+// to the segment data.
+func (mr *mkvReader) seekPositions(seekHead *mkvElement) (map[uint32][]int64, error) {
+	positions := map[uint32][]int64{}
+	err := mr.children(seekHead, func(seek *mkvElement) error {
+		if seek.id != mkvIdSeek {
+			return nil
+		}
+		var id uint32
+		var position int64 = -1
+		err := mr.children(seek, func(child *mkvElement) error {
+			switch child.id {
+			case mkvIdSeekID, mkvIdSeekPosition:
+				data, err := mr.readData(child)
+				if err != nil {
+					return err
+				}
+				if child.id == mkvIdSeekID {
+					id = uint32(decodeEBMLUint(data))
+				} else {
+					position = int64(decodeEBMLUint(data))
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if id != 0 && position >= 0 {
+			positions[id] = append(positions[id], position)
+		}
+		return nil
+	})
+	return positions, err
+}
+
+// findTopLevel looks for the top-level element with the given id in the
+// segment, using the seek head when available.
+func (mr *mkvReader) findTopLevel(segment *mkvElement, id uint32) (*mkvElement, error) {
+	first, err := mr.readElement(segment.dataOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	if first.id == mkvIdSeekHead {
+		seekHeads := []*mkvElement{first}
+		visited := map[int64]bool{first.offset: true}
+		for len(seekHeads) > 0 {
+			seekHead := seekHeads[0]
+			seekHeads = seekHeads[1:]
+			positions, err := mr.seekPositions(seekHead)
+			if err != nil {
+				return nil, err
+			}
+			for _, pos := range positions[id] {
+				el, err := mr.readElement(segment.dataOffset + pos)
+				if err == nil && el.id == id {
+					return el, nil
+				}
+			}
+			// seek head may point to another seek head, e.g. at the end of file
+			for _, pos := range positions[mkvIdSeekHead] {
+				off := segment.dataOffset + pos
+				if visited[off] {
+					continue
+				}
+				visited[off] = true
+				if el, err := mr.readElement(off); err == nil && el.id == mkvIdSeekHead {
+					seekHeads = append(seekHeads, el)
+				}
+			}
+		}
+	}
+
+	// no usable seek head, look at the top-level elements before the clusters
+	segmentEnd := segment.end()
+	if segment.dataSize < 0 {
+		segmentEnd = mr.size
+	}
+	off := segment.dataOffset
+	for range mkvMaxTopLevelScan {
+		el, err := mr.readElement(off)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case el.id == id:
+			return el, nil
+		case el.id == mkvIdCluster, el.dataSize < 0:
+			return nil, nil
+		}
+		off = el.end()
+		if off >= segmentEnd {
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
+
+func (mr *mkvReader) readAttachedFile(el *mkvElement) (*MKVAttachment, error) {
+	att := &MKVAttachment{}
+	err := mr.children(el, func(child *mkvElement) error {
+		switch child.id {
+		case mkvIdFileData:
+			att.Offset = child.dataOffset
+			att.Size = child.dataSize
+		case mkvIdFileName, mkvIdFileMimeType, mkvIdFileDescription, mkvIdFileUID:
+			data, err := mr.readData(child)
+			if err != nil {
+				return err
+			}
+			switch child.id {
+			case mkvIdFileName:
+				att.Name = string(data)
+			case mkvIdFileMimeType:
+				att.MimeType = string(data)
+			case mkvIdFileDescription:
+				att.Description = string(data)
+			case mkvIdFileUID:
+				att.UID = decodeEBMLUint(data)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return att, nil
+}
+
+func (mr *mkvReader) readSegment() (*mkvElement, error) {
+	header, err := mr.readElement(0)
+	if err != nil || header.id != mkvIdEBML || header.dataSize < 0 {
+		return nil, ErrNotMatroska
+	}
+
+	segment, err := mr.readElement(header.end())
+	if err != nil {
+		return nil, err
+	}
+	if segment.id != mkvIdSegment {
+		return nil, ErrNotMatroska
+	}
+	return segment, nil
+}
+
+// ReadMKVAttachments lists the attached files (e.g. fonts) of a matroska
+// file. Only the element headers and the small metadata elements are read,
+// the attachment data is located by offset.
+func ReadMKVAttachments(r io.ReadSeeker, size int64) ([]MKVAttachment, error) {
+	mr := &mkvReader{r: r, size: size}
+
+	segment, err := mr.readSegment()
+	if err != nil {
+		return nil, err
+	}
+
+	attachments, err := mr.findTopLevel(segment, mkvIdAttachments)
+	if err != nil {
+		return nil, err
+	}
+	if attachments == nil {
+		return []MKVAttachment{}, nil
+	}
+
+	files := []MKVAttachment{}
+	err = mr.children(attachments, func(child *mkvElement) error {
+		if child.id != mkvIdAttachedFile {
+			return nil
+		}
+		att, err := mr.readAttachedFile(child)
+		if err != nil {
+			return err
+		}
+		if att.Name != "" {
+			files = append(files, *att)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// ReadMKVDuration reads the duration from the segment info of a matroska
+// file. Returns 0 if the duration is not set.
+func ReadMKVDuration(r io.ReadSeeker, size int64) (time.Duration, error) {
+	mr := &mkvReader{r: r, size: size}
+
+	segment, err := mr.readSegment()
+	if err != nil {
+		return 0, err
+	}
+	return mr.readDuration(segment)
+}
+
+func (mr *mkvReader) readDuration(segment *mkvElement) (time.Duration, error) {
+	info, err := mr.findTopLevel(segment, mkvIdInfo)
+	if err != nil || info == nil {
+		return 0, err
+	}
+
+	// nanoseconds per tick
+	scale := uint64(1000000)
+	duration := float64(0)
+	err = mr.children(info, func(child *mkvElement) error {
+		switch child.id {
+		case mkvIdTimestampScale, mkvIdDuration:
+			data, err := mr.readData(child)
+			if err != nil {
+				return err
+			}
+			if child.id == mkvIdTimestampScale {
+				scale = decodeEBMLUint(data)
+				return nil
+			}
+			switch len(data) {
+			case 4:
+				duration = float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+			case 8:
+				duration = math.Float64frombits(binary.BigEndian.Uint64(data))
+			default:
+				return fmt.Errorf("mkv: invalid duration size at %d", child.offset)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(duration * float64(scale)), nil
+}
+
+// readDocType reads the doc type from the ebml header, e.g. "matroska" or
+// "webm".
+func (mr *mkvReader) readDocType() (string, error) {
+	header, err := mr.readElement(0)
+	if err != nil {
+		return "", err
+	}
+	docType := ""
+	err = mr.children(header, func(child *mkvElement) error {
+		if child.id != mkvIdDocType {
+			return nil
+		}
+		data, err := mr.readData(child)
+		if err != nil {
+			return err
+		}
+		docType = string(data)
+		return nil
+	})
+	return docType, err
+}
+
+// readVideoTrack fills in the codec and resolution of the first video track.
+func (mr *mkvReader) readVideoTrack(segment *mkvElement, info *MediaInfo) error {
+	tracks, err := mr.findTopLevel(segment, mkvIdTracks)
+	if err != nil || tracks == nil {
+		return err
+	}
+
+	found := false
+	return mr.children(tracks, func(entry *mkvElement) error {
+		if found || entry.id != mkvIdTrackEntry {
+			return nil
+		}
+		var trackType uint64
+		var codecId string
+		var width, height uint64
+		err := mr.children(entry, func(child *mkvElement) error {
+			switch child.id {
+			case mkvIdTrackType, mkvIdCodecID:
+				data, err := mr.readData(child)
+				if err != nil {
+					return err
+				}
+				if child.id == mkvIdTrackType {
+					trackType = decodeEBMLUint(data)
+				} else {
+					codecId = string(data)
+				}
+			case mkvIdVideo:
+				return mr.children(child, func(child *mkvElement) error {
+					switch child.id {
+					case mkvIdPixelWidth, mkvIdPixelHeight:
+						data, err := mr.readData(child)
+						if err != nil {
+							return err
+						}
+						if child.id == mkvIdPixelWidth {
+							width = decodeEBMLUint(data)
+						} else {
+							height = decodeEBMLUint(data)
+						}
+					}
+					return nil
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if trackType != mkvTrackTypeVideo {
+			return nil
+		}
+		found = true
+		info.VideoCodec = getMKVVideoCodec(codecId)
+		info.Width = int(width)
+		info.Height = int(height)
+		return nil
+	})
+}
+
+var mkvVideoCodecById = map[string]string{
+	"V_MPEG4/ISO/AVC":  VideoCodecH264,
+	"V_MPEGH/ISO/HEVC": VideoCodecHEVC,
+	"V_AV1":            VideoCodecAV1,
+	"V_VP8":            VideoCodecVP8,
+	"V_VP9":            VideoCodecVP9,
+	"V_MPEG2":          VideoCodecMPEG2,
+	"V_MPEG4/ISO/ASP":  VideoCodecMPEG4,
+	"V_MPEG4/ISO/SP":   VideoCodecMPEG4,
+}
+
+func getMKVVideoCodec(codecId string) string {
+	if codec, ok := mkvVideoCodecById[codecId]; ok {
+		return codec
+	}
+	return codecId
+}
+
+func readMKVMediaInfo(r io.ReadSeeker, size int64) (*MediaInfo, error) {
+	mr := &mkvReader{r: r, size: size}
+
+	segment, err := mr.readSegment()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &MediaInfo{Container: ContainerMatroska}
+	if docType, err := mr.readDocType(); err == nil && docType == "webm" {
+		info.Container = ContainerWebM
+	}
+	if info.Duration, err = mr.readDuration(segment); err != nil {
+		return nil, err
+	}
+	if err := mr.readVideoTrack(segment, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
@@ -0,0 +1,136 @@
+package usenet_pool
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ebmlElement(id uint32, data ...[]byte) []byte {
+	var buf bytes.Buffer
+	switch {
+	case id > 0xFFFFFF:
+		buf.Write([]byte{byte(id >> 24), byte(id >> 16), byte(id >> 8), byte(id)})
+	case id > 0xFFFF:
+		buf.Write([]byte{byte(id >> 16), byte(id >> 8), byte(id)})
+	case id > 0xFF:
+		buf.Write([]byte{byte(id >> 8), byte(id)})
+	default:
+		buf.WriteByte(byte(id))
+	}
+	body := bytes.Join(data, nil)
+	// always use 8 byte size, so that offsets are easy to compute
+	size := uint64(len(body))
+	buf.Write([]byte{0x01, byte(size >> 48), byte(size >> 40), byte(size >> 32), byte(size >> 24), byte(size >> 16), byte(size >> 8), byte(size)})
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+func ebmlUint(v uint64) []byte {
+	return []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+}
+
+type seekOnlyReader struct {
+	io.ReadSeeker
+}
+
+func TestReadMKVAttachments(t *testing.T) {
+	header := ebmlElement(mkvIdEBML, ebmlElement(0x4282, []byte("matroska")))
+	fontData := []byte("font-data")
+	attachments := ebmlElement(mkvIdAttachments,
+		ebmlElement(mkvIdAttachedFile,
+			ebmlElement(mkvIdFileName, []byte("Arial.ttf")),
+			ebmlElement(mkvIdFileMimeType, []byte("font/ttf")),
+			ebmlElement(mkvIdFileUID, ebmlUint(42)),
+			ebmlElement(mkvIdFileData, fontData),
+		),
+	)
+	cluster := ebmlElement(mkvIdCluster, make([]byte, 64))
+
+	assertAttachments := func(t *testing.T, data []byte) {
+		for _, r := range []io.ReadSeeker{bytes.NewReader(data), seekOnlyReader{bytes.NewReader(data)}} {
+			files, err := ReadMKVAttachments(r, int64(len(data)))
+			require.NoError(t, err)
+			require.Len(t, files, 1)
+			att := files[0]
+			assert.Equal(t, "Arial.ttf", att.Name)
+			assert.Equal(t, "font/ttf", att.MimeType)
+			assert.Equal(t, uint64(42), att.UID)
+			assert.Equal(t, int64(len(fontData)), att.Size)
+			assert.Equal(t, fontData, data[att.Offset:att.Offset+att.Size])
+		}
+	}
+
+	t.Run("SeekHead", func(t *testing.T) {
+		seekHead := func(pos uint64) []byte {
+			return ebmlElement(mkvIdSeekHead,
+				ebmlElement(mkvIdSeek,
+					ebmlElement(mkvIdSeekID, ebmlUint(mkvIdAttachments)),
+					ebmlElement(mkvIdSeekPosition, ebmlUint(pos)),
+				),
+			)
+		}
+		// attachments after the clusters, only reachable through the seek head
+		pos := uint64(len(seekHead(0)) + len(cluster))
+		data := slices.Concat(header, ebmlElement(mkvIdSegment, seekHead(pos), cluster, attachments))
+		assertAttachments(t, data)
+	})
+
+	t.Run("Scan", func(t *testing.T) {
+		data := slices.Concat(header, ebmlElement(mkvIdSegment, attachments, cluster))
+		assertAttachments(t, data)
+	})
+
+	t.Run("NoAttachments", func(t *testing.T) {
+		data := slices.Concat(header, ebmlElement(mkvIdSegment, cluster))
+		files, err := ReadMKVAttachments(bytes.NewReader(data), int64(len(data)))
+		require.NoError(t, err)
+		assert.Empty(t, files)
+	})
+
+	t.Run("NotMatroska", func(t *testing.T) {
+		data := []byte("not a matroska file")
+		_, err := ReadMKVAttachments(bytes.NewReader(data), int64(len(data)))
+		assert.ErrorIs(t, err, ErrNotMatroska)
+	})
+}
+
+func TestReadMKVDuration(t *testing.T) {
+	header := ebmlElement(mkvIdEBML, ebmlElement(0x4282, []byte("matroska")))
+	cluster := ebmlElement(mkvIdCluster, make([]byte, 64))
+
+	t.Run("Float64", func(t *testing.T) {
+		info := ebmlElement(mkvIdInfo,
+			ebmlElement(mkvIdTimestampScale, ebmlUint(1000000)),
+			ebmlElement(mkvIdDuration, binary.BigEndian.AppendUint64(nil, math.Float64bits(90000))),
+		)
+		data := slices.Concat(header, ebmlElement(mkvIdSegment, info, cluster))
+		duration, err := ReadMKVDuration(bytes.NewReader(data), int64(len(data)))
+		require.NoError(t, err)
+		assert.Equal(t, 90*time.Second, duration)
+	})
+
+	t.Run("Float32", func(t *testing.T) {
+		info := ebmlElement(mkvIdInfo,
+			ebmlElement(mkvIdDuration, binary.BigEndian.AppendUint32(nil, math.Float32bits(1500))),
+		)
+		data := slices.Concat(header, ebmlElement(mkvIdSegment, info, cluster))
+		duration, err := ReadMKVDuration(bytes.NewReader(data), int64(len(data)))
+		require.NoError(t, err)
+		assert.Equal(t, 1500*time.Millisecond, duration)
+	})
+
+	t.Run("NoInfo", func(t *testing.T) {
+		data := slices.Concat(header, ebmlElement(mkvIdSegment, cluster))
+		duration, err := ReadMKVDuration(bytes.NewReader(data), int64(len(data)))
+		require.NoError(t, err)
+		assert.Zero(t, duration)
+	})
+}
@@ -0,0 +1,259 @@
+package usenet_pool
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var errNotMP4 = errors.New("usenet: not a mp4 file")
+
+const (
+	// top-level boxes looked at for the movie box, e.g. after the media data
+	mp4MaxTopLevelScan = 64
+	// full boxes read in whole are expected to be smaller than this
+	mp4MaxSmallBoxSize = 4096
+)
+
+type mp4Box struct {
+	typ        string
+	offset     int64 // offset of the box header
+	dataOffset int64
+	dataSize   int64
+}
+
+func (b *mp4Box) end() int64 {
+	return b.dataOffset + b.dataSize
+}
+
+type mp4Reader struct {
+	r    io.ReadSeeker
+	size int64
+}
+
+func (mr *mp4Reader) readBox(off int64) (*mp4Box, error) {
+	n := min(16, mr.size-off)
+	if n < 8 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	buf := make([]byte, n)
+	if _, err := readAtOffset(mr.r, buf, off); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	box := &mp4Box{typ: string(buf[4:8]), offset: off}
+	size := int64(binary.BigEndian.Uint32(buf[0:4]))
+	switch size {
+	case 0:
+		// extends to the end of file
+		box.dataOffset = off + 8
+		box.dataSize = mr.size - box.dataOffset
+		return box, nil
+	case 1:
+		if n < 16 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		size = int64(binary.BigEndian.Uint64(buf[8:16]))
+		box.dataOffset = off + 16
+	default:
+		box.dataOffset = off + 8
+	}
+	box.dataSize = off + size - box.dataOffset
+	if box.dataSize < 0 || box.end() > mr.size {
+		return nil, fmt.Errorf("mp4: invalid box size at %d", off)
+	}
+	return box, nil
+}
+
+func (mr *mp4Reader) readData(box *mp4Box) ([]byte, error) {
+	if box.dataSize > mp4MaxSmallBoxSize {
+		return nil, fmt.Errorf("mp4: unexpected box size at %d", box.offset)
+	}
+	buf := make([]byte, box.dataSize)
+	if _, err := readAtOffset(mr.r, buf, box.dataOffset); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// children calls fn for every child of the container box
+func (mr *mp4Reader) children(box *mp4Box, fn func(child *mp4Box) error) error {
+	for off := box.dataOffset; off+8 <= box.end(); {
+		child, err := mr.readBox(off)
+		if err != nil {
+			return err
+		}
+		if err := fn(child); err != nil {
+			return err
+		}
+		off = child.end()
+	}
+	return nil
+}
+
+func (mr *mp4Reader) findMovie() (*mp4Box, error) {
+	first, err := mr.readBox(0)
+	if err != nil || first.typ != "ftyp" {
+		return nil, errNotMP4
+	}
+	off := first.end()
+	for range mp4MaxTopLevelScan {
+		if off >= mr.size {
+			break
+		}
+		box, err := mr.readBox(off)
+		if err != nil {
+			return nil, err
+		}
+		if box.typ == "moov" {
+			return box, nil
+		}
+		off = box.end()
+	}
+	return nil, fmt.Errorf("mp4: movie box not found")
+}
+
+// readMP4MovieDuration reads the duration from the movie header.
+func readMP4MovieDuration(data []byte) (time.Duration, error) {
+	var timescale, duration uint64
+	switch {
+	case len(data) >= 32 && data[0] == 1:
+		timescale = uint64(binary.BigEndian.Uint32(data[20:24]))
+		duration = binary.BigEndian.Uint64(data[24:32])
+	case len(data) >= 20 && data[0] == 0:
+		timescale = uint64(binary.BigEndian.Uint32(data[12:16]))
+		duration = uint64(binary.BigEndian.Uint32(data[16:20]))
+	default:
+		return 0, fmt.Errorf("mp4: invalid movie header")
+	}
+	if timescale == 0 {
+		return 0, nil
+	}
+	return time.Duration(float64(duration) / float64(timescale) * float64(time.Second)), nil
+}
+
+// readMP4TrackSize reads the presentation size, in 16.16 fixed point, from
+// the track header.
+func readMP4TrackSize(data []byte) (int, int) {
+	offset := 76
+	if len(data) > 0 && data[0] == 1 {
+		offset = 88
+	}
+	if len(data) < offset+8 {
+		return 0, 0
+	}
+	width := binary.BigEndian.Uint32(data[offset : offset+4])
+	height := binary.BigEndian.Uint32(data[offset+4 : offset+8])
+	return int(width >> 16), int(height >> 16)
+}
+
+type mp4Track struct {
+	handler string
+	codec   string
+	width   int
+	height  int
+}
+
+func (mr *mp4Reader) readTrack(trak *mp4Box) (*mp4Track, error) {
+	track := &mp4Track{}
+	var walk func(box *mp4Box) error
+	walk = func(box *mp4Box) error {
+		return mr.children(box, func(child *mp4Box) error {
+			switch child.typ {
+			case "mdia", "minf", "stbl":
+				return walk(child)
+			case "tkhd":
+				data, err := mr.readData(child)
+				if err != nil {
+					return err
+				}
+				track.width, track.height = readMP4TrackSize(data)
+			case "hdlr":
+				data, err := mr.readData(child)
+				if err != nil {
+					return err
+				}
+				if len(data) >= 12 {
+					track.handler = string(data[8:12])
+				}
+			case "stsd":
+				// the first sample entry follows the version, flags and entry count
+				if child.dataSize < 16 {
+					return nil
+				}
+				entry, err := mr.readBox(child.dataOffset + 8)
+				if err != nil {
+					return err
+				}
+				track.codec = entry.typ
+			}
+			return nil
+		})
+	}
+	if err := walk(trak); err != nil {
+		return nil, err
+	}
+	return track, nil
+}
+
+var mp4VideoCodecByType = map[string]string{
+	"avc1": VideoCodecH264,
+	"avc3": VideoCodecH264,
+	"hvc1": VideoCodecHEVC,
+	"hev1": VideoCodecHEVC,
+	"av01": VideoCodecAV1,
+	"vp08": VideoCodecVP8,
+	"vp09": VideoCodecVP9,
+	"mp4v": VideoCodecMPEG4,
+}
+
+func getMP4VideoCodec(typ string) string {
+	if codec, ok := mp4VideoCodecByType[typ]; ok {
+		return codec
+	}
+	return typ
+}
+
+func readMP4MediaInfo(r io.ReadSeeker, size int64) (*MediaInfo, error) {
+	mr := &mp4Reader{r: r, size: size}
+
+	moov, err := mr.findMovie()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &MediaInfo{Container: ContainerMP4}
+	found := false
+	err = mr.children(moov, func(child *mp4Box) error {
+		switch child.typ {
+		case "mvhd":
+			data, err := mr.readData(child)
+			if err != nil {
+				return err
+			}
+			info.Duration, err = readMP4MovieDuration(data)
+			return err
+		case "trak":
+			if found {
+				return nil
+			}
+			track, err := mr.readTrack(child)
+			if err != nil {
+				return err
+			}
+			if track.handler == "vide" {
+				found = true
+				info.VideoCodec = getMP4VideoCodec(track.codec)
+				info.Width = track.width
+				info.Height = track.height
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
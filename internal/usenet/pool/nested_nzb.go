@@ -0,0 +1,167 @@
+package usenet_pool
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/cache"
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/server"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+)
+
+var ErrNestedNZB = NewError(server.ErrorCodeNoStreamable, "usenet: no video or archive files, only nested nzb")
+
+func isNZBFile(filename string) bool {
+	return strings.EqualFold(filepath.Ext(filename), ".nzb")
+}
+
+func hasNestedNZB(files []NZBContentFile) bool {
+	return slices.ContainsFunc(files, func(f NZBContentFile) bool {
+		return f.Type == NZBContentFileTypeNZB
+	})
+}
+
+type nestedNZBKey struct{}
+
+// withinNestedNZB marks the context as working on a nested NZB, which is not
+// followed any further.
+func withinNestedNZB(ctx context.Context) context.Context {
+	return context.WithValue(ctx, nestedNZBKey{}, true)
+}
+
+func isWithinNestedNZB(ctx context.Context) bool {
+	within, _ := ctx.Value(nestedNZBKey{}).(bool)
+	return within
+}
+
+func canFollowNestedNZB(ctx context.Context) bool {
+	return config.Newz.NZBFollowNested && !isWithinNestedNZB(ctx)
+}
+
+// nestedNZBCache holds the parsed nested nzbs by the message id of their first
+// segment, so that inspecting and then streaming one does not download and
+// parse it again.
+var nestedNZBCache = cache.NewLRUCache[*nzb.NZB](&cache.CacheConfig{
+	Name:     "newz_nested_nzb",
+	Lifetime: 1 * time.Hour,
+	MaxSize:  100,
+})
+
+// copyNestedNZB returns a copy of the cached nzb with its own files, which
+// memoize their size and message ids on first use.
+func copyNestedNZB(nzbDoc *nzb.NZB) *nzb.NZB {
+	c := *nzbDoc
+	c.Files = slices.Clone(nzbDoc.Files)
+	return &c
+}
+
+// fetchNestedNZB downloads the nzb carried as a file of the NZB and parses it,
+// subject to the same limits as an uploaded one.
+func (p *Pool) fetchNestedNZB(ctx context.Context, file *nzb.File) (*nzb.NZB, error) {
+	if file.SegmentCount() == 0 {
+		return nil, fmt.Errorf("nested nzb %s has no segments", file.Name())
+	}
+
+	cacheKey := file.Segments[0].MessageId
+	var cached *nzb.NZB
+	if nestedNZBCache.Get(cacheKey, &cached) {
+		return copyNestedNZB(cached), nil
+	}
+
+	maxSize := config.Newz.NZBFileMaxSize
+
+	stream, err := NewFileStream(ctx, p, file, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	if maxSize > 0 && stream.Size() > maxSize {
+		return nil, fmt.Errorf("nested nzb %s is too large: %d bytes (max %d)", file.Name(), stream.Size(), maxSize)
+	}
+
+	blob, err := io.ReadAll(stream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read nested nzb %s: %w", file.Name(), err)
+	}
+
+	nzbDoc, err := nzb.ParseBytes(blob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse nested nzb %s: %w", file.Name(), err)
+	}
+	if err := CheckNZBLimits(nzbDoc); err != nil {
+		return nil, fmt.Errorf("nested nzb %s: %w", file.Name(), err)
+	}
+
+	nestedNZBCache.Add(cacheKey, copyNestedNZB(nzbDoc))
+	return nzbDoc, nil
+}
+
+// inspectNestedNZB lists the content of the nested nzb under its entry, when
+// following it is enabled.
+func (p *Pool) inspectNestedNZB(ctx context.Context, file *nzb.File, password string) NZBContentFile {
+	entry := NZBContentFile{
+		Type: NZBContentFileTypeNZB,
+		Name: file.Name(),
+		Size: file.Size(),
+	}
+
+	if !canFollowNestedNZB(ctx) {
+		entry.addReason(NZBContentFileReasonNestedNZB, "nested nzb is not followed")
+		return entry
+	}
+
+	nzbDoc, err := p.fetchNestedNZB(ctx, file)
+	if err != nil {
+		inspectLog.Warn("failed to fetch nested nzb", "error", err, "name", file.Name())
+		entry.addReason(NZBContentFileReasonFetchFailed, err.Error())
+		return entry
+	}
+
+	content, err := p.InspectNZBContent(withinNestedNZB(ctx), nzbDoc, password)
+	if err != nil {
+		inspectLog.Warn("failed to inspect nested nzb", "error", err, "name", file.Name())
+		entry.addReason(NZBContentFileReasonOpenFailed, err.Error())
+		return entry
+	}
+
+	entry.Files = content.Files
+	entry.Streamable = content.Streamable
+	if !entry.Streamable {
+		entry.addReason(NZBContentFileReasonNestedNZB, "nested nzb has no streamable video")
+	}
+	return entry
+}
+
+// streamFromNestedNZB streams the target at the rest of the content path from
+// the nested nzb.
+func (p *Pool) streamFromNestedNZB(
+	ctx context.Context,
+	file *nzb.File,
+	contentFile *NZBContentFile,
+	targetParts []string,
+	conf *StreamConfig,
+) (*Stream, error) {
+	if !canFollowNestedNZB(ctx) {
+		return nil, ErrNestedNZB
+	}
+
+	nzbDoc, err := p.fetchNestedNZB(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+
+	innerConf := *conf
+	innerConf.ContentFiles = nil
+	if contentFile != nil {
+		innerConf.ContentFiles = contentFile.Files
+	}
+
+	return p.StreamByContentPath(withinNestedNZB(ctx), nzbDoc, joinContentPath(targetParts...), &innerConf)
+}
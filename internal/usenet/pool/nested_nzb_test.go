@@ -0,0 +1,130 @@
+package usenet_pool
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/logger"
+	"github.com/MunifTanjim/stremthru/internal/nntp"
+	"github.com/MunifTanjim/stremthru/internal/nntp/nntptest"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNestedNZB(t *testing.T) {
+	videoData := makeTestBytes(200)
+	videoEncoded := encodeYenc(videoData, "movie.mkv", 1, 1, int64(len(videoData)), 1)
+
+	innerNZB := fmt.Appendf(nil, `<?xml version="1.0" encoding="UTF-8"?>
+<nzb xmlns="http://www.newzbin.com/DTD/2003/nzb">
+  <file poster="test@example.com" date="1234567890" subject="Test - &quot;movie.mkv&quot; yEnc (1/1)">
+    <groups><group>alt.binaries.test</group></groups>
+    <segments><segment bytes="%d" number="1">movie@test.com</segment></segments>
+  </file>
+</nzb>
+`, len(videoEncoded))
+	innerEncoded := encodeYenc(innerNZB, "movie.nzb", 1, 1, int64(len(innerNZB)), 1)
+
+	largeNZB := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<nzb xmlns="http://www.newzbin.com/DTD/2003/nzb">
+  <file poster="test@example.com" date="1234567890" subject="Test - &quot;movie.cd1.mkv&quot; yEnc (1/1)">
+    <groups><group>alt.binaries.test</group></groups>
+    <segments><segment bytes="100" number="1">cd1@test.com</segment></segments>
+  </file>
+  <file poster="test@example.com" date="1234567890" subject="Test - &quot;movie.cd2.mkv&quot; yEnc (1/1)">
+    <groups><group>alt.binaries.test</group></groups>
+    <segments><segment bytes="100" number="1">cd2@test.com</segment></segments>
+  </file>
+</nzb>
+`)
+	largeEncoded := encodeYenc(largeNZB, "large.nzb", 1, 1, int64(len(largeNZB)), 1)
+
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.SetResponse("GROUP alt.binaries.test", "211 1 1 1 alt.binaries.test")
+	server.SetResponse("BODY <movie@test.com>", "222 0 <movie@test.com>", strings.Split(strings.TrimSpace(string(videoEncoded)), "\r\n"))
+	server.SetResponse("BODY <nzb@test.com>", "222 0 <nzb@test.com>", strings.Split(strings.TrimSpace(string(innerEncoded)), "\r\n"))
+	server.SetResponse("BODY <large@test.com>", "222 0 <large@test.com>", strings.Split(strings.TrimSpace(string(largeEncoded)), "\r\n"))
+	server.Start(t)
+
+	usenetPool := &Pool{
+		Log:          logger.Scoped("test/usenet/pool"),
+		providers:    []*providerPool{{Pool: nntptest.NewPool(t, server, &nntp.PoolConfig{})}},
+		segmentCache: NewSegmentCache(10*1024*1024, t.TempDir()),
+	}
+
+	nzbDoc := createTestNZB(nzb.File{
+		Subject:  `Test - "movie.nzb" yEnc (1/1)`,
+		Segments: []nzb.Segment{{MessageId: "nzb@test.com", Bytes: int64(len(innerEncoded)), Number: 1}},
+	})
+
+	prevFollowNested := config.Newz.NZBFollowNested
+	prevMaxFiles := config.Newz.NZBMaxFiles
+	t.Cleanup(func() {
+		config.Newz.NZBFollowNested = prevFollowNested
+		config.Newz.NZBMaxFiles = prevMaxFiles
+		nestedNZBCache.Remove("nzb@test.com")
+	})
+
+	t.Run("NotFollowed", func(t *testing.T) {
+		config.Newz.NZBFollowNested = false
+
+		content, err := usenetPool.InspectNZBContent(t.Context(), nzbDoc, "")
+		require.NoError(t, err)
+		assert.False(t, content.Streamable)
+		assert.ErrorIs(t, content.Err, ErrNestedNZB)
+		require.Len(t, content.Files, 1)
+		assert.Equal(t, NZBContentFileTypeNZB, content.Files[0].Type)
+		assert.Equal(t, NZBContentFileReasonNestedNZB, content.Files[0].Reasons[0].Code)
+
+		_, err = usenetPool.StreamByContentPath(t.Context(), nzbDoc, "movie.nzb::movie.mkv", nil)
+		assert.ErrorIs(t, err, ErrNestedNZB)
+	})
+
+	t.Run("Followed", func(t *testing.T) {
+		config.Newz.NZBFollowNested = true
+
+		content, err := usenetPool.InspectNZBContent(t.Context(), nzbDoc, "")
+		require.NoError(t, err)
+		assert.True(t, content.Streamable)
+		assert.NoError(t, content.Err)
+		assert.Equal(t, []string{"movie.nzb::movie.mkv"}, GetStreamableVideoContentPaths(content.Files))
+
+		stream, err := usenetPool.StreamByContentPath(t.Context(), nzbDoc, "movie.nzb::movie.mkv", &StreamConfig{ContentFiles: content.Files})
+		require.NoError(t, err)
+		defer stream.Close()
+
+		assert.Equal(t, "movie.nzb::movie.mkv", stream.Path)
+		data, err := io.ReadAll(stream)
+		require.NoError(t, err)
+		assert.Equal(t, videoData, data)
+	})
+	t.Run("Cached", func(t *testing.T) {
+		nestedNZBCache.Remove("nzb@test.com")
+
+		first, err := usenetPool.fetchNestedNZB(t.Context(), &nzbDoc.Files[0])
+		require.NoError(t, err)
+		assert.True(t, nestedNZBCache.Has("nzb@test.com"))
+
+		second, err := usenetPool.fetchNestedNZB(t.Context(), &nzbDoc.Files[0])
+		require.NoError(t, err)
+		assert.NotSame(t, first, second)
+		assert.Equal(t, first.Files[0].Name(), second.Files[0].Name())
+		assert.Equal(t, first.Files[0].Segments, second.Files[0].Segments)
+	})
+
+	t.Run("TooManyFiles", func(t *testing.T) {
+		config.Newz.NZBMaxFiles = 1
+
+		largeDoc := createTestNZB(nzb.File{
+			Subject:  `Test - "large.nzb" yEnc (1/1)`,
+			Segments: []nzb.Segment{{MessageId: "large@test.com", Bytes: int64(len(largeEncoded)), Number: 1}},
+		})
+		_, err := usenetPool.fetchNestedNZB(t.Context(), &largeDoc.Files[0])
+		assert.ErrorContains(t, err, "too many files")
+		assert.False(t, nestedNZBCache.Has("large@test.com"))
+	})
+}
@@ -0,0 +1,99 @@
+package usenet_pool
+
+import (
+	"context"
+	"errors"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+	"github.com/alitto/pond/v2"
+)
+
+type CheckHealthConfig struct {
+	SampleSize int // segments fetched across the whole nzb, evenly spaced
+}
+
+type NZBHealth struct {
+	Sampled     int `json:"sampled"`
+	Missing     int `json:"missing"`
+	CRCMismatch int `json:"crc_mismatch"`
+}
+
+func (h *NZBHealth) Failed() int {
+	return h.Missing + h.CRCMismatch
+}
+
+// Score is the fraction of the sampled segments that were fetched intact, or
+// -1 if nothing was sampled.
+func (h *NZBHealth) Score() float64 {
+	if h.Sampled == 0 {
+		return -1
+	}
+	return 1 - float64(h.Failed())/float64(h.Sampled)
+}
+
+// CheckHealth fetches a bounded sample of segments, spread across all the
+// files, and counts the ones missing on every provider or failing the CRC
+// check. Segments that could not be fetched for other reasons, e.g. timeout,
+// are left out of the sample.
+func (p *Pool) CheckHealth(
+	ctx context.Context,
+	nzbDoc *nzb.NZB,
+	conf *CheckHealthConfig,
+) (*NZBHealth, error) {
+	if conf == nil {
+		conf = &CheckHealthConfig{}
+	}
+	sampleSize := conf.SampleSize
+	if sampleSize <= 0 {
+		sampleSize = config.Newz.InspectHealthSample
+	}
+
+	if p.CountProviders() == 0 {
+		return nil, ErrNoProvidersConfigured
+	}
+
+	type segmentRef struct {
+		file    *nzb.File
+		segment *nzb.Segment
+	}
+	refs := []segmentRef{}
+	for i := range nzbDoc.Files {
+		f := &nzbDoc.Files[i]
+		for j := range f.Segments {
+			refs = append(refs, segmentRef{file: f, segment: &f.Segments[j]})
+		}
+	}
+
+	indices := sampleSegmentIndices(len(refs), sampleSize)
+	errs := make([]error, len(indices))
+	fetchPool := pond.NewPool(config.Newz.MaxConnectionPerStream)
+	for i, idx := range indices {
+		ref := refs[idx]
+		fetchPool.Submit(func() {
+			_, errs[i] = p.fetchSegment(ctx, ref.segment, ref.file.Groups)
+		})
+	}
+	fetchPool.StopAndWait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	health := &NZBHealth{}
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			health.Sampled++
+		case errors.Is(err, ErrArticleNotFound):
+			health.Sampled++
+			health.Missing++
+		case IsCRCMismatchError(err):
+			health.Sampled++
+			health.CRCMismatch++
+		default:
+			p.Log.Debug("check health - failed to fetch segment", "error", err)
+		}
+	}
+	return health, nil
+}
@@ -0,0 +1,60 @@
+package usenet_pool
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/nntp/nntptest"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckHealth(t *testing.T) {
+	data := makeTestBytes(100)
+	ok := encodeYenc(data, "movie.mkv", 1, 3, 300, 1)
+	corrupt := regexp.MustCompile(`pcrc32=[0-9a-fA-F]+`).ReplaceAll(encodeYenc(data, "movie.mkv", 3, 3, 300, 201), []byte("pcrc32=00000000"))
+
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.SetResponse("GROUP alt.binaries.test", "211 1 1 1 alt.binaries.test")
+	server.SetResponse("BODY <seg1@test.com>", "222 0 <seg1@test.com>", strings.Split(strings.TrimSpace(string(ok)), "\r\n"))
+	server.SetResponse("BODY <seg2@test.com>", "430 No Such Article")
+	server.SetResponse("BODY <seg3@test.com>", "222 0 <seg3@test.com>", strings.Split(strings.TrimSpace(string(corrupt)), "\r\n"))
+	server.Start(t)
+
+	usenetPool := createTestPool(t, server)
+	usenetPool.segmentCache = NewSegmentCache(10*1024*1024, t.TempDir())
+
+	nzbDoc := createTestNZB(nzb.File{
+		Subject: `Test - "movie.mkv" yEnc (1/3)`,
+		Segments: []nzb.Segment{
+			{MessageId: "seg1@test.com", Bytes: int64(len(ok)), Number: 1},
+			{MessageId: "seg2@test.com", Bytes: int64(len(ok)), Number: 2},
+			{MessageId: "seg3@test.com", Bytes: int64(len(corrupt)), Number: 3},
+		},
+	})
+
+	t.Run("All", func(t *testing.T) {
+		health, err := usenetPool.CheckHealth(t.Context(), nzbDoc, &CheckHealthConfig{SampleSize: 10})
+		require.NoError(t, err)
+		assert.Equal(t, 3, health.Sampled)
+		assert.Equal(t, 1, health.Missing)
+		assert.Equal(t, 1, health.CRCMismatch)
+		assert.InDelta(t, 1.0/3, health.Score(), 0.001)
+	})
+
+	t.Run("Bounded", func(t *testing.T) {
+		health, err := usenetPool.CheckHealth(t.Context(), nzbDoc, &CheckHealthConfig{SampleSize: 1})
+		require.NoError(t, err)
+		assert.Equal(t, 1, health.Sampled)
+		assert.Equal(t, 0, health.Failed())
+		assert.Equal(t, 1.0, health.Score())
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		health, err := usenetPool.CheckHealth(t.Context(), createTestNZB(), &CheckHealthConfig{SampleSize: 10})
+		require.NoError(t, err)
+		assert.Equal(t, -1.0, health.Score())
+	})
+}
@@ -0,0 +1,24 @@
+package usenet_pool
+
+import (
+	"fmt"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+)
+
+// CheckNZBLimits returns an error if the nzb has more files or segments than
+// allowed by the config.
+func CheckNZBLimits(nzbDoc *nzb.NZB) error {
+	if limit := config.Newz.NZBMaxFiles; limit > 0 {
+		if count := nzbDoc.FileCount(); count > limit {
+			return fmt.Errorf("nzb has too many files: %d (max %d)", count, limit)
+		}
+	}
+	if limit := config.Newz.NZBMaxSegments; limit > 0 {
+		if count := nzbDoc.SegmentCount(); count > limit {
+			return fmt.Errorf("nzb has too many segments: %d (max %d)", count, limit)
+		}
+	}
+	return nil
+}
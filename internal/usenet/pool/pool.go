@@ -4,25 +4,41 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"net"
 	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/mnightingale/rapidyenc"
 	"golang.org/x/sync/singleflight"
 
+	"github.com/MunifTanjim/stremthru/internal/config"
 	"github.com/MunifTanjim/stremthru/internal/logger"
 	"github.com/MunifTanjim/stremthru/internal/nntp"
+	"github.com/MunifTanjim/stremthru/internal/server"
 	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
 )
 
-var ErrNoProvidersConfigured = errors.New("usenet: no providers configured")
-var ErrNoProvidersAvailable = errors.New("usenet: no available providers")
-var ErrArticleNotFound = errors.New("usenet: article not found")
+var ErrNoProvidersConfigured = NewError(server.ErrorCodeNoProviders, "usenet: no providers configured")
+var ErrNoProvidersAvailable = NewError(server.ErrorCodeNoProviders, "usenet: no available providers")
+var ErrArticleNotFound = NewError(server.ErrorCodeArticleMissing, "usenet: article not found")
+var ErrFirstSegmentTimeout = NewError(server.ErrorCodeFetchTimeout, "usenet: first segment fetch timed out")
+var ErrSegmentFetchTimeout = NewError(server.ErrorCodeFetchTimeout, "usenet: segment fetch timed out")
+var ErrResolutionTimeout = NewError(server.ErrorCodeFetchTimeout, "usenet: content path resolution timed out")
+var ErrEmptyFile = NewError(server.ErrorCodeEmptyFile, "usenet: empty file")
+var ErrArchiveSolid = NewError(server.ErrorCodeArchiveSolid, "usenet: solid or compressed archive")
+var ErrPasswordRequired = NewError(server.ErrorCodePasswordRequired, "usenet: password required")
+var ErrSegmentTooLarge = NewError(server.ErrorCodeFileTooLarge, "usenet: segment too large")
+var ErrSegmentNotCached = NewError(server.ErrorCodeSegmentNotCached, "usenet: segment not cached")
+var ErrNoStreamableContent = NewError(server.ErrorCodeNoStreamable, "usenet: no video or archive files")
 
 type ProviderConfig struct {
 	nntp.PoolConfig
 	Priority int
 	IsBackup bool
+	Weight   int // share among the providers with same priority, defaults to 1
 }
 
 type Config struct {
@@ -31,6 +47,9 @@ type Config struct {
 	RequiredCapabilities []string
 	MinConnections       int
 	SegmentCache         SegmentCache
+	// SegmentCacheNamespace is prefixed to the segment cache keys, to keep
+	// entries from different pools sharing the same cache apart.
+	SegmentCacheNamespace string
 }
 
 func (conf *Config) setDefaults() {
@@ -47,18 +66,38 @@ func (conf *Config) setDefaults() {
 
 type providerPool struct {
 	*nntp.Pool
-	priority int
-	isBackup bool
+	priority      int
+	isBackup      bool
+	weight        int
+	currentWeight int // for smooth weighted round-robin, guarded by Pool.selectMutex
+	selections    atomic.Int64
+}
+
+func (pp *providerPool) isSaturated() bool {
+	return pp.Stat().AcquiredResources() == pp.MaxSize()
+}
+
+// markOffline takes the provider out of rotation and lets the nntp pool's
+// reconnection loop bring it back, e.g. network not ready at startup.
+func (pp *providerPool) markOffline() {
+	pp.SetState(nntp.PoolStateOffline)
+	pp.ScheduleReconnect()
 }
 
 type Pool struct {
 	Log                  *logger.Logger
 	providers            []*providerPool
 	providersMutex       sync.RWMutex
+	selectMutex          sync.Mutex
 	requiredCapabilities []string
 	minConnections       int
 	fetchGroup           singleflight.Group
 	segmentCache         SegmentCache
+	segmentCacheNS       string
+	segmentLimiter       *segmentLimiter
+	decodeLimiter        *segmentLimiter
+	memoryBudget         *memoryBudget
+	downloaded           downloadCounter
 }
 
 func NewPool(conf *Config) (*Pool, error) {
@@ -70,7 +109,15 @@ func NewPool(conf *Config) (*Pool, error) {
 		requiredCapabilities: conf.RequiredCapabilities,
 		minConnections:       conf.MinConnections,
 		segmentCache:         conf.SegmentCache,
+		segmentCacheNS:       conf.SegmentCacheNamespace,
 	}
+	up.segmentLimiter = newSegmentLimiter(up.getMaxConnections)
+	up.decodeLimiter = newSegmentLimiter(func() int {
+		return config.Newz.DecodeConcurrency
+	})
+	up.memoryBudget = newMemoryBudget(func() int64 {
+		return config.Newz.StreamMemoryLimit
+	})
 
 	for i := range conf.Providers {
 		provider := &conf.Providers[i]
@@ -122,7 +169,7 @@ func (p *Pool) verifyProvider(provider *providerPool) {
 	c, err := provider.Acquire(context.Background())
 	if err != nil {
 		p.Log.Error("marking provider pool offline due to failed connection test", "error", err, "id", provider.Id())
-		provider.SetState(nntp.PoolStateOffline)
+		provider.markOffline()
 		return
 	}
 	defer c.Release()
@@ -131,7 +178,7 @@ func (p *Pool) verifyProvider(provider *providerPool) {
 		caps, err := c.Capabilities()
 		if err != nil {
 			p.Log.Error("marking provider pool offline due to failed capabilities test", "error", err, "id", provider.Id())
-			provider.SetState(nntp.PoolStateOffline)
+			provider.markOffline()
 			return
 		}
 
@@ -155,12 +202,10 @@ func (p *Pool) verifyProviders() {
 	wg.Wait()
 }
 
-func (p *Pool) GetConnection(ctx context.Context, excludeProvider []string, maxPriority int, useBackup bool) (*nntp.PooledConnection, error) {
+func (p *Pool) filterProviders(excludeProvider []string, maxPriority int, useBackup bool) []*providerPool {
 	p.providersMutex.RLock()
-	if len(p.providers) == 0 {
-		p.providersMutex.RUnlock()
-		return nil, ErrNoProvidersConfigured
-	}
+	defer p.providersMutex.RUnlock()
+
 	providers := make([]*providerPool, 0, len(p.providers))
 	for _, provider := range p.providers {
 		if !provider.IsOnline() {
@@ -177,24 +222,96 @@ func (p *Pool) GetConnection(ctx context.Context, excludeProvider []string, maxP
 		}
 		providers = append(providers, provider)
 	}
-	p.providersMutex.RUnlock()
+	return providers
+}
 
-	if len(providers) == 0 {
-		return nil, ErrNoProvidersAvailable
+// pickWeighted moves the provider picked by smooth weighted round-robin, among
+// the unsaturated ones with the top priority, to the front.
+func (p *Pool) pickWeighted(providers []*providerPool) {
+	if len(providers) < 2 {
+		return
+	}
+
+	p.selectMutex.Lock()
+	defer p.selectMutex.Unlock()
+
+	topPriority := providers[0].priority
+	for _, provider := range providers[1:] {
+		topPriority = min(topPriority, provider.priority)
 	}
 
+	picked, totalWeight := -1, 0
+	for i, provider := range providers {
+		if provider.priority != topPriority || provider.isSaturated() {
+			continue
+		}
+		provider.currentWeight += provider.weight
+		totalWeight += provider.weight
+		if picked == -1 || provider.currentWeight > providers[picked].currentWeight {
+			picked = i
+		}
+	}
+	if picked == -1 {
+		return
+	}
+
+	provider := providers[picked]
+	provider.currentWeight -= totalWeight
+	copy(providers[1:picked+1], providers[:picked])
+	providers[0] = provider
+}
+
+func (p *Pool) tryAcquire(ctx context.Context, providers []*providerPool) *nntp.PooledConnection {
+	p.pickWeighted(providers)
 	for _, provider := range providers {
-		if provider.Stat().AcquiredResources() == provider.MaxSize() {
+		if provider.isSaturated() {
 			continue
 		}
 		conn, err := provider.Acquire(ctx)
 		if err == nil {
-			return conn, nil
+			provider.selections.Add(1)
+			return conn
 		}
 		p.Log.Debug("failed to acquire connection from provider", "error", err, "provider_id", provider.Id())
 	}
+	return nil
+}
+
+func (p *Pool) GetConnection(ctx context.Context, excludeProvider []string, maxPriority int, useBackup bool) (*nntp.PooledConnection, error) {
+	if p.CountProviders() == 0 {
+		return nil, ErrNoProvidersConfigured
+	}
+
+	providers := p.filterProviders(excludeProvider, maxPriority, useBackup)
+	if len(providers) == 0 {
+		return nil, ErrNoProvidersAvailable
+	}
 
-	return providers[0].Acquire(ctx)
+	if conn := p.tryAcquire(ctx, providers); conn != nil {
+		return conn, nil
+	}
+
+	// overflow to the backups before waiting on the saturated providers
+	if !useBackup {
+		if backups := p.filterProviders(excludeProvider, math.MaxInt, true); len(backups) > 0 {
+			if conn := p.tryAcquire(ctx, backups); conn != nil {
+				p.Log.Trace("overflowed to backup provider", "provider_id", conn.ProviderId())
+				return conn, nil
+			}
+		}
+	}
+
+	conn, err := providers[0].Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	providers[0].selections.Add(1)
+	return conn, nil
+}
+
+func isTimeoutError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
 }
 
 func isArticleNotFoundError(err error) bool {
@@ -243,15 +360,40 @@ func (p *Pool) getProviderPriorities(useBackup bool) []int {
 	return priorities
 }
 
+func (p *Pool) segmentCacheKey(messageId string) string {
+	if p.segmentCacheNS == "" {
+		return messageId
+	}
+	return p.segmentCacheNS + ":" + messageId
+}
+
 func (p *Pool) fetchSegment(ctx context.Context, segment *nzb.Segment, groups []string) (*SegmentData, error) {
+	start := time.Now()
 	messageId := segment.MessageId
-	if cachedData, ok := p.segmentCache.Get(messageId); ok {
-		p.Log.Trace("fetch segment - cache hit", "segment_num", segment.Number, "message_id", messageId, "size", len(cachedData.Body))
-		return &cachedData, nil
+	if !isBypassCache(ctx) {
+		if cachedData, ok := p.segmentCache.Get(p.segmentCacheKey(messageId)); ok {
+			p.Log.Trace("fetch segment - cache hit", "segment_num", segment.Number, "message_id", messageId, "size", len(cachedData.Body))
+			recordCacheHit(ctx, true)
+			p.traceSegmentFetch(ctx, segment, start, "", true, &cachedData, nil)
+			return &cachedData, nil
+		}
+	}
+
+	if isCachedOnly(ctx) {
+		return nil, fmt.Errorf("%w: segment %d <%s>", ErrSegmentNotCached, segment.Number, messageId)
 	}
 
-	result, err, _ := p.fetchGroup.Do(messageId, func() (any, error) {
-		var excludeProviders []string
+	groups = resolveGroups(ctx, groups)
+	allowlist := getProviderAllowlist(ctx)
+
+	// set only for the caller that did the fetch
+	var providerId string
+	result, err, _ := p.fetchGroup.Do(getFetchKey(messageId, allowlist), func() (any, error) {
+		excludeProviders := p.getDisallowedProviders(allowlist)
+		disallowedCount := len(excludeProviders)
+		if disallowedCount > 0 && disallowedCount == p.CountProviders() {
+			return nil, fmt.Errorf("%w: none of the allowed providers is configured", ErrNoProvidersAvailable)
+		}
 		errs := []error{}
 		failedAttempts := 0
 		useBackup := false
@@ -263,7 +405,7 @@ func (p *Pool) fetchSegment(ctx context.Context, segment *nzb.Segment, groups []
 		}
 
 		for failedAttempts < 3 {
-			if len(excludeProviders) > 0 || priorityIdx > 0 || useBackup {
+			if len(excludeProviders) > disallowedCount || priorityIdx > 0 || useBackup {
 				p.Log.Trace("fetch segment - retry", "segment_num", segment.Number, "message_id", messageId, "failed_attempts", failedAttempts, "excluded_providers", len(excludeProviders), "curr_priority", currPriority, "use_backup", useBackup)
 			}
 
@@ -306,10 +448,16 @@ func (p *Pool) fetchSegment(ctx context.Context, segment *nzb.Segment, groups []
 				continue
 			}
 
+			timeout := config.Newz.SegmentFetchTimeout
+			if timeout > 0 {
+				conn.SetDeadline(time.Now().Add(timeout))
+			}
+
 			article, err := conn.Body("<" + messageId + ">")
 			if err != nil {
-				errs = append(errs, err)
 				if isArticleNotFoundError(err) {
+					errs = append(errs, err)
+					conn.SetDeadline(time.Time{})
 					conn.Release()
 					excludeProviders = append(excludeProviders, conn.ProviderId())
 					p.Log.Trace("fetch segment - article not found", "segment_num", segment.Number, "message_id", messageId, "provider_id", conn.ProviderId())
@@ -318,19 +466,78 @@ func (p *Pool) fetchSegment(ctx context.Context, segment *nzb.Segment, groups []
 
 				conn.Destroy()
 				failedAttempts++
+				if isTimeoutError(err) {
+					errs = append(errs, fmt.Errorf("%w: %w", ErrSegmentFetchTimeout, err))
+					excludeProviders = append(excludeProviders, conn.ProviderId())
+					p.Log.Warn("fetch segment - timed out getting body", "timeout", timeout, "segment_num", segment.Number, "message_id", messageId, "provider_id", conn.ProviderId())
+					continue
+				}
+				errs = append(errs, err)
 				p.Log.Warn("fetch segment - failed to get body", "error", err, "segment_num", segment.Number, "message_id", messageId, "provider_id", conn.ProviderId())
 				continue
 			}
 
 			p.Log.Trace("fetch segment - got body", "segment_num", segment.Number, "message_id", messageId, "provider_id", conn.ProviderId())
 
-			decoder := NewYEncDecoder(article.Body)
-			defer decoder.Close()
-
-			data, err := decoder.ReadAll()
+			// the body is decoded as it is read if a decode slot is free.
+			// Otherwise it is buffered as is and decoded after the connection
+			// is released, so the connection is not held while waiting for a
+			// slot, at the cost of holding the encoded body in memory.
+			var rawBody []byte
+			var segmentData *SegmentData
+			if p.decodeLimiter.TryAcquire() {
+				decoder := NewSegmentDecoder(article.Body)
+				segmentData, err = decoder.ReadAllMax(config.Newz.SegmentMaxSize)
+				decoder.Close()
+				p.decodeLimiter.Release()
+				// a crc mismatch is reported once the whole body is read, the
+				// connection is still usable
+				if err != nil && !errors.Is(err, ErrSegmentTooLarge) && !isTimeoutError(err) && !IsCRCMismatchError(err) {
+					// rest of the body may not be read, connection can not be reused
+					conn.Destroy()
+					errs = append(errs, err)
+					failedAttempts++
+					p.Log.Warn("fetch segment - failed to decode", "error", err, "segment_num", segment.Number, "message_id", messageId, "provider_id", conn.ProviderId())
+					continue
+				}
+			} else {
+				rawBody, err = readRawSegmentBody(article.Body, maxRawSegmentSize(config.Newz.SegmentMaxSize))
+				article.Body.Close()
+				if err != nil && !errors.Is(err, ErrSegmentTooLarge) && !isTimeoutError(err) {
+					conn.Destroy()
+					errs = append(errs, err)
+					failedAttempts++
+					p.Log.Warn("fetch segment - failed to read body", "error", err, "segment_num", segment.Number, "message_id", messageId, "provider_id", conn.ProviderId())
+					continue
+				}
+			}
+			if err != nil && errors.Is(err, ErrSegmentTooLarge) {
+				// rest of the body is not read, connection can not be reused
+				conn.Destroy()
+				errs = append(errs, err)
+				excludeProviders = append(excludeProviders, conn.ProviderId())
+				failedAttempts++
+				p.Log.Warn("fetch segment - body too large", "error", err, "segment_num", segment.Number, "message_id", messageId, "provider_id", conn.ProviderId())
+				continue
+			}
+			if err != nil && isTimeoutError(err) {
+				conn.Destroy()
+				errs = append(errs, fmt.Errorf("%w: %w", ErrSegmentFetchTimeout, err))
+				excludeProviders = append(excludeProviders, conn.ProviderId())
+				failedAttempts++
+				p.Log.Warn("fetch segment - timed out reading body", "timeout", timeout, "segment_num", segment.Number, "message_id", messageId, "provider_id", conn.ProviderId())
+				continue
+			}
 
+			conn.SetDeadline(time.Time{})
 			conn.Release()
 
+			if err == nil && rawBody != nil {
+				segmentData, err = p.decodeRawSegmentBody(ctx, rawBody)
+				if err != nil && ctx.Err() != nil {
+					return nil, err
+				}
+			}
 			if err != nil {
 				errs = append(errs, err)
 				failedAttempts++
@@ -338,13 +545,16 @@ func (p *Pool) fetchSegment(ctx context.Context, segment *nzb.Segment, groups []
 				continue
 			}
 
-			segmentData := data.ToSegmentData()
-
 			p.Log.Debug("fetch segment - decoded body", "segment_num", segment.Number, "message_id", messageId, "decoded_size", len(segmentData.Body))
 
-			p.segmentCache.Set(messageId, segmentData)
+			p.segmentCache.Set(p.segmentCacheKey(messageId), *segmentData)
 
-			return &segmentData, nil
+			if hash := getNZBHash(ctx); hash != "" {
+				p.downloaded.add(hash, int64(len(segmentData.Body)))
+			}
+
+			providerId = conn.ProviderId()
+			return segmentData, nil
 		}
 
 		allArticleNotFound := len(errs) > 0
@@ -362,10 +572,26 @@ func (p *Pool) fetchSegment(ctx context.Context, segment *nzb.Segment, groups []
 	})
 
 	if err != nil {
+		p.traceSegmentFetch(ctx, segment, start, providerId, false, nil, err)
 		return nil, err
 	}
 
-	return result.(*SegmentData), nil
+	recordCacheHit(ctx, false)
+	data := result.(*SegmentData)
+	p.traceSegmentFetch(ctx, segment, start, providerId, false, data, nil)
+	return data, nil
+}
+
+// FetchSegment fetches and decodes a single segment, bypassing the streaming
+// machinery. It is meant for diagnosing broken posts.
+func (p *Pool) FetchSegment(ctx context.Context, segment *nzb.Segment, groups []string) (*SegmentData, error) {
+	return p.fetchSegment(ctx, segment, groups)
+}
+
+// IsCRCMismatchError reports whether the segment was fetched, but its decoded
+// data did not match the CRC from the yEnc trailer.
+func IsCRCMismatchError(err error) bool {
+	return errors.Is(err, rapidyenc.ErrCrcMismatch)
 }
 
 func (p *Pool) Close() {
@@ -391,6 +617,7 @@ func (p *Pool) addProvider(provider *ProviderConfig) error {
 		Pool:     pool,
 		priority: provider.Priority,
 		isBackup: provider.IsBackup,
+		weight:   max(provider.Weight, 1),
 	}
 
 	p.verifyProvider(pPool)
@@ -429,6 +656,21 @@ func (p *Pool) CountProviders() int {
 	return len(p.providers)
 }
 
+// CountOnlineProviders returns the number of providers that can be used to
+// fetch segments right now.
+func (p *Pool) CountOnlineProviders() int {
+	p.providersMutex.RLock()
+	defer p.providersMutex.RUnlock()
+
+	count := 0
+	for _, provider := range p.providers {
+		if provider.IsOnline() {
+			count++
+		}
+	}
+	return count
+}
+
 func (p *Pool) HasProvider(serverId string) bool {
 	p.providersMutex.RLock()
 	defer p.providersMutex.RUnlock()
@@ -453,6 +695,19 @@ func (p *Pool) HasActiveConnections() bool {
 	return false
 }
 
+func (p *Pool) getMaxConnections() int {
+	p.providersMutex.RLock()
+	defer p.providersMutex.RUnlock()
+
+	count := 0
+	for _, provider := range p.providers {
+		if provider.IsOnline() {
+			count += int(provider.MaxSize())
+		}
+	}
+	return count
+}
+
 func (p *Pool) GetAcquiredConnectionCount(providerId string) int {
 	p.providersMutex.RLock()
 	defer p.providersMutex.RUnlock()
@@ -470,6 +725,8 @@ type ProviderInfo struct {
 	State             nntp.PoolState `json:"state"`
 	Priority          int            `json:"priority"`
 	IsBackup          bool           `json:"is_backup"`
+	Weight            int            `json:"weight"`
+	Selections        int64          `json:"selections"` // connections handed out
 	MaxConnections    int            `json:"max_connections"`
 	TotalConnections  int            `json:"total_connections"`
 	ActiveConnections int            `json:"active_connections"`
@@ -478,6 +735,7 @@ type ProviderInfo struct {
 
 type PoolInfo struct {
 	TotalProviders    int            `json:"total_providers"`
+	OnlineProviders   int            `json:"online_providers"`
 	MaxConnections    int            `json:"max_connections"`
 	ActiveConnections int            `json:"active_connections"`
 	IdleConnections   int            `json:"idle_connections"`
@@ -500,6 +758,8 @@ func (p *Pool) GetPoolInfo() PoolInfo {
 			State:             provider.GetState(),
 			Priority:          provider.priority,
 			IsBackup:          provider.isBackup,
+			Weight:            provider.weight,
+			Selections:        provider.selections.Load(),
 			MaxConnections:    int(provider.MaxSize()),
 			TotalConnections:  int(stat.TotalResources()),
 			ActiveConnections: int(stat.AcquiredResources()),
@@ -507,6 +767,7 @@ func (p *Pool) GetPoolInfo() PoolInfo {
 		}
 
 		if provider.IsOnline() {
+			info.OnlineProviders++
 			info.MaxConnections += pi.MaxConnections
 			info.ActiveConnections += pi.ActiveConnections
 			info.IdleConnections += pi.IdleConnections
@@ -0,0 +1,281 @@
+package usenet_pool
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/logger"
+	"github.com/MunifTanjim/stremthru/internal/nntp"
+	"github.com/MunifTanjim/stremthru/internal/nntp/nntptest"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchSegmentTimeout(t *testing.T) {
+	originalTimeout := config.Newz.SegmentFetchTimeout
+	config.Newz.SegmentFetchTimeout = 100 * time.Millisecond
+	t.Cleanup(func() {
+		config.Newz.SegmentFetchTimeout = originalTimeout
+	})
+
+	// no response for BODY, so the article never arrives
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.Start(t)
+
+	usenetPool := &Pool{
+		Log:          logger.Scoped("test/usenet/pool"),
+		providers:    []*providerPool{{Pool: nntptest.NewPool(t, server, &nntp.PoolConfig{})}},
+		segmentCache: NewSegmentCache(10*1024*1024, ""),
+	}
+
+	segment := &nzb.Segment{MessageId: "stuck@test.com", Bytes: 100, Number: 1}
+
+	start := time.Now()
+	_, err := usenetPool.fetchSegment(t.Context(), segment, nil)
+	assert.ErrorIs(t, err, ErrSegmentFetchTimeout)
+	assert.NotErrorIs(t, err, ErrArticleNotFound)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestFetchSegmentCacheNamespace(t *testing.T) {
+	cache := NewSegmentCache(10*1024*1024, "")
+	cache.Set("ns-a:shared@test.com", SegmentData{Body: []byte("from a")})
+
+	segment := &nzb.Segment{MessageId: "shared@test.com", Bytes: 6, Number: 1}
+
+	poolA := &Pool{
+		Log:            logger.Scoped("test/usenet/pool"),
+		providers:      []*providerPool{},
+		segmentCache:   cache,
+		segmentCacheNS: "ns-a",
+	}
+	data, err := poolA.fetchSegment(t.Context(), segment, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("from a"), data.Body)
+
+	poolB := &Pool{
+		Log:            logger.Scoped("test/usenet/pool"),
+		providers:      []*providerPool{},
+		segmentCache:   cache,
+		segmentCacheNS: "ns-b",
+	}
+	_, err = poolB.fetchSegment(t.Context(), segment, nil)
+	assert.Error(t, err)
+
+	poolDefault := &Pool{
+		Log:          logger.Scoped("test/usenet/pool"),
+		providers:    []*providerPool{},
+		segmentCache: cache,
+	}
+	assert.Equal(t, "shared@test.com", poolDefault.segmentCacheKey(segment.MessageId))
+}
+
+func TestFetchSegmentCachedOnly(t *testing.T) {
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.SetResponse("BODY <missing@test.com>", "430 No Such Article")
+	server.Start(t)
+
+	cache := NewSegmentCache(10*1024*1024, "")
+	cache.Set("cached@test.com", SegmentData{Body: []byte("cached")})
+
+	usenetPool := &Pool{
+		Log:          logger.Scoped("test/usenet/pool"),
+		providers:    []*providerPool{{Pool: nntptest.NewPool(t, server, &nntp.PoolConfig{})}},
+		segmentCache: cache,
+	}
+
+	ctx := withCachedOnly(t.Context(), true)
+
+	data, err := usenetPool.fetchSegment(ctx, &nzb.Segment{MessageId: "cached@test.com", Bytes: 6, Number: 1}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("cached"), data.Body)
+
+	_, err = usenetPool.fetchSegment(ctx, &nzb.Segment{MessageId: "missing@test.com", Bytes: 6, Number: 2}, nil)
+	assert.ErrorIs(t, err, ErrSegmentNotCached)
+	assert.False(t, server.GetRequestCommands().HasCommand("BODY <missing@test.com>"))
+}
+
+func TestFetchSegmentBypassCache(t *testing.T) {
+	data := []byte("fresh")
+	encoded := encodeYenc(data, "movie.mkv", 1, 1, int64(len(data)), 1)
+
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.SetResponse("BODY <seg@test.com>", "222 0 <seg@test.com>", strings.Split(strings.TrimSpace(string(encoded)), "\r\n"))
+	server.Start(t)
+
+	cache := NewSegmentCache(10*1024*1024, "")
+	cache.Set("seg@test.com", SegmentData{Body: []byte("stale")})
+
+	usenetPool := &Pool{
+		Log:          logger.Scoped("test/usenet/pool"),
+		providers:    []*providerPool{{Pool: nntptest.NewPool(t, server, &nntp.PoolConfig{})}},
+		segmentCache: cache,
+	}
+
+	segment := &nzb.Segment{MessageId: "seg@test.com", Bytes: int64(len(encoded)), Number: 1}
+
+	cached, err := usenetPool.fetchSegment(t.Context(), segment, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("stale"), cached.Body)
+
+	fresh, err := usenetPool.fetchSegment(withBypassCache(t.Context(), true), segment, nil)
+	require.NoError(t, err)
+	assert.Equal(t, data, fresh.Body)
+	assert.True(t, server.GetRequestCommands().HasCommand("BODY <seg@test.com>"))
+
+	recached, ok := cache.Get("seg@test.com")
+	require.True(t, ok)
+	assert.Equal(t, data, recached.Body)
+}
+
+func TestFetchSegmentProviderAllowlist(t *testing.T) {
+	// told apart by the server port
+	newProvider := func(priority int, body string) *providerPool {
+		encoded := encodeYenc([]byte(body), "movie.mkv", 1, 1, int64(len(body)), 1)
+		server := nntptest.NewServer(t, "200 NNTP Service Ready")
+		server.SetResponse("BODY <seg@test.com>", "222 0 <seg@test.com>", strings.Split(strings.TrimSpace(string(encoded)), "\r\n"))
+		server.Start(t)
+		return &providerPool{
+			Pool:     nntptest.NewPool(t, server, &nntp.PoolConfig{}),
+			priority: priority,
+		}
+	}
+
+	primary := newProvider(0, "from primary")
+	secondary := newProvider(1, "from secondary")
+	usenetPool := &Pool{
+		Log:          logger.Scoped("test/usenet/pool"),
+		providers:    []*providerPool{primary, secondary},
+		segmentCache: NewSegmentCache(10*1024*1024, ""),
+	}
+
+	segment := &nzb.Segment{MessageId: "seg@test.com", Bytes: 100, Number: 1}
+
+	ctx := withBypassCache(WithProviderAllowlist(t.Context(), []string{secondary.Id()}), true)
+	data, err := usenetPool.fetchSegment(ctx, segment, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("from secondary"), data.Body)
+
+	data, err = usenetPool.fetchSegment(withBypassCache(t.Context(), true), segment, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("from primary"), data.Body)
+
+	ctx = withBypassCache(WithProviderAllowlist(t.Context(), []string{"unknown:119:"}), true)
+	_, err = usenetPool.fetchSegment(ctx, segment, nil)
+	assert.ErrorIs(t, err, ErrNoProvidersAvailable)
+}
+
+func TestFetchSegmentCRCMismatchKeepsConnection(t *testing.T) {
+	data := []byte("corrupt")
+	encoded := regexp.MustCompile(`crc32=[0-9a-fA-F]+`).ReplaceAll(encodeYenc(data, "movie.mkv", 1, 1, int64(len(data)), 1), []byte("crc32=00000000"))
+
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.SetResponse("BODY <seg@test.com>", "222 0 <seg@test.com>", strings.Split(strings.TrimSpace(string(encoded)), "\r\n"))
+	server.Start(t)
+
+	provider := nntptest.NewPool(t, server, &nntp.PoolConfig{MaxSize: 1})
+	usenetPool := &Pool{
+		Log:          logger.Scoped("test/usenet/pool"),
+		providers:    []*providerPool{{Pool: provider}},
+		segmentCache: NewSegmentCache(10*1024*1024, ""),
+	}
+
+	_, err := usenetPool.fetchSegment(t.Context(), &nzb.Segment{MessageId: "seg@test.com", Bytes: int64(len(encoded)), Number: 1}, nil)
+	assert.True(t, IsCRCMismatchError(err))
+
+	stat := provider.Stat()
+	assert.Equal(t, int32(1), stat.TotalResources(), "connection is not destroyed")
+	assert.Equal(t, int32(1), stat.IdleResources())
+}
+
+func TestPickWeighted(t *testing.T) {
+	newProvider := func(username string, priority, weight int) *providerPool {
+		server := nntptest.NewServer(t, "200 NNTP Service Ready")
+		return &providerPool{
+			Pool:     nntptest.NewPool(t, server, &nntp.PoolConfig{ConnectionConfig: nntp.ConnectionConfig{Username: username}}),
+			priority: priority,
+			weight:   weight,
+		}
+	}
+
+	usenetPool := &Pool{Log: logger.Scoped("test/usenet/pool")}
+	providers := []*providerPool{
+		newProvider("secondary", 1, 10),
+		newProvider("primary-a", 0, 3),
+		newProvider("primary-b", 0, 1),
+	}
+
+	picks := map[*providerPool]int{}
+	for range 8 {
+		ordered := slices.Clone(providers)
+		usenetPool.pickWeighted(ordered)
+		assert.ElementsMatch(t, providers, ordered)
+		picks[ordered[0]]++
+	}
+	assert.Equal(t, 0, picks[providers[0]])
+	assert.Equal(t, 6, picks[providers[1]])
+	assert.Equal(t, 2, picks[providers[2]])
+}
+
+func TestGetConnectionOverflowToBackup(t *testing.T) {
+	newServer := func() *nntptest.Server {
+		server := nntptest.NewServer(t, "200 NNTP Service Ready")
+		server.Start(t)
+		return server
+	}
+	primary := &providerPool{
+		Pool:   nntptest.NewPool(t, newServer(), &nntp.PoolConfig{MaxSize: 1}),
+		weight: 1,
+	}
+	backup := &providerPool{
+		Pool:     nntptest.NewPool(t, newServer(), &nntp.PoolConfig{MaxSize: 1}),
+		isBackup: true,
+		weight:   1,
+	}
+	usenetPool := &Pool{
+		Log:       logger.Scoped("test/usenet/pool"),
+		providers: []*providerPool{primary, backup},
+	}
+
+	conn, err := usenetPool.GetConnection(t.Context(), nil, 0, false)
+	require.NoError(t, err)
+	defer conn.Release()
+	assert.Equal(t, primary.Id(), conn.ProviderId())
+
+	overflowConn, err := usenetPool.GetConnection(t.Context(), nil, 0, false)
+	require.NoError(t, err)
+	defer overflowConn.Release()
+	assert.Equal(t, backup.Id(), overflowConn.ProviderId())
+
+	info := usenetPool.GetPoolInfo()
+	assert.Equal(t, int64(1), info.Providers[0].Selections)
+	assert.Equal(t, int64(1), info.Providers[1].Selections)
+}
+
+func TestMarkOfflineReconnects(t *testing.T) {
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.Start(t)
+
+	provider := &providerPool{
+		Pool:   nntptest.NewPool(t, server, &nntp.PoolConfig{ReconnectDelay: time.Millisecond}),
+		weight: 1,
+	}
+	usenetPool := &Pool{
+		Log:       logger.Scoped("test/usenet/pool"),
+		providers: []*providerPool{provider},
+	}
+
+	// e.g. network not ready at startup
+	provider.markOffline()
+	assert.Equal(t, 0, usenetPool.CountOnlineProviders())
+
+	assert.Eventually(t, func() bool {
+		return usenetPool.CountOnlineProviders() == 1
+	}, 5*time.Second, time.Millisecond)
+	assert.Equal(t, 1, usenetPool.GetPoolInfo().OnlineProviders)
+}
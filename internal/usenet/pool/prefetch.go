@@ -0,0 +1,56 @@
+package usenet_pool
+
+import (
+	"context"
+	"sync"
+)
+
+// PrefetchGroup tracks the file streams opened for a stream, so that their
+// read-ahead can be paused while nothing is reading from it.
+type PrefetchGroup struct {
+	mu      sync.Mutex
+	streams []*FileStream
+}
+
+func (g *PrefetchGroup) add(s *FileStream) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.streams = append(g.streams, s)
+}
+
+// Pause drops the segments read ahead by the file streams, the next read
+// fetches them again from its position.
+func (g *PrefetchGroup) Pause() {
+	if g == nil {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	open := g.streams[:0]
+	for _, s := range g.streams {
+		if s.pausePrefetch() {
+			open = append(open, s)
+		}
+	}
+	clear(g.streams[len(open):])
+	g.streams = open
+}
+
+type prefetchGroupContextKey struct{}
+
+// withPrefetchGroup tracks the file streams opened using the context in g.
+func withPrefetchGroup(ctx context.Context, g *PrefetchGroup) context.Context {
+	if g == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, prefetchGroupContextKey{}, g)
+}
+
+func trackPrefetch(ctx context.Context, s *FileStream) {
+	if g, _ := ctx.Value(prefetchGroupContextKey{}).(*PrefetchGroup); g != nil {
+		g.add(s)
+	}
+}
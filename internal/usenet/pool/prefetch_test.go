@@ -0,0 +1,88 @@
+package usenet_pool
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/logger"
+	"github.com/MunifTanjim/stremthru/internal/nntp"
+	"github.com/MunifTanjim/stremthru/internal/nntp/nntptest"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefetchGroup(t *testing.T) {
+	const segmentCount = 5
+	const segmentSize = 50
+
+	data := makeTestBytes(segmentCount * segmentSize)
+
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.SetResponse("GROUP alt.test", "211 5 1 5 alt.test")
+
+	segments := make([]nzb.Segment, segmentCount)
+	for i := range segmentCount {
+		msgId := fmt.Sprintf("seg%d@test.com", i+1)
+		encoded := encodeYenc(data[i*segmentSize:(i+1)*segmentSize], "test.mkv", i+1, segmentCount, int64(len(data)), int64(i*segmentSize)+1)
+		server.SetResponse("BODY <"+msgId+">", "222 0 <"+msgId+">", strings.Split(strings.TrimSpace(string(encoded)), "\r\n"))
+		segments[i] = nzb.Segment{MessageId: msgId, Bytes: int64(len(encoded)), Number: i + 1}
+	}
+	server.Start(t)
+
+	usenetPool := &Pool{
+		Log:          logger.Scoped("test/usenet/pool"),
+		providers:    []*providerPool{{Pool: nntptest.NewPool(t, server, &nntp.PoolConfig{})}},
+		segmentCache: NewSegmentCache(10*1024*1024, ""),
+	}
+
+	file := &nzb.File{Segments: segments, Groups: []string{"alt.test"}}
+
+	t.Run("PauseResumesFromPosition", func(t *testing.T) {
+		group := &PrefetchGroup{}
+		stream, err := NewFileStream(withPrefetchGroup(t.Context(), group), usenetPool, file, 0)
+		require.NoError(t, err)
+		defer stream.Close()
+		require.Len(t, group.streams, 1)
+
+		buf := make([]byte, 70)
+		_, err = io.ReadFull(stream, buf)
+		require.NoError(t, err)
+		assert.Equal(t, data[:70], buf)
+
+		buf = make([]byte, 60)
+		_, err = stream.ReadAt(buf, 120)
+		require.NoError(t, err)
+		require.NotNil(t, stream.stream)
+		require.Len(t, stream.readers, 1)
+
+		group.Pause()
+		assert.Nil(t, stream.stream)
+		assert.Empty(t, stream.readers)
+
+		rest, err := io.ReadAll(stream)
+		require.NoError(t, err)
+		assert.Equal(t, data[70:], rest)
+	})
+
+	t.Run("DropsClosedStreams", func(t *testing.T) {
+		group := &PrefetchGroup{}
+		ctx := withPrefetchGroup(t.Context(), group)
+		closed, err := NewFileStream(ctx, usenetPool, file, 0)
+		require.NoError(t, err)
+		open, err := NewFileStream(ctx, usenetPool, file, 0)
+		require.NoError(t, err)
+		defer open.Close()
+
+		closed.Close()
+		group.Pause()
+		assert.Equal(t, []*FileStream{open}, group.streams)
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		var group *PrefetchGroup
+		assert.NotPanics(t, group.Pause)
+	})
+}
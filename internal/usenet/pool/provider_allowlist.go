@@ -0,0 +1,51 @@
+package usenet_pool
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+type providerAllowlistContextKey struct{}
+
+// WithProviderAllowlist restricts the segments fetched using the context to
+// the providers with the given ids, e.g. when only one of them has the post.
+func WithProviderAllowlist(ctx context.Context, providerIds []string) context.Context {
+	if len(providerIds) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, providerAllowlistContextKey{}, providerIds)
+}
+
+func getProviderAllowlist(ctx context.Context) []string {
+	providerIds, _ := ctx.Value(providerAllowlistContextKey{}).([]string)
+	return providerIds
+}
+
+// getDisallowedProviders returns the ids of the providers left out by the
+// allowlist, to be skipped in the failover order.
+func (p *Pool) getDisallowedProviders(allowlist []string) []string {
+	if len(allowlist) == 0 {
+		return nil
+	}
+
+	p.providersMutex.RLock()
+	defer p.providersMutex.RUnlock()
+
+	disallowed := []string{}
+	for _, provider := range p.providers {
+		if id := provider.Id(); !slices.Contains(allowlist, id) {
+			disallowed = append(disallowed, id)
+		}
+	}
+	return disallowed
+}
+
+// getFetchKey tells apart the fetches of the same segment with different
+// allowlists, those can not share the result.
+func getFetchKey(messageId string, allowlist []string) string {
+	if len(allowlist) == 0 {
+		return messageId
+	}
+	return messageId + "|" + strings.Join(allowlist, ",")
+}
@@ -0,0 +1,86 @@
+package usenet_pool
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/MunifTanjim/stremthru/internal/util"
+)
+
+type NZBContentEpisode struct {
+	Episodes []int
+	Name     string
+	Path     string
+}
+
+type NZBContentSeason struct {
+	Season   int
+	Episodes []NZBContentEpisode
+}
+
+type NZBContentSeasons struct {
+	Seasons []NZBContentSeason
+	// streamable videos not detected as episode
+	Other []NZBContentEpisode
+}
+
+// parseContentPathEpisode looks for season/episode in the content path parts,
+// starting from the innermost one, e.g. the video inside the archive.
+func parseContentPathEpisode(contentPath string) (season int, episodes []int) {
+	parts := strings.Split(strings.Trim(contentPath, "/"), "::")
+	for i := len(parts) - 1; i >= 0; i-- {
+		r, err := util.ParseTorrentTitle(filepath.Base(parts[i]))
+		if err != nil || len(r.Seasons) == 0 || len(r.Episodes) == 0 {
+			continue
+		}
+		return r.Seasons[0], r.Episodes
+	}
+	return -1, nil
+}
+
+// GroupNZBContentBySeason groups the streamable videos by season, ordered by
+// episode. Returns nil if no episode is detected.
+func GroupNZBContentBySeason(files []NZBContentFile) *NZBContentSeasons {
+	result := &NZBContentSeasons{}
+	for _, path := range GetStreamableVideoContentPaths(files) {
+		name := path
+		if idx := strings.LastIndex(path, "::"); idx != -1 {
+			name = path[idx+2:]
+		}
+		item := NZBContentEpisode{
+			Path: path,
+			Name: filepath.Base(name),
+		}
+
+		season, episodes := parseContentPathEpisode(path)
+		if season < 0 {
+			result.Other = append(result.Other, item)
+			continue
+		}
+		item.Episodes = episodes
+
+		idx := slices.IndexFunc(result.Seasons, func(s NZBContentSeason) bool {
+			return s.Season == season
+		})
+		if idx == -1 {
+			result.Seasons = append(result.Seasons, NZBContentSeason{Season: season})
+			idx = len(result.Seasons) - 1
+		}
+		result.Seasons[idx].Episodes = append(result.Seasons[idx].Episodes, item)
+	}
+
+	if len(result.Seasons) == 0 {
+		return nil
+	}
+
+	slices.SortFunc(result.Seasons, func(a, b NZBContentSeason) int {
+		return a.Season - b.Season
+	})
+	for i := range result.Seasons {
+		slices.SortStableFunc(result.Seasons[i].Episodes, func(a, b NZBContentEpisode) int {
+			return a.Episodes[0] - b.Episodes[0]
+		})
+	}
+	return result
+}
@@ -0,0 +1,50 @@
+package usenet_pool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupNZBContentBySeason(t *testing.T) {
+	t.Run("SeasonPack", func(t *testing.T) {
+		files := []NZBContentFile{
+			{Type: NZBContentFileTypeVideo, Name: "Show.S02E01.1080p.WEB.mkv", Streamable: true},
+			{Type: NZBContentFileTypeVideo, Name: "Show.S01E02.1080p.WEB.mkv", Streamable: true},
+			{
+				Type:       NZBContentFileTypeArchive,
+				Name:       "Show.S01E01.1080p.WEB.rar",
+				Streamable: true,
+				Files: []NZBContentFile{
+					{Type: NZBContentFileTypeVideo, Name: "abc123.mkv", Streamable: true},
+				},
+			},
+			{Type: NZBContentFileTypeVideo, Name: "Show.Extras.1080p.WEB.mkv", Streamable: true},
+		}
+
+		result := GroupNZBContentBySeason(files)
+		require.NotNil(t, result)
+		require.Len(t, result.Seasons, 2)
+
+		assert.Equal(t, 1, result.Seasons[0].Season)
+		assert.Equal(t, []NZBContentEpisode{
+			{Episodes: []int{1}, Name: "abc123.mkv", Path: "Show.S01E01.1080p.WEB.rar::abc123.mkv"},
+			{Episodes: []int{2}, Name: "Show.S01E02.1080p.WEB.mkv", Path: "Show.S01E02.1080p.WEB.mkv"},
+		}, result.Seasons[0].Episodes)
+
+		assert.Equal(t, 2, result.Seasons[1].Season)
+		assert.Len(t, result.Seasons[1].Episodes, 1)
+
+		assert.Equal(t, []NZBContentEpisode{
+			{Name: "Show.Extras.1080p.WEB.mkv", Path: "Show.Extras.1080p.WEB.mkv"},
+		}, result.Other)
+	})
+
+	t.Run("Movie", func(t *testing.T) {
+		files := []NZBContentFile{
+			{Type: NZBContentFileTypeVideo, Name: "Movie.2020.1080p.BluRay.mkv", Streamable: true},
+		}
+		assert.Nil(t, GroupNZBContentBySeason(files))
+	})
+}
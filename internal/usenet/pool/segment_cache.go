@@ -2,8 +2,10 @@ package usenet_pool
 
 import (
 	"sync"
+	"time"
 
 	"github.com/MunifTanjim/stremthru/internal/cache"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
 )
 
 type SegmentData struct {
@@ -11,6 +13,7 @@ type SegmentData struct {
 	ByteRange ByteRange
 	FileSize  int64
 	Size      int64
+	Encoding  SegmentEncoding // empty for yEnc
 }
 
 func (sd SegmentData) CacheSize() int64 {
@@ -20,6 +23,7 @@ func (sd SegmentData) CacheSize() int64 {
 type SegmentCache interface {
 	Get(messageId string) (SegmentData, bool)
 	Set(messageId string, data SegmentData)
+	Has(messageId string) bool
 }
 
 var (
@@ -31,9 +35,10 @@ type segmentCache struct {
 	cache cache.Cache[SegmentData]
 }
 
-func NewSegmentCache(size int64) SegmentCache {
+func NewSegmentCache(size int64, dir string) SegmentCache {
 	cache := cache.NewCache[SegmentData](&cache.CacheConfig{
 		Name:       "newz_segment",
+		Dir:        dir,
 		MaxSize:    size,
 		DiskBacked: true,
 	})
@@ -43,6 +48,20 @@ func NewSegmentCache(size int64) SegmentCache {
 	}
 }
 
+// NewRedisSegmentCache creates a segment cache in Redis, to be shared by
+// multiple instances. Unlike the disk backed one, it is not bounded by size,
+// segments expire after the lifetime instead.
+func NewRedisSegmentCache(lifetime time.Duration) SegmentCache {
+	cache := cache.NewRedisCache[SegmentData](&cache.CacheConfig{
+		Name:     "newz_segment",
+		Lifetime: lifetime,
+	})
+
+	return &segmentCache{
+		cache: cache,
+	}
+}
+
 func (c *segmentCache) Get(messageId string) (SegmentData, bool) {
 	var data SegmentData
 	ok := c.cache.Get(messageId, &data)
@@ -53,6 +72,10 @@ func (c *segmentCache) Set(messageId string, data SegmentData) {
 	c.cache.Add(messageId, data)
 }
 
+func (c *segmentCache) Has(messageId string) bool {
+	return c.cache.Has(messageId)
+}
+
 type noopSegmentCache struct{}
 
 func (n *noopSegmentCache) Get(messageId string) (SegmentData, bool) {
@@ -62,6 +85,53 @@ func (n *noopSegmentCache) Get(messageId string) (SegmentData, bool) {
 func (n *noopSegmentCache) Set(messageId string, data SegmentData) {
 }
 
+func (n *noopSegmentCache) Has(messageId string) bool {
+	return false
+}
+
 var getNoopSegmentCache = sync.OnceValue(func() SegmentCache {
 	return &noopSegmentCache{}
 })
+
+type CachedSegment struct {
+	Number    int    `json:"number"`
+	MessageId string `json:"message_id"`
+	Size      int64  `json:"size"`
+}
+
+type CachedSegmentsFile struct {
+	Name         string          `json:"name"`
+	SegmentCount int             `json:"segment_count"`
+	Size         int64           `json:"size"`
+	CachedSize   int64           `json:"cached_size"`
+	Segments     []CachedSegment `json:"segments"`
+}
+
+// GetCachedSegments reports the segments of each file present in the
+// segment cache. Sizes are the article sizes listed in the NZB.
+func (p *Pool) GetCachedSegments(nzbDoc *nzb.NZB) []CachedSegmentsFile {
+	files := make([]CachedSegmentsFile, 0, len(nzbDoc.Files))
+	for i := range nzbDoc.Files {
+		file := &nzbDoc.Files[i]
+		cf := CachedSegmentsFile{
+			Name:         file.Name(),
+			SegmentCount: file.SegmentCount(),
+			Segments:     []CachedSegment{},
+		}
+		for j, messageId := range file.MessageIds() {
+			segment := &file.Segments[j]
+			cf.Size += segment.Bytes
+			if !p.segmentCache.Has(p.segmentCacheKey(messageId)) {
+				continue
+			}
+			cf.CachedSize += segment.Bytes
+			cf.Segments = append(cf.Segments, CachedSegment{
+				Number:    segment.Number,
+				MessageId: messageId,
+				Size:      segment.Bytes,
+			})
+		}
+		files = append(files, cf)
+	}
+	return files
+}
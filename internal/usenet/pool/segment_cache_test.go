@@ -0,0 +1,43 @@
+package usenet_pool
+
+import (
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/logger"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCachedSegments(t *testing.T) {
+	usenetPool := &Pool{
+		Log:            logger.Scoped("test/usenet/pool"),
+		segmentCache:   NewSegmentCache(10*1024*1024, t.TempDir()),
+		segmentCacheNS: "test",
+	}
+
+	nzbDoc := createTestNZB(nzb.File{
+		Subject: `Test - "movie.mkv" yEnc (1/3)`,
+		Segments: []nzb.Segment{
+			{MessageId: "cached-seg1@test.com", Bytes: 100, Number: 1},
+			{MessageId: "cached-seg2@test.com", Bytes: 100, Number: 2},
+			{MessageId: "cached-seg3@test.com", Bytes: 50, Number: 3},
+		},
+	})
+
+	usenetPool.segmentCache.Set(usenetPool.segmentCacheKey("cached-seg1@test.com"), SegmentData{Body: []byte("data"), Size: 4})
+	usenetPool.segmentCache.Set(usenetPool.segmentCacheKey("cached-seg3@test.com"), SegmentData{Body: []byte("data"), Size: 4})
+	// not namespaced, belongs to another instance
+	usenetPool.segmentCache.Set("cached-seg2@test.com", SegmentData{Body: []byte("data"), Size: 4})
+
+	files := usenetPool.GetCachedSegments(nzbDoc)
+	require.Len(t, files, 1)
+	assert.Equal(t, "movie.mkv", files[0].Name)
+	assert.Equal(t, 3, files[0].SegmentCount)
+	assert.Equal(t, int64(250), files[0].Size)
+	assert.Equal(t, int64(150), files[0].CachedSize)
+	assert.Equal(t, []CachedSegment{
+		{Number: 1, MessageId: "cached-seg1@test.com", Size: 100},
+		{Number: 3, MessageId: "cached-seg3@test.com", Size: 50},
+	}, files[0].Segments)
+}
@@ -0,0 +1,300 @@
+package usenet_pool
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"regexp"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+)
+
+type SegmentEncoding string
+
+const (
+	SegmentEncodingYEnc     SegmentEncoding = "yenc"
+	SegmentEncodingUUEncode SegmentEncoding = "uuencode"
+	SegmentEncodingBase64   SegmentEncoding = "base64"
+)
+
+var ErrInvalidSegmentEncoding = errors.New("usenet: invalid segment encoding")
+
+// ErrUnplacedSegmentEncoding is returned for a file split into multiple
+// uuencode or base64 segments, as those carry no offsets to place the parts
+// by.
+var ErrUnplacedSegmentEncoding = errors.New("usenet: multi-segment file without part offsets")
+
+var (
+	uuBeginLineRegex  = regexp.MustCompile(`^begin [0-7]{3,4} \S`)
+	b64BeginLineRegex = regexp.MustCompile(`^begin-base64 [0-7]{3,4} \S`)
+	base64LineRegex   = regexp.MustCompile(`^[A-Za-z0-9+/]+={0,2}$`)
+)
+
+// SegmentDecoder decodes an article body encoded with yEnc, uuencode or
+// base64. The encoding is detected from the first non-empty line of the body.
+type SegmentDecoder struct {
+	r        *bufio.Reader
+	closer   io.Closer
+	encoding SegmentEncoding
+}
+
+func NewSegmentDecoder(r io.Reader) *SegmentDecoder {
+	d := &SegmentDecoder{r: bufio.NewReaderSize(r, yencBufferSize)}
+	if closer, ok := r.(io.Closer); ok {
+		d.closer = closer
+	}
+	return d
+}
+
+func (d *SegmentDecoder) Close() error {
+	if d.closer != nil {
+		return d.closer.Close()
+	}
+	return nil
+}
+
+// Encoding peeks at the body up to the first non-empty line and detects
+// the encoding from it. Bodies without a known preamble are left to the yEnc
+// decoder to reject.
+func (d *SegmentDecoder) Encoding() (SegmentEncoding, error) {
+	if d.encoding != "" {
+		return d.encoding, nil
+	}
+
+	d.encoding = SegmentEncodingYEnc
+
+	var text []byte
+	for {
+		buf, _ := d.r.Peek(d.r.Buffered())
+		text = bytes.TrimLeft(buf, "\r\n")
+		if idx := bytes.IndexByte(text, '\n'); idx != -1 {
+			text = text[:idx]
+			break
+		}
+		if len(buf) == d.r.Size() {
+			// too long for a preamble, must be yEnc data
+			return d.encoding, nil
+		}
+		// the body is always terminated, so waiting for one more byte won't hang
+		if _, err := d.r.Peek(len(buf) + 1); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return "", err
+		}
+	}
+	text = bytes.TrimRight(text, "\r")
+
+	switch {
+	case bytes.HasPrefix(text, []byte("=ybegin ")):
+	case uuBeginLineRegex.Match(text):
+		d.encoding = SegmentEncodingUUEncode
+	case b64BeginLineRegex.Match(text), len(text)%4 == 0 && base64LineRegex.Match(text):
+		d.encoding = SegmentEncodingBase64
+	}
+	return d.encoding, nil
+}
+
+// ReadAllMax decodes the whole body, stopping with ErrSegmentTooLarge as
+// soon as it grows past maxSize. A maxSize of 0 means unlimited.
+//
+// Unlike yEnc, uuencode and base64 carry no part offsets, so those segments
+// are treated as a whole file on their own, see ErrUnplacedSegmentEncoding.
+func (d *SegmentDecoder) ReadAllMax(maxSize int64) (*SegmentData, error) {
+	encoding, err := d.Encoding()
+	if err != nil {
+		return nil, err
+	}
+
+	if encoding == SegmentEncodingYEnc {
+		data, err := NewYEncDecoder(d.r).ReadAllMax(maxSize)
+		if err != nil {
+			return nil, err
+		}
+		segmentData := data.ToSegmentData()
+		return &segmentData, nil
+	}
+
+	lines := bufio.NewScanner(textproto.NewReader(d.r).DotReader())
+	lines.Buffer(make([]byte, 0, yencBufferSize), yencBufferSize)
+
+	var body []byte
+	switch encoding {
+	case SegmentEncodingUUEncode:
+		body, err = decodeUUEncode(lines, maxSize)
+	case SegmentEncodingBase64:
+		body, err = decodeBase64(lines, maxSize)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	yencLog.Trace("segment - decoded", "encoding", encoding, "decoded_size", len(body))
+
+	size := int64(len(body))
+	return &SegmentData{
+		Body:      body,
+		ByteRange: ByteRange{Start: 0, End: size},
+		FileSize:  size,
+		Size:      size,
+		Encoding:  encoding,
+	}, nil
+}
+
+func checkDecodedSize(size int, maxSize int64) error {
+	if maxSize > 0 && int64(size) > maxSize {
+		return fmt.Errorf("%w: exceeds %d bytes", ErrSegmentTooLarge, maxSize)
+	}
+	return nil
+}
+
+// decodeUUEncode decodes the lines between `begin <mode> <name>` and `end`.
+// The rest of the body is drained so the connection can be reused.
+func decodeUUEncode(lines *bufio.Scanner, maxSize int64) ([]byte, error) {
+	var body []byte
+	begun, ended := false, false
+	for lines.Scan() {
+		line := lines.Bytes()
+		if ended {
+			continue
+		}
+		if !begun {
+			begun = uuBeginLineRegex.Match(line)
+			continue
+		}
+		if bytes.Equal(bytes.TrimRight(line, " "), []byte("end")) {
+			ended = true
+			continue
+		}
+		if len(line) == 0 {
+			continue
+		}
+
+		n := int((line[0] - ' ') & 0x3f)
+		if n == 0 {
+			continue
+		}
+		chars := line[1:]
+		if len(chars) < (n+2)/3*4 {
+			// trailing spaces are often stripped in transit
+			chars = append(chars[:len(chars):len(chars)], bytes.Repeat([]byte{' '}, (n+2)/3*4-len(chars))...)
+		}
+
+		decoded := make([]byte, 0, (n+2)/3*3)
+		for i := 0; i+4 <= len(chars) && len(decoded) < n; i += 4 {
+			var c [4]byte
+			for j := range c {
+				if chars[i+j] < ' ' || chars[i+j] > '`' {
+					return nil, fmt.Errorf("%w: invalid uuencode character %q", ErrInvalidSegmentEncoding, chars[i+j])
+				}
+				c[j] = (chars[i+j] - ' ') & 0x3f
+			}
+			decoded = append(decoded, c[0]<<2|c[1]>>4, c[1]<<4|c[2]>>2, c[2]<<6|c[3])
+		}
+		if len(decoded) < n {
+			return nil, fmt.Errorf("%w: truncated uuencode line", ErrInvalidSegmentEncoding)
+		}
+
+		body = append(body, decoded[:n]...)
+		if err := checkDecodedSize(len(body), maxSize); err != nil {
+			return nil, err
+		}
+	}
+	if err := lines.Err(); err != nil {
+		return nil, err
+	}
+	if !begun {
+		return nil, fmt.Errorf("%w: missing uuencode begin line", ErrInvalidSegmentEncoding)
+	}
+	return body, nil
+}
+
+// decodeBase64 decodes the base64 lines of the body, optionally wrapped
+// between `begin-base64 <mode> <name>` and `====`.
+func decodeBase64(lines *bufio.Scanner, maxSize int64) ([]byte, error) {
+	var encoded []byte
+	begun, ended := false, false
+	for lines.Scan() {
+		line := bytes.TrimSpace(lines.Bytes())
+		if ended || len(line) == 0 {
+			continue
+		}
+		if !begun {
+			begun = true
+			if b64BeginLineRegex.Match(line) {
+				continue
+			}
+		}
+		if bytes.Equal(line, []byte("====")) {
+			ended = true
+			continue
+		}
+		encoded = append(encoded, bytes.TrimRight(line, "=")...)
+		if err := checkDecodedSize(base64.RawStdEncoding.DecodedLen(len(encoded)), maxSize); err != nil {
+			return nil, err
+		}
+	}
+	if err := lines.Err(); err != nil {
+		return nil, err
+	}
+
+	body, err := base64.RawStdEncoding.DecodeString(string(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSegmentEncoding, err)
+	}
+	return body, nil
+}
+
+// maxRawSegmentSize allows for the encoding overhead over the decoded
+// maxSize, which is about a third for uuencode and base64.
+func maxRawSegmentSize(maxSize int64) int64 {
+	if maxSize <= 0 {
+		return 0
+	}
+	return maxSize + maxSize/2 + 64*1024
+}
+
+// readRawSegmentBody reads the body as is, still encoded and dot-stuffed, up
+// to and including the terminating line. A maxSize of 0 means unlimited.
+func readRawSegmentBody(r io.Reader, maxSize int64) ([]byte, error) {
+	br := bufio.NewReaderSize(r, yencBufferSize)
+	body := []byte{}
+	lineStart := 0
+	for {
+		chunk, err := br.ReadSlice('\n')
+		body = append(body, chunk...)
+		if maxSize > 0 && int64(len(body)) > maxSize {
+			return nil, fmt.Errorf("%w: exceeds %d bytes before decoding", ErrSegmentTooLarge, maxSize)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		line := body[lineStart:]
+		if bytes.Equal(line, []byte(".\r\n")) || bytes.Equal(line, []byte(".\n")) {
+			return body, nil
+		}
+		lineStart = len(body)
+	}
+}
+
+// decodeRawSegmentBody decodes the body read by readRawSegmentBody, bounded
+// by the decode concurrency.
+func (p *Pool) decodeRawSegmentBody(ctx context.Context, rawBody []byte) (*SegmentData, error) {
+	if err := p.decodeLimiter.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer p.decodeLimiter.Release()
+
+	return NewSegmentDecoder(bytes.NewReader(rawBody)).ReadAllMax(config.Newz.SegmentMaxSize)
+}
@@ -0,0 +1,189 @@
+package usenet_pool
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/MunifTanjim/stremthru/internal/logger"
+	"github.com/MunifTanjim/stremthru/internal/nntp"
+	"github.com/MunifTanjim/stremthru/internal/nntp/nntptest"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeUU(data []byte, filename string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("begin 644 " + filename + "\r\n")
+	for i := 0; i < len(data); i += 45 {
+		chunk := data[i:min(i+45, len(data))]
+		buf.WriteByte(' ' + byte(len(chunk)))
+		for j := 0; j < len(chunk); j += 3 {
+			var b [3]byte
+			copy(b[:], chunk[j:])
+			for _, c := range []byte{b[0] >> 2, (b[0]<<4 | b[1]>>4) & 0x3f, (b[1]<<2 | b[2]>>6) & 0x3f, b[2] & 0x3f} {
+				if c == 0 {
+					buf.WriteByte('`')
+				} else {
+					buf.WriteByte(' ' + c)
+				}
+			}
+		}
+		buf.WriteString("\r\n")
+	}
+	buf.WriteString("`\r\nend\r\n")
+	return buf.Bytes()
+}
+
+func encodeBase64Lines(data []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var buf bytes.Buffer
+	for i := 0; i < len(encoded); i += 76 {
+		buf.WriteString(encoded[i:min(i+76, len(encoded))] + "\r\n")
+	}
+	return buf.Bytes()
+}
+
+// toDotStream terminates the body the way it comes off the wire.
+func toDotStream(body []byte) []byte {
+	lines := strings.Split(strings.TrimSuffix(string(body), "\r\n"), "\r\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") {
+			lines[i] = "." + line
+		}
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n.\r\n")
+}
+
+func TestSegmentDecoder(t *testing.T) {
+	data := makeTestBytes(1000)
+
+	for _, tc := range []struct {
+		name     string
+		body     []byte
+		encoding SegmentEncoding
+	}{
+		{"YEnc", encodeYenc(data, "test.bin", 1, 2, int64(len(data)), 1), SegmentEncodingYEnc},
+		{"UUEncode", encodeUU(data, "test.bin"), SegmentEncodingUUEncode},
+		{"Base64", encodeBase64Lines(data), SegmentEncodingBase64},
+		{"BeginBase64", append(append([]byte("begin-base64 644 test.bin\r\n"), encodeBase64Lines(data)...), "====\r\n"...), SegmentEncodingBase64},
+		{"LeadingEmptyLines", append([]byte("\r\n\r\n"), encodeUU(data, "test.bin")...), SegmentEncodingUUEncode},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			decoder := NewSegmentDecoder(bytes.NewReader(toDotStream(tc.body)))
+			defer decoder.Close()
+
+			encoding, err := decoder.Encoding()
+			require.NoError(t, err)
+			assert.Equal(t, tc.encoding, encoding)
+
+			segmentData, err := decoder.ReadAllMax(0)
+			require.NoError(t, err)
+			assert.Equal(t, data, segmentData.Body)
+			assert.Equal(t, ByteRange{Start: 0, End: int64(len(data))}, segmentData.ByteRange)
+			assert.Equal(t, int64(len(data)), segmentData.FileSize)
+			assert.Equal(t, int64(len(data)), segmentData.Size)
+		})
+	}
+
+	t.Run("StopsAtTerminator", func(t *testing.T) {
+		// the connection stays open after the body
+		body := io.MultiReader(bytes.NewReader(toDotStream(encodeUU(data, "test.bin"))), iotest.ErrReader(errors.New("read past terminator")))
+		segmentData, err := NewSegmentDecoder(body).ReadAllMax(0)
+		require.NoError(t, err)
+		assert.Equal(t, data, segmentData.Body)
+	})
+
+	t.Run("TooLarge", func(t *testing.T) {
+		for _, body := range [][]byte{encodeUU(data, "test.bin"), encodeBase64Lines(data)} {
+			decoder := NewSegmentDecoder(bytes.NewReader(toDotStream(body)))
+			_, err := decoder.ReadAllMax(500)
+			assert.ErrorIs(t, err, ErrSegmentTooLarge)
+		}
+	})
+
+	t.Run("InvalidUUEncode", func(t *testing.T) {
+		decoder := NewSegmentDecoder(bytes.NewReader(toDotStream([]byte("begin 644 test.bin\r\nM~~~~\r\nend\r\n"))))
+		_, err := decoder.ReadAllMax(0)
+		assert.ErrorIs(t, err, ErrInvalidSegmentEncoding)
+	})
+}
+
+func TestReadRawSegmentBody(t *testing.T) {
+	data := makeTestBytes(1000)
+	wire := toDotStream(encodeYenc(data, "test.bin", 1, 1, int64(len(data)), 1))
+
+	t.Run("StopsAtTerminator", func(t *testing.T) {
+		body := io.MultiReader(bytes.NewReader(wire), iotest.ErrReader(errors.New("read past terminator")))
+		rawBody, err := readRawSegmentBody(body, 0)
+		require.NoError(t, err)
+		assert.Equal(t, wire, rawBody)
+
+		segmentData, err := NewSegmentDecoder(bytes.NewReader(rawBody)).ReadAllMax(0)
+		require.NoError(t, err)
+		assert.Equal(t, data, segmentData.Body)
+	})
+
+	t.Run("Unterminated", func(t *testing.T) {
+		_, err := readRawSegmentBody(bytes.NewReader(wire[:len(wire)-3]), 0)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+
+	t.Run("TooLarge", func(t *testing.T) {
+		_, err := readRawSegmentBody(bytes.NewReader(wire), 500)
+		assert.ErrorIs(t, err, ErrSegmentTooLarge)
+	})
+
+	t.Run("DecodeWaitsWithContext", func(t *testing.T) {
+		p := &Pool{decodeLimiter: newSegmentLimiter(func() int { return 1 })}
+		require.True(t, p.decodeLimiter.TryAcquire())
+
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		_, err := p.decodeRawSegmentBody(ctx, wire)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		p.decodeLimiter.Release()
+		segmentData, err := p.decodeRawSegmentBody(t.Context(), wire)
+		require.NoError(t, err)
+		assert.Equal(t, data, segmentData.Body)
+	})
+}
+
+func TestFileStreamUnplacedSegmentEncoding(t *testing.T) {
+	data := makeTestBytes(1000)
+
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.SetResponse("GROUP alt.test", "211 2 1 2 alt.test")
+	server.SetResponse("BODY <uu1@test.com>", "222 0 <uu1@test.com>", strings.Split(strings.TrimSpace(string(encodeUU(data, "test.bin"))), "\r\n"))
+	server.Start(t)
+
+	usenetPool := &Pool{
+		Log:          logger.Scoped("test/usenet/pool"),
+		providers:    []*providerPool{{Pool: nntptest.NewPool(t, server, &nntp.PoolConfig{})}},
+		segmentCache: NewSegmentCache(10*1024*1024, ""),
+	}
+
+	t.Run("SingleSegment", func(t *testing.T) {
+		file := &nzb.File{Segments: []nzb.Segment{{MessageId: "uu1@test.com", Number: 1}}, Groups: []string{"alt.test"}}
+		stream, err := NewFileStream(t.Context(), usenetPool, file, 0)
+		require.NoError(t, err)
+		defer stream.Close()
+
+		got, err := io.ReadAll(stream)
+		require.NoError(t, err)
+		assert.Equal(t, data, got)
+	})
+
+	t.Run("MultiSegment", func(t *testing.T) {
+		file := &nzb.File{Segments: []nzb.Segment{{MessageId: "uu1@test.com", Number: 1}, {MessageId: "uu2@test.com", Number: 2}}, Groups: []string{"alt.test"}}
+		_, err := NewFileStream(t.Context(), usenetPool, file, 0)
+		assert.ErrorIs(t, err, ErrUnplacedSegmentEncoding)
+	})
+}
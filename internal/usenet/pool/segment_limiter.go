@@ -0,0 +1,88 @@
+package usenet_pool
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// segmentLimiter bounds concurrent segment fetches across all streams. The
+// limit is re-evaluated on every acquire, so it follows provider changes.
+type segmentLimiter struct {
+	mu      sync.Mutex
+	active  int
+	waiters []chan struct{}
+	limit   func() int
+}
+
+func newSegmentLimiter(limit func() int) *segmentLimiter {
+	return &segmentLimiter{limit: limit}
+}
+
+func (l *segmentLimiter) Acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	for {
+		l.mu.Lock()
+		if limit := l.limit(); limit <= 0 || l.active < limit {
+			l.active++
+			l.mu.Unlock()
+			return nil
+		}
+		ready := make(chan struct{})
+		l.waiters = append(l.waiters, ready)
+		l.mu.Unlock()
+
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			l.mu.Lock()
+			if idx := slices.Index(l.waiters, ready); idx != -1 {
+				l.waiters = slices.Delete(l.waiters, idx, idx+1)
+			} else {
+				// already woken up, pass it on
+				l.notifyOne()
+			}
+			l.mu.Unlock()
+			return ctx.Err()
+		}
+	}
+}
+
+// TryAcquire acquires without waiting, it reports false if the limit is
+// reached.
+func (l *segmentLimiter) TryAcquire() bool {
+	if l == nil {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if limit := l.limit(); limit <= 0 || l.active < limit {
+		l.active++
+		return true
+	}
+	return false
+}
+
+func (l *segmentLimiter) Release() {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.active--
+	l.notifyOne()
+}
+
+func (l *segmentLimiter) notifyOne() {
+	if len(l.waiters) > 0 {
+		close(l.waiters[0])
+		l.waiters = l.waiters[1:]
+	}
+}
@@ -0,0 +1,76 @@
+package usenet_pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegmentLimiter(t *testing.T) {
+	t.Run("BlocksAtLimit", func(t *testing.T) {
+		l := newSegmentLimiter(func() int { return 2 })
+
+		require.NoError(t, l.Acquire(t.Context()))
+		require.NoError(t, l.Acquire(t.Context()))
+
+		acquired := make(chan struct{})
+		go func() {
+			if l.Acquire(context.Background()) == nil {
+				close(acquired)
+			}
+		}()
+
+		select {
+		case <-acquired:
+			t.Fatal("acquired beyond limit")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		l.Release()
+
+		select {
+		case <-acquired:
+		case <-time.After(time.Second):
+			t.Fatal("not woken up after release")
+		}
+	})
+
+	t.Run("RespectsContextCancellation", func(t *testing.T) {
+		l := newSegmentLimiter(func() int { return 1 })
+		require.NoError(t, l.Acquire(t.Context()))
+
+		ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, l.Acquire(ctx), context.DeadlineExceeded)
+		assert.Empty(t, l.waiters)
+
+		l.Release()
+		require.NoError(t, l.Acquire(t.Context()))
+	})
+
+	t.Run("TryAcquire", func(t *testing.T) {
+		l := newSegmentLimiter(func() int { return 1 })
+		assert.True(t, l.TryAcquire())
+		assert.False(t, l.TryAcquire())
+
+		l.Release()
+		assert.True(t, l.TryAcquire())
+	})
+
+	t.Run("UnlimitedWithoutProviders", func(t *testing.T) {
+		l := newSegmentLimiter(func() int { return 0 })
+		for range 10 {
+			require.NoError(t, l.Acquire(t.Context()))
+		}
+	})
+
+	t.Run("NilLimiter", func(t *testing.T) {
+		var l *segmentLimiter
+		assert.NoError(t, l.Acquire(t.Context()))
+		assert.True(t, l.TryAcquire())
+		l.Release()
+	})
+}
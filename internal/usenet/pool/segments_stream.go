@@ -2,6 +2,7 @@ package usenet_pool
 
 import (
 	"context"
+	"errors"
 	"io"
 	"sync"
 	"sync/atomic"
@@ -41,12 +42,17 @@ type SegmentsStream struct {
 	bufferCond          *sync.Cond   // signals when buffer space available
 	bufferSizeRemaining atomic.Int64 // remaining buffer space
 
+	memoryBudget streamMemoryBudget // share of the budget across all streams
+	readIdx      int                // index of the next segment to read
+
 	mu       sync.Mutex
 	currData []byte // Current segment's remaining data
 	currPos  int    // Position within currentData
 	closed   bool
 
 	workerCount int
+
+	allowPartial bool
 }
 
 func NewSegmentsStream(
@@ -55,6 +61,19 @@ func NewSegmentsStream(
 	segments []nzb.Segment,
 	groups []string,
 	bufferSize int64,
+) *SegmentsStream {
+	return newSegmentsStream(ctx, pool, segments, groups, bufferSize, false)
+}
+
+// newSegmentsStream with allowPartial ends the stream cleanly before the
+// first missing segment, instead of failing with ErrArticleNotFound.
+func newSegmentsStream(
+	ctx context.Context,
+	pool *Pool,
+	segments []nzb.Segment,
+	groups []string,
+	bufferSize int64,
+	allowPartial bool,
 ) *SegmentsStream {
 	ctx, cancel := context.WithCancel(ctx)
 
@@ -70,8 +89,13 @@ func NewSegmentsStream(
 		errChan:     make(chan error, 1),
 		bufferCond:  sync.NewCond(&sync.Mutex{}),
 		workerCount: workerCount,
+
+		allowPartial: allowPartial,
 	}
 	s.bufferSizeRemaining.Store(bufferSize)
+	if pool != nil {
+		s.memoryBudget.budget = pool.memoryBudget
+	}
 
 	segmentLog.Trace("segments stream - created", "segment_count", len(segments), "buffer_size", bufferSize, "worker_count", workerCount)
 
@@ -131,6 +155,10 @@ func (s *SegmentsStream) startSegmentFetchDispatcher(segmentChan chan<- segmentW
 		s.bufferSizeRemaining.Add(-segment.Bytes)
 		s.bufferCond.L.Unlock()
 
+		if err := s.memoryBudget.acquire(s.ctx, segment.Bytes); err != nil {
+			return
+		}
+
 		select {
 		case <-s.ctx.Done():
 			return
@@ -147,7 +175,20 @@ func (s *SegmentsStream) startFetcher(segmentChan <-chan segmentWithIdx, resultC
 		default:
 		}
 
+		// cached only fetches never hit the providers, so they are not limited
+		cachedOnly := isCachedOnly(s.ctx)
+		if !cachedOnly {
+			if err := s.pool.segmentLimiter.Acquire(s.ctx); err != nil {
+				return
+			}
+		}
 		data, err := s.pool.fetchSegment(s.ctx, segmentWithIdx.Segment, s.groups)
+		if !cachedOnly {
+			s.pool.segmentLimiter.Release()
+		}
+		if errors.Is(err, ErrSegmentFetchTimeout) {
+			segmentLog.Warn("segments stream - segment fetch timed out", "segment_num", segmentWithIdx.Number, "idx", segmentWithIdx.idx)
+		}
 		if data != nil {
 			if adjustment := segmentWithIdx.Bytes - data.Size; adjustment != 0 {
 				s.bufferSizeRemaining.Add(adjustment)
@@ -171,7 +212,10 @@ func (s *SegmentsStream) startSegmentResultCollector(resultCh <-chan segmentResu
 	totalSegments := len(s.segments)
 	receivedCount := 0
 
-	for receivedCount < totalSegments {
+	// with allowPartial, the stream ends before the first missing segment
+	endIdx := totalSegments
+
+	for receivedCount < totalSegments && nextIdx < endIdx {
 		select {
 		case <-s.ctx.Done():
 			return
@@ -182,6 +226,16 @@ func (s *SegmentsStream) startSegmentResultCollector(resultCh <-chan segmentResu
 
 			receivedCount++
 
+			if result.idx >= endIdx {
+				continue
+			}
+
+			if result.err != nil && s.allowPartial && errors.Is(result.err, ErrArticleNotFound) {
+				segmentLog.Debug("segments stream - missing segment, truncating", "idx", result.idx)
+				endIdx = min(endIdx, result.idx)
+				continue
+			}
+
 			if result.err != nil {
 				segmentLog.Trace("segments stream - failed result", "error", result.err, "idx", result.idx)
 				select {
@@ -195,7 +249,7 @@ func (s *SegmentsStream) startSegmentResultCollector(resultCh <-chan segmentResu
 
 			pending[result.idx] = result.data
 
-			for {
+			for nextIdx < endIdx {
 				data, ok := pending[nextIdx]
 				if !ok {
 					break
@@ -241,6 +295,7 @@ func (s *SegmentsStream) Read(p []byte) (n int, err error) {
 		data, ok := <-s.dataChan
 		if !ok {
 			segmentLog.Trace("segments stream - no more segments", "segment_count", len(s.segments))
+			s.memoryBudget.releaseAll()
 			if n > 0 {
 				return n, nil
 			}
@@ -249,6 +304,9 @@ func (s *SegmentsStream) Read(p []byte) (n int, err error) {
 
 		s.bufferSizeRemaining.Add(data.Size)
 		s.bufferCond.Signal()
+		// segments are received in order
+		s.memoryBudget.release(s.segments[s.readIdx].Bytes)
+		s.readIdx++
 
 		segmentLog.Trace("segments stream - segment received", "size", len(data.Body))
 
@@ -259,6 +317,14 @@ func (s *SegmentsStream) Read(p []byte) (n int, err error) {
 	return n, nil
 }
 
+// growBuffer extends the read-ahead buffer of the running stream.
+func (s *SegmentsStream) growBuffer(delta int64) {
+	s.bufferCond.L.Lock()
+	s.bufferSizeRemaining.Add(delta)
+	s.bufferCond.L.Unlock()
+	s.bufferCond.Broadcast()
+}
+
 func (s *SegmentsStream) Close() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -275,6 +341,7 @@ func (s *SegmentsStream) Close() error {
 	for range s.dataChan {
 		// drain
 	}
+	s.memoryBudget.releaseAll()
 
 	return nil
 }
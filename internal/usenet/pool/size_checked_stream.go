@@ -0,0 +1,42 @@
+package usenet_pool
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+)
+
+var ErrStreamEndedShort = errors.New("usenet: stream ended before the file size")
+
+// sizeCheckedFileStream verifies that the bytes read add up to the file size
+// when the stream ends, to catch a segment silently dropped on the way. A
+// mismatch is logged, and returned as an error in place of io.EOF only when
+// strict size is enabled in the config.
+type sizeCheckedFileStream struct {
+	*FileStream
+	position int64
+}
+
+func newSizeCheckedFileStream(stream *FileStream) *sizeCheckedFileStream {
+	return &sizeCheckedFileStream{FileStream: stream}
+}
+
+func (s *sizeCheckedFileStream) Read(p []byte) (int, error) {
+	n, err := s.FileStream.Read(p)
+	s.position += int64(n)
+	if err == io.EOF && s.position != s.Size() {
+		fileLog.Warn("file stream - ended before the file size", "position", s.position, "file_size", s.Size())
+		if config.Newz.StreamStrictSize {
+			return n, fmt.Errorf("%w: ended at %d of %d bytes", ErrStreamEndedShort, s.position, s.Size())
+		}
+	}
+	return n, err
+}
+
+func (s *sizeCheckedFileStream) Seek(offset int64, whence int) (int64, error) {
+	position, err := s.FileStream.Seek(offset, whence)
+	s.position = position
+	return position, err
+}
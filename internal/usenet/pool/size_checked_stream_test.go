@@ -0,0 +1,81 @@
+package usenet_pool
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/logger"
+	"github.com/MunifTanjim/stremthru/internal/nntp"
+	"github.com/MunifTanjim/stremthru/internal/nntp/nntptest"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSizeCheckedFileStream(t *testing.T) {
+	const segmentSize = 50
+
+	// the segments claim a file of 3 parts, the NZB lists only 2 of them
+	data := makeTestBytes(3 * segmentSize)
+
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.SetResponse("GROUP alt.test", "211 2 1 2 alt.test")
+
+	segments := make([]nzb.Segment, 2)
+	for i := range segments {
+		msgId := fmt.Sprintf("short%d@test.com", i+1)
+		encoded := encodeYenc(data[i*segmentSize:(i+1)*segmentSize], "test.mkv", i+1, 3, int64(len(data)), int64(i*segmentSize)+1)
+		server.SetResponse("BODY <"+msgId+">", "222 0 <"+msgId+">", strings.Split(strings.TrimSpace(string(encoded)), "\r\n"))
+		segments[i] = nzb.Segment{MessageId: msgId, Bytes: int64(len(encoded)), Number: i + 1}
+	}
+	server.Start(t)
+
+	usenetPool := &Pool{
+		Log:          logger.Scoped("test/usenet/pool"),
+		providers:    []*providerPool{{Pool: nntptest.NewPool(t, server, &nntp.PoolConfig{})}},
+		segmentCache: NewSegmentCache(10*1024*1024, t.TempDir()),
+	}
+
+	file := &nzb.File{Segments: segments, Groups: []string{"alt.test"}}
+
+	prevStrictSize := config.Newz.StreamStrictSize
+	t.Cleanup(func() {
+		config.Newz.StreamStrictSize = prevStrictSize
+	})
+
+	newStream := func(t *testing.T) *sizeCheckedFileStream {
+		stream, err := NewFileStream(t.Context(), usenetPool, file, 0)
+		require.NoError(t, err)
+		t.Cleanup(func() { stream.Close() })
+		return newSizeCheckedFileStream(stream)
+	}
+
+	t.Run("LogOnly", func(t *testing.T) {
+		config.Newz.StreamStrictSize = false
+
+		got, err := io.ReadAll(newStream(t))
+		require.NoError(t, err)
+		assert.Equal(t, data[:2*segmentSize], got)
+	})
+
+	t.Run("Strict", func(t *testing.T) {
+		config.Newz.StreamStrictSize = true
+
+		_, err := io.ReadAll(newStream(t))
+		assert.ErrorIs(t, err, ErrStreamEndedShort)
+	})
+
+	t.Run("SeekPastMissing", func(t *testing.T) {
+		config.Newz.StreamStrictSize = true
+
+		stream := newStream(t)
+		_, err := stream.Seek(0, io.SeekEnd)
+		require.NoError(t, err)
+		n, err := stream.Read(make([]byte, 10))
+		assert.Equal(t, 0, n)
+		assert.Equal(t, io.EOF, err)
+	})
+}
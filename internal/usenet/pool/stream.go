@@ -7,9 +7,12 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
+	"regexp"
 	"slices"
 	"strings"
+	"time"
 
+	"github.com/MunifTanjim/stremthru/internal/config"
 	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
 )
 
@@ -18,9 +21,22 @@ var (
 )
 
 type StreamConfig struct {
-	Password          string
-	SegmentBufferSize int64
-	ContentFiles      []NZBContentFile
+	Password             string
+	SegmentBufferSize    int64
+	ContentFiles         []NZBContentFile
+	RateLimitBytesPerSec int64          // 0 means unlimited
+	AllowPartial         bool           // serve the available prefix of a plain file with missing trailing segments
+	NZBHash              string         // downloaded bytes are attributed to it
+	CachedOnly           bool           // serve only from the segment cache, never hit the providers
+	BypassCache          bool           // fetch the segments afresh, skipping the segment cache read
+	VideoSelect          VideoSelect    // for archives with multiple videos, defaults to config
+	CacheStats           *CacheStats    // segment cache hits/misses are recorded in it
+	AllowCompressed      bool           // serve compressed archive entries forward-only, through the decompressor
+	VideoContentType     string         // for a video of unknown type, defaults to config
+	GroupsOverride       []string       // newsgroups used in place of the ones listed in the NZB
+	KeepFileGroups       bool           // try the NZB's newsgroups after GroupsOverride, instead of dropping them
+	ProviderAllowlist    []string       // ids of the providers the segments are fetched from, any if empty
+	PrefetchGroup        *PrefetchGroup // the file streams are tracked in it, to pause their read-ahead
 }
 
 type Stream struct {
@@ -28,8 +44,42 @@ type Stream struct {
 	Name        string
 	Size        int64
 	ContentType string
+	Path        string // resolved content path, '::'-joined
+	Truncated   bool   // only a prefix of the file is available
+	ForwardOnly bool   // decompressed on the fly, seeking is not supported
 }
 
+// withVideoContentType serves the video as contentType, if its own type is
+// unknown.
+func (s *Stream) withVideoContentType(contentType string) *Stream {
+	if contentType != "" && s.ContentType == contentTypeUnknown {
+		s.ContentType = contentType
+	}
+	return s
+}
+
+// withStreamConfig carries the settings that apply to the segment fetches.
+func withStreamConfig(ctx context.Context, config *StreamConfig) context.Context {
+	ctx = withCacheStats(ctx, config.CacheStats)
+	ctx = withGroupsOverride(ctx, config.GroupsOverride, config.KeepFileGroups)
+	ctx = withBypassCache(ctx, config.BypassCache)
+	ctx = WithProviderAllowlist(ctx, config.ProviderAllowlist)
+	ctx = withPrefetchGroup(ctx, config.PrefetchGroup)
+	return withCachedOnly(withNZBHash(ctx, config.NZBHash), config.CachedOnly)
+}
+
+func joinContentPath(parts ...string) string {
+	return strings.Join(parts, "::")
+}
+
+// contentPathWildcard as the last content path part (or an empty one) selects
+// the largest streamable video inside the archive, e.g. 'archive.rar::*'.
+//
+// A disc title inside the archive is selected by its playlist or title set
+// ifo, e.g. 'archive.rar::BDMV/PLAYLIST/00800.mpls' or
+// 'archive.rar::VIDEO_TS/VTS_01_0.IFO', and streamed as the joined clips.
+const contentPathWildcard = "*"
+
 func (p *Pool) streamFile(
 	ctx context.Context,
 	nzbDoc *nzb.NZB,
@@ -39,6 +89,7 @@ func (p *Pool) streamFile(
 	if config == nil {
 		config = &StreamConfig{}
 	}
+	ctx = withStreamConfig(ctx, config)
 
 	if fileIdx < 0 || fileIdx >= nzbDoc.FileCount() {
 		return nil, fmt.Errorf("file index %d out of range [0, %d)", fileIdx, nzbDoc.FileCount())
@@ -51,7 +102,7 @@ func (p *Pool) streamFile(
 
 	p.Log.Trace("found file", "idx", fileIdx, "name", file.Name(), "segment_count", file.SegmentCount())
 
-	firstSegment, err := p.fetchFirstSegment(ctx, file)
+	firstSegment, err := p.fetchFileHeader(ctx, file)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch file header: %w", err)
 	}
@@ -62,16 +113,21 @@ func (p *Pool) streamFile(
 
 	p.Log.Trace("file type detected", "type", fileType, "filename", filename)
 
+	var stream *Stream
 	switch fileType {
 	case FileTypePlain:
-		return p.streamPlainFile(file, config)
-	case FileTypeRAR:
-		return p.streamRARFile(ctx, nzbDoc, config)
-	case FileType7z:
-		return p.stream7zFile(ctx, nzbDoc, config)
+		stream, err = p.streamPlainFile(file, config)
+	case FileTypeRAR, FileType7z, FileTypeZIP:
+		stream, err = p.streamArchive(ctx, nzbDoc, fileType, config, func(archive Archive, archiveType FileType) (*Stream, error) {
+			return p.streamArchiveFile(archive, archiveType, config.getVideoSelect(), config.AllowCompressed)
+		})
 	default:
 		return nil, fmt.Errorf("unsupported file type: %s", fileType)
 	}
+	if err != nil {
+		return nil, err
+	}
+	return stream.throttle(ctx, config.RateLimitBytesPerSec), nil
 }
 
 func (p *Pool) fetchFirstSegment(
@@ -81,16 +137,77 @@ func (p *Pool) fetchFirstSegment(
 	p.Log.Trace("fetch first segment - start")
 
 	firstSegment := &file.Segments[0]
-	data, err := p.fetchSegment(ctx, firstSegment, file.Groups)
-	if err != nil {
-		return nil, err
+
+	type fetchResult struct {
+		data *SegmentData
+		err  error
+	}
+	done := make(chan fetchResult, 1)
+	go func() {
+		data, err := p.fetchSegment(ctx, firstSegment, file.Groups)
+		done <- fetchResult{data: data, err: err}
+	}()
+
+	// the first segment gates the whole stream setup, so fail fast on a slow
+	// provider. the fetch itself keeps going and ends up in the segment cache.
+	timeout := config.Newz.FirstSegmentTimeout
+	var timeoutC <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutC = timer.C
+	}
+
+	var data *SegmentData
+	select {
+	case result := <-done:
+		if result.err != nil {
+			return nil, result.err
+		}
+		data = result.data
+	case <-timeoutC:
+		p.Log.Warn("fetch first segment - timed out", "message_id", firstSegment.MessageId, "timeout", timeout)
+		return nil, fmt.Errorf("%w: segment <%s> after %s", ErrFirstSegmentTimeout, firstSegment.MessageId, timeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 
 	p.Log.Trace("fetch first segment - done", "size", data.Size)
 
+	if data.Encoding != "" && file.SegmentCount() > 1 {
+		return nil, fmt.Errorf("%w: %s in %d segments", ErrUnplacedSegmentEncoding, data.Encoding, file.SegmentCount())
+	}
+
 	return data, nil
 }
 
+// fetchFileHeader fetches the first segment of the file, for its type and
+// size. If the first segment is missing, the size is recovered from one of
+// the next few segments, as configured. The returned data has no body then,
+// so the type is detected by the filename.
+func (p *Pool) fetchFileHeader(ctx context.Context, file *nzb.File) (*SegmentData, error) {
+	data, err := p.fetchFirstSegment(ctx, file)
+	fallback := config.Newz.FirstSegmentFallback
+	if err == nil || fallback <= 0 || !errors.Is(err, ErrArticleNotFound) {
+		return data, err
+	}
+
+	for i := 1; i <= fallback && i < file.SegmentCount(); i++ {
+		segment := &file.Segments[i]
+		next, nextErr := p.fetchSegment(ctx, segment, file.Groups)
+		if nextErr != nil {
+			p.Log.Debug("fetch file header - fallback segment failed", "error", nextErr, "segment_num", segment.Number)
+			continue
+		}
+		if next.FileSize <= 0 {
+			continue
+		}
+		p.Log.Warn("fetch file header - first segment missing, size recovered from a later segment", "segment_num", segment.Number, "file_size", next.FileSize)
+		return &SegmentData{FileSize: next.FileSize}, nil
+	}
+	return nil, err
+}
+
 func (p *Pool) streamPlainFile(
 	file *nzb.File,
 	config *StreamConfig,
@@ -100,7 +217,7 @@ func (p *Pool) streamPlainFile(
 	p.Log.Trace("creating stream", "stream_type", "plain", "filename", filename, "segment_count", file.SegmentCount())
 
 	stream, err := NewFileStream(
-		context.Background(),
+		withStreamConfig(context.Background(), config),
 		p,
 		file,
 		config.SegmentBufferSize,
@@ -109,30 +226,98 @@ func (p *Pool) streamPlainFile(
 		return nil, err
 	}
 
+	if config.AllowPartial {
+		if err := stream.enablePartial(); err != nil {
+			stream.Close()
+			return nil, err
+		}
+	}
+
 	return &Stream{
-		ReadSeekCloser: stream,
+		ReadSeekCloser: newSizeCheckedFileStream(stream),
 		Name:           filename,
 		Size:           stream.Size(),
-		ContentType:    GetContentType(filename),
+		ContentType:    detectContentType(stream, filename),
+		Path:           filename,
+		Truncated:      stream.Truncated(),
 	}, nil
 }
 
+func getArchiveFilesSize(files []ArchiveFile) (size int64) {
+	for _, f := range files {
+		size += max(f.Size(), 0)
+	}
+	return size
+}
+
 func filterVideoFiles(files []ArchiveFile) []ArchiveFile {
 	videos := make([]ArchiveFile, 0)
 	for _, f := range files {
-		if isVideoFile(f.Name()) {
+		if isMainVideoFile(f.Name()) {
 			videos = append(videos, f)
 		}
 	}
 	return videos
 }
 
+// nonStreamableArchiveError tells apart a solid archive from one that could
+// not be read, e.g. for a missing password.
+func nonStreamableArchiveError(archive Archive, archiveType FileType) error {
+	if ra, ok := archive.(*RARArchive); ok {
+		if _, err := ra.isSolid(); err != nil {
+			return fmt.Errorf("non-streamable %s archive: %w", archiveType, err)
+		}
+	}
+	return fmt.Errorf("non-streamable %s archive: %w", archiveType, ErrArchiveSolid)
+}
+
+// getSolidSequentialVideo returns the only video of a solid rar archive, if
+// it is allowed to be decompressed sequentially.
+func getSolidSequentialVideo(archive Archive) *UsenetRARFile {
+	if !config.Newz.AllowSolidSequential {
+		return nil
+	}
+	ra, ok := archive.(*RARArchive)
+	if !ok {
+		return nil
+	}
+	if solid, err := ra.isSolid(); err != nil || !solid {
+		return nil
+	}
+	files, err := ra.GetFiles()
+	if err != nil {
+		return nil
+	}
+	videos := filterVideoFiles(files)
+	if len(videos) != 1 || videos[0].Size() <= 0 {
+		return nil
+	}
+	video, _ := videos[0].(*UsenetRARFile)
+	return video
+}
+
+// streamSolidSequential serves the video forward-only, the entries preceding
+// it are decompressed and discarded before the first byte.
+func (p *Pool) streamSolidSequential(video *UsenetRARFile, archiveType FileType) (*Stream, error) {
+	p.Log.Debug("stream archive file - streaming solid video sequentially", "type", archiveType, "filename", video.Name())
+	r, err := video.openSolidSequential()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open: %w", err)
+	}
+	return newForwardOnlyStream(video, r), nil
+}
+
 func (p *Pool) streamArchiveFile(
 	archive Archive,
 	archiveType FileType,
+	videoSelect VideoSelect,
+	allowCompressed bool,
 ) (*Stream, error) {
 	if !archive.IsStreamable() {
-		return nil, fmt.Errorf("non-streamable %s archive", archiveType)
+		if video := getSolidSequentialVideo(archive); video != nil {
+			return p.streamSolidSequential(video, archiveType)
+		}
+		return nil, nonStreamableArchiveError(archive, archiveType)
 	}
 
 	files, err := archive.GetFiles()
@@ -140,9 +325,9 @@ func (p *Pool) streamArchiveFile(
 		return nil, err
 	}
 
-	if archiveGroups := groupArchiveVolumes(files); len(archiveGroups) > 0 {
+	if archiveGroups := groupArchiveVolumes(typeAliasedArchiveParts(files)); len(archiveGroups) > 0 {
 		p.Log.Trace("stream archive file - found nested archives, trying them first", "type", archiveType)
-		stream, err := p.streamNestedArchive(archiveGroups)
+		stream, err := p.streamNestedArchive(archiveGroups, videoSelect, allowCompressed)
 		if err == nil {
 			return stream, nil
 		}
@@ -154,34 +339,64 @@ func (p *Pool) streamArchiveFile(
 		return nil, fmt.Errorf("no video files or nested archives found in %s archive", archiveType)
 	}
 
-	return p.streamVideoFromArchive(videos, archiveType)
+	return p.streamVideoFromArchive(videos, archiveType, videoSelect, getVideoMinSize(getArchiveFilesSize(files)), allowCompressed)
 }
 
-func (p *Pool) streamVideoFromArchive(videos []ArchiveFile, archiveType FileType) (*Stream, error) {
-	file := slices.MaxFunc(videos, func(a, b ArchiveFile) int {
-		return cmp.Compare(a.Size(), b.Size())
-	})
+func (p *Pool) streamVideoFromArchive(videos []ArchiveFile, archiveType FileType, videoSelect VideoSelect, minSize int64, allowCompressed bool) (*Stream, error) {
+	videos = sortVideos(videos, videoSelect, minSize)
 
-	p.Log.Trace("stream archive file - target selected", "type", archiveType, "filename", file.Name())
+	var lastErr error
+	var compressed ArchiveFile
+	for _, file := range videos {
+		p.Log.Trace("stream archive file - target selected", "type", archiveType, "filename", file.Name())
+
+		if !file.IsStreamable() {
+			if allowCompressed && compressed == nil && file.Size() > 0 && canDecompress(file) {
+				compressed = file
+			}
+			p.Log.Debug("stream archive file - skipping non-streamable video", "type", archiveType, "filename", file.Name())
+			continue
+		}
+
+		if file.Size() <= 0 {
+			lastErr = fmt.Errorf("%w: %s", ErrEmptyFile, file.Name())
+			p.Log.Debug("stream archive file - skipping empty video", "type", archiveType, "filename", file.Name())
+			continue
+		}
 
-	if !file.IsStreamable() {
-		return nil, fmt.Errorf("non-streamable file in %s archive", archiveType)
+		r, err := file.Open()
+		if err != nil {
+			lastErr = fmt.Errorf("failed to open: %w", err)
+			p.Log.Debug("stream archive file - failed to open video", "error", err, "type", archiveType, "filename", file.Name())
+			continue
+		}
+
+		return &Stream{
+			ReadSeekCloser: r,
+			Name:           file.Name(),
+			Size:           file.Size(),
+			ContentType:    detectContentType(r, file.Name()),
+			Path:           file.Name(),
+		}, nil
 	}
 
-	r, err := file.Open()
-	if err != nil {
-		return nil, fmt.Errorf("failed to open: %w", err)
+	// seekable videos are preferred, the compressed one is the last resort
+	if compressed != nil {
+		p.Log.Debug("stream archive file - streaming compressed video forward-only", "type", archiveType, "filename", compressed.Name())
+		stream, err := openForwardOnly(compressed)
+		if err == nil {
+			return stream, nil
+		}
+		lastErr = fmt.Errorf("failed to open: %w", err)
 	}
 
-	return &Stream{
-		ReadSeekCloser: r,
-		Name:           file.Name(),
-		Size:           file.Size(),
-		ContentType:    GetContentType(file.Name()),
-	}, nil
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, fmt.Errorf("non-streamable file in %s archive: %w", archiveType, ErrArchiveSolid)
 }
 
-func (p *Pool) streamNestedArchive(archiveGroups []archiveVolumeGroup[ArchiveFile]) (*Stream, error) {
+func (p *Pool) streamNestedArchive(archiveGroups []archiveVolumeGroup[ArchiveFile], videoSelect VideoSelect, allowCompressed bool) (*Stream, error) {
 	var lastErr error
 	for i := range archiveGroups {
 		group := &archiveGroups[i]
@@ -191,7 +406,7 @@ func (p *Pool) streamNestedArchive(archiveGroups []archiveVolumeGroup[ArchiveFil
 			"parts", len(group.Files),
 			"total_size", group.TotalSize)
 
-		stream, err := p.tryStreamNestedArchiveGroup(group)
+		stream, err := p.tryStreamNestedArchiveGroup(group, videoSelect, allowCompressed)
 		if err != nil {
 			p.Log.Debug("stream nested archive - group failed", "error", err)
 			lastErr = err
@@ -206,21 +421,24 @@ func (p *Pool) streamNestedArchive(archiveGroups []archiveVolumeGroup[ArchiveFil
 	return nil, fmt.Errorf("no streamable content found in nested archives")
 }
 
-func (p *Pool) tryStreamNestedArchiveGroup(group *archiveVolumeGroup[ArchiveFile]) (*Stream, error) {
+func (p *Pool) tryStreamNestedArchiveGroup(group *archiveVolumeGroup[ArchiveFile], videoSelect VideoSelect, allowCompressed bool) (*Stream, error) {
 	for _, f := range group.Files {
 		if !f.IsStreamable() {
-			return nil, fmt.Errorf("inner archive part %s is not streamable", f.Name())
+			return nil, fmt.Errorf("inner archive part %s is not streamable: %w", f.Name(), ErrArchiveSolid)
 		}
 	}
 
-	afs := NewArchiveFS(group.Files)
+	archiveFiles, archiveName := getArchiveGroupFiles(group)
+	afs := NewArchiveFS(archiveFiles)
 
 	var innerArchive Archive
 	switch group.FileType {
 	case FileTypeRAR:
-		innerArchive = NewRARArchive(afs, filepath.Base(group.Files[0].Name()))
+		innerArchive = NewRARArchive(afs, archiveName)
 	case FileType7z:
-		innerArchive = NewSevenZipArchive(afs.toAfero(), filepath.Base(group.Files[0].Name()))
+		innerArchive = NewSevenZipArchive(afs.toAfero(), archiveName)
+	case FileTypeZIP:
+		innerArchive = NewZIPArchive(afs.toAfero(), archiveName)
 	default:
 		afs.Close()
 		return nil, fmt.Errorf("unsupported inner archive type: %s", group.FileType)
@@ -231,7 +449,7 @@ func (p *Pool) tryStreamNestedArchiveGroup(group *archiveVolumeGroup[ArchiveFile
 		return nil, fmt.Errorf("failed to open inner archive: %w", err)
 	}
 
-	stream, err := p.streamArchiveFileInner(innerArchive, group.FileType)
+	stream, err := p.streamArchiveFileInner(innerArchive, group.FileType, videoSelect, allowCompressed)
 	if err != nil {
 		innerArchive.Close()
 		return nil, err
@@ -245,12 +463,14 @@ func (p *Pool) tryStreamNestedArchiveGroup(group *archiveVolumeGroup[ArchiveFile
 		Name:        stream.Name,
 		Size:        stream.Size,
 		ContentType: stream.ContentType,
+		Path:        joinContentPath(group.Files[0].Name(), stream.Path),
+		ForwardOnly: stream.ForwardOnly,
 	}, nil
 }
 
-func (p *Pool) streamArchiveFileInner(archive Archive, archiveType FileType) (*Stream, error) {
+func (p *Pool) streamArchiveFileInner(archive Archive, archiveType FileType, videoSelect VideoSelect, allowCompressed bool) (*Stream, error) {
 	if !archive.IsStreamable() {
-		return nil, fmt.Errorf("non-streamable inner %s archive", archiveType)
+		return nil, fmt.Errorf("non-streamable inner %s archive: %w", archiveType, ErrArchiveSolid)
 	}
 
 	files, err := archive.GetFiles()
@@ -263,7 +483,7 @@ func (p *Pool) streamArchiveFileInner(archive Archive, archiveType FileType) (*S
 		return nil, fmt.Errorf("no video files found in inner %s archive", archiveType)
 	}
 
-	return p.streamVideoFromArchive(videos, archiveType)
+	return p.streamVideoFromArchive(videos, archiveType, videoSelect, getVideoMinSize(getArchiveFilesSize(files)), allowCompressed)
 }
 
 type nestedArchiveStream struct {
@@ -277,41 +497,95 @@ func (nas *nestedArchiveStream) Close() error {
 	return errors.Join(streamErr, archiveErr)
 }
 
-func (p *Pool) streamRARFile(
+// archiveStreamSelector picks the stream out of an opened archive.
+type archiveStreamSelector func(archive Archive, archiveType FileType) (*Stream, error)
+
+// streamArchive opens the nzb as an archive of archiveType and streams the
+// file picked by selectStream from it. The archive is closed if it fails.
+func (p *Pool) streamArchive(
 	ctx context.Context,
 	nzbDoc *nzb.NZB,
+	archiveType FileType,
 	config *StreamConfig,
+	selectStream archiveStreamSelector,
 ) (*Stream, error) {
 	ufs := NewUsenetFS(ctx, &UsenetFSConfig{
 		NZB:               nzbDoc,
 		Pool:              p,
 		SegmentBufferSize: config.SegmentBufferSize,
 	})
-	archive := NewUsenetRARArchive(ufs)
+
+	var archive Archive
+	var archiveName string
+	switch archiveType {
+	case FileTypeRAR:
+		rarArchive := NewUsenetRARArchive(ufs)
+		archive, archiveName = rarArchive, rarArchive.name
+	case FileType7z:
+		sevenZipArchive := NewUsenetSevenZipArchive(ufs)
+		archive, archiveName = sevenZipArchive, sevenZipArchive.name
+	case FileTypeZIP:
+		zipArchive := NewUsenetZIPArchive(ufs)
+		archive, archiveName = zipArchive, zipArchive.name
+	default:
+		ufs.Close()
+		return nil, fmt.Errorf("unsupported file type: %s", archiveType)
+	}
+
 	if err := archive.Open(config.Password); err != nil {
+		archive.Close()
+		return nil, err
+	}
+	stream, err := selectStream(archive, archiveType)
+	if err != nil {
+		archive.Close()
 		return nil, err
 	}
-	return p.streamArchiveFile(archive, FileTypeRAR)
+	stream.Path = joinContentPath(archiveName, stream.Path)
+	return stream, nil
 }
 
-func (p *Pool) stream7zFile(
+func (p *Pool) StreamLargestFile(
 	ctx context.Context,
 	nzbDoc *nzb.NZB,
 	config *StreamConfig,
 ) (*Stream, error) {
-	ufs := NewUsenetFS(ctx, &UsenetFSConfig{
-		NZB:               nzbDoc,
-		Pool:              p,
-		SegmentBufferSize: config.SegmentBufferSize,
+	if len(nzbDoc.Files) == 0 {
+		return nil, errors.New("NZB has no files")
+	}
+
+	if config == nil {
+		config = &StreamConfig{}
+	}
+
+	largestFileIdx := nzbDoc.GetLargestFileIdx(func(filename string) bool {
+		return !isMainVideoFile(filename) && !IsArchiveFile(filename)
 	})
-	archive := NewUsenetSevenZipArchive(ufs)
-	if err := archive.Open(config.Password); err != nil {
+	if largestFileIdx == -1 {
+		return nil, ErrNoStreamableContent
+	}
+	if f := &nzbDoc.Files[largestFileIdx]; isVideoFile(f.Name()) && f.Size() < getVideoMinSize(nzbDoc.TotalSize()) {
+		// every video is a likely sample, the archives may have the real one
+		if archiveIdx := nzbDoc.GetLargestFileIdx(func(filename string) bool {
+			return !IsArchiveFile(filename)
+		}); archiveIdx != -1 {
+			p.Log.Trace("skipping undersized video for archive", "name", f.Name(), "size", f.Size())
+			largestFileIdx = archiveIdx
+		}
+	}
+
+	p.Log.Trace("found largest file", "idx", largestFileIdx)
+
+	stream, err := p.streamFile(ctx, nzbDoc, largestFileIdx, config)
+	if err != nil {
 		return nil, err
 	}
-	return p.streamArchiveFile(archive, FileType7z)
+	return stream.withVideoContentType(config.getVideoContentType()), nil
 }
 
-func (p *Pool) StreamLargestFile(
+// StreamLargestFileOfAnyType streams the largest file as is, regardless of
+// its type. Unlike StreamLargestFile, archives are not looked into.
+func (p *Pool) StreamLargestFileOfAnyType(
 	ctx context.Context,
 	nzbDoc *nzb.NZB,
 	config *StreamConfig,
@@ -320,13 +594,117 @@ func (p *Pool) StreamLargestFile(
 		return nil, errors.New("NZB has no files")
 	}
 
+	if config == nil {
+		config = &StreamConfig{}
+	}
+
+	largestFileIdx := nzbDoc.GetLargestFileIdx(nil)
+	if largestFileIdx == -1 {
+		return nil, errors.New("NZB has no non-empty files")
+	}
+
+	p.Log.Trace("found largest file of any type", "idx", largestFileIdx)
+
+	stream, err := p.streamPlainFile(&nzbDoc.Files[largestFileIdx], config)
+	if err != nil {
+		return nil, err
+	}
+	return stream.throttle(ctx, config.RateLimitBytesPerSec), nil
+}
+
+// StreamSampleFile streams the smallest streamable video, e.g. a sample, for
+// previews without pulling the whole release. Archives are looked into only
+// when the NZB has no video file at the top level.
+func (p *Pool) StreamSampleFile(
+	ctx context.Context,
+	nzbDoc *nzb.NZB,
+	config *StreamConfig,
+) (*Stream, error) {
+	if len(nzbDoc.Files) == 0 {
+		return nil, errors.New("NZB has no files")
+	}
+
+	if config == nil {
+		config = &StreamConfig{}
+	}
+	ctx = withStreamConfig(ctx, config)
+
+	videos := []*nzb.File{}
+	for i := range nzbDoc.Files {
+		f := &nzbDoc.Files[i]
+		if isVideoFile(f.Name()) && f.SegmentCount() > 0 && f.Size() > 0 {
+			videos = append(videos, f)
+		}
+	}
+	if len(videos) > 0 {
+		sample := slices.MinFunc(videos, func(a, b *nzb.File) int {
+			return cmp.Compare(a.Size(), b.Size())
+		})
+		p.Log.Trace("found sample file", "name", sample.Name())
+
+		stream, err := p.streamPlainFile(sample, config)
+		if err != nil {
+			return nil, err
+		}
+		stream.withVideoContentType(config.getVideoContentType())
+		return stream.throttle(ctx, config.RateLimitBytesPerSec), nil
+	}
+
 	largestFileIdx := nzbDoc.GetLargestFileIdx(func(filename string) bool {
-		return !isVideoFile(filename) && !IsArchiveFile(filename)
+		return !IsArchiveFile(filename)
 	})
+	if largestFileIdx == -1 {
+		return nil, ErrNoStreamableContent
+	}
 
-	p.Log.Trace("found largest file", "idx", largestFileIdx)
+	file := &nzbDoc.Files[largestFileIdx]
+	firstSegment, err := p.fetchFirstSegment(ctx, file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch file header: %w", err)
+	}
 
-	return p.streamFile(ctx, nzbDoc, largestFileIdx, config)
+	fileType := DetectFileType(firstSegment.Body, file.Name())
+	stream, err := p.streamArchive(ctx, nzbDoc, fileType, config, p.streamSampleFromArchive)
+	if err != nil {
+		return nil, err
+	}
+	stream.withVideoContentType(config.getVideoContentType())
+	return stream.throttle(ctx, config.RateLimitBytesPerSec), nil
+}
+
+func (p *Pool) streamSampleFromArchive(archive Archive, archiveType FileType) (*Stream, error) {
+	if !archive.IsStreamable() {
+		return nil, nonStreamableArchiveError(archive, archiveType)
+	}
+
+	files, err := archive.GetFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	videos := slices.DeleteFunc(slices.Clone(files), func(f ArchiveFile) bool {
+		return !isVideoFile(f.Name()) || !f.IsStreamable() || f.Size() <= 0
+	})
+	if len(videos) == 0 {
+		return nil, fmt.Errorf("no streamable video files found in %s archive", archiveType)
+	}
+
+	sample := slices.MinFunc(videos, func(a, b ArchiveFile) int {
+		return cmp.Compare(a.Size(), b.Size())
+	})
+	p.Log.Trace("found sample file in archive", "type", archiveType, "filename", sample.Name())
+
+	r, err := sample.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", sample.Name(), err)
+	}
+	return &Stream{
+		ReadSeekCloser: r,
+		Name:           sample.Name(),
+		Size:           sample.Size(),
+		ContentType:    detectContentType(r, sample.Name()),
+		Path:           sample.Name(),
+	}, nil
 }
 
 func (p *Pool) StreamFileByName(
@@ -343,27 +721,88 @@ func (p *Pool) StreamFileByName(
 	return nil, fmt.Errorf("no file matching '%s' found", filename)
 }
 
+// StreamLargestFileMatching streams the largest file with the name, or the
+// alias from the content files, matching the pattern, e.g. an episode of a
+// pack. Archives are looked into like StreamLargestFile.
+func (p *Pool) StreamLargestFileMatching(
+	ctx context.Context,
+	nzbDoc *nzb.NZB,
+	pattern *regexp.Regexp,
+	config *StreamConfig,
+) (*Stream, error) {
+	if len(nzbDoc.Files) == 0 {
+		return nil, errors.New("NZB has no files")
+	}
+
+	if config == nil {
+		config = &StreamConfig{}
+	}
+
+	aliases := map[string]string{}
+	for i := range config.ContentFiles {
+		if cf := &config.ContentFiles[i]; cf.Alias != "" {
+			aliases[cf.Name] = cf.Alias
+		}
+	}
+
+	largestFileIdx := nzbDoc.GetLargestFileIdx(func(filename string) bool {
+		if alias, ok := aliases[filename]; ok && pattern.MatchString(alias) {
+			return false
+		}
+		return !pattern.MatchString(filename)
+	})
+	if largestFileIdx == -1 {
+		return nil, fmt.Errorf("no file matching '%s' found", pattern)
+	}
+
+	p.Log.Trace("found largest matching file", "idx", largestFileIdx, "pattern", pattern)
+
+	return p.streamFile(ctx, nzbDoc, largestFileIdx, config)
+}
+
 func (p *Pool) streamTargetFromArchive(
 	archive Archive,
 	targetParts []string,
 	archiveType FileType,
+	videoSelect VideoSelect,
+	allowCompressed bool,
 ) (*Stream, error) {
+	targetName := strings.Trim(targetParts[0], "/")
+	remainingParts := targetParts[1:]
+
+	if !archive.IsStreamable() && len(remainingParts) == 0 {
+		video := getSolidSequentialVideo(archive)
+		if video != nil && (targetName == contentPathWildcard || targetName == "" || strings.EqualFold(video.Name(), targetName)) {
+			return p.streamSolidSequential(video, archiveType)
+		}
+	}
+
+	if len(remainingParts) == 0 && (targetName == contentPathWildcard || targetName == "") {
+		return p.streamArchiveFileInner(archive, archiveType, videoSelect, allowCompressed)
+	}
+
 	files, err := archive.GetFiles()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get archive files: %w", err)
 	}
 
-	targetName := strings.Trim(targetParts[0], "/")
-	remainingParts := targetParts[1:]
-
 	for _, f := range files {
 		if !strings.EqualFold(f.Name(), targetName) {
 			continue
 		}
 
 		if len(remainingParts) == 0 {
+			if isDiscTitleFile(f.Name()) {
+				return streamDiscTitle(files, f)
+			}
 			if !f.IsStreamable() {
-				return nil, fmt.Errorf("file %s is not streamable", f.Name())
+				if allowCompressed && canDecompress(f) && f.Size() > 0 {
+					return openForwardOnly(f)
+				}
+				return nil, fmt.Errorf("file %s is not streamable: %w", f.Name(), ErrArchiveSolid)
+			}
+			if f.Size() <= 0 {
+				return nil, fmt.Errorf("%w: %s", ErrEmptyFile, f.Name())
 			}
 			r, err := f.Open()
 			if err != nil {
@@ -373,66 +812,17 @@ func (p *Pool) streamTargetFromArchive(
 				ReadSeekCloser: r,
 				Name:           f.Name(),
 				Size:           f.Size(),
-				ContentType:    GetContentType(f.Name()),
+				ContentType:    detectContentType(r, f.Name()),
+				Path:           f.Name(),
 			}, nil
 		}
 
-		if !f.IsStreamable() {
-			return nil, fmt.Errorf("inner archive %s is not streamable", f.Name())
-		}
-
-		innerFileType := DetectArchiveFileTypeByExtension(f.Name())
-
-		archiveGroups := groupArchiveVolumes(files)
-		var matchedGroup *archiveVolumeGroup[ArchiveFile]
-		for i := range archiveGroups {
-			for _, gf := range archiveGroups[i].Files {
-				if strings.EqualFold(gf.Name(), targetName) {
-					matchedGroup = &archiveGroups[i]
-					break
-				}
-			}
-			if matchedGroup != nil {
-				break
-			}
-		}
-
-		archiveFiles := []ArchiveFile{f}
-		archiveFileType := innerFileType
-		if matchedGroup != nil {
-			for _, mf := range matchedGroup.Files {
-				if !mf.IsStreamable() {
-					return nil, fmt.Errorf("inner archive part %s is not streamable", mf.Name())
-				}
-			}
-			archiveFiles = matchedGroup.Files
-			archiveFileType = matchedGroup.FileType
-		}
-
-		var innerArchive Archive
-		afs := NewArchiveFS(archiveFiles)
-		switch archiveFileType {
-		case FileTypeRAR:
-			innerArchive = NewRARArchive(afs, filepath.Base(archiveFiles[0].Name()))
-		case FileType7z:
-			innerArchive = NewSevenZipArchive(afs.toAfero(), filepath.Base(archiveFiles[0].Name()))
-		default:
-			afs.Close()
-			return nil, fmt.Errorf("unsupported inner archive type: %s", archiveFileType)
-		}
-		innerFileType = archiveFileType
-
-		if err := innerArchive.Open(""); err != nil {
-			innerArchive.Close()
-			return nil, fmt.Errorf("failed to open inner archive: %w", err)
-		}
-
-		if !innerArchive.IsStreamable() {
-			innerArchive.Close()
-			return nil, fmt.Errorf("non-streamable inner %s archive", innerFileType)
+		innerArchive, innerFileType, err := openInnerArchive(files, f)
+		if err != nil {
+			return nil, err
 		}
 
-		stream, err := p.streamTargetFromArchive(innerArchive, remainingParts, innerFileType)
+		stream, err := p.streamTargetFromArchive(innerArchive, remainingParts, innerFileType, videoSelect, allowCompressed)
 		if err != nil {
 			innerArchive.Close()
 			return nil, err
@@ -446,12 +836,87 @@ func (p *Pool) streamTargetFromArchive(
 			Name:        stream.Name,
 			Size:        stream.Size,
 			ContentType: stream.ContentType,
+			Path:        joinContentPath(f.Name(), stream.Path),
+			ForwardOnly: stream.ForwardOnly,
 		}, nil
 	}
 
 	return nil, fmt.Errorf("no file matching '%s' found in archive", targetName)
 }
 
+// openInnerArchive opens the archive f found inside an outer archive along with
+// its sibling volumes from files, rejecting it unless it is streamable.
+func openInnerArchive(files []ArchiveFile, f ArchiveFile) (Archive, FileType, error) {
+	innerArchive, archiveFileType, err := openInnerArchiveHeaders(files, f)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !innerArchive.IsStreamable() {
+		innerArchive.Close()
+		return nil, 0, fmt.Errorf("non-streamable inner %s archive: %w", archiveFileType, ErrArchiveSolid)
+	}
+
+	return innerArchive, archiveFileType, nil
+}
+
+// openInnerArchiveHeaders is openInnerArchive, without requiring the inner
+// archive itself to be streamable. Its volumes are still read directly from
+// the outer archive, so those must be stored.
+func openInnerArchiveHeaders(files []ArchiveFile, f ArchiveFile) (Archive, FileType, error) {
+	if !f.IsStreamable() {
+		return nil, 0, fmt.Errorf("inner archive %s is not streamable: %w", f.Name(), ErrArchiveSolid)
+	}
+
+	archiveGroups := groupArchiveVolumes(typeAliasedArchiveParts(files))
+	var matchedGroup *archiveVolumeGroup[ArchiveFile]
+	for i := range archiveGroups {
+		for _, gf := range archiveGroups[i].Files {
+			if strings.EqualFold(gf.Name(), f.Name()) {
+				matchedGroup = &archiveGroups[i]
+				break
+			}
+		}
+		if matchedGroup != nil {
+			break
+		}
+	}
+
+	archiveFiles := []ArchiveFile{f}
+	archiveName := filepath.Base(f.Name())
+	archiveFileType := DetectArchiveFileTypeByExtension(f.Name())
+	if matchedGroup != nil {
+		for _, mf := range matchedGroup.Files {
+			if !mf.IsStreamable() {
+				return nil, 0, fmt.Errorf("inner archive part %s is not streamable: %w", mf.Name(), ErrArchiveSolid)
+			}
+		}
+		archiveFiles, archiveName = getArchiveGroupFiles(matchedGroup)
+		archiveFileType = matchedGroup.FileType
+	}
+
+	var innerArchive Archive
+	afs := NewArchiveFS(archiveFiles)
+	switch archiveFileType {
+	case FileTypeRAR:
+		innerArchive = NewRARArchive(afs, archiveName)
+	case FileType7z:
+		innerArchive = NewSevenZipArchive(afs.toAfero(), archiveName)
+	case FileTypeZIP:
+		innerArchive = NewZIPArchive(afs.toAfero(), archiveName)
+	default:
+		afs.Close()
+		return nil, 0, fmt.Errorf("unsupported inner archive type: %s", archiveFileType)
+	}
+
+	if err := innerArchive.Open(""); err != nil {
+		innerArchive.Close()
+		return nil, 0, fmt.Errorf("failed to open inner archive: %w", err)
+	}
+
+	return innerArchive, archiveFileType, nil
+}
+
 func findFileByName(nzbDoc *nzb.NZB, contentFiles []NZBContentFile, name string) (*nzb.File, *NZBContentFile) {
 	var file *nzb.File
 	var contentFile *NZBContentFile
@@ -496,6 +961,7 @@ func (p *Pool) StreamByContentPath(
 	if config == nil {
 		config = &StreamConfig{}
 	}
+	ctx = withStreamConfig(ctx, config)
 
 	name := pathParts[0]
 	file, contentFile := findFileByName(nzbDoc, config.ContentFiles, name)
@@ -504,18 +970,161 @@ func (p *Pool) StreamByContentPath(
 	}
 
 	if len(pathParts) == 1 {
-		return p.streamPlainFile(file, config)
+		stream, err := p.streamPlainFile(file, config)
+		if err != nil {
+			return nil, err
+		}
+		// the alias of an obfuscated file tells it is a video
+		if isVideoFile(stream.Name) || contentFile != nil && contentFile.Type == NZBContentFileTypeVideo {
+			stream.withVideoContentType(config.getVideoContentType())
+		}
+		return stream.throttle(ctx, config.RateLimitBytesPerSec), nil
+	}
+
+	if isNZBFile(file.Name()) {
+		stream, err := p.streamFromNestedNZB(ctx, file, contentFile, pathParts[1:], config)
+		if err != nil {
+			return nil, err
+		}
+		stream.Path = joinContentPath(name, stream.Path)
+		return stream, nil
+	}
+
+	stream, err := p.resolveArchiveTarget(ctx, nzbDoc, file, name, contentFile, pathParts[1:], config)
+	if err != nil {
+		return nil, err
+	}
+	stream.Path = joinContentPath(name, stream.Path)
+	if isVideoFile(stream.Name) {
+		stream.withVideoContentType(config.getVideoContentType())
+	}
+
+	return stream.throttle(ctx, config.RateLimitBytesPerSec), nil
+}
+
+type cancelOnCloseStream struct {
+	io.ReadSeekCloser
+	cancel context.CancelFunc
+}
+
+func (s *cancelOnCloseStream) Close() error {
+	defer s.cancel()
+	return s.ReadSeekCloser.Close()
+}
+
+// resolveArchiveTarget opens the archive and finds the target in it. A slow
+// provider can stall the listing, so the resolution is bounded by its own
+// deadline, apart from the playback that follows.
+func (p *Pool) resolveArchiveTarget(
+	ctx context.Context,
+	nzbDoc *nzb.NZB,
+	file *nzb.File,
+	name string,
+	contentFile *NZBContentFile,
+	targetParts []string,
+	conf *StreamConfig,
+) (*Stream, error) {
+	resolve := func(ctx context.Context) (*Stream, error) {
+		archive, fileType, err := p.openArchiveFile(ctx, nzbDoc, file, name, contentFile, conf)
+		if err != nil {
+			return nil, err
+		}
+
+		stream, err := p.streamTargetFromArchive(archive, targetParts, fileType, conf.getVideoSelect(), conf.AllowCompressed)
+		if err != nil {
+			archive.Close()
+			return nil, err
+		}
+		return stream, nil
+	}
+
+	timeout := config.Newz.ContentResolveTimeout
+	if timeout <= 0 {
+		return resolve(ctx)
 	}
 
-	archiveName := contentFile.Name
-	if contentFile.Alias != "" {
+	// the archive reads during playback go through this context too, so it
+	// lives as long as the stream.
+	ctx, cancel := context.WithCancel(ctx)
+
+	type resolveResult struct {
+		stream *Stream
+		err    error
+	}
+	done := make(chan resolveResult, 1)
+	go func() {
+		stream, err := resolve(ctx)
+		done <- resolveResult{stream: stream, err: err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case result := <-done:
+		if result.err != nil {
+			cancel()
+			return nil, result.err
+		}
+		result.stream.ReadSeekCloser = &cancelOnCloseStream{
+			ReadSeekCloser: result.stream.ReadSeekCloser,
+			cancel:         cancel,
+		}
+		return result.stream, nil
+	case <-timer.C:
+		cancel()
+		go func() {
+			if result := <-done; result.stream != nil {
+				result.stream.Close()
+			}
+		}()
+		p.Log.Warn("stream by content path - resolution timed out", "name", name, "timeout", timeout)
+		return nil, fmt.Errorf("%w: %s after %s", ErrResolutionTimeout, name, timeout)
+	}
+}
+
+// openArchiveFile opens the archive for streaming, rejecting it unless its
+// entries can be read directly, or its only video sequentially.
+func (p *Pool) openArchiveFile(
+	ctx context.Context,
+	nzbDoc *nzb.NZB,
+	file *nzb.File,
+	name string,
+	contentFile *NZBContentFile,
+	config *StreamConfig,
+) (Archive, FileType, error) {
+	archive, fileType, err := p.openArchiveHeaders(ctx, nzbDoc, file, name, contentFile, config)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !archive.IsStreamable() && getSolidSequentialVideo(archive) == nil {
+		err := nonStreamableArchiveError(archive, fileType)
+		archive.Close()
+		return nil, 0, err
+	}
+
+	return archive, fileType, nil
+}
+
+// openArchiveHeaders opens the archive for reading its headers, whether or
+// not its entries are streamable.
+func (p *Pool) openArchiveHeaders(
+	ctx context.Context,
+	nzbDoc *nzb.NZB,
+	file *nzb.File,
+	name string,
+	contentFile *NZBContentFile,
+	config *StreamConfig,
+) (Archive, FileType, error) {
+	archiveName := file.Name()
+	if contentFile != nil && contentFile.Alias != "" {
 		archiveName = contentFile.Alias
 	}
-	archiveFile := file
 
-	firstSegment, err := p.fetchFirstSegment(ctx, archiveFile)
+	firstSegment, err := p.fetchFirstSegment(ctx, file)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch archive header: %w", err)
+		return nil, 0, fmt.Errorf("failed to fetch archive header: %w", err)
 	}
 	fileType := DetectFileType(firstSegment.Body, archiveName)
 
@@ -526,10 +1135,14 @@ func (p *Pool) StreamByContentPath(
 	})
 
 	var aliases map[string]string
-	for _, part := range contentFile.Parts {
+	var parts []NZBContentFile
+	if contentFile != nil {
+		parts = contentFile.Parts
+	}
+	for _, part := range parts {
 		if part.Alias != "" {
 			if aliases == nil {
-				aliases = make(map[string]string, len(contentFile.Parts))
+				aliases = make(map[string]string, len(parts))
 			}
 			aliases[part.Alias] = part.Name
 		}
@@ -542,32 +1155,24 @@ func (p *Pool) StreamByContentPath(
 		archive = NewRARArchive(ufs, name)
 	case FileType7z:
 		archive = NewSevenZipArchive(ufs.toAfero(), name)
+	case FileTypeZIP:
+		archive = NewZIPArchive(ufs.toAfero(), name)
 	default:
-		return nil, fmt.Errorf("file '%s' is not an archive", name)
+		return nil, 0, fmt.Errorf("file '%s' is not an archive", name)
 	}
 
 	if err := archive.Open(config.Password); err != nil {
-		return nil, fmt.Errorf("failed to open archive: %w", err)
-	}
-
-	if !archive.IsStreamable() {
-		archive.Close()
-		return nil, fmt.Errorf("non-streamable %s archive", fileType)
+		return nil, 0, fmt.Errorf("failed to open archive: %w", err)
 	}
 
-	stream, err := p.streamTargetFromArchive(archive, pathParts[1:], fileType)
-	if err != nil {
-		archive.Close()
-		return nil, err
-	}
-
-	return stream, nil
+	return archive, fileType, nil
 }
 
 type StreamSegmentsConfig struct {
-	Segments   []nzb.Segment // Segments to stream
-	Groups     []string      // Newsgroups
-	BufferSize int64
+	Segments             []nzb.Segment // Segments to stream
+	Groups               []string      // Newsgroups
+	BufferSize           int64
+	RateLimitBytesPerSec int64 // 0 means unlimited
 }
 
 type StreamSegmentsResult struct {
@@ -597,8 +1202,13 @@ func (p *Pool) StreamSegments(
 		return nil, err
 	}
 
+	var r io.ReadCloser = stream
+	if limiter := newByteRateLimiter(conf.RateLimitBytesPerSec); limiter != nil {
+		r = &throttledReadCloser{ReadCloser: stream, ctx: ctx, limiter: limiter}
+	}
+
 	return &StreamSegmentsResult{
-		ReadCloser: stream,
+		ReadCloser: r,
 		Size:       firstSegment.FileSize,
 	}, nil
 }
@@ -0,0 +1,102 @@
+package usenet_pool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+)
+
+const (
+	defaultBenchmarkReadSize = 16 * 1024 * 1024
+	benchmarkBufferSize      = 64 * 1024
+)
+
+type StreamBenchmarkConfig struct {
+	ContentPath  string // stream to benchmark, the largest video if empty
+	ReadSize     int64  // bytes read from the start of the stream
+	StreamConfig *StreamConfig
+}
+
+type StreamBenchmarkResult struct {
+	Path           string  `json:"path"`
+	Size           int64   `json:"size"`
+	ReadBytes      int64   `json:"read_bytes"`
+	ResolveMs      float64 `json:"resolve_ms"`
+	FirstByteMs    float64 `json:"first_byte_ms"`
+	ReadMs         float64 `json:"read_ms"`
+	ThroughputMBps float64 `json:"throughput_mbps"` // megabytes per second, over the read after the first byte
+}
+
+// BenchmarkStream opens the stream the same way playback does, reads the
+// first bytes of it and reports how long each step took. It goes through the
+// normal streaming path, so it warms the cache no more than playback would.
+func (p *Pool) BenchmarkStream(
+	ctx context.Context,
+	nzbDoc *nzb.NZB,
+	conf *StreamBenchmarkConfig,
+) (*StreamBenchmarkResult, error) {
+	if conf == nil {
+		conf = &StreamBenchmarkConfig{}
+	}
+	readSize := conf.ReadSize
+	if readSize <= 0 {
+		readSize = defaultBenchmarkReadSize
+	}
+
+	if p.CountProviders() == 0 {
+		return nil, ErrNoProvidersConfigured
+	}
+
+	start := time.Now()
+	var stream *Stream
+	var err error
+	if conf.ContentPath != "" {
+		stream, err = p.StreamByContentPath(ctx, nzbDoc, conf.ContentPath, conf.StreamConfig)
+	} else {
+		stream, err = p.StreamLargestFile(ctx, nzbDoc, conf.StreamConfig)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	result := &StreamBenchmarkResult{
+		Path:      stream.Path,
+		Size:      stream.Size,
+		ResolveMs: toDurationMs(time.Since(start)),
+	}
+
+	readStart := time.Now()
+	var firstByteAt time.Time
+	buf := make([]byte, benchmarkBufferSize)
+	for result.ReadBytes < readSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		n, err := stream.Read(buf[:min(int64(len(buf)), readSize-result.ReadBytes)])
+		if n > 0 && firstByteAt.IsZero() {
+			firstByteAt = time.Now()
+			result.FirstByteMs = toDurationMs(firstByteAt.Sub(readStart))
+		}
+		result.ReadBytes += int64(n)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	readEnd := time.Now()
+	result.ReadMs = toDurationMs(readEnd.Sub(readStart))
+
+	if !firstByteAt.IsZero() {
+		if sustained := readEnd.Sub(firstByteAt); sustained > 0 {
+			result.ThroughputMBps = float64(result.ReadBytes) / (1024 * 1024) / sustained.Seconds()
+		}
+	}
+
+	return result, nil
+}
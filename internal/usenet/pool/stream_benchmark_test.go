@@ -0,0 +1,56 @@
+package usenet_pool
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/nntp/nntptest"
+	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBenchmarkStream(t *testing.T) {
+	data := makeTestBytes(200)
+	encoded := encodeYenc(data, "movie.mkv", 1, 1, int64(len(data)), 1)
+
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.SetResponse("GROUP alt.binaries.test", "211 1 1 1 alt.binaries.test")
+	server.SetResponse("BODY <seg1@test.com>", "222 0 <seg1@test.com>", strings.Split(strings.TrimSpace(string(encoded)), "\r\n"))
+	server.Start(t)
+
+	usenetPool := createTestPool(t, server)
+	usenetPool.segmentCache = NewSegmentCache(10*1024*1024, t.TempDir())
+
+	nzbDoc := createTestNZB(nzb.File{
+		Subject:  `Test - "movie.mkv" yEnc (1/1)`,
+		Segments: []nzb.Segment{{MessageId: "seg1@test.com", Bytes: int64(len(encoded)), Number: 1}},
+	})
+
+	t.Run("ReadSize", func(t *testing.T) {
+		result, err := usenetPool.BenchmarkStream(t.Context(), nzbDoc, &StreamBenchmarkConfig{ReadSize: 50})
+		require.NoError(t, err)
+		assert.Equal(t, "movie.mkv", result.Path)
+		assert.Equal(t, int64(len(data)), result.Size)
+		assert.Equal(t, int64(50), result.ReadBytes)
+		assert.LessOrEqual(t, result.FirstByteMs, result.ReadMs)
+	})
+
+	t.Run("PastEnd", func(t *testing.T) {
+		result, err := usenetPool.BenchmarkStream(t.Context(), nzbDoc, &StreamBenchmarkConfig{ContentPath: "movie.mkv"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(data)), result.ReadBytes)
+	})
+
+	t.Run("MissingPath", func(t *testing.T) {
+		_, err := usenetPool.BenchmarkStream(t.Context(), nzbDoc, &StreamBenchmarkConfig{ContentPath: "other.mkv"})
+		assert.Error(t, err)
+	})
+
+	t.Run("StreamConfig", func(t *testing.T) {
+		_, err := usenetPool.BenchmarkStream(t.Context(), nzbDoc, &StreamBenchmarkConfig{
+			StreamConfig: &StreamConfig{BypassCache: true, ProviderAllowlist: []string{"unknown:119:"}},
+		})
+		assert.Error(t, err)
+	})
+}
@@ -1,13 +1,21 @@
 package usenet_pool
 
 import (
+	"bytes"
+	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/MunifTanjim/stremthru/internal/config"
 	"github.com/MunifTanjim/stremthru/internal/logger"
 	"github.com/MunifTanjim/stremthru/internal/nntp"
 	"github.com/MunifTanjim/stremthru/internal/nntp/nntptest"
+	"github.com/MunifTanjim/stremthru/internal/server"
 	"github.com/MunifTanjim/stremthru/internal/usenet/nzb"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -32,7 +40,7 @@ func TestDirectFileStreaming(t *testing.T) {
 		usenetPool := &Pool{
 			Log:          logger.Scoped("test/usenet/pool"),
 			providers:    []*providerPool{{Pool: nntpPool}},
-			segmentCache: NewSegmentCache(10 * 1024 * 1024),
+			segmentCache: NewSegmentCache(10*1024*1024, ""),
 		}
 
 		segments := []nzb.Segment{
@@ -80,7 +88,7 @@ func TestDirectFileStreaming(t *testing.T) {
 		usenetPool := &Pool{
 			Log:          logger.Scoped("test/usenet/pool"),
 			providers:    []*providerPool{{Pool: nntpPool}},
-			segmentCache: NewSegmentCache(10 * 1024 * 1024),
+			segmentCache: NewSegmentCache(10*1024*1024, ""),
 		}
 
 		segments := []nzb.Segment{
@@ -120,7 +128,7 @@ func TestDirectFileStreaming(t *testing.T) {
 		usenetPool := &Pool{
 			Log:          logger.Scoped("test/usenet/pool"),
 			providers:    []*providerPool{{Pool: nntpPool}},
-			segmentCache: NewSegmentCache(10 * 1024 * 1024),
+			segmentCache: NewSegmentCache(10*1024*1024, ""),
 		}
 
 		segments := []nzb.Segment{
@@ -161,7 +169,7 @@ func TestDirectFileStreaming(t *testing.T) {
 		usenetPool := &Pool{
 			Log:          logger.Scoped("test/usenet/pool"),
 			providers:    []*providerPool{{Pool: nntpPool}},
-			segmentCache: NewSegmentCache(10 * 1024 * 1024),
+			segmentCache: NewSegmentCache(10*1024*1024, ""),
 		}
 
 		ctx := t.Context()
@@ -191,7 +199,7 @@ func TestStreamSegmentsAPI(t *testing.T) {
 		usenetPool := &Pool{
 			Log:          logger.Scoped("test/usenet/pool"),
 			providers:    []*providerPool{{Pool: nntpPool}},
-			segmentCache: NewSegmentCache(10 * 1024 * 1024),
+			segmentCache: NewSegmentCache(10*1024*1024, ""),
 		}
 
 		segments := []nzb.Segment{
@@ -209,3 +217,578 @@ func TestStreamSegmentsAPI(t *testing.T) {
 		assert.Equal(t, totalFileSize, result.Size)
 	})
 }
+
+func TestStreamByContentPath(t *testing.T) {
+	t.Run("PlainFileResolvedPath", func(t *testing.T) {
+		originalData := makeTestBytes(200)
+		encoded := encodeYenc(originalData, "video.mkv", 1, 1, int64(len(originalData)), 1)
+
+		server := nntptest.NewServer(t, "200 NNTP Service Ready")
+		server.SetResponse("GROUP alt.binaries.test", "211 1 1 1 alt.binaries.test")
+		lines := strings.Split(strings.TrimSpace(string(encoded)), "\r\n")
+		server.SetResponse("BODY <video@test.com>", "222 0 <video@test.com>", lines)
+		server.Start(t)
+
+		nntpPool := nntptest.NewPool(t, server, &nntp.PoolConfig{})
+
+		usenetPool := &Pool{
+			Log:          logger.Scoped("test/usenet/pool"),
+			providers:    []*providerPool{{Pool: nntpPool}},
+			segmentCache: NewSegmentCache(10*1024*1024, ""),
+		}
+
+		nzbDoc := createTestNZB(nzb.File{
+			Subject: `Test - "video.mkv" yEnc (1/1)`,
+			Segments: []nzb.Segment{
+				{MessageId: "video@test.com", Bytes: int64(len(encoded)), Number: 1},
+			},
+		})
+
+		stream, err := usenetPool.StreamByContentPath(t.Context(), nzbDoc, "/video.mkv", nil)
+		require.NoError(t, err)
+		defer stream.Close()
+
+		assert.Equal(t, "video.mkv", stream.Name)
+		assert.Equal(t, "video.mkv", stream.Path)
+		assert.Equal(t, "video/x-matroska", stream.ContentType)
+	})
+
+	t.Run("VideoContentTypeFallback", func(t *testing.T) {
+		originalData := makeTestBytes(200)
+		encoded := encodeYenc(originalData, "a1b2c3d4", 1, 1, int64(len(originalData)), 1)
+
+		server := nntptest.NewServer(t, "200 NNTP Service Ready")
+		server.SetResponse("GROUP alt.binaries.test", "211 1 1 1 alt.binaries.test")
+		lines := strings.Split(strings.TrimSpace(string(encoded)), "\r\n")
+		server.SetResponse("BODY <obfuscated@test.com>", "222 0 <obfuscated@test.com>", lines)
+		server.SetResponse("BODY <notes@test.com>", "222 0 <notes@test.com>", lines)
+		server.Start(t)
+
+		usenetPool := &Pool{
+			Log:          logger.Scoped("test/usenet/pool"),
+			providers:    []*providerPool{{Pool: nntptest.NewPool(t, server, &nntp.PoolConfig{})}},
+			segmentCache: NewSegmentCache(10*1024*1024, t.TempDir()),
+		}
+
+		nzbDoc := createTestNZB(
+			nzb.File{
+				Subject:  `Test - "a1b2c3d4" yEnc (1/1)`,
+				Segments: []nzb.Segment{{MessageId: "obfuscated@test.com", Bytes: int64(len(encoded)), Number: 1}},
+			},
+			nzb.File{
+				Subject:  `Test - "notes" yEnc (1/1)`,
+				Segments: []nzb.Segment{{MessageId: "notes@test.com", Bytes: int64(len(encoded)), Number: 1}},
+			},
+		)
+
+		streamConfig := &StreamConfig{
+			ContentFiles: []NZBContentFile{
+				{Name: "a1b2c3d4", Alias: "movie.ogm", Type: NZBContentFileTypeVideo},
+			},
+			VideoContentType: "video/mp4",
+		}
+
+		stream, err := usenetPool.StreamByContentPath(t.Context(), nzbDoc, "/movie.ogm", streamConfig)
+		require.NoError(t, err)
+		defer stream.Close()
+		assert.Equal(t, "video/mp4", stream.ContentType)
+
+		// not a video, left as is
+		other, err := usenetPool.StreamByContentPath(t.Context(), nzbDoc, "/notes", streamConfig)
+		require.NoError(t, err)
+		defer other.Close()
+		assert.Equal(t, contentTypeUnknown, other.ContentType)
+	})
+}
+
+func TestStreamLargestFileOfAnyType(t *testing.T) {
+	isoData := makeTestBytes(300)
+	isoEncoded := encodeYenc(isoData, "disc.iso", 1, 1, int64(len(isoData)), 1)
+
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.SetResponse("GROUP alt.binaries.test", "211 1 1 1 alt.binaries.test")
+	server.SetResponse("BODY <iso@test.com>", "222 0 <iso@test.com>", strings.Split(strings.TrimSpace(string(isoEncoded)), "\r\n"))
+	server.Start(t)
+
+	usenetPool := &Pool{
+		Log:          logger.Scoped("test/usenet/pool"),
+		providers:    []*providerPool{{Pool: nntptest.NewPool(t, server, &nntp.PoolConfig{})}},
+		segmentCache: NewSegmentCache(10*1024*1024, ""),
+	}
+
+	nzbDoc := createTestNZB(
+		nzb.File{
+			Subject:  `Test - "sample.mkv" yEnc (1/1)`,
+			Segments: []nzb.Segment{{MessageId: "sample@test.com", Bytes: 100, Number: 1}},
+		},
+		nzb.File{
+			Subject:  `Test - "disc.iso" yEnc (1/1)`,
+			Segments: []nzb.Segment{{MessageId: "iso@test.com", Bytes: int64(len(isoEncoded)), Number: 1}},
+		},
+	)
+
+	stream, err := usenetPool.StreamLargestFileOfAnyType(t.Context(), nzbDoc, nil)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	assert.Equal(t, "disc.iso", stream.Name)
+	assert.Equal(t, "application/x-iso9660-image", stream.ContentType)
+
+	data, err := io.ReadAll(stream)
+	require.NoError(t, err)
+	assert.Equal(t, isoData, data)
+}
+
+func TestStreamLargestFileMatching(t *testing.T) {
+	episodeData := makeTestBytes(150)
+	episodeEncoded := encodeYenc(episodeData, "a1b2c3", 1, 1, int64(len(episodeData)), 1)
+
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.SetResponse("GROUP alt.binaries.test", "211 1 1 1 alt.binaries.test")
+	server.SetResponse("BODY <e01@test.com>", "222 0 <e01@test.com>", strings.Split(strings.TrimSpace(string(episodeEncoded)), "\r\n"))
+	server.Start(t)
+
+	usenetPool := &Pool{
+		Log:          logger.Scoped("test/usenet/pool"),
+		providers:    []*providerPool{{Pool: nntptest.NewPool(t, server, &nntp.PoolConfig{})}},
+		segmentCache: NewSegmentCache(10*1024*1024, t.TempDir()),
+	}
+
+	nzbDoc := createTestNZB(
+		nzb.File{
+			Subject:  `Test - "a1b2c3" yEnc (1/1)`,
+			Segments: []nzb.Segment{{MessageId: "e01@test.com", Bytes: int64(len(episodeEncoded)), Number: 1}},
+		},
+		nzb.File{
+			Subject:  `Test - "Show.S01E02.mkv" yEnc (1/1)`,
+			Segments: []nzb.Segment{{MessageId: "e02@test.com", Bytes: 100000, Number: 1}},
+		},
+	)
+	streamConfig := &StreamConfig{
+		ContentFiles: []NZBContentFile{{Name: "a1b2c3", Alias: "Show.S01E01.mkv", Type: NZBContentFileTypeVideo}},
+	}
+
+	stream, err := usenetPool.StreamLargestFileMatching(t.Context(), nzbDoc, regexp.MustCompile(`(?i)s01e01`), streamConfig)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	assert.Equal(t, "a1b2c3", stream.Name)
+	data, err := io.ReadAll(stream)
+	require.NoError(t, err)
+	assert.Equal(t, episodeData, data)
+
+	_, err = usenetPool.StreamLargestFileMatching(t.Context(), nzbDoc, regexp.MustCompile(`S02E01`), streamConfig)
+	assert.ErrorContains(t, err, "no file matching")
+}
+
+func TestStreamSampleFile(t *testing.T) {
+	sampleData := makeTestBytes(100)
+	sampleEncoded := encodeYenc(sampleData, "movie.sample.mkv", 1, 1, int64(len(sampleData)), 1)
+
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.SetResponse("GROUP alt.binaries.test", "211 1 1 1 alt.binaries.test")
+	server.SetResponse("BODY <sample@test.com>", "222 0 <sample@test.com>", strings.Split(strings.TrimSpace(string(sampleEncoded)), "\r\n"))
+	server.Start(t)
+
+	usenetPool := &Pool{
+		Log:          logger.Scoped("test/usenet/pool"),
+		providers:    []*providerPool{{Pool: nntptest.NewPool(t, server, &nntp.PoolConfig{})}},
+		segmentCache: NewSegmentCache(10*1024*1024, ""),
+	}
+
+	nzbDoc := createTestNZB(
+		nzb.File{
+			Subject:  `Test - "movie.mkv" yEnc (1/1)`,
+			Segments: []nzb.Segment{{MessageId: "movie@test.com", Bytes: 100000, Number: 1}},
+		},
+		nzb.File{
+			Subject:  `Test - "movie.sample.mkv" yEnc (1/1)`,
+			Segments: []nzb.Segment{{MessageId: "sample@test.com", Bytes: int64(len(sampleEncoded)), Number: 1}},
+		},
+		nzb.File{
+			Subject:  `Test - "movie.nfo" yEnc (1/1)`,
+			Segments: []nzb.Segment{{MessageId: "nfo@test.com", Bytes: 10, Number: 1}},
+		},
+	)
+
+	stream, err := usenetPool.StreamSampleFile(t.Context(), nzbDoc, nil)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	assert.Equal(t, "movie.sample.mkv", stream.Name)
+
+	data, err := io.ReadAll(stream)
+	require.NoError(t, err)
+	assert.Equal(t, sampleData, data)
+}
+
+func TestStreamNoStreamableContent(t *testing.T) {
+	usenetPool := &Pool{Log: logger.Scoped("test/usenet/pool")}
+
+	nzbDoc := createTestNZB(nzb.File{
+		Subject:  `Test - "movie.nfo" yEnc (1/1)`,
+		Segments: []nzb.Segment{{MessageId: "nfo@test.com", Bytes: 10, Number: 1}},
+	})
+
+	_, err := usenetPool.StreamLargestFile(t.Context(), nzbDoc, nil)
+	assert.ErrorIs(t, err, ErrNoStreamableContent)
+
+	_, err = usenetPool.StreamSampleFile(t.Context(), nzbDoc, nil)
+	assert.ErrorIs(t, err, ErrNoStreamableContent)
+}
+
+func TestStreamSampleFromArchive(t *testing.T) {
+	usenetPool := &Pool{Log: logger.Scoped("test/usenet/pool")}
+
+	archive := &testArchive{files: []ArchiveFile{
+		&testArchiveFile{name: "movie.mkv", size: 100, streamable: true},
+		&testArchiveFile{name: "empty.mkv", size: 0, streamable: true},
+		&testArchiveFile{name: "broken.mkv", size: 5, streamable: false},
+		&testArchiveFile{name: "sample.mkv", size: 10, streamable: true},
+		&testArchiveFile{name: "info.nfo", size: 1, streamable: true},
+	}}
+
+	stream, err := usenetPool.streamSampleFromArchive(archive, FileTypeRAR)
+	require.NoError(t, err)
+	defer stream.Close()
+	assert.Equal(t, "sample.mkv", stream.Name)
+	assert.Equal(t, int64(10), stream.Size)
+}
+
+type blockingSegmentCache struct {
+	release chan struct{}
+}
+
+func (c *blockingSegmentCache) Get(messageId string) (SegmentData, bool) {
+	<-c.release
+	return SegmentData{Body: []byte("data"), FileSize: 4}, true
+}
+
+func (c *blockingSegmentCache) Set(messageId string, data SegmentData) {}
+func (c *blockingSegmentCache) Has(messageId string) bool              { return false }
+
+func TestFetchFirstSegmentTimeout(t *testing.T) {
+	originalTimeout := config.Newz.FirstSegmentTimeout
+	config.Newz.FirstSegmentTimeout = 50 * time.Millisecond
+	t.Cleanup(func() {
+		config.Newz.FirstSegmentTimeout = originalTimeout
+	})
+
+	cache := &blockingSegmentCache{release: make(chan struct{})}
+	defer close(cache.release)
+
+	usenetPool := &Pool{
+		Log:          logger.Scoped("test/usenet/pool"),
+		segmentCache: cache,
+	}
+
+	file := &nzb.File{
+		Segments: []nzb.Segment{{MessageId: "slow@test.com", Bytes: 100, Number: 1}},
+	}
+
+	start := time.Now()
+	_, err := usenetPool.fetchFirstSegment(t.Context(), file)
+	assert.ErrorIs(t, err, ErrFirstSegmentTimeout)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestStreamFirstSegmentFallback(t *testing.T) {
+	const segmentSize = 50
+	data := makeTestBytes(3 * segmentSize)
+
+	server := nntptest.NewServer(t, "200 NNTP Service Ready")
+	server.SetResponse("GROUP alt.binaries.test", "211 3 1 3 alt.binaries.test")
+	server.SetResponse("BODY <seg1@test.com>", "430 No Such Article")
+
+	segments := make([]nzb.Segment, 3)
+	for i := range segments {
+		msgId := fmt.Sprintf("seg%d@test.com", i+1)
+		encoded := encodeYenc(data[i*segmentSize:(i+1)*segmentSize], "movie.mkv", i+1, 3, int64(len(data)), int64(i*segmentSize)+1)
+		if i > 0 {
+			server.SetResponse("BODY <"+msgId+">", "222 0 <"+msgId+">", strings.Split(strings.TrimSpace(string(encoded)), "\r\n"))
+		}
+		segments[i] = nzb.Segment{MessageId: msgId, Bytes: int64(len(encoded)), Number: i + 1}
+	}
+	server.Start(t)
+
+	usenetPool := createTestPool(t, server)
+	usenetPool.segmentCache = NewSegmentCache(10*1024*1024, t.TempDir())
+
+	nzbDoc := createTestNZB(nzb.File{
+		Subject:  `Test - "movie.mkv" yEnc (1/3)`,
+		Segments: segments,
+	})
+
+	prevFallback := config.Newz.FirstSegmentFallback
+	t.Cleanup(func() {
+		config.Newz.FirstSegmentFallback = prevFallback
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		config.Newz.FirstSegmentFallback = 0
+
+		_, err := usenetPool.StreamLargestFile(t.Context(), nzbDoc, nil)
+		assert.ErrorIs(t, err, ErrArticleNotFound)
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		config.Newz.FirstSegmentFallback = 2
+
+		stream, err := usenetPool.StreamLargestFile(t.Context(), nzbDoc, nil)
+		require.NoError(t, err)
+		defer stream.Close()
+
+		assert.Equal(t, int64(len(data)), stream.Size)
+
+		_, err = stream.Seek(segmentSize, io.SeekStart)
+		require.NoError(t, err)
+		got, err := io.ReadAll(stream)
+		require.NoError(t, err)
+		assert.Equal(t, data[segmentSize:], got)
+	})
+}
+
+func TestStreamByContentPathResolutionTimeout(t *testing.T) {
+	originalFirstSegmentTimeout := config.Newz.FirstSegmentTimeout
+	originalResolveTimeout := config.Newz.ContentResolveTimeout
+	config.Newz.FirstSegmentTimeout = 0
+	config.Newz.ContentResolveTimeout = 50 * time.Millisecond
+	t.Cleanup(func() {
+		config.Newz.FirstSegmentTimeout = originalFirstSegmentTimeout
+		config.Newz.ContentResolveTimeout = originalResolveTimeout
+	})
+
+	cache := &blockingSegmentCache{release: make(chan struct{})}
+	defer close(cache.release)
+
+	usenetPool := &Pool{
+		Log:          logger.Scoped("test/usenet/pool"),
+		segmentCache: cache,
+	}
+
+	nzbDoc := createTestNZB(nzb.File{
+		Subject:  `Test - "movie.rar" yEnc (1/1)`,
+		Segments: []nzb.Segment{{MessageId: "slow@test.com", Bytes: 100, Number: 1}},
+	})
+
+	start := time.Now()
+	_, err := usenetPool.StreamByContentPath(t.Context(), nzbDoc, "movie.rar::movie.mkv", nil)
+	assert.ErrorIs(t, err, ErrResolutionTimeout)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+type testArchiveFile struct {
+	name       string
+	size       int64
+	streamable bool
+}
+
+func (f *testArchiveFile) Name() string       { return f.name }
+func (f *testArchiveFile) Size() int64        { return f.size }
+func (f *testArchiveFile) PackedSize() int64  { return f.size }
+func (f *testArchiveFile) IsStreamable() bool { return f.streamable }
+
+type nopReadSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopReadSeekCloser) Close() error { return nil }
+
+func (f *testArchiveFile) Open() (io.ReadSeekCloser, error) {
+	return nopReadSeekCloser{strings.NewReader(strings.Repeat("x", int(f.size)))}, nil
+}
+
+func TestStreamVideoFromArchive(t *testing.T) {
+	usenetPool := &Pool{Log: logger.Scoped("test/usenet/pool")}
+
+	t.Run("PrefersLargestStreamable", func(t *testing.T) {
+		videos := []ArchiveFile{
+			&testArchiveFile{name: "sample.mkv", size: 10, streamable: true},
+			&testArchiveFile{name: "movie.mkv", size: 100, streamable: true},
+		}
+		stream, err := usenetPool.streamVideoFromArchive(videos, FileTypeRAR, VideoSelectLargest, 0, false)
+		require.NoError(t, err)
+		defer stream.Close()
+		assert.Equal(t, "movie.mkv", stream.Name)
+	})
+
+	t.Run("FallsBackWhenLargestNotStreamable", func(t *testing.T) {
+		videos := []ArchiveFile{
+			&testArchiveFile{name: "movie.mkv", size: 100, streamable: false},
+			&testArchiveFile{name: "small.mkv", size: 10, streamable: true},
+			&testArchiveFile{name: "medium.mkv", size: 50, streamable: true},
+		}
+		stream, err := usenetPool.streamVideoFromArchive(videos, FileTypeRAR, VideoSelectLargest, 0, false)
+		require.NoError(t, err)
+		defer stream.Close()
+		assert.Equal(t, "medium.mkv", stream.Name)
+		assert.Equal(t, int64(50), stream.Size)
+	})
+
+	t.Run("SelectsFirstByName", func(t *testing.T) {
+		videos := []ArchiveFile{
+			&testArchiveFile{name: "Show.E10.mkv", size: 100, streamable: true},
+			&testArchiveFile{name: "Show.E2.mkv", size: 10, streamable: true},
+		}
+		stream, err := usenetPool.streamVideoFromArchive(videos, FileTypeRAR, VideoSelectName, 0, false)
+		require.NoError(t, err)
+		defer stream.Close()
+		assert.Equal(t, "Show.E2.mkv", stream.Name)
+	})
+
+	t.Run("RuntimeFallsBackToLargest", func(t *testing.T) {
+		videos := []ArchiveFile{
+			&testArchiveFile{name: "a.mp4", size: 10, streamable: true},
+			&testArchiveFile{name: "b.mp4", size: 100, streamable: true},
+		}
+		stream, err := usenetPool.streamVideoFromArchive(videos, FileTypeRAR, VideoSelectRuntime, 0, false)
+		require.NoError(t, err)
+		defer stream.Close()
+		assert.Equal(t, "b.mp4", stream.Name)
+	})
+
+	t.Run("DeprioritizesUndersized", func(t *testing.T) {
+		videos := []ArchiveFile{
+			&testArchiveFile{name: "Show.E1.mkv", size: 10, streamable: true},
+			&testArchiveFile{name: "Show.E2.mkv", size: 100, streamable: true},
+		}
+		stream, err := usenetPool.streamVideoFromArchive(videos, FileTypeRAR, VideoSelectName, 50, false)
+		require.NoError(t, err)
+		defer stream.Close()
+		assert.Equal(t, "Show.E2.mkv", stream.Name)
+	})
+
+	t.Run("UndersizedAsLastResort", func(t *testing.T) {
+		videos := []ArchiveFile{
+			&testArchiveFile{name: "movie.mkv", size: 100, streamable: false},
+			&testArchiveFile{name: "sample.mkv", size: 10, streamable: true},
+		}
+		stream, err := usenetPool.streamVideoFromArchive(videos, FileTypeRAR, VideoSelectLargest, 50, false)
+		require.NoError(t, err)
+		defer stream.Close()
+		assert.Equal(t, "sample.mkv", stream.Name)
+	})
+
+	t.Run("NoneStreamable", func(t *testing.T) {
+		videos := []ArchiveFile{
+			&testArchiveFile{name: "movie.mkv", size: 100, streamable: false},
+		}
+		_, err := usenetPool.streamVideoFromArchive(videos, FileTypeRAR, VideoSelectLargest, 0, false)
+		assert.ErrorContains(t, err, "non-streamable")
+	})
+
+	t.Run("EmptyFile", func(t *testing.T) {
+		videos := []ArchiveFile{
+			&testArchiveFile{name: "movie.mkv", size: 0, streamable: true},
+		}
+		_, err := usenetPool.streamVideoFromArchive(videos, FileTypeRAR, VideoSelectLargest, 0, false)
+		assert.ErrorIs(t, err, ErrEmptyFile)
+	})
+}
+
+func TestStreamTargetFromArchiveWildcard(t *testing.T) {
+	usenetPool := &Pool{Log: logger.Scoped("test/usenet/pool")}
+
+	archive := &testArchive{files: []ArchiveFile{
+		&testArchiveFile{name: "sample.mkv", size: 10, streamable: true},
+		&testArchiveFile{name: "movie.mkv", size: 100, streamable: true},
+		&testArchiveFile{name: "info.nfo", size: 1000, streamable: true},
+	}}
+
+	for _, target := range []string{"*", ""} {
+		stream, err := usenetPool.streamTargetFromArchive(archive, []string{target}, FileTypeRAR, VideoSelectLargest, false)
+		require.NoError(t, err)
+		assert.Equal(t, "movie.mkv", stream.Name)
+		assert.Equal(t, "movie.mkv", stream.Path)
+		stream.Close()
+	}
+
+	_, err := usenetPool.streamTargetFromArchive(&testArchive{files: []ArchiveFile{
+		&testArchiveFile{name: "info.nfo", size: 1, streamable: true},
+	}}, []string{"*"}, FileTypeRAR, VideoSelectLargest, false)
+	assert.ErrorContains(t, err, "no video files found")
+}
+
+type testArchive struct {
+	files []ArchiveFile
+}
+
+func (a *testArchive) Open(password string) error       { return nil }
+func (a *testArchive) Close() error                     { return nil }
+func (a *testArchive) GetFiles() ([]ArchiveFile, error) { return a.files, nil }
+func (a *testArchive) IsStreamable() bool               { return true }
+
+func TestSolidSequential(t *testing.T) {
+	video := bytes.Repeat([]byte("0123456789"), 10)
+	solid := buildRAR4ArchiveFiles(
+		rar4TestFile{name: "movie.nfo", data: []byte("nfo")},
+		rar4TestFile{name: "movie.mkv", data: video, flags: rar4FileFlagSolid},
+	)
+
+	setAllowSolidSequential := func(t *testing.T, allow bool) {
+		prev := config.Newz.AllowSolidSequential
+		config.Newz.AllowSolidSequential = allow
+		t.Cleanup(func() {
+			config.Newz.AllowSolidSequential = prev
+		})
+	}
+
+	t.Run("DisabledByDefault", func(t *testing.T) {
+		assert.False(t, config.Newz.AllowSolidSequential)
+
+		usenetPool, nzbDoc := createTestNZBServer(t, testNZBFile{"movie.rar", solid})
+		_, err := usenetPool.StreamByContentPath(t.Context(), nzbDoc, "/movie.rar::movie.mkv", nil)
+		assert.ErrorIs(t, err, ErrArchiveSolid)
+	})
+
+	t.Run("Inspect", func(t *testing.T) {
+		setAllowSolidSequential(t, true)
+
+		usenetPool, nzbDoc := createTestNZBServer(t, testNZBFile{"movie.rar", solid})
+		content, err := usenetPool.InspectNZBContent(t.Context(), nzbDoc, "")
+		require.NoError(t, err)
+		require.Len(t, content.Files, 1)
+		assert.True(t, content.Files[0].Streamable)
+
+		streamable := map[string]bool{}
+		for _, f := range content.Files[0].Files {
+			streamable[f.Name] = f.Streamable
+		}
+		assert.Equal(t, map[string]bool{"movie.nfo": false, "movie.mkv": true}, streamable)
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		setAllowSolidSequential(t, true)
+
+		usenetPool, nzbDoc := createTestNZBServer(t, testNZBFile{"movie.rar", solid})
+		stream, err := usenetPool.StreamByContentPath(t.Context(), nzbDoc, "/movie.rar::movie.mkv", nil)
+		require.NoError(t, err)
+		defer stream.Close()
+		assert.True(t, stream.ForwardOnly)
+		assert.Equal(t, int64(len(video)), stream.Size)
+
+		w := httptest.NewRecorder()
+		server.ServeForwardOnlyContent(w, httptest.NewRequest(http.MethodGet, "/", nil), stream.Size, stream, func() string {
+			return ""
+		})
+		assert.Equal(t, "none", w.Header().Get("Accept-Ranges"))
+		assert.Equal(t, video, w.Body.Bytes())
+	})
+
+	t.Run("MultipleVideos", func(t *testing.T) {
+		setAllowSolidSequential(t, true)
+
+		usenetPool, nzbDoc := createTestNZBServer(t, testNZBFile{"movie.rar", buildRAR4ArchiveFiles(
+			rar4TestFile{name: "movie.nfo", data: []byte("nfo")},
+			rar4TestFile{name: "movie.cd1.mkv", data: video, flags: rar4FileFlagSolid},
+			rar4TestFile{name: "movie.cd2.mkv", data: video, flags: rar4FileFlagSolid},
+		)})
+		_, err := usenetPool.StreamByContentPath(t.Context(), nzbDoc, "/movie.rar::movie.cd2.mkv", nil)
+		assert.ErrorIs(t, err, ErrArchiveSolid)
+
+		content, err := usenetPool.InspectNZBContent(t.Context(), nzbDoc, "")
+		require.NoError(t, err)
+		require.Len(t, content.Files, 1)
+		assert.False(t, content.Files[0].Streamable)
+	})
+}
@@ -0,0 +1,109 @@
+package usenet_pool
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+var (
+	_ io.ReadSeekCloser = (*throttledReadSeekCloser)(nil)
+	_ io.ReadCloser     = (*throttledReadCloser)(nil)
+)
+
+// token bucket, with burst of one second worth of bytes
+type byteRateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // bytes per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newByteRateLimiter(bytesPerSec int64) *byteRateLimiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return &byteRateLimiter{
+		rate:   float64(bytesPerSec),
+		burst:  float64(bytesPerSec),
+		tokens: float64(bytesPerSec),
+		last:   time.Now(),
+	}
+}
+
+func (l *byteRateLimiter) maxChunk() int {
+	return max(int(l.burst), 1)
+}
+
+func (l *byteRateLimiter) waitN(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens -= float64(n)
+	deficit := -l.tokens
+	l.mu.Unlock()
+
+	if deficit <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(time.Duration(deficit / l.rate * float64(time.Second)))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens += float64(n)
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+func throttledRead(ctx context.Context, r io.Reader, l *byteRateLimiter, p []byte) (int, error) {
+	if len(p) > l.maxChunk() {
+		p = p[:l.maxChunk()]
+	}
+	n, err := r.Read(p)
+	if n > 0 {
+		if werr := l.waitN(ctx, n); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return n, err
+}
+
+type throttledReadSeekCloser struct {
+	io.ReadSeekCloser
+	ctx     context.Context
+	limiter *byteRateLimiter
+}
+
+func (t *throttledReadSeekCloser) Read(p []byte) (int, error) {
+	return throttledRead(t.ctx, t.ReadSeekCloser, t.limiter, p)
+}
+
+type throttledReadCloser struct {
+	io.ReadCloser
+	ctx     context.Context
+	limiter *byteRateLimiter
+}
+
+func (t *throttledReadCloser) Read(p []byte) (int, error) {
+	return throttledRead(t.ctx, t.ReadCloser, t.limiter, p)
+}
+
+func (s *Stream) throttle(ctx context.Context, bytesPerSec int64) *Stream {
+	if limiter := newByteRateLimiter(bytesPerSec); limiter != nil {
+		s.ReadSeekCloser = &throttledReadSeekCloser{
+			ReadSeekCloser: s.ReadSeekCloser,
+			ctx:            ctx,
+			limiter:        limiter,
+		}
+	}
+	return s
+}
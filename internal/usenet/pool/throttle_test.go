@@ -0,0 +1,45 @@
+package usenet_pool
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestByteRateLimiter(t *testing.T) {
+	t.Run("Unlimited", func(t *testing.T) {
+		assert.Nil(t, newByteRateLimiter(0))
+	})
+
+	t.Run("ThrottlesOutput", func(t *testing.T) {
+		data := makeTestBytes(100_000)
+		r := &throttledReadCloser{
+			ReadCloser: io.NopCloser(bytes.NewReader(data)),
+			ctx:        t.Context(),
+			limiter:    newByteRateLimiter(50_000),
+		}
+
+		start := time.Now()
+		out, err := io.ReadAll(r)
+		elapsed := time.Since(start)
+
+		require.NoError(t, err)
+		assert.Equal(t, data, out)
+		// first second is covered by burst
+		assert.GreaterOrEqual(t, elapsed, 900*time.Millisecond)
+	})
+
+	t.Run("RespectsContextCancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+
+		l := newByteRateLimiter(10)
+		require.NoError(t, l.waitN(ctx, 10))
+		assert.ErrorIs(t, l.waitN(ctx, 10), context.Canceled)
+	})
+}
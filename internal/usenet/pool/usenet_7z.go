@@ -30,7 +30,7 @@ func (usa *SevenZipArchive) Open(password string) error {
 	}
 	reader, err := sevenzip.OpenReader(usa.name, opts...)
 	if err != nil {
-		return err
+		return wrapArchiveOpenError(err)
 	}
 	usa.r = reader
 	return nil
@@ -116,10 +116,12 @@ var sevenzipPartNumberRegex = regexp.MustCompile(`(?i)\.7z\.(\d+)$`)
 // .7z
 var sevenzipFirstPartRegex = regexp.MustCompile(`(?i)\.7z$`)
 
+// Get7zVolumeNumber returns the 0-based volume number, where both .7z and
+// .7z.001 are volume 0.
 func Get7zVolumeNumber(filename string) int {
 	if matches := sevenzipPartNumberRegex.FindStringSubmatch(filename); len(matches) > 1 {
 		n, _ := strconv.Atoi(matches[1])
-		return n
+		return max(n-1, 0)
 	}
 
 	if sevenzipFirstPartRegex.MatchString(filename) {
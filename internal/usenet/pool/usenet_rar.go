@@ -1,18 +1,25 @@
 package usenet_pool
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"io/fs"
 	"regexp"
 	"slices"
 	"strconv"
 
+	"github.com/MunifTanjim/stremthru/internal/logger"
 	"github.com/nwaples/rardecode/v2"
 )
 
+var rarLog = logger.Scoped("usenet/pool/rar")
+
 var (
 	_ Archive     = (*RARArchive)(nil)
 	_ ArchiveFile = (*UsenetRARFile)(nil)
+
+	_ decompressibleFile = (*UsenetRARFile)(nil)
 )
 
 type RARArchive struct {
@@ -32,7 +39,7 @@ func (ura *RARArchive) open() error {
 		}
 		r, err := rardecode.OpenFS(ura.name, opts...)
 		if err != nil {
-			return err
+			return wrapArchiveOpenError(err)
 		}
 		ura.r = r
 	}
@@ -64,7 +71,7 @@ func (ura *RARArchive) isSolid() (bool, error) {
 		}
 		iter, err := rardecode.OpenIter(ura.name, opts...)
 		if err != nil {
-			return false, err
+			return false, wrapArchiveOpenError(err)
 		}
 		defer iter.Close()
 
@@ -76,7 +83,7 @@ func (ura *RARArchive) isSolid() (bool, error) {
 			}
 		}
 		if err := iter.Err(); err != nil {
-			return false, err
+			return false, wrapArchiveOpenError(err)
 		}
 		ura.solid = &solid
 	}
@@ -91,7 +98,7 @@ func (ura *RARArchive) GetFiles() ([]ArchiveFile, error) {
 		}
 		iter, err := rardecode.OpenIter(ura.name, opts...)
 		if err != nil {
-			return nil, err
+			return nil, wrapArchiveOpenError(err)
 		}
 		defer iter.Close()
 
@@ -128,6 +135,15 @@ func (urf *UsenetRARFile) Name() string {
 }
 
 func (urf *UsenetRARFile) Open() (io.ReadSeekCloser, error) {
+	// stored file in usenet volumes can be read directly from the segments
+	if ufs, ok := urf.a.fs.(*UsenetFS); ok && urf.IsStreamable() {
+		f, err := openRARStoredFile(ufs, urf.a.name, urf.name, urf.unPackedSize)
+		if err == nil {
+			return f, nil
+		}
+		rarLog.Debug("stored file - falling back to decoder", "error", err, "name", urf.name)
+	}
+
 	if err := urf.a.open(); err != nil {
 		return nil, err
 	}
@@ -150,14 +166,84 @@ func (urf *UsenetRARFile) IsStreamable() bool {
 	return !urf.solid && urf.packedSize == urf.unPackedSize
 }
 
+func (urf *UsenetRARFile) canDecompress() bool {
+	return !urf.solid
+}
+
+func (urf *UsenetRARFile) openDecompressed() (io.ReadCloser, error) {
+	if err := urf.a.open(); err != nil {
+		return nil, err
+	}
+	return urf.a.r.Open(urf.name)
+}
+
+// openSolidSequential decompresses the archive from the start, as a solid
+// entry depends on the ones preceding it, and stops at this one.
+func (urf *UsenetRARFile) openSolidSequential() (io.ReadCloser, error) {
+	opts := []rardecode.Option{rardecode.FileSystem(urf.a.fs), rardecode.SkipCheck}
+	if urf.a.password != "" {
+		opts = append(opts, rardecode.Password(urf.a.password))
+	}
+	r, err := rardecode.OpenReader(urf.a.name, opts...)
+	if err != nil {
+		return nil, wrapArchiveOpenError(err)
+	}
+	for {
+		h, err := r.Next()
+		if err != nil {
+			r.Close()
+			if err == io.EOF {
+				return nil, fmt.Errorf("file %s not found in archive: %w", urf.name, fs.ErrNotExist)
+			}
+			return nil, err
+		}
+		if h.Name == urf.name {
+			return r, nil
+		}
+	}
+}
+
+// VerifyCRC reads the file in full, to check it against the checksum in the
+// archive header. Fails with rardecode.ErrBadFileChecksum on mismatch.
+func (urf *UsenetRARFile) VerifyCRC(ctx context.Context) error {
+	opts := []rardecode.Option{rardecode.FileSystem(urf.a.fs)}
+	if urf.a.password != "" {
+		opts = append(opts, rardecode.Password(urf.a.password))
+	}
+	r, err := rardecode.OpenFS(urf.a.name, opts...)
+	if err != nil {
+		return wrapArchiveOpenError(err)
+	}
+	f, err := r.Open(urf.name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(io.Discard, &contextReader{ctx: ctx, r: f})
+	return err
+}
+
+// contextReader stops reading once the context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
+
 // .part01.rar format
 var rarPartNumberRegex = regexp.MustCompile(`(?i)\.part(\d+)\.rar$`)
 
 // .r00, .r01 format (.rar is first part, .r00 is second, etc.)
 var rarRNumberRegex = regexp.MustCompile(`(?i)\.r(\d+)$`)
 
-// .rar
-var rarFirstPartRegex = regexp.MustCompile(`(?i)\.rar$`)
+// .rar, .cbr
+var rarFirstPartRegex = regexp.MustCompile(`(?i)\.(rar|cbr)$`)
 
 func GetRARVolumeNumber(filename string) int {
 	if matches := rarPartNumberRegex.FindStringSubmatch(filename); len(matches) > 1 {
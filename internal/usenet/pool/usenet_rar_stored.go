@@ -0,0 +1,566 @@
+package usenet_pool
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	errRARStoredUnsupported = errors.New("rar: unsupported for stored file streaming")
+	errRARHeaderEncrypted   = errors.New("rar: headers are encrypted")
+)
+
+const (
+	rar5HeaderTypeFile       = 2
+	rar5HeaderTypeEncryption = 4
+	rar5HeaderTypeEnd        = 5
+
+	rar5HeaderFlagExtra     = 0x0001
+	rar5HeaderFlagData      = 0x0002
+	rar5HeaderFlagSplitNext = 0x0010
+
+	rar5FileFlagDirectory = 0x0001
+	rar5FileFlagTime      = 0x0002
+	rar5FileFlagCRC       = 0x0004
+
+	rar5CompressionSolid      = 0x0040
+	rar5CompressionMethodMask = 0x0380
+
+	rar5ExtraRecordEncryption = 0x01
+
+	// spec limits the header size to 2MB
+	rar5MaxHeaderSize = 2 * 1024 * 1024
+)
+
+const (
+	rar4BlockTypeMain = 0x73
+	rar4BlockTypeFile = 0x74
+	rar4BlockTypeEnd  = 0x7B
+
+	rar4BlockFlagLong = 0x8000
+
+	rar4MainFlagPassword = 0x0080
+
+	rar4FileFlagSplitAfter = 0x0002
+	rar4FileFlagPassword   = 0x0004
+	rar4FileFlagSolid      = 0x0010
+	rar4FileFlagDirectory  = 0x00E0
+	rar4FileFlagLarge      = 0x0100
+	rar4FileFlagUnicode    = 0x0200
+
+	rar4MethodStore = 0x30
+
+	rar4FileHeaderBaseSize = 32
+)
+
+// max headers looked at in a volume, before giving up on finding the file
+const rarStoredMaxHeaderScan = 64
+
+// rarStoredBlock is the data region of a stored file in an archive volume.
+type rarStoredBlock struct {
+	volume *rarStoredFileVolume
+	offset int64 // data offset in the volume
+	size   int64
+	start  int64 // position in the file
+}
+
+type rarStoredBlockHeader struct {
+	name       string
+	dataOffset int64
+	dataSize   int64
+	splitNext  bool
+	isDir      bool
+	stored     bool
+	solid      bool
+	encrypted  bool
+}
+
+func (h *rarStoredBlockHeader) isStreamable() bool {
+	return !h.isDir && h.stored && !h.solid && !h.encrypted
+}
+
+// nextRARVolumeName returns the name of the volume following the given one.
+func nextRARVolumeName(name string) (string, bool) {
+	if loc := rarPartNumberRegex.FindStringSubmatchIndex(name); loc != nil {
+		return replaceRARVolumeNumber(name, loc[2], loc[3]), true
+	}
+	if loc := rarRNumberRegex.FindStringSubmatchIndex(name); loc != nil {
+		return replaceRARVolumeNumber(name, loc[2], loc[3]), true
+	}
+	if loc := rarFirstPartRegex.FindStringIndex(name); loc != nil {
+		return name[:loc[0]] + name[loc[0]:loc[0]+2] + "00", true
+	}
+	return "", false
+}
+
+func replaceRARVolumeNumber(name string, start, end int) string {
+	n, _ := strconv.Atoi(name[start:end])
+	return name[:start] + fmt.Sprintf("%0*d", end-start, n+1) + name[end:]
+}
+
+func readRAR5Vint(b []byte) (uint64, int, bool) {
+	var value uint64
+	for i := 0; i < len(b) && i < 10; i++ {
+		value |= uint64(b[i]&0x7F) << (7 * i)
+		if b[i]&0x80 == 0 {
+			return value, i + 1, true
+		}
+	}
+	return 0, 0, false
+}
+
+type rarHeaderReader struct {
+	r    io.ReaderAt
+	size int64
+	buf  []byte
+}
+
+func (hr *rarHeaderReader) readAt(off int64, n int) ([]byte, error) {
+	if off < 0 || off+int64(n) > hr.size {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if cap(hr.buf) < n {
+		hr.buf = make([]byte, n)
+	}
+	buf := hr.buf[:n]
+	if _, err := hr.r.ReadAt(buf, off); err != nil && !(errors.Is(err, io.EOF) && off+int64(n) == hr.size) {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// readRAR5Header reads the block header at off, returning the file header
+// for file blocks, and the offset of the next block.
+func (hr *rarHeaderReader) readRAR5Header(off int64) (header *rarStoredBlockHeader, headerType uint64, next int64, err error) {
+	prefix, err := hr.readAt(off, min(4+3, int(hr.size-off)))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if len(prefix) < 5 {
+		return nil, 0, 0, io.ErrUnexpectedEOF
+	}
+	headerSize, sizeLen, ok := readRAR5Vint(prefix[4:])
+	if !ok || headerSize == 0 || headerSize > rar5MaxHeaderSize {
+		return nil, 0, 0, fmt.Errorf("rar: invalid header size at %d", off)
+	}
+	headerOffset := off + 4 + int64(sizeLen)
+	b, err := hr.readAt(headerOffset, int(headerSize))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	pos := 0
+	readVint := func() uint64 {
+		if err != nil {
+			return 0
+		}
+		v, n, ok := readRAR5Vint(b[pos:])
+		if !ok {
+			err = fmt.Errorf("rar: invalid header at %d", off)
+			return 0
+		}
+		pos += n
+		return v
+	}
+	skip := func(n int) {
+		if err == nil && pos+n > len(b) {
+			err = fmt.Errorf("rar: invalid header at %d", off)
+		}
+		pos += n
+	}
+
+	headerType = readVint()
+	flags := readVint()
+	var extraSize, dataSize uint64
+	if flags&rar5HeaderFlagExtra != 0 {
+		extraSize = readVint()
+	}
+	if flags&rar5HeaderFlagData != 0 {
+		dataSize = readVint()
+	}
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	dataOffset := headerOffset + int64(headerSize)
+	next = dataOffset + int64(dataSize)
+	if headerType != rar5HeaderTypeFile {
+		return nil, headerType, next, nil
+	}
+
+	fileFlags := readVint()
+	readVint() // unpacked size
+	readVint() // attributes
+	if fileFlags&rar5FileFlagTime != 0 {
+		skip(4)
+	}
+	if fileFlags&rar5FileFlagCRC != 0 {
+		skip(4)
+	}
+	compression := readVint()
+	readVint() // host os
+	nameLen := int(readVint())
+	skip(nameLen)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	header = &rarStoredBlockHeader{
+		name:       string(b[pos-nameLen : pos]),
+		dataOffset: dataOffset,
+		dataSize:   int64(dataSize),
+		splitNext:  flags&rar5HeaderFlagSplitNext != 0,
+		isDir:      fileFlags&rar5FileFlagDirectory != 0,
+		stored:     compression&rar5CompressionMethodMask == 0,
+		solid:      compression&rar5CompressionSolid != 0,
+	}
+
+	if extraSize > 0 {
+		extraStart := len(b) - int(extraSize)
+		if extraStart < pos {
+			return nil, 0, 0, fmt.Errorf("rar: invalid header at %d", off)
+		}
+		for pos = extraStart; pos < len(b) && err == nil; {
+			recordSize := int(readVint())
+			recordStart := pos
+			recordType := readVint()
+			if recordType == rar5ExtraRecordEncryption {
+				header.encrypted = true
+			}
+			pos = recordStart
+			skip(recordSize)
+		}
+		if err != nil {
+			return nil, 0, 0, err
+		}
+	}
+
+	return header, headerType, next, nil
+}
+
+// readRAR4Header reads the block header at off, returning the file header
+// for file blocks, and the offset of the next block.
+func (hr *rarHeaderReader) readRAR4Header(off int64) (header *rarStoredBlockHeader, blockType byte, next int64, err error) {
+	b, err := hr.readAt(off, 7)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	blockType = b[2]
+	flags := binary.LittleEndian.Uint16(b[3:5])
+	headerSize := int64(binary.LittleEndian.Uint16(b[5:7]))
+	if headerSize < 7 {
+		return nil, 0, 0, fmt.Errorf("rar: invalid header size at %d", off)
+	}
+
+	if blockType != rar4BlockTypeFile {
+		next = off + headerSize
+		if flags&rar4BlockFlagLong != 0 {
+			b, err := hr.readAt(off+7, 4)
+			if err != nil {
+				return nil, 0, 0, err
+			}
+			next += int64(binary.LittleEndian.Uint32(b))
+		}
+		if blockType == rar4BlockTypeMain && flags&rar4MainFlagPassword != 0 {
+			return nil, 0, 0, errRARHeaderEncrypted
+		}
+		return nil, blockType, next, nil
+	}
+
+	if headerSize < rar4FileHeaderBaseSize {
+		return nil, 0, 0, fmt.Errorf("rar: invalid file header at %d", off)
+	}
+	b, err = hr.readAt(off, int(headerSize))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	packedSize := int64(binary.LittleEndian.Uint32(b[7:11]))
+	method := b[25]
+	nameSize := int(binary.LittleEndian.Uint16(b[26:28]))
+	pos := rar4FileHeaderBaseSize
+	if flags&rar4FileFlagLarge != 0 {
+		if len(b) < pos+8 {
+			return nil, 0, 0, fmt.Errorf("rar: invalid file header at %d", off)
+		}
+		packedSize |= int64(binary.LittleEndian.Uint32(b[pos:pos+4])) << 32
+		pos += 8
+	}
+	if len(b) < pos+nameSize {
+		return nil, 0, 0, fmt.Errorf("rar: invalid file header at %d", off)
+	}
+	name := b[pos : pos+nameSize]
+	if flags&rar4FileFlagUnicode != 0 {
+		// the ascii name is followed by the encoded unicode name
+		if idx := bytes.IndexByte(name, 0); idx != -1 {
+			name = name[:idx]
+		}
+	}
+
+	header = &rarStoredBlockHeader{
+		name:       strings.ReplaceAll(string(name), `\`, "/"),
+		dataOffset: off + headerSize,
+		dataSize:   packedSize,
+		splitNext:  flags&rar4FileFlagSplitAfter != 0,
+		isDir:      flags&rar4FileFlagDirectory == rar4FileFlagDirectory,
+		stored:     method == rar4MethodStore,
+		solid:      flags&rar4FileFlagSolid != 0,
+		encrypted:  flags&rar4FileFlagPassword != 0,
+	}
+	return header, blockType, header.dataOffset + header.dataSize, nil
+}
+
+// walkFiles calls fn for every file block header in the volume, until fn
+// returns true. Returns errRARHeaderEncrypted if the headers are encrypted.
+func (hr *rarHeaderReader) walkFiles(fn func(header *rarStoredBlockHeader) bool) error {
+	signature, err := hr.readAt(0, min(len(magicBytesRAR5), int(hr.size)))
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case bytes.HasPrefix(signature, magicBytesRAR5):
+		off := int64(len(magicBytesRAR5))
+		for range rarStoredMaxHeaderScan {
+			header, headerType, next, err := hr.readRAR5Header(off)
+			if err != nil {
+				return err
+			}
+			switch headerType {
+			case rar5HeaderTypeEncryption:
+				return errRARHeaderEncrypted
+			case rar5HeaderTypeEnd:
+				return nil
+			}
+			if header != nil && fn(header) {
+				return nil
+			}
+			if next >= hr.size {
+				return nil
+			}
+			off = next
+		}
+	case bytes.HasPrefix(signature, magicBytesRAR4):
+		off := int64(len(magicBytesRAR4))
+		for range rarStoredMaxHeaderScan {
+			header, blockType, next, err := hr.readRAR4Header(off)
+			if err != nil {
+				return err
+			}
+			if blockType == rar4BlockTypeEnd {
+				return nil
+			}
+			if header != nil && fn(header) {
+				return nil
+			}
+			if next >= hr.size {
+				return nil
+			}
+			off = next
+		}
+	default:
+		return errRARStoredUnsupported
+	}
+	return nil
+}
+
+// findBlock looks for the data block of the named file in the volume.
+func (hr *rarHeaderReader) findBlock(name string) (*rarStoredBlockHeader, error) {
+	var block *rarStoredBlockHeader
+	err := hr.walkFiles(func(header *rarStoredBlockHeader) bool {
+		if header.name == name {
+			block = header
+			return true
+		}
+		return false
+	})
+	return block, err
+}
+
+type rarStoredFileVolume struct {
+	fs.File
+	io.ReaderAt
+}
+
+func openRARStoredFileVolume(fsys fs.FS, name string) (*rarStoredFileVolume, int64, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	ra, ok := f.(io.ReaderAt)
+	if !ok {
+		f.Close()
+		return nil, 0, errRARStoredUnsupported
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return &rarStoredFileVolume{File: f, ReaderAt: ra}, fi.Size(), nil
+}
+
+var (
+	_ io.ReadSeekCloser = (*rarStoredFile)(nil)
+	_ io.ReaderAt       = (*rarStoredFile)(nil)
+)
+
+// rarStoredFile reads a stored (uncompressed) file directly from the data
+// blocks in the archive volumes, without going through the decoder.
+type rarStoredFile struct {
+	blocks  []rarStoredBlock
+	volumes []*rarStoredFileVolume
+	size    int64
+	pos     int64
+	mu      sync.Mutex
+}
+
+// openRARStoredFile locates the data blocks of the stored file across the
+// volumes, starting from firstVolume. Returns errRARStoredUnsupported if the
+// file can not be read directly.
+func openRARStoredFile(fsys fs.FS, firstVolume string, name string, size int64) (*rarStoredFile, error) {
+	f := &rarStoredFile{size: size}
+
+	volume := firstVolume
+	var start int64
+	for {
+		v, volumeSize, err := openRARStoredFileVolume(fsys, volume)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		f.volumes = append(f.volumes, v)
+
+		hr := &rarHeaderReader{r: v, size: volumeSize}
+		header, err := hr.findBlock(name)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if header == nil {
+			if len(f.blocks) == 0 {
+				// file starts in a later volume
+				f.volumes = f.volumes[:len(f.volumes)-1]
+				v.Close()
+				if volume, err = nextVolumeName(volume); err != nil {
+					f.Close()
+					return nil, err
+				}
+				continue
+			}
+			f.Close()
+			return nil, fmt.Errorf("rar: missing continuation of %s in %s", name, volume)
+		}
+		if !header.isStreamable() || header.dataOffset+header.dataSize > volumeSize {
+			f.Close()
+			return nil, errRARStoredUnsupported
+		}
+
+		f.blocks = append(f.blocks, rarStoredBlock{
+			volume: v,
+			offset: header.dataOffset,
+			size:   header.dataSize,
+			start:  start,
+		})
+		start += header.dataSize
+
+		if !header.splitNext {
+			break
+		}
+		if volume, err = nextVolumeName(volume); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+
+	if start != size {
+		f.Close()
+		return nil, errRARStoredUnsupported
+	}
+	return f, nil
+}
+
+func nextVolumeName(volume string) (string, error) {
+	next, ok := nextRARVolumeName(volume)
+	if !ok {
+		return "", errRARStoredUnsupported
+	}
+	return next, nil
+}
+
+func (f *rarStoredFile) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("rar: negative offset")
+	}
+	for n < len(p) {
+		if off >= f.size {
+			return n, io.EOF
+		}
+		idx := sort.Search(len(f.blocks), func(i int) bool {
+			return f.blocks[i].start+f.blocks[i].size > off
+		})
+		block := &f.blocks[idx]
+		rel := off - block.start
+		m := int(min(int64(len(p)-n), block.size-rel))
+		read, err := block.volume.ReadAt(p[n:n+m], block.offset+rel)
+		n += read
+		off += int64(read)
+		if err != nil && !(errors.Is(err, io.EOF) && read == m) {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+func (f *rarStoredFile) Read(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n, err := f.ReadAt(p, f.pos)
+	f.pos += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (f *rarStoredFile) Seek(offset int64, whence int) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = f.pos + offset
+	case io.SeekEnd:
+		pos = f.size + offset
+	default:
+		return 0, errors.New("rar: invalid whence")
+	}
+	if pos < 0 {
+		return 0, errors.New("rar: negative position")
+	}
+	f.pos = pos
+	return pos, nil
+}
+
+func (f *rarStoredFile) Close() error {
+	var errs []error
+	for _, v := range f.volumes {
+		if err := v.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	f.volumes = nil
+	return errors.Join(errs...)
+}
@@ -0,0 +1,205 @@
+package usenet_pool
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nwaples/rardecode/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func appendRAR5Vint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+func appendRAR5Header(b []byte, header []byte) []byte {
+	sized := appendRAR5Vint(nil, uint64(len(header)))
+	sized = append(sized, header...)
+	b = binary.LittleEndian.AppendUint32(b, crc32.ChecksumIEEE(sized))
+	return append(b, sized...)
+}
+
+func buildRAR5Volume(volume int, name string, data []byte, splitPrev, splitNext bool) []byte {
+	b := bytes.Clone(magicBytesRAR5)
+
+	archiveFlags := uint64(0x0001)
+	main := appendRAR5Vint(nil, 1)
+	main = appendRAR5Vint(main, 0)
+	if volume > 0 {
+		archiveFlags |= 0x0002
+	}
+	main = appendRAR5Vint(main, archiveFlags)
+	if volume > 0 {
+		main = appendRAR5Vint(main, uint64(volume))
+	}
+	b = appendRAR5Header(b, main)
+
+	flags := uint64(rar5HeaderFlagData)
+	if splitPrev {
+		flags |= 0x0008
+	}
+	if splitNext {
+		flags |= rar5HeaderFlagSplitNext
+	}
+	file := appendRAR5Vint(nil, rar5HeaderTypeFile)
+	file = appendRAR5Vint(file, flags)
+	file = appendRAR5Vint(file, uint64(len(data)))
+	file = appendRAR5Vint(file, 0)     // file flags
+	file = appendRAR5Vint(file, 10)    // unpacked size
+	file = appendRAR5Vint(file, 0x1A4) // attributes
+	file = appendRAR5Vint(file, 0)     // compression info
+	file = appendRAR5Vint(file, 1)     // host os
+	file = appendRAR5Vint(file, uint64(len(name)))
+	file = append(file, name...)
+	b = appendRAR5Header(b, file)
+	b = append(b, data...)
+
+	end := appendRAR5Vint(nil, rar5HeaderTypeEnd)
+	end = appendRAR5Vint(end, 0)
+	if splitNext {
+		end = appendRAR5Vint(end, 0x0001)
+	} else {
+		end = appendRAR5Vint(end, 0)
+	}
+	return appendRAR5Header(b, end)
+}
+
+func appendRAR4Block(b []byte, blockType byte, flags uint16, body []byte) []byte {
+	header := []byte{blockType}
+	header = binary.LittleEndian.AppendUint16(header, flags)
+	header = binary.LittleEndian.AppendUint16(header, uint16(7+len(body)))
+	header = append(header, body...)
+	b = binary.LittleEndian.AppendUint16(b, uint16(crc32.ChecksumIEEE(header)))
+	return append(b, header...)
+}
+
+type rar4TestFile struct {
+	name  string
+	data  []byte
+	flags uint16
+}
+
+func buildRAR4Archive(name string, data []byte, fileFlags uint16) []byte {
+	return buildRAR4ArchiveFiles(rar4TestFile{name, data, fileFlags})
+}
+
+func buildRAR4ArchiveFiles(files ...rar4TestFile) []byte {
+	b := bytes.Clone(magicBytesRAR4)
+	b = appendRAR4Block(b, rar4BlockTypeMain, 0, make([]byte, 6))
+
+	for _, f := range files {
+		body := binary.LittleEndian.AppendUint32(nil, uint32(len(f.data))) // packed size
+		body = binary.LittleEndian.AppendUint32(body, uint32(len(f.data))) // unpacked size
+		body = append(body, 3)                                             // host os
+		body = binary.LittleEndian.AppendUint32(body, crc32.ChecksumIEEE(f.data))
+		body = binary.LittleEndian.AppendUint32(body, 0) // time
+		body = append(body, 29, rar4MethodStore)
+		body = binary.LittleEndian.AppendUint16(body, uint16(len(f.name)))
+		body = binary.LittleEndian.AppendUint32(body, 0x1A4) // attributes
+		body = append(body, f.name...)
+		b = appendRAR4Block(b, rar4BlockTypeFile, rar4BlockFlagLong|f.flags, body)
+		b = append(b, f.data...)
+	}
+
+	return appendRAR4Block(b, rar4BlockTypeEnd, 0, nil)
+}
+
+func readAllWithRardecode(t *testing.T, fsys fstest.MapFS, volume, name string) []byte {
+	t.Helper()
+	rfs, err := rardecode.OpenFS(volume, rardecode.FileSystem(fsys))
+	require.NoError(t, err)
+	f, err := rfs.Open(name)
+	require.NoError(t, err)
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	return data
+}
+
+func TestOpenRARStoredFile(t *testing.T) {
+	content := []byte("0123456789")
+
+	t.Run("rar5 multi volume", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"movie.part1.rar": {Data: buildRAR5Volume(0, "dir/movie.mkv", content[:4], false, true)},
+			"movie.part2.rar": {Data: buildRAR5Volume(1, "dir/movie.mkv", content[4:], true, false)},
+		}
+		require.Equal(t, content, readAllWithRardecode(t, fsys, "movie.part1.rar", "dir/movie.mkv"))
+
+		f, err := openRARStoredFile(fsys, "movie.part1.rar", "dir/movie.mkv", int64(len(content)))
+		require.NoError(t, err)
+		defer f.Close()
+		require.Len(t, f.blocks, 2)
+
+		data, err := io.ReadAll(f)
+		require.NoError(t, err)
+		assert.Equal(t, content, data)
+
+		buf := make([]byte, 4)
+		n, err := f.ReadAt(buf, 2)
+		require.NoError(t, err)
+		assert.Equal(t, "2345", string(buf[:n]))
+
+		n, err = f.ReadAt(buf, 8)
+		assert.ErrorIs(t, err, io.EOF)
+		assert.Equal(t, "89", string(buf[:n]))
+
+		pos, err := f.Seek(-3, io.SeekEnd)
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), pos)
+		data, err = io.ReadAll(f)
+		require.NoError(t, err)
+		assert.Equal(t, "789", string(data))
+	})
+
+	t.Run("rar4", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"movie.rar": {Data: buildRAR4Archive(`dir\movie.mkv`, content, 0)},
+		}
+		require.Equal(t, content, readAllWithRardecode(t, fsys, "movie.rar", "dir/movie.mkv"))
+
+		f, err := openRARStoredFile(fsys, "movie.rar", "dir/movie.mkv", int64(len(content)))
+		require.NoError(t, err)
+		defer f.Close()
+
+		data, err := io.ReadAll(f)
+		require.NoError(t, err)
+		assert.Equal(t, content, data)
+	})
+
+	t.Run("size mismatch", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"movie.rar": {Data: buildRAR4Archive("movie.mkv", content, 0)},
+		}
+		_, err := openRARStoredFile(fsys, "movie.rar", "movie.mkv", int64(len(content))+1)
+		assert.ErrorIs(t, err, errRARStoredUnsupported)
+	})
+}
+
+func TestNextRARVolumeName(t *testing.T) {
+	for _, tc := range []struct {
+		name, next string
+	}{
+		{"movie.part01.rar", "movie.part02.rar"},
+		{"movie.part9.rar", "movie.part10.rar"},
+		{"movie.rar", "movie.r00"},
+		{"movie.RAR", "movie.R00"},
+		{"movie.r09", "movie.r10"},
+	} {
+		next, ok := nextRARVolumeName(tc.name)
+		assert.True(t, ok)
+		assert.Equal(t, tc.next, next, tc.name)
+	}
+
+	_, ok := nextRARVolumeName("movie.mkv")
+	assert.False(t, ok)
+}
@@ -0,0 +1,56 @@
+package usenet_pool
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/nwaples/rardecode/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyArchiveFilesCRC(t *testing.T) {
+	prevTimeout := config.Newz.InspectCRCTimeout
+	t.Cleanup(func() {
+		config.Newz.InspectCRCTimeout = prevTimeout
+	})
+	config.Newz.InspectCRCTimeout = time.Minute
+
+	content := []byte("0123456789")
+	corrupt := buildRAR4Archive("movie.mkv", content, 0)
+	// flip a byte of the stored data, leaving the headers intact
+	corrupt[bytes.Index(corrupt, content)] ^= 0xFF
+
+	for _, tc := range []struct {
+		name       string
+		data       []byte
+		crc        NZBContentFileCRC
+		streamable bool
+	}{
+		{"ok", buildRAR4Archive("movie.mkv", content, 0), NZBContentFileCRCOk, true},
+		{"mismatch", corrupt, NZBContentFileCRCMismatch, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			archive := NewRARArchive(fstest.MapFS{"movie.rar": {Data: tc.data}}, "movie.rar")
+			require.NoError(t, archive.Open(""))
+			files, err := archive.GetFiles()
+			require.NoError(t, err)
+
+			entries := []NZBContentFile{newArchiveContentFile(files[0])}
+			verifyArchiveFilesCRC(t.Context(), files, entries)
+			assert.Equal(t, tc.crc, entries[0].CRC)
+			assert.Equal(t, tc.streamable, entries[0].Streamable)
+		})
+	}
+
+	t.Run("mismatch error", func(t *testing.T) {
+		archive := NewRARArchive(fstest.MapFS{"movie.rar": {Data: corrupt}}, "movie.rar")
+		require.NoError(t, archive.Open(""))
+		files, err := archive.GetFiles()
+		require.NoError(t, err)
+		assert.ErrorIs(t, files[0].(*UsenetRARFile).VerifyCRC(t.Context()), rardecode.ErrBadFileChecksum)
+	})
+}
@@ -0,0 +1,197 @@
+package usenet_pool
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"regexp"
+
+	"github.com/spf13/afero"
+)
+
+var (
+	_ Archive     = (*ZIPArchive)(nil)
+	_ ArchiveFile = (*UsenetZIPFile)(nil)
+
+	_ decompressibleFile = (*UsenetZIPFile)(nil)
+)
+
+// compressed entries up to this size are inflated in memory to be served,
+// e.g. the pages of a comic book archive.
+const zipInflateMaxSize = 32 * 1024 * 1024
+
+const zipFlagEncrypted = 0x1
+
+type ZIPArchive struct {
+	fs    afero.Fs
+	name  string
+	f     afero.File
+	r     *zip.Reader
+	files []ArchiveFile
+}
+
+func (uza *ZIPArchive) Open(password string) error {
+	f, err := uza.fs.Open(uza.name)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r, err := zip.NewReader(f, fi.Size())
+	if err != nil {
+		f.Close()
+		return wrapArchiveOpenError(err)
+	}
+	uza.f = f
+	uza.r = r
+	return nil
+}
+
+func (uza *ZIPArchive) Close() error {
+	var errs []error
+	if uza.f != nil {
+		if err := uza.f.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		uza.f = nil
+		uza.r = nil
+	}
+	if c, ok := uza.fs.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (uza *ZIPArchive) GetFiles() ([]ArchiveFile, error) {
+	if uza.files == nil {
+		files := []ArchiveFile{}
+		for _, entry := range uza.r.File {
+			if entry.FileInfo().IsDir() {
+				continue
+			}
+			files = append(files, &UsenetZIPFile{
+				a:     uza,
+				entry: entry,
+			})
+		}
+		uza.files = files
+	}
+	return uza.files, nil
+}
+
+// IsStreamable is always true, ZIP entries are compressed independently.
+func (uza *ZIPArchive) IsStreamable() bool {
+	return true
+}
+
+type UsenetZIPFile struct {
+	a     *ZIPArchive
+	entry *zip.File
+}
+
+func (f *UsenetZIPFile) Name() string {
+	return f.entry.Name
+}
+
+func (f *UsenetZIPFile) Size() int64 {
+	return int64(f.entry.UncompressedSize64)
+}
+
+func (f *UsenetZIPFile) PackedSize() int64 {
+	return int64(f.entry.CompressedSize64)
+}
+
+func (f *UsenetZIPFile) isStored() bool {
+	return f.entry.Method == zip.Store
+}
+
+func (f *UsenetZIPFile) IsStreamable() bool {
+	if f.entry.Flags&zipFlagEncrypted != 0 {
+		return false
+	}
+	return f.isStored() || f.Size() <= zipInflateMaxSize
+}
+
+func (f *UsenetZIPFile) Open() (io.ReadSeekCloser, error) {
+	if f.isStored() {
+		offset, err := f.entry.DataOffset()
+		if err != nil {
+			return nil, err
+		}
+		return &zipEntryFile{io.NewSectionReader(f.a.f, offset, f.Size())}, nil
+	}
+
+	r, err := f.entry.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(io.LimitReader(r, zipInflateMaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > zipInflateMaxSize {
+		return nil, errors.New("zip: entry too large to inflate: " + f.Name())
+	}
+	return &zipEntryFile{bytes.NewReader(data)}, nil
+}
+
+func (f *UsenetZIPFile) canDecompress() bool {
+	return f.entry.Method == zip.Deflate && f.entry.Flags&zipFlagEncrypted == 0
+}
+
+func (f *UsenetZIPFile) openDecompressed() (io.ReadCloser, error) {
+	return f.entry.Open()
+}
+
+type zipEntryReader interface {
+	io.ReadSeeker
+	io.ReaderAt
+}
+
+type zipEntryFile struct {
+	zipEntryReader
+}
+
+func (f *zipEntryFile) Close() error {
+	return nil
+}
+
+// .zip, .cbz
+var zipRegex = regexp.MustCompile(`(?i)\.(zip|cbz)$`)
+
+// GetZIPVolumeNumber returns 0 for a ZIP archive, split ZIP archives are not
+// supported.
+func GetZIPVolumeNumber(filename string) int {
+	if zipRegex.MatchString(filename) {
+		return 0
+	}
+	return -1
+}
+
+// NewUsenetZIPArchive opens the largest ZIP archive in the NZB.
+func NewUsenetZIPArchive(ufs *UsenetFS) *ZIPArchive {
+	var name string
+	var size int64
+	for i := range ufs.nzb.Files {
+		file := &ufs.nzb.Files[i]
+		if GetZIPVolumeNumber(file.Name()) == 0 && (name == "" || file.Size() > size) {
+			name, size = file.Name(), file.Size()
+		}
+	}
+
+	return &ZIPArchive{
+		fs:   ufs.toAfero(),
+		name: name,
+	}
+}
+
+func NewZIPArchive(fs afero.Fs, name string) *ZIPArchive {
+	return &ZIPArchive{fs: fs, name: name}
+}
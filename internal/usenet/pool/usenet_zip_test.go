@@ -0,0 +1,85 @@
+package usenet_pool
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type zipTestEntry struct {
+	name   string
+	method uint16
+	data   []byte
+}
+
+func buildZIPArchive(t *testing.T, entries ...zipTestEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: entry.name, Method: entry.method})
+		require.NoError(t, err)
+		_, err = w.Write(entry.data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestZIPArchive(t *testing.T) {
+	data := map[string][]byte{
+		"page01.jpg": bytes.Repeat([]byte("jpeg"), 256),
+		"page02.png": bytes.Repeat([]byte("png"), 256),
+	}
+	archiveData := buildZIPArchive(t,
+		zipTestEntry{"page01.jpg", zip.Store, data["page01.jpg"]},
+		zipTestEntry{"page02.png", zip.Deflate, data["page02.png"]},
+	)
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "comic.cbz", archiveData, 0644))
+
+	archive := NewZIPArchive(fs, "comic.cbz")
+	require.NoError(t, archive.Open(""))
+	defer archive.Close()
+	assert.True(t, archive.IsStreamable())
+
+	files, err := archive.GetFiles()
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	for _, f := range files {
+		assert.True(t, f.IsStreamable(), f.Name())
+		assert.Equal(t, int64(len(data[f.Name()])), f.Size())
+
+		r, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, data[f.Name()], content, f.Name())
+
+		_, err = r.Seek(4, io.SeekStart)
+		require.NoError(t, err)
+		content, err = io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, data[f.Name()][4:], content, f.Name())
+		require.NoError(t, r.Close())
+	}
+
+	stream, err := (&Pool{}).streamTargetFromArchive(archive, []string{"page02.png"}, FileTypeZIP, VideoSelectLargest, false)
+	require.NoError(t, err)
+	defer stream.Close()
+	assert.Equal(t, "image/png", stream.ContentType)
+	assert.Equal(t, int64(len(data["page02.png"])), stream.Size)
+}
+
+func TestGetZIPVolumeNumber(t *testing.T) {
+	assert.Equal(t, 0, GetZIPVolumeNumber("archive.zip"))
+	assert.Equal(t, 0, GetZIPVolumeNumber("comic.CBZ"))
+	assert.Equal(t, -1, GetZIPVolumeNumber("archive.rar"))
+}
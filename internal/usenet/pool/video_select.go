@@ -0,0 +1,98 @@
+package usenet_pool
+
+import (
+	"cmp"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/util"
+)
+
+// VideoSelect is the strategy for picking the video to stream from an
+// archive with multiple videos.
+type VideoSelect string
+
+const (
+	VideoSelectLargest VideoSelect = "largest"
+	VideoSelectName    VideoSelect = "name"
+	VideoSelectRuntime VideoSelect = "runtime"
+)
+
+func (c *StreamConfig) getVideoSelect() VideoSelect {
+	if c.VideoSelect != "" {
+		return c.VideoSelect
+	}
+	return VideoSelect(config.Newz.VideoSelect)
+}
+
+// getVideoRuntime returns the runtime of the video, or 0 if not known.
+func getVideoRuntime(f ArchiveFile) time.Duration {
+	if !f.IsStreamable() || f.Size() <= 0 || !strings.EqualFold(filepath.Ext(f.Name()), ".mkv") {
+		return 0
+	}
+	r, err := f.Open()
+	if err != nil {
+		return 0
+	}
+	defer r.Close()
+	duration, err := ReadMKVDuration(r, f.Size())
+	if err != nil {
+		return 0
+	}
+	return duration
+}
+
+// getVideoMinSize returns the size below which a video is treated as a
+// sample, for content of the given total size.
+func getVideoMinSize(totalSize int64) int64 {
+	return config.Newz.VideoMinSize.Get(totalSize)
+}
+
+// sortVideos orders the videos by preference for the given strategy, with the
+// ones smaller than minSize last.
+func sortVideos(videos []ArchiveFile, videoSelect VideoSelect, minSize int64) []ArchiveFile {
+	videos = slices.Clone(videos)
+	bySize := func(a, b ArchiveFile) int {
+		return cmp.Compare(b.Size(), a.Size())
+	}
+
+	switch videoSelect {
+	case VideoSelectName:
+		slices.SortStableFunc(videos, func(a, b ArchiveFile) int {
+			return util.CompareNatural(a.Name(), b.Name())
+		})
+	case VideoSelectRuntime:
+		type video struct {
+			file    ArchiveFile
+			runtime time.Duration
+		}
+		items := make([]video, len(videos))
+		for i, f := range videos {
+			items[i] = video{file: f, runtime: getVideoRuntime(f)}
+		}
+		slices.SortStableFunc(items, func(a, b video) int {
+			return cmp.Or(cmp.Compare(b.runtime, a.runtime), bySize(a.file, b.file))
+		})
+		for i := range items {
+			videos[i] = items[i].file
+		}
+	default:
+		slices.SortStableFunc(videos, bySize)
+	}
+
+	if minSize > 0 {
+		undersized := func(f ArchiveFile) int {
+			if f.Size() < minSize {
+				return 1
+			}
+			return 0
+		}
+		slices.SortStableFunc(videos, func(a, b ArchiveFile) int {
+			return cmp.Compare(undersized(a), undersized(b))
+		})
+	}
+	return videos
+}
@@ -0,0 +1,26 @@
+package usenet_pool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortVideos(t *testing.T) {
+	videos := []ArchiveFile{
+		&testArchiveFile{name: "Show.E10.mkv", size: 100},
+		&testArchiveFile{name: "Show.E1.sample.mkv", size: 5},
+		&testArchiveFile{name: "Show.E2.mkv", size: 80},
+	}
+	names := func(videos []ArchiveFile) []string {
+		result := make([]string, len(videos))
+		for i, f := range videos {
+			result[i] = f.Name()
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"Show.E1.sample.mkv", "Show.E2.mkv", "Show.E10.mkv"}, names(sortVideos(videos, VideoSelectName, 0)))
+	assert.Equal(t, []string{"Show.E2.mkv", "Show.E10.mkv", "Show.E1.sample.mkv"}, names(sortVideos(videos, VideoSelectName, 10)))
+	assert.Equal(t, []string{"Show.E10.mkv", "Show.E2.mkv", "Show.E1.sample.mkv"}, names(sortVideos(videos, VideoSelectLargest, 10)))
+}
@@ -1,6 +1,8 @@
 package usenet_pool
 
 import (
+	"errors"
+	"fmt"
 	"io"
 
 	"github.com/MunifTanjim/stremthru/internal/logger"
@@ -15,6 +17,8 @@ var yencLog = logger.Scoped("usenet/pool/yenc")
 
 const yencBufferSize = 32 * 1024
 
+var ErrInvalidSegmentPlacement = errors.New("usenet: invalid segment placement")
+
 type YEncHeader struct{ rapidyenc.DecodedMeta }
 
 func (h *YEncHeader) ByteRange() ByteRange {
@@ -24,6 +28,19 @@ func (h *YEncHeader) ByteRange() ByteRange {
 	}
 }
 
+// validatePlacement checks the range from `=ypart begin= end=` of a multipart
+// segment against the file size, since the segment is placed in the file by
+// it. A single part segment, without `=ypart`, is the whole file.
+func (h *YEncHeader) validatePlacement() error {
+	if h.PartNumber == 0 {
+		return nil
+	}
+	if h.Offset < 0 || h.PartSize < 0 || (h.FileSize > 0 && h.End() > h.FileSize) {
+		return fmt.Errorf("%w: part %d at [%d, %d) of %d bytes", ErrInvalidSegmentPlacement, h.PartNumber, h.Offset, h.End(), h.FileSize)
+	}
+	return nil
+}
+
 type prependReader struct {
 	prepended []byte
 	reader    io.Reader
@@ -117,6 +134,12 @@ func (d *YEncDecodedData) ToSegmentData() SegmentData {
 }
 
 func (d *YEncDecoder) ReadAll() (*YEncDecodedData, error) {
+	return d.ReadAllMax(0)
+}
+
+// ReadAllMax is like ReadAll, but stops decoding with ErrSegmentTooLarge as
+// soon as the body grows past maxSize. A maxSize of 0 means unlimited.
+func (d *YEncDecoder) ReadAllMax(maxSize int64) (*YEncDecodedData, error) {
 	yencLog.Trace("yenc - read all started")
 
 	header, err := d.Header()
@@ -124,12 +147,19 @@ func (d *YEncDecoder) ReadAll() (*YEncDecodedData, error) {
 		return nil, err
 	}
 
-	body := make([]byte, 0, header.PartSize)
+	capacity := header.PartSize
+	if maxSize > 0 {
+		capacity = min(capacity, maxSize)
+	}
+	body := make([]byte, 0, max(capacity, 0))
 
 	buf := make([]byte, yencBufferSize)
 	for {
 		n, err := d.Read(buf)
 		if n > 0 {
+			if maxSize > 0 && int64(len(body)+n) > maxSize {
+				return nil, fmt.Errorf("%w: exceeds %d bytes", ErrSegmentTooLarge, maxSize)
+			}
 			body = append(body, buf[:n]...)
 		}
 		if err == io.EOF {
@@ -142,6 +172,11 @@ func (d *YEncDecoder) ReadAll() (*YEncDecodedData, error) {
 
 	yencLog.Trace("yenc - read all done", "decoded_size", len(body))
 
+	// the part size is final only after `=yend`
+	if err := header.validatePlacement(); err != nil {
+		return nil, err
+	}
+
 	return &YEncDecodedData{
 		header: header,
 		body:   body,
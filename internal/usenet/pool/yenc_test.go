@@ -100,6 +100,20 @@ func TestYencDecoder(t *testing.T) {
 		assert.Equal(t, int64(100), header2.End())
 	})
 
+	t.Run("PlacementOutsideFile", func(t *testing.T) {
+		data := makeTestBytes(50)
+
+		// Part 2: bytes 51-100 (1-based) of a 80 bytes file
+		encoded := encodeYenc(data, "test.bin", 2, 2, 80, 51)
+		_, err := NewYEncDecoder(bytes.NewReader(encoded)).ReadAllMax(0)
+		assert.ErrorIs(t, err, ErrInvalidSegmentPlacement)
+
+		encoded = encodeYenc(data, "test.bin", 2, 2, 100, 51)
+		decoded, err := NewYEncDecoder(bytes.NewReader(encoded)).ReadAllMax(0)
+		require.NoError(t, err)
+		assert.Equal(t, ByteRange{Start: 50, End: 100}, decoded.ToSegmentData().ByteRange)
+	})
+
 	t.Run("ByteRangeConversion", func(t *testing.T) {
 		header := &YEncHeader{}
 		header.Offset = 0
@@ -129,4 +143,15 @@ func TestYencDecoder(t *testing.T) {
 		assert.Equal(t, 0, n)
 		assert.Equal(t, io.EOF, err)
 	})
+	t.Run("ReadAllMax", func(t *testing.T) {
+		data := makeTestBytes(100 * 1024)
+		encoded := encodeYenc(data, "test.bin", 1, 1, int64(len(data)), 1)
+
+		decoded, err := NewYEncDecoder(bytes.NewReader(encoded)).ReadAllMax(int64(len(data)))
+		require.NoError(t, err)
+		assert.Equal(t, data, decoded.body)
+
+		_, err = NewYEncDecoder(bytes.NewReader(encoded)).ReadAllMax(64 * 1024)
+		assert.ErrorIs(t, err, ErrSegmentTooLarge)
+	})
 }
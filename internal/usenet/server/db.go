@@ -39,6 +39,7 @@ type UsenetServer struct {
 	TLSSkipVerify  bool
 	Priority       int
 	IsBackup       bool
+	Weight         int
 	MaxConnections int
 	Disabled       bool
 	CAt            db.Timestamp
@@ -49,7 +50,7 @@ func (s *UsenetServer) ProviderId() string {
 	return s.Host + ":" + util.IntToString(s.Port) + ":" + s.Username
 }
 
-func NewUsenetServer(name, host string, port int, username, password string, tls, tlsSkipVerify bool, priority int, isBackup bool, maxConnections int) (*UsenetServer, error) {
+func NewUsenetServer(name, host string, port int, username, password string, tls, tlsSkipVerify bool, priority int, isBackup bool, weight int, maxConnections int) (*UsenetServer, error) {
 	server := &UsenetServer{
 		Id:             xid.New().String(),
 		Name:           name,
@@ -60,6 +61,7 @@ func NewUsenetServer(name, host string, port int, username, password string, tls
 		TLSSkipVerify:  tlsSkipVerify,
 		Priority:       priority,
 		IsBackup:       isBackup,
+		Weight:         weight,
 		MaxConnections: maxConnections,
 	}
 	err := server.SetPassword(password)
@@ -93,6 +95,7 @@ var Column = struct {
 	TLSSkipVerify  string
 	Priority       string
 	IsBackup       string
+	Weight         string
 	MaxConnections string
 	Disabled       string
 	CAt            string
@@ -108,6 +111,7 @@ var Column = struct {
 	TLSSkipVerify:  "tls_skip_verify",
 	Priority:       "priority",
 	IsBackup:       "is_backup",
+	Weight:         "weight",
 	MaxConnections: "max_conn",
 	Disabled:       "disabled",
 	CAt:            "cat",
@@ -125,6 +129,7 @@ var columns = []string{
 	Column.TLSSkipVerify,
 	Column.Priority,
 	Column.IsBackup,
+	Column.Weight,
 	Column.MaxConnections,
 	Column.Disabled,
 	Column.CAt,
@@ -147,6 +152,7 @@ var query_upsert = fmt.Sprintf(
 		fmt.Sprintf(`%s = EXCLUDED.%s`, Column.TLSSkipVerify, Column.TLSSkipVerify),
 		fmt.Sprintf(`%s = EXCLUDED.%s`, Column.Priority, Column.Priority),
 		fmt.Sprintf(`%s = EXCLUDED.%s`, Column.IsBackup, Column.IsBackup),
+		fmt.Sprintf(`%s = EXCLUDED.%s`, Column.Weight, Column.Weight),
 		fmt.Sprintf(`%s = EXCLUDED.%s`, Column.MaxConnections, Column.MaxConnections),
 		fmt.Sprintf(`%s = EXCLUDED.%s`, Column.Disabled, Column.Disabled),
 		fmt.Sprintf(`%s = %s`, Column.UAt, db.CurrentTimestamp),
@@ -165,6 +171,7 @@ func (s *UsenetServer) Upsert() error {
 		s.TLSSkipVerify,
 		s.Priority,
 		s.IsBackup,
+		s.Weight,
 		s.MaxConnections,
 		s.Disabled,
 	)
@@ -189,7 +196,7 @@ func GetAll() ([]UsenetServer, error) {
 	items := []UsenetServer{}
 	for rows.Next() {
 		item := UsenetServer{}
-		if err := rows.Scan(&item.Id, &item.Name, &item.Host, &item.Port, &item.Username, &item.Password, &item.TLS, &item.TLSSkipVerify, &item.Priority, &item.IsBackup, &item.MaxConnections, &item.Disabled, &item.CAt, &item.UAt); err != nil {
+		if err := rows.Scan(&item.Id, &item.Name, &item.Host, &item.Port, &item.Username, &item.Password, &item.TLS, &item.TLSSkipVerify, &item.Priority, &item.IsBackup, &item.Weight, &item.MaxConnections, &item.Disabled, &item.CAt, &item.UAt); err != nil {
 			return nil, err
 		}
 		items = append(items, item)
@@ -216,7 +223,7 @@ func GetAllEnabled() ([]UsenetServer, error) {
 	items := []UsenetServer{}
 	for rows.Next() {
 		item := UsenetServer{}
-		if err := rows.Scan(&item.Id, &item.Name, &item.Host, &item.Port, &item.Username, &item.Password, &item.TLS, &item.TLSSkipVerify, &item.Priority, &item.IsBackup, &item.MaxConnections, &item.Disabled, &item.CAt, &item.UAt); err != nil {
+		if err := rows.Scan(&item.Id, &item.Name, &item.Host, &item.Port, &item.Username, &item.Password, &item.TLS, &item.TLSSkipVerify, &item.Priority, &item.IsBackup, &item.Weight, &item.MaxConnections, &item.Disabled, &item.CAt, &item.UAt); err != nil {
 			return nil, err
 		}
 		items = append(items, item)
@@ -235,7 +242,7 @@ func GetById(id string) (*UsenetServer, error) {
 	row := db.QueryRow(query_get_by_id, id)
 
 	item := UsenetServer{}
-	if err := row.Scan(&item.Id, &item.Name, &item.Host, &item.Port, &item.Username, &item.Password, &item.TLS, &item.TLSSkipVerify, &item.Priority, &item.IsBackup, &item.MaxConnections, &item.Disabled, &item.CAt, &item.UAt); err != nil {
+	if err := row.Scan(&item.Id, &item.Name, &item.Host, &item.Port, &item.Username, &item.Password, &item.TLS, &item.TLSSkipVerify, &item.Priority, &item.IsBackup, &item.Weight, &item.MaxConnections, &item.Disabled, &item.CAt, &item.UAt); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"cmp"
 	"regexp"
 	"strconv"
 	"strings"
@@ -82,3 +83,36 @@ func LevenshteinDistance(a, b string, normalizer *StringNormalizer) int {
 func MaxLevenshteinDistance(distance int, a, b string, normalizer *StringNormalizer) bool {
 	return LevenshteinDistance(a, b, normalizer) < distance
 }
+
+// CompareNatural compares the strings case-insensitively, with the digit
+// runs compared by their numeric value, e.g. 'E2' < 'E10'.
+func CompareNatural(a, b string) int {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	for a != "" && b != "" {
+		aDigits, bDigits := leadingDigits(a), leadingDigits(b)
+		if aDigits != "" && bDigits != "" {
+			aNum, bNum := strings.TrimLeft(aDigits, "0"), strings.TrimLeft(bDigits, "0")
+			if c := cmp.Compare(len(aNum), len(bNum)); c != 0 {
+				return c
+			}
+			if c := strings.Compare(aNum, bNum); c != 0 {
+				return c
+			}
+			a, b = a[len(aDigits):], b[len(bDigits):]
+			continue
+		}
+		if a[0] != b[0] {
+			return cmp.Compare(a[0], b[0])
+		}
+		a, b = a[1:], b[1:]
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+func leadingDigits(s string) string {
+	i := 0
+	for i < len(s) && unicode.IsDigit(rune(s[i])) {
+		i++
+	}
+	return s[:i]
+}
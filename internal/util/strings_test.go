@@ -1,6 +1,7 @@
 package util
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -21,3 +22,9 @@ func TestStringNormalizer(t *testing.T) {
 		assert.Equal(t, tc.out, n.Normalize(tc.in))
 	}
 }
+
+func TestCompareNatural(t *testing.T) {
+	names := []string{"Show.E10.mkv", "show.e02.mkv", "Show.E1.mkv", "Show.E2.extra.mkv", "Show.mkv"}
+	slices.SortStableFunc(names, CompareNatural)
+	assert.Equal(t, []string{"Show.E1.mkv", "Show.E2.extra.mkv", "show.e02.mkv", "Show.E10.mkv", "Show.mkv"}, names)
+}
@@ -0,0 +1,8 @@
+package migrations
+
+import "embed"
+
+// FS holds the schema migrations, under a directory per dialect.
+//
+//go:embed sqlite/*.sql postgres/*.sql
+var FS embed.FS
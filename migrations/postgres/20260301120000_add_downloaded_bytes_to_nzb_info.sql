@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "public"."nzb_info" ADD COLUMN "downloaded_bytes" bigint NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "public"."nzb_info" DROP COLUMN IF EXISTS "downloaded_bytes";
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "public"."usenet_server"
+  ADD COLUMN "weight" integer NOT NULL DEFAULT 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "public"."usenet_server"
+  DROP COLUMN IF EXISTS "weight";
+-- +goose StatementEnd
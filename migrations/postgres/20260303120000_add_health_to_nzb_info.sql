@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "public"."nzb_info" ADD COLUMN "health_sampled" integer NOT NULL DEFAULT 0;
+ALTER TABLE "public"."nzb_info" ADD COLUMN "health_failed" integer NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "public"."nzb_info" DROP COLUMN IF EXISTS "health_failed";
+ALTER TABLE "public"."nzb_info" DROP COLUMN IF EXISTS "health_sampled";
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `nzb_info` ADD COLUMN `downloaded_bytes` int NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE `nzb_info` DROP COLUMN `downloaded_bytes`;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `usenet_server`
+  ADD COLUMN `weight` int NOT NULL DEFAULT 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE `usenet_server`
+  DROP COLUMN `weight`;
+-- +goose StatementEnd
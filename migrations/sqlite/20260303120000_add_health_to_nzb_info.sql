@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `nzb_info` ADD COLUMN `health_sampled` int NOT NULL DEFAULT 0;
+ALTER TABLE `nzb_info` ADD COLUMN `health_failed` int NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE `nzb_info` DROP COLUMN `health_failed`;
+ALTER TABLE `nzb_info` DROP COLUMN `health_sampled`;
+-- +goose StatementEnd
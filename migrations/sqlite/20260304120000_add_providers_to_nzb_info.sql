@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `nzb_info` ADD COLUMN `providers` jsonb NOT NULL DEFAULT '[]';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE `nzb_info` DROP COLUMN `providers`;
+-- +goose StatementEnd
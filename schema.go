@@ -1,21 +1,18 @@
 package main
 
 import (
-	"embed"
 	"log"
 	"os"
 
 	"github.com/MunifTanjim/stremthru/internal/db"
+	"github.com/MunifTanjim/stremthru/migrations"
 	"github.com/pressly/goose/v3"
 )
 
-//go:embed migrations/**/*.sql
-var migrationsFS embed.FS
-
 func RunSchemaMigration(uri db.ConnectionURI, database *db.DB) {
 	l := log.New(os.Stderr, "=", 0)
 
-	goose.SetBaseFS(migrationsFS)
+	goose.SetBaseFS(migrations.FS)
 	goose.SetTableName("db_migration_version")
 	goose.SetLogger(log.New(os.Stderr, "=   ", 0))
 
@@ -23,10 +20,10 @@ func RunSchemaMigration(uri db.ConnectionURI, database *db.DB) {
 	switch uri.Dialect {
 	case db.DBDialectSQLite:
 		goose.SetDialect("sqlite")
-		dir = "migrations/sqlite"
+		dir = "sqlite"
 	case db.DBDialectPostgres:
 		goose.SetDialect("postgres")
-		dir = "migrations/postgres"
+		dir = "postgres"
 	}
 
 	lock := db.NewAdvisoryLock("goose", "migration")
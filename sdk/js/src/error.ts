@@ -1,13 +1,20 @@
 export type ErrorCode =
+  | "ARCHIVE_SOLID"
+  | "ARTICLE_MISSING"
   | "BAD_GATEWAY"
   | "BAD_REQUEST"
   | "CONFLICT"
+  | "EMPTY_FILE"
+  | "FETCH_TIMEOUT"
+  | "FILE_TOO_LARGE"
   | "FORBIDDEN"
   | "GONE"
   | "INTERNAL_SERVER_ERROR"
   | "METHOD_NOT_ALLOWED"
   | "NOT_FOUND"
   | "NOT_IMPLEMENTED"
+  | "NO_PROVIDERS"
+  | "PASSWORD_REQUIRED"
   | "PAYMENT_REQUIRED"
   | "PROXY_AUTHENTICATION_REQUIRED"
   | "SERVICE_UNAVAILABLE"
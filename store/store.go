@@ -328,10 +328,19 @@ const (
 	NewzStatusProcessing  NewzStatus = "processing"
 	NewzStatusDownloaded  NewzStatus = "downloaded"
 	NewzStatusFailed      NewzStatus = "failed"
+	NewzStatusCancelled   NewzStatus = "cancelled"
 	NewzStatusInvalid     NewzStatus = "invalid"
 	NewzStatusUnknown     NewzStatus = "unknown"
 )
 
+func (s NewzStatus) IsValid() bool {
+	switch s {
+	case NewzStatusCached, NewzStatusQueued, NewzStatusDownloading, NewzStatusProcessing, NewzStatusDownloaded, NewzStatusFailed, NewzStatusCancelled, NewzStatusInvalid, NewzStatusUnknown:
+		return true
+	}
+	return false
+}
+
 type CheckNewzParams struct {
 	Ctx
 	Hashes []string
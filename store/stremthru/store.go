@@ -447,15 +447,24 @@ func (c *StoreClient) GenerateNewzLink(params *store.GenerateNewzLinkParams) (*s
 		return nil, notFoundError()
 	}
 
-	encLink, err := core.Encrypt(ba.Password, params.Link)
+	link, err := createNewzStreamLink(ba.Username, ba.Password, params.Link, file.Name)
 	if err != nil {
 		return nil, err
 	}
 
+	return &store.GenerateNewzLinkData{Link: link}, nil
+}
+
+func createNewzStreamLink(user, password, lockedLink, filename string) (string, error) {
+	encLink, err := core.Encrypt(password, lockedLink)
+	if err != nil {
+		return "", err
+	}
+
 	claims := core.JWTClaims[newzStreamTokenData]{
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    "stremthru",
-			Subject:   ba.Username,
+			Subject:   user,
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(12 * time.Hour)),
 		},
 		Data: &newzStreamTokenData{
@@ -463,14 +472,23 @@ func (c *StoreClient) GenerateNewzLink(params *store.GenerateNewzLinkParams) (*s
 			EncFormat: core.EncryptionFormat,
 		},
 	}
-	token, err := core.CreateJWT(ba.Password, claims)
+	token, err := core.CreateJWT(password, claims)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	link := config.BaseURL.JoinPath("/v0/store/newz/stream", token, file.Name).String()
+	return config.BaseURL.JoinPath("/v0/store/newz/stream", token, filename).String(), nil
+}
 
-	return &store.GenerateNewzLinkData{Link: link}, nil
+// CreateNewzStreamLink creates a tokenized stream link for the file at the
+// content path of the newz, signed with the user's credentials. Unlike the
+// dash endpoints, it needs no session, so external players can open it.
+func CreateNewzStreamLink(user, id, path, filename string) (string, error) {
+	password := config.Auth.GetPassword(user)
+	if password == "" {
+		return "", errors.New("missing credentials for user: " + user)
+	}
+	return createNewzStreamLink(user, password, LockedFileLink("").Create(id, path), filename)
 }
 
 type unwrappedNewzStreamTokenData struct {